@@ -2,200 +2,263 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
 	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
-	"math/big"
 	"os"
+	"os/signal"
 	"sort"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
 	"github.com/primevprotocol/validator-registry/pkg/mevcommitavs"
 	"github.com/primevprotocol/validator-registry/pkg/mevcommitmiddleware"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
 	"github.com/primevprotocol/validator-registry/pkg/validatoroptinrouter"
 	"github.com/primevprotocol/validator-registry/pkg/vanillaregistry"
 )
 
-type optedInValidator struct {
-	pubKey         []byte
-	optInType      string
-	optInBlock     uint64
-	podOwner       common.Address
-	vault          common.Address
-	operator       common.Address
-	withdrawalAddr common.Address
-}
+var (
+	mevCommitAVSAddress        = common.HexToAddress("0xBc77233855e3274E1903771675Eb71E602D9DC2e")
+	mevCommitMiddlewareAddress = common.HexToAddress("0x21fD239311B050bbeE7F32850d99ADc224761382")
+	vanillaRegistryAddress     = common.HexToAddress("0x47afdcB2B089C16CEe354811EA1Bbe0DB7c335E9")
+)
 
 func main() {
+	groupByType := flag.Bool("group-by-type", false, "also write one CSV per opt-in source with only the columns relevant to it")
+	watch := flag.Bool("watch", false, "after exporting, subscribe to new opt-ins and append them to opted_in_validators.csv until interrupted")
+	wsURL := flag.String("ws-url", "wss://ethereum-rpc.publicnode.com", "websocket RPC endpoint --watch subscribes through")
+	outDir := flag.String("out-dir", ".", cliutil.OutDirUsage)
+	since := flag.String("since", "", cliutil.SinceUsage)
+	cursorFile := flag.String("cursor", "opted_in_validators.cursor", "file holding the last scanned block; later runs resume after it and append to the existing CSV")
+	full := flag.Bool("full", false, "ignore the cursor, rescanning from the deployment block and regenerating the CSV")
+	sqlitePath := flag.String("sqlite", "", "if set, also write the opted in validators to this SQLite database, indexed by pubkey and opt-in type")
+	strict := flag.Bool("strict", false, "exit with an error if the router doesn't report every collected validator as opted in")
+	concurrency := flag.Int("concurrency", 1, "number of router calls in flight at once during the sanity check")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
 
-	client, err := ethclient.Dial("https://ethereum-rpc.publicnode.com")
-	if err != nil {
-		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+	// Watch mode runs until interrupted, so a deadline would only turn a
+	// healthy watcher into a failed run.
+	if *watch && *timeout > 0 {
+		cliutil.Fail(cliutil.ExitConfig, "--timeout can't be combined with --watch")
 	}
 
-	chainID, err := client.ChainID(context.Background())
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	outputPath, err := cliutil.OutPath(*outDir, "opted_in_validators.csv")
 	if err != nil {
-		log.Fatalf("Failed to get chain id: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "%v", err)
 	}
-	if chainID.Cmp(big.NewInt(1)) != 0 {
-		log.Fatalf("Chain ID is not mainnet: %v", chainID)
+	cursorPath, err := cliutil.OutPath(*outDir, *cursorFile)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "%v", err)
 	}
 
-	mevCommitAVSAddress := common.HexToAddress("0xBc77233855e3274E1903771675Eb71E602D9DC2e")
-	avsFilterer, err := mevcommitavs.NewMevcommitavsFilterer(mevCommitAVSAddress, client)
-	if err != nil {
-		log.Fatalf("Failed to create Validator Registry caller: %v", err)
+	var sqliteWriter *optins.SQLiteWriter
+	if *sqlitePath != "" {
+		*sqlitePath, err = cliutil.OutPath(*outDir, *sqlitePath)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "%v", err)
+		}
+		sqliteWriter, err = optins.NewSQLiteWriter(*sqlitePath)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to open SQLite database: %v", err)
+		}
+		defer sqliteWriter.Close()
 	}
 
-	mevCommitMiddlewareAddress := common.HexToAddress("0x21fD239311B050bbeE7F32850d99ADc224761382")
-	middlewareFilterer, err := mevcommitmiddleware.NewMevcommitmiddlewareFilterer(mevCommitMiddlewareAddress, client)
+	client, err := ethclient.Dial("https://ethereum-rpc.publicnode.com")
 	if err != nil {
-		log.Fatalf("Failed to create Validator Registry caller: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to connect to the Ethereum client: %v", err)
 	}
 
-	vanillaRegistryAddress := common.HexToAddress("0x47afdcB2B089C16CEe354811EA1Bbe0DB7c335E9")
-	vanillaFilterer, err := vanillaregistry.NewVanillaregistryFilterer(vanillaRegistryAddress, client)
-	if err != nil {
-		log.Fatalf("Failed to create Validator Registry caller: %v", err)
+	if err := utils.EnsureChainID(ctx, client, config.Mainnet.ChainID); err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Chain ID is not mainnet: %v", err)
 	}
 
 	validatorOptInRouterAddress := common.HexToAddress("0x821798d7b9d57dF7Ed7616ef9111A616aB19ed64")
 	routerCaller, err := validatoroptinrouter.NewValidatoroptinrouterCaller(validatorOptInRouterAddress, client)
 	if err != nil {
-		log.Fatalf("Failed to create Validator Registry caller: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry caller: %v", err)
 	}
 
-	latestBlock, err := client.BlockNumber(context.Background())
+	latestBlock, err := utils.BlockNumberWithRetry(ctx, client)
 	if err != nil {
-		log.Fatalf("Failed to get latest block number: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to get latest block number: %v", err)
 	}
 
-	batchSize := uint64(50000)
 	startBlock := uint64(21162202) // deployment block
-
-	optedInValidators := make([]optedInValidator, 0, 1000)
-
-	for startBlock <= latestBlock {
-		fmt.Printf("Processing blocks %d to %d\n", startBlock, startBlock+batchSize-1)
-		endBlock := startBlock + batchSize - 1
-		if endBlock > latestBlock {
-			endBlock = latestBlock
-		}
-
-		opts := &bind.FilterOpts{
-			Start:   startBlock,
-			End:     &endBlock,
-			Context: context.Background(),
-		}
-
-		events, err := avsFilterer.FilterValidatorRegistered(opts, nil)
+	if *since != "" {
+		sinceBlock, err := cliutil.ResolveStartBlock(ctx, client, *since)
 		if err != nil {
-			log.Fatalf("Failed to filter Validator Registered events for blocks %d to %d: %v", startBlock, endBlock, err)
+			cliutil.Fail(cliutil.ExitConfig, "Failed to resolve --since: %v", err)
 		}
+		startBlock = max(startBlock, sinceBlock)
+	}
 
-		for events.Next() {
-			optedInValidators = append(optedInValidators, optedInValidator{
-				pubKey:     events.Event.ValidatorPubKey,
-				optInType:  "Eigen",
-				optInBlock: events.Event.Raw.BlockNumber,
-				podOwner:   events.Event.PodOwner,
-			})
+	startBlock, incremental, err := resumeStart(cursorPath, startBlock, *full)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read cursor: %v", err)
+	}
+	if incremental {
+		if *groupByType {
+			cliutil.Fail(cliutil.ExitConfig, "--group-by-type regenerates every CSV, rerun with --full")
 		}
+		fmt.Printf("Resuming from block %d per %s\n", startBlock, cursorPath)
+	}
 
-		middlewareEvents, err := middlewareFilterer.FilterValRecordAdded(opts, nil, nil, nil)
-		if err != nil {
-			log.Fatalf("Failed to filter Validator Registered events for blocks %d to %d: %v", startBlock, endBlock, err)
+	collector := newCollector(client)
+	optedInValidators, err := collector.Collect(ctx, startBlock, latestBlock)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "Failed to collect opted in validators: %v", err)
+	}
+	mismatches, err := optins.SanityCheck(ctx, routerCaller, optedInValidators, 50, *concurrency)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "Failed to check if validators are opted in: %v", err)
+	}
+	for _, pubKey := range mismatches {
+		fmt.Printf("Val pubkey %s is not opted in according to the router\n", hex.EncodeToString(pubKey))
+	}
+	if len(mismatches) > 0 && *strict {
+		cliutil.Fail(cliutil.ExitGeneric, "%d collected validators are not opted in according to the router", len(mismatches))
+	}
+	if incremental {
+		appendToCsv(outputPath, optedInValidators)
+	} else {
+		exportToCsv(*outDir, outputPath, optedInValidators, *groupByType)
+	}
+	if sqliteWriter != nil {
+		if err := sqliteWriter.Write(optedInValidators); err != nil {
+			cliutil.Fail(cliutil.ExitGeneric, "Failed to write to SQLite database: %v", err)
 		}
-
-		for middlewareEvents.Next() {
-			optedInValidators = append(optedInValidators, optedInValidator{
-				pubKey:     middlewareEvents.Event.BlsPubkey,
-				optInType:  "Symbiotic",
-				optInBlock: middlewareEvents.Event.Raw.BlockNumber,
-				vault:      middlewareEvents.Event.Vault,
-				operator:   middlewareEvents.Event.Operator,
-			})
+		fmt.Printf("Wrote %d opted in validators to %s\n", len(optedInValidators), *sqlitePath)
+	}
+	// A lagging RPC node can report a latest block behind the cursor, which
+	// must not move back.
+	if startBlock <= latestBlock {
+		if err := utils.WriteBlockCursor(cursorPath, latestBlock); err != nil {
+			cliutil.Fail(cliutil.ExitGeneric, "Failed to save cursor: %v", err)
 		}
+	}
 
-		vanillaEvents, err := vanillaFilterer.FilterStaked(opts, nil, nil)
+	if *watch {
+		wsClient, err := ethclient.DialContext(ctx, *wsURL)
 		if err != nil {
-			log.Fatalf("Failed to filter Validator Registered events for blocks %d to %d: %v", startBlock, endBlock, err)
-		}
-
-		for vanillaEvents.Next() {
-			optedInValidators = append(optedInValidators, optedInValidator{
-				pubKey:         vanillaEvents.Event.ValBLSPubKey,
-				optInType:      "Vanilla",
-				optInBlock:     vanillaEvents.Event.Raw.BlockNumber,
-				withdrawalAddr: vanillaEvents.Event.WithdrawalAddress,
-			})
+			cliutil.Fail(cliutil.ExitRPC, "Failed to connect to %s: %v", *wsURL, err)
 		}
+		defer wsClient.Close()
+		watchForOptIns(ctx, wsClient, newCollector(wsClient), sqliteWriter, outputPath, cursorPath, max(startBlock, latestBlock+1))
+	}
+}
 
-		startBlock = endBlock + 1
+// newCollector binds the opt-in sources through backend.
+func newCollector(backend bind.ContractBackend) *optins.Collector {
+	avsFilterer, err := mevcommitavs.NewMevcommitavsFilterer(mevCommitAVSAddress, backend)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry caller: %v", err)
+	}
+	middlewareFilterer, err := mevcommitmiddleware.NewMevcommitmiddlewareFilterer(mevCommitMiddlewareAddress, backend)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry caller: %v", err)
+	}
+	vanillaFilterer, err := vanillaregistry.NewVanillaregistryFilterer(vanillaRegistryAddress, backend)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry caller: %v", err)
 	}
-	sanityCheckAgainstRouter(optedInValidators, routerCaller)
-	exportToCsv(optedInValidators)
+	return optins.NewCollector(avsFilterer, middlewareFilterer, vanillaFilterer, 50000)
 }
 
-func sanityCheckAgainstRouter(optedInValidators []optedInValidator, routerCaller *validatoroptinrouter.ValidatoroptinrouterCaller) {
-	batchSize := 50
-	for i := 0; i < len(optedInValidators); i += batchSize {
-		end := i + batchSize
-		fmt.Printf("Checking batch %d to %d against router\n", i, end)
-		if end > len(optedInValidators) {
-			end = len(optedInValidators)
-		}
-		batch := make([][]byte, 0)
-		for _, validator := range optedInValidators[i:end] {
-			batch = append(batch, validator.pubKey)
+// watchForOptIns appends opt-ins from new blocks to the CSV at path,
+// and to sqliteWriter if not nil, advancing the cursor at cursorPath, until
+// interrupted.
+func watchForOptIns(ctx context.Context, client optins.BlockNumberer, collector *optins.Collector, sqliteWriter *optins.SQLiteWriter, path, cursorPath string, fromBlock uint64) {
+	writer, err := optins.NewValidatorWriter(path)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitGeneric, "Failed to open CSV file for appending: %v", err)
+	}
+	defer writer.Close()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	fmt.Printf("Watching for new opt-ins from block %d\n", fromBlock)
+	err = collector.Watch(ctx, client, fromBlock, func(validators []optins.Validator, throughBlock uint64) error {
+		if len(validators) > 0 {
+			fmt.Printf("Appending %d new opted in validators through block %d\n", len(validators), throughBlock)
 		}
-		isOptedIn, err := routerCaller.AreValidatorsOptedIn(nil, batch)
-		if err != nil {
-			log.Fatalf("Failed to check if validators are opted in: %v", err)
+		if err := writer.Write(validators); err != nil {
+			return err
 		}
-		for idxValidator := range optedInValidators[i:end] {
-			if isOptedIn[idxValidator].IsAvsOptedIn ||
-				isOptedIn[idxValidator].IsMiddlewareOptedIn ||
-				isOptedIn[idxValidator].IsVanillaOptedIn {
-				// fmt.Printf("Val pubkey %s is opted in\n", hex.EncodeToString(optedInValidators[i+idxValidator].pubKey))
-			} else {
-				panic(fmt.Sprintf("Val pubkey %s is not opted in", hex.EncodeToString(optedInValidators[i+idxValidator].pubKey)))
+		if sqliteWriter != nil {
+			if err := sqliteWriter.Write(validators); err != nil {
+				return err
 			}
 		}
+		return utils.WriteBlockCursor(cursorPath, throughBlock)
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		cliutil.Fail(cliutil.ExitGeneric, "Failed to watch for opt-ins: %v", err)
 	}
 }
 
-func exportToCsv(optedInValidators []optedInValidator) {
-	fmt.Printf("Exporting %d opted in validators to csv\n", len(optedInValidators))
-	csvFile, err := os.Create("opted_in_validators.csv")
+// resumeStart returns the block a run starts scanning from and whether it
+// appends to the existing CSV: the block after the cursor at cursorPath if
+// there is one and full is false, but no earlier than startBlock.
+func resumeStart(cursorPath string, startBlock uint64, full bool) (uint64, bool, error) {
+	if full {
+		return startBlock, false, nil
+	}
+	cursor, ok, err := utils.ReadBlockCursor(cursorPath)
+	if err != nil || !ok {
+		return startBlock, false, err
+	}
+	return max(startBlock, cursor+1), true, nil
+}
+
+// appendToCsv appends the opt-ins found since the last run to the CSV at
+// path.
+func appendToCsv(path string, optedInValidators []optins.Validator) {
+	sort.Slice(optedInValidators, func(i, j int) bool {
+		return optedInValidators[i].OptInBlock < optedInValidators[j].OptInBlock
+	})
+
+	writer, err := optins.NewValidatorWriter(path)
 	if err != nil {
-		log.Fatalf("Failed to create CSV file: %v", err)
+		cliutil.Fail(cliutil.ExitGeneric, "Failed to open CSV file for appending: %v", err)
 	}
-	defer csvFile.Close()
+	defer writer.Close()
+	if err := writer.Write(optedInValidators); err != nil {
+		cliutil.Fail(cliutil.ExitGeneric, "Failed to append to CSV file: %v", err)
+	}
+	fmt.Printf("Appended %d new opted in validators to %s\n", len(optedInValidators), path)
+}
+
+func exportToCsv(outDir, path string, optedInValidators []optins.Validator, groupByType bool) {
+	fmt.Printf("Exporting %d opted in validators to csv\n", len(optedInValidators))
 
 	sort.Slice(optedInValidators, func(i, j int) bool {
-		return optedInValidators[i].optInBlock < optedInValidators[j].optInBlock
+		return optedInValidators[i].OptInBlock < optedInValidators[j].OptInBlock
 	})
 
-	writer := csv.NewWriter(csvFile)
-	writer.Write([]string{"pubKey", "optInBlock", "optInType", "podOwner", "vault", "operator", "withdrawalAddr"})
-	for _, validator := range optedInValidators {
-		writer.Write([]string{
-			hex.EncodeToString(validator.pubKey),
-			fmt.Sprintf("%d", validator.optInBlock),
-			validator.optInType,
-			validator.podOwner.Hex(),
-			validator.vault.Hex(),
-			validator.operator.Hex(),
-			validator.withdrawalAddr.Hex(),
-		})
-	}
-	writer.Flush()
-	if err := writer.Error(); err != nil {
-		log.Fatalf("Failed to write CSV file: %v", err)
+	// The combined CSV is written even with groupByType, as the cursor
+	// saved after this export assumes it holds every opt-in so far.
+	if err := optins.WriteValidatorsFile(path, optedInValidators); err != nil {
+		cliutil.Fail(cliutil.ExitGeneric, "Failed to write CSV file: %v", err)
+	}
+	if groupByType {
+		if err := optins.WriteValidatorsGroupedByType(outDir, optedInValidators); err != nil {
+			cliutil.Fail(cliutil.ExitGeneric, "Failed to write CSV file: %v", err)
+		}
 	}
 	fmt.Printf("Exported %d opted in validators to csv\n", len(optedInValidators))
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+func TestResumeStartFromCursor(t *testing.T) {
+	cursorPath := filepath.Join(t.TempDir(), "opted_in_validators.cursor")
+	if err := utils.WriteBlockCursor(cursorPath, 22000000); err != nil {
+		t.Fatal(err)
+	}
+
+	start, incremental, err := resumeStart(cursorPath, 21162202, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 22000001 || !incremental {
+		t.Errorf("got start %d, incremental %v, want 22000001, true", start, incremental)
+	}
+
+	// --since past the cursor wins.
+	start, _, err = resumeStart(cursorPath, 22500000, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 22500000 {
+		t.Errorf("got start %d with a later start block, want 22500000", start)
+	}
+}
+
+func TestResumeStartWithoutCursor(t *testing.T) {
+	cursorPath := filepath.Join(t.TempDir(), "opted_in_validators.cursor")
+	start, incremental, err := resumeStart(cursorPath, 21162202, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 21162202 || incremental {
+		t.Errorf("got start %d, incremental %v, want 21162202, false", start, incremental)
+	}
+}
+
+func TestResumeStartFullIgnoresCursor(t *testing.T) {
+	cursorPath := filepath.Join(t.TempDir(), "opted_in_validators.cursor")
+	if err := utils.WriteBlockCursor(cursorPath, 22000000); err != nil {
+		t.Fatal(err)
+	}
+	start, incremental, err := resumeStart(cursorPath, 21162202, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 21162202 || incremental {
+		t.Errorf("got start %d, incremental %v, want 21162202, false", start, incremental)
+	}
+}
+
+func TestExportGroupedByTypeWritesCombinedCsv(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "opted_in_validators.csv")
+	validators := []optins.Validator{
+		{PubKey: strings.Repeat("bb", 48), OptInType: optins.OptInTypeVanilla, OptInBlock: 20},
+		{PubKey: strings.Repeat("aa", 48), OptInType: optins.OptInTypeEigen, OptInBlock: 10},
+	}
+
+	exportToCsv(dir, path, validators, true)
+
+	written, err := optins.ReadValidatorsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 2 {
+		t.Errorf("combined CSV holds %d validators, want 2", len(written))
+	}
+	for _, name := range optins.GroupedFileNames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("grouped CSV %s not written: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/points"
+	"github.com/primevprotocol/validator-registry/pkg/query"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+	"github.com/primevprotocol/validator-registry/pkg/validatoroptinrouter"
+)
+
+func main() {
+	ledgerPath := flag.String("ledger", "../manual-points/posted_manual_entries.txt", "ledger of pubkeys credited by manual-points")
+	batchSize := flag.Int("batch-size", 50, "number of pubkeys per router call")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	credited, err := points.ReadLedger(*ledgerPath)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read points ledger: %v", err)
+	}
+	fmt.Printf("Auditing %d credited pubkeys from %s\n", len(credited), *ledgerPath)
+
+	client, err := config.Mainnet.Dial()
+	if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "Failed to connect to the Ethereum client: %v", err)
+	}
+	if err := utils.EnsureChainID(ctx, client, config.Mainnet.ChainID); err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Chain ID is not mainnet: %v", err)
+	}
+
+	validatorOptInRouterAddress := common.HexToAddress("0x821798d7b9d57dF7Ed7616ef9111A616aB19ed64")
+	routerCaller, err := validatoroptinrouter.NewValidatoroptinrouterCaller(validatorOptInRouterAddress, client)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Opt-In Router caller: %v", err)
+	}
+
+	notOptedIn, err := auditCredited(ctx, routerCaller, credited, *batchSize)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "Failed to audit credited pubkeys: %v", err)
+	}
+	for _, pubkey := range notOptedIn {
+		fmt.Printf("Val pubkey %s is credited points but not opted in according to the router\n", pubkey)
+	}
+	if len(notOptedIn) > 0 {
+		cliutil.Fail(cliutil.ExitGeneric, "%d of %d credited pubkeys are not opted in", len(notOptedIn), len(credited))
+	}
+	fmt.Printf("All %d credited pubkeys are opted in\n", len(credited))
+}
+
+// auditCredited returns the credited pubkeys the router does not report as
+// opted in.
+func auditCredited(ctx context.Context, router optins.RouterCaller, credited []string, batchSize int) ([]string, error) {
+	pubkeys := make([][]byte, 0, len(credited))
+	for _, pubkey := range credited {
+		b, err := hex.DecodeString(pubkey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pubkey %q: %w", pubkey, err)
+		}
+		pubkeys = append(pubkeys, b)
+	}
+
+	statuses, err := query.OptedInStatus(ctx, router, pubkeys, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	notOptedIn := []string{}
+	for i, status := range statuses {
+		if !optins.IsOptedIn(status) {
+			notOptedIn = append(notOptedIn, credited[i])
+		}
+	}
+	return notOptedIn, nil
+}
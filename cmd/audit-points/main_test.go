@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/primevprotocol/validator-registry/pkg/points"
+	"github.com/primevprotocol/validator-registry/pkg/validatoroptinrouter"
+)
+
+// middlewareRouter reports the pubkeys it holds as opted in through the
+// middleware.
+type middlewareRouter map[string]bool
+
+func (r middlewareRouter) AreValidatorsOptedIn(_ *bind.CallOpts, pubKeys [][]byte) ([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, error) {
+	statuses := make([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		statuses[i].IsMiddlewareOptedIn = r[string(pubKey)]
+	}
+	return statuses, nil
+}
+
+func TestAuditCreditedReportsLedgerPubkeysNotOptedIn(t *testing.T) {
+	ledger := filepath.Join(t.TempDir(), "posted_manual_entries.txt")
+	if err := os.WriteFile(ledger, []byte("0xAABB\n\nccdd\neeff\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	credited, err := points.ReadLedger(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := middlewareRouter{"\xaa\xbb": true, "\xee\xff": true}
+	notOptedIn, err := auditCredited(context.Background(), router, credited, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notOptedIn) != 1 || notOptedIn[0] != "ccdd" {
+		t.Errorf("got %v not opted in, want [ccdd]", notOptedIn)
+	}
+}
+
+func TestAuditCreditedRejectsInvalidPubkey(t *testing.T) {
+	if _, err := auditCredited(context.Background(), middlewareRouter{}, []string{"zz"}, 50); err == nil {
+		t.Error("got no error for a pubkey that isn't hex")
+	}
+}
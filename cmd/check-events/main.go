@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+)
+
+// check-events is a preflight for migrations: it reports stored events
+// whose pubkey, originator or amount would make a stake batch revert.
+func main() {
+	eventTypeFlag := flag.String("type", events.EventStaked.String(), fmt.Sprintf("stored event type to check, one of %v", events.EventKinds))
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	eventType, err := events.ParseEventKind(*eventTypeFlag)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "%v", err)
+	}
+
+	stored, err := events.ReadEvents(eventType)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read %s events: %v", eventType, err)
+	}
+
+	issues := events.ValidateAll(stored)
+	counts := make(map[events.IssueKind]int)
+	for _, issue := range issues {
+		fmt.Println(issue)
+		counts[issue.Kind]++
+	}
+	for _, kind := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Printf("%s: %d\n", kind, counts[kind])
+	}
+	if len(issues) > 0 {
+		cliutil.Fail(cliutil.ExitGeneric, "Found %d issues in %d %s events", len(issues), len(stored), eventType)
+	}
+	fmt.Printf("All %d %s events are valid\n", len(stored), eventType)
+}
@@ -3,22 +3,25 @@ package main
 import (
 	"context"
 	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"math/big"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
 	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/migrate"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
 	"github.com/primevprotocol/validator-registry/pkg/query"
 	"github.com/primevprotocol/validator-registry/pkg/utils"
 	optinrouter "github.com/primevprotocol/validator-registry/pkg/validatoroptinrouter"
@@ -26,157 +29,341 @@ import (
 	vrv1_aug15 "github.com/primevprotocol/validator-registry/pkg/validatorregistryv1_aug15"
 )
 
-type Batch struct {
-	pubKeys         [][]byte
-	stakeOriginator common.Address
-}
-
 func main() {
+	confirmationWait := flag.Duration("confirmation-wait", 30*time.Second, "max time to wait for the pending nonce to advance after each sub batch (0 disables)")
+	fundingTimeout := flag.Duration("funding-timeout", 0, "if underfunded, wait up to this long for the signing account to be funded instead of exiting")
+	useNonceManager := flag.Bool("nonce-manager", false, "allocate nonces locally instead of re-querying the pending nonce for each sub batch")
+	maxBatches := flag.Int("max-batches", 0, "stop after processing this many batches; 0 processes all")
+	receiptsOut := flag.String("receipts-out", "", "if set, append a JSON line per included DelegateStake tx with its originator, pubkeys, block and status")
+	checkpointPath := flag.String("checkpoint", "holesky_migrate_checkpoint.txt", "file recording completed batches, which later runs skip")
+	stakedSetPath := flag.String("staked-set", "", "file of pubkeys staked by earlier runs, excluded from the plan and appended to after each successful sub batch; disabled if empty")
+	signer := flag.String("signer", os.Getenv("SIGNER_ADDRESS"), "address of the keystore account to sign with; defaults to $SIGNER_ADDRESS, or the only account in the keystore dir")
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	planOut := flag.String("plan-out", "", "write the migration plan (sub batch pubkeys and values per originator, skipped validators) to this JSON file")
+	planIn := flag.String("plan-in", "", "execute the migration plan in this JSON file, as written by --plan-out, instead of building one from the old registry's events")
+	planOnly := flag.Bool("plan-only", false, "build the migration plan, then exit without sending any transaction")
+	waitOptedIn := flag.Duration("wait-opted-in", 0, "after all batches complete, wait up to this long for the router to report every migrated validator as opted in (0 disables)")
+	verifyTotals := flag.Bool("verify-totals", false, "after all batches complete, check the amount staked on the new registry for the plan's validators equals the plan's total value")
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var signerAddress common.Address
+	if *signer != "" {
+		if !common.IsHexAddress(*signer) {
+			cliutil.Fail(cliutil.ExitConfig, "Invalid signer address: %s", *signer)
+		}
+		signerAddress = common.HexToAddress(*signer)
+	}
 
 	keystorePath := os.Getenv("PRIVATE_KEYSTORE_PATH")
 	if keystorePath == "" {
-		log.Fatalf("PRIVATE_KEYSTORE_PATH is not set")
+		cliutil.Fail(cliutil.ExitConfig, "PRIVATE_KEYSTORE_PATH is not set")
 	}
 
 	_, err := os.Stat(keystorePath)
 	if err != nil {
-		log.Fatalf("Failed to stat keystore path: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to stat keystore path: %v", err)
 	}
 
 	keystorePassword := os.Getenv("PRIVATE_KEYSTORE_PASSWORD")
 	if keystorePassword == "" {
-		log.Fatalf("PRIVATE_KEYSTORE_PASSWORD is not set")
+		cliutil.Fail(cliutil.ExitConfig, "PRIVATE_KEYSTORE_PASSWORD is not set")
 	}
 
 	dir := filepath.Dir(keystorePath)
 
 	keystore := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
-	ksAccounts := keystore.Accounts()
-
-	var account accounts.Account
-	if len(ksAccounts) == 0 {
-		log.Fatalf("no accounts in dir: %s", dir)
-	} else {
-		found := false
-		for _, acc := range ksAccounts {
-			if acc.Address == common.HexToAddress("0x4535bd6fF24860b5fd2889857651a85fb3d3C6b1") {
-				account = acc
-				found = true
-				break
-			}
-		}
-		if !found {
-			log.Fatalf("account %s not found in keystore dir: %s", "0x4535bd6fF24860b5fd2889857651a85fb3d3C6b1", dir)
-		}
+	account, err := utils.SelectAccount(keystore, signerAddress)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to select signing account in keystore dir %s: %v", dir, err)
 	}
 
 	if err := keystore.Unlock(account, keystorePassword); err != nil {
-		log.Fatalf("failed to unlock account: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "failed to unlock account: %v", err)
 	}
 
 	client, err := ethclient.Dial("https://ethereum-holesky-rpc.publicnode.com")
 	if err != nil {
-		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to connect to the Ethereum client: %v", err)
 	}
 
-	chainID, err := client.ChainID(context.Background())
-	if err != nil {
-		log.Fatalf("Failed to get chain id: %v", err)
+	chainID := config.Holesky.ChainID
+	if err := utils.EnsureChainID(ctx, client, chainID); err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Refusing to transact on unexpected network: %v", err)
 	}
 	fmt.Println("Chain ID: ", chainID)
 
 	tOpts, err := bind.NewKeyStoreTransactorWithChainID(keystore, account, chainID)
 	if err != nil {
-		log.Fatalf("failed to get auth: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "failed to get auth: %v", err)
 	}
 	tOpts.From = account.Address
 	tOpts.GasLimit = 10000000
 
-	balance, err := client.BalanceAt(context.Background(), account.Address, nil)
-	if err != nil {
-		log.Fatalf("Failed to get account balance: %v", err)
+	minBalance := big.NewInt(params.Ether)
+	err = utils.EnsureMinBalance(ctx, client, account.Address, minBalance)
+	var insufficient *utils.ErrInsufficientBalance
+	if errors.As(err, &insufficient) && *fundingTimeout > 0 {
+		fmt.Printf("%v, waiting up to %s\n", err, *fundingTimeout)
+		fundingCtx, cancel := context.WithTimeout(ctx, *fundingTimeout)
+		err = utils.WaitForMinBalance(fundingCtx, client, account.Address, minBalance, 10*time.Second)
+		cancel()
 	}
-	if balance.Cmp(big.NewInt(1000000000000000000)) == -1 {
-		log.Fatalf("Insufficient balance. Please fund %v with at least 1 ETH", account.Address.Hex())
+	if errors.As(err, &insufficient) {
+		cliutil.Fail(cliutil.ExitInsufficientFunds, "%v", err)
+	} else if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "%v", err)
 	}
 
 	oldValRegAddr := common.HexToAddress("0x5d4fC7B5Aeea4CF4F0Ca6Be09A2F5AaDAd2F2803") // Holesky validator registry 6/13
 	vrf, err := vrv1.NewValidatorregistryv1Filterer(oldValRegAddr, client)
 	if err != nil {
-		log.Fatalf("Failed to create Validator Registry filterer: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry filterer: %v", err)
 	}
 
 	newValRegAddr := common.HexToAddress("0x87D5F694fAD0b6C8aaBCa96277DE09451E277Bcf")
 	vrta15, err := vrv1_aug15.NewValidatorregistryv1Transactor(newValRegAddr, client)
 	if err != nil {
-		log.Fatalf("Failed to create Validator Registry aug15 transactor: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry aug15 transactor: %v", err)
 	}
 
 	optInRouterAddr := common.HexToAddress("0xF3e5E8eB71f821D299EFf0E826a50A95589eD043")
 	vRouter, err := optinrouter.NewValidatoroptinrouterCaller(optInRouterAddr, client)
 	if err != nil {
-		log.Fatalf("Failed to create Validator Registry aug15 caller: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry aug15 caller: %v", err)
 	}
 
-	valRegV1Obtained, err := vRouter.VanillaRegistry(&bind.CallOpts{Context: context.Background()})
+	valRegV1Obtained, err := vRouter.VanillaRegistry(&bind.CallOpts{Context: ctx})
 	if err != nil {
-		log.Fatalf("Failed to get validator registry v1 address from router: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to get validator registry v1 address from router: %v", err)
 	}
 	if valRegV1Obtained != newValRegAddr {
-		log.Fatalf("validator registry v1 address in router doesn't match expected address %v, got %v",
+		cliutil.Fail(cliutil.ExitConfig, "validator registry v1 address in router doesn't match expected address %v, got %v",
 			newValRegAddr.Hex(), valRegV1Obtained.Hex())
 	}
 
-	ec := utils.NewETHClient(client)
-	// ec.CancelPendingTxes(context.Background(), privateKey)
+	// utils.NewETHClient(client).CancelPendingTxes(ctx, privateKey)
+
+	amountPerValidator := new(big.Int)
+	// 0.0001 ether
+	amountPerValidator.SetString("100000000000000", 10)
+
+	cfg := migrate.Config{
+		Registry:           newValRegAddr,
+		SubBatchSize:       migrate.MaxDelegateStakeBatchSize,
+		AmountPerValidator: amountPerValidator,
+		UseNonceManager:    *useNonceManager,
+		ConfirmationWait:   *confirmationWait,
+		ContinueOnRevert:   true,
+		MaxBatches:         *maxBatches,
+	}
+
+	var plan migrate.Plan
+	if *planIn != "" {
+		plan, err = migrate.LoadPlanFile(*planIn)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to load migration plan: %v", err)
+		}
+		fmt.Printf("Loaded migration plan of %d batches staking %s wei from %s\n", len(plan.Batches), plan.TotalValue, *planIn)
+	} else {
+		batches, skipped := batchesFromEvents(ctx, client, vrf, vRouter)
+		plan, err = migrate.NewPlan(batches, skipped, cfg)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to build migration plan: %v", err)
+		}
+	}
+
+	var stakedSet *migrate.StakedSet
+	if *stakedSetPath != "" {
+		stakedSet, err = migrate.OpenStakedSet(*stakedSetPath)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to open staked set: %v", err)
+		}
+		defer stakedSet.Close()
+		skipped := len(plan.Skipped)
+		plan, err = plan.ExcludeStaked(stakedSet)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to exclude staked set from migration plan: %v", err)
+		}
+		fmt.Printf("Excluded %d validators found in the staked set of %d pubkeys\n", len(plan.Skipped)-skipped, stakedSet.Len())
+	}
+
+	if *planOut != "" {
+		if err := migrate.WritePlanFile(*planOut, plan); err != nil {
+			cliutil.Fail(cliutil.ExitGeneric, "Failed to write migration plan: %v", err)
+		}
+		fmt.Printf("Wrote migration plan staking %s wei to %s\n", plan.TotalValue, *planOut)
+	}
+	if *planOnly {
+		fmt.Println("--plan-only set, exiting without sending any transaction")
+		return
+	}
+
+	executor, err := migrate.NewExecutor(client, tOpts, vrta15, cfg)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Invalid migration config: %v", err)
+	}
+	checkpoint, err := migrate.OpenCheckpoint(*checkpointPath)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to open checkpoint: %v", err)
+	}
+	defer checkpoint.Close()
+	executor.SetCheckpoint(checkpoint)
+	if stakedSet != nil {
+		executor.SetStakedSet(stakedSet)
+	}
+	executor.SetOptInRouter(vRouter)
+	if *receiptsOut != "" {
+		records, err := migrate.NewTxRecordWriter(*receiptsOut)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to open receipts file: %v", err)
+		}
+		defer records.Close()
+		executor.SetRecordWriter(records)
+	}
+	result, err := executor.ExecutePlan(ctx, plan)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitCode(err), "Failed to execute migration: %v", err)
+	}
+	for _, f := range result.Failed {
+		revertReason := getRevertReason(ctx, f.Receipt, client)
+		fmt.Printf("Transaction failed. Receipt status: %d, Revert reason: %s\n", f.Receipt.Status, revertReason)
+		fmt.Printf("Stake originator: %s\n", f.StakeOriginator.Hex())
+		fmt.Printf("Number of validators in this batch: %d\n", len(f.PubKeys))
+		for _, pubKey := range f.PubKeys {
+			fmt.Printf("Validator pubkey: %x\n", pubKey)
+		}
+	}
+	for _, u := range result.Unconfirmed {
+		fmt.Printf("Transaction not confirmed, check its outcome on chain. Stake originator: %s\n", u.StakeOriginator.Hex())
+		for _, pubKey := range u.PubKeys {
+			fmt.Printf("Validator pubkey: %x\n", pubKey)
+		}
+	}
+	if len(result.OptedIn) > 0 {
+		fmt.Printf("Dropped %d validators that opted in after the plan was built\n", len(result.OptedIn))
+	}
+	if len(result.Failed) > 0 || len(result.Unconfirmed) > 0 {
+		cliutil.Fail(cliutil.ExitPartialFailure, "%d sub batches failed, %d not confirmed", len(result.Failed), len(result.Unconfirmed))
+	}
+	if result.Remaining > 0 {
+		fmt.Printf("Stopped after %d batches, %d remaining. Rerun to continue.\n", result.Processed, result.Remaining)
+		return
+	}
+	fmt.Println("All batches completed!")
+
+	if *verifyTotals {
+		vrc15, err := vrv1_aug15.NewValidatorregistryv1Caller(newValRegAddr, client)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry aug15 caller: %v", err)
+		}
+		// Validators dropped for opting in were never staked.
+		migrated, err := plan.Without(result.OptedIn)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Invalid migration plan: %v", err)
+		}
+		onChain, expected, err := migrate.VerifyTotals(ctx, vrc15, migrated)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitRPC, "Failed to verify migrated totals: %v", err)
+		}
+		if onChain.Cmp(expected) != 0 {
+			cliutil.Fail(cliutil.ExitPartialFailure, "Staked %s wei on the new registry for the plan's validators, expected %s wei", onChain, expected)
+		}
+		fmt.Printf("Verified %s wei staked on the new registry, matching the plan\n", onChain)
+	}
+
+	if *waitOptedIn > 0 {
+		pubKeys, err := plan.PubKeys()
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Invalid migration plan: %v", err)
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, *waitOptedIn)
+		err = query.WaitOptedIn(waitCtx, vRouter, pubKeys, 12*time.Second)
+		cancel()
+		if err != nil {
+			cliutil.Fail(cliutil.ExitPartialFailure, "Migrated validators not opted in according to the router: %v", err)
+		}
+		fmt.Printf("All %d migrated validators are opted in according to the router\n", len(pubKeys))
+	}
+}
+
+func getRevertReason(ctx context.Context, receipt *types.Receipt, client *ethclient.Client) string {
+	tx, _, err := client.TransactionByHash(ctx, receipt.TxHash)
+	if err != nil {
+		return fmt.Sprintf("failed to get transaction: %v", err)
+	}
+
+	reason, err := utils.RevertReasonAtBlock(ctx, client, tx, receipt.BlockNumber)
+	if err != nil {
+		return fmt.Sprintf("failed to re-simulate transaction: %v", err)
+	}
+	return reason
+}
 
-	currentBlock, err := client.BlockByNumber(context.Background(), nil)
+// batchesFromEvents batches the validators staked in the old registry that
+// are still staked there, not staked by the default dev account, and not
+// yet staked in the new registry according to router.
+func batchesFromEvents(ctx context.Context, client *ethclient.Client, vrf *vrv1.Validatorregistryv1Filterer, router optins.RouterCaller) ([]migrate.Batch, []migrate.SkippedValidator) {
+	currentBlock, err := utils.SafeTip(ctx, client, 0)
 	if err != nil {
-		log.Fatalf("Failed to get current block: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to get current block: %v", err)
 	}
-	fmt.Println("Current block: ", currentBlock.NumberU64())
+	fmt.Println("Current block: ", currentBlock)
 
 	// // obtain events from old registry, in batches of 50000
 	// start at block 1700000 (before contract deployment)
 	totEvents := make(map[string]events.Event)
-	for i := 1700000; i < int(currentBlock.NumberU64()); i += 50000 {
+	for i := 1700000; i < int(currentBlock); i += 50000 {
 		start := uint64(i)
 		end := uint64(i + 50000)
-		if end > currentBlock.NumberU64() {
-			end = currentBlock.NumberU64()
+		if end > currentBlock {
+			end = currentBlock
 		}
 		opts := &bind.FilterOpts{
 			Start:   start,
 			End:     &end,
-			Context: context.Background(),
+			Context: ctx,
 		}
 		stakedEvents, err := vrf.FilterStaked(opts, nil)
 		if err != nil {
-			log.Fatalf("Failed to get staked events: %v", err)
+			cliutil.Fail(cliutil.ExitRPC, "Failed to get staked events: %v", err)
 		}
-		for stakedEvents.Next() {
+		staked, err := utils.CollectEvents(utils.IterEvents(stakedEvents, func() *vrv1.Validatorregistryv1Staked {
+			return stakedEvents.Event
+		}))
+		if err != nil {
+			cliutil.Fail(cliutil.ExitRPC, "Failed to iterate staked events for blocks %d to %d: %v", start, end, err)
+		}
+		for _, staked := range staked {
 			event := events.Event{
-				ValBLSPubKey: hex.EncodeToString(stakedEvents.Event.ValBLSPubKey),
-				TxOriginator: stakedEvents.Event.TxOriginator.Hex(),
-				Amount:       stakedEvents.Event.Amount,
+				ValBLSPubKey: hex.EncodeToString(staked.ValBLSPubKey),
+				TxOriginator: staked.TxOriginator.Hex(),
+				Amount:       staked.Amount,
 			}
 			totEvents[event.ValBLSPubKey] = event
 		}
 		fmt.Println("Next iteration")
 	}
 
+	var skipped []migrate.SkippedValidator
 	deletedFromDefault := 0
 	for _, event := range totEvents {
 		if event.TxOriginator == "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+			skipped = append(skipped, migrate.SkippedValidator{Event: event, Reason: migrate.SkipExcludedOriginator})
 			delete(totEvents, event.ValBLSPubKey)
 			deletedFromDefault++
 		}
 	}
 	fmt.Println("Number of events deleted from default account: ", deletedFromDefault)
 
-	stakedValidators, err := query.GetAllStakedValsFromRegistry()
+	stakedValidators, err := query.GetAllStakedVals(ctx, client)
 	if err != nil {
-		log.Fatalf("Failed to get staked validators: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to get staked validators: %v", err)
 	}
 
 	stakedValidatorsMap := make(map[string]bool)
@@ -188,6 +375,7 @@ func main() {
 	deletedFromStaked := 0
 	for _, event := range totEvents {
 		if !stakedValidatorsMap[event.ValBLSPubKey] {
+			skipped = append(skipped, migrate.SkippedValidator{Event: event, Reason: migrate.SkipNotStaked})
 			delete(totEvents, event.ValBLSPubKey)
 			deletedFromStaked++
 		}
@@ -195,175 +383,15 @@ func main() {
 	fmt.Println("Number of events deleted from staked validators: ", deletedFromStaked)
 
 	// delete events for vals that are already staked in new reg
-	batchSize := 1000
-	var keysToDelete []string
-	keys := make([]string, 0, len(totEvents))
-	for key := range totEvents {
-		keys = append(keys, key)
-	}
-
-	for i := 0; i < len(keys); i += batchSize {
-		end := i + batchSize
-		if end > len(keys) {
-			end = len(keys)
-		}
-
-		batch := make([][]byte, 0, batchSize)
-		batchKeys := keys[i:end]
-
-		for _, key := range batchKeys {
-			pubKeyBytes, err := hex.DecodeString(key)
-			if err != nil {
-				log.Printf("Failed to decode pubkey %s: %v", key, err)
-				continue
-			}
-			batch = append(batch, pubKeyBytes)
-		}
-	}
-
-	for _, key := range keysToDelete {
-		delete(totEvents, key)
-	}
-	fmt.Printf("Number of events deleted for validators already staked in new reg: %d\n", len(keysToDelete))
-
-	numEvents := 0
-	for _, _ = range totEvents {
-		numEvents++
-		// fmt.Println(event.TxOriginator)
-		// fmt.Println(event.ValBLSPubKey)
-		// fmt.Println(event.Amount)
-		// fmt.Println("-------------------")
-	}
-	fmt.Println("Number of events to act upon: ", numEvents)
-
-	// organize into map of txOriginator to slice of pubKeys
-	batches := make(map[string]Batch)
-	for _, event := range totEvents {
-		if batch, exists := batches[event.TxOriginator]; exists {
-			batch.pubKeys = append(batch.pubKeys, common.Hex2Bytes(event.ValBLSPubKey))
-			batches[event.TxOriginator] = batch
-		} else {
-			batches[event.TxOriginator] = Batch{
-				pubKeys:         [][]byte{common.Hex2Bytes(event.ValBLSPubKey)},
-				stakeOriginator: common.HexToAddress(event.TxOriginator),
-			}
-		}
-	}
-
-	// print lens of batches
-	fmt.Println("Number of batches: ", len(batches))
-	for _, batch := range batches {
-		fmt.Println("Batch size: ", len(batch.pubKeys))
-		fmt.Println("Stake originator: ", batch.stakeOriginator.Hex())
-	}
-
-	biggestBatchSize := 20
-	for idx, batch := range batches {
-		// split into sub batches of 20 or less
-		for i := 0; i < len(batch.pubKeys); i += biggestBatchSize {
-			end := i + biggestBatchSize
-			if end > len(batch.pubKeys) {
-				end = len(batch.pubKeys)
-			}
-			subBatch := batch.pubKeys[i:end]
-
-			amountPerValidator := new(big.Int)
-			// 0.0001 ether
-			amountPerValidator.SetString("100000000000000", 10)
-			totalAmount := new(big.Int).Mul(amountPerValidator, big.NewInt(int64(len(subBatch))))
-			tOpts.Value = totalAmount
-
-			nonce, err := client.PendingNonceAt(context.Background(), account.Address)
-			if err != nil {
-				log.Fatalf("failed to get pending nonce: %v", err)
-			}
-			tOpts.Nonce = big.NewInt(int64(nonce))
-
-			gasTip, gasPrice, err := SuggestGasTipCapAndPrice(context.Background(), client)
-			if err != nil {
-				log.Fatalf("failed to suggest gas tip cap and price: %v", err)
-			}
-			tOpts.GasFeeCap = gasPrice
-			tOpts.GasTipCap = gasTip
-
-			submitTx := func(
-				ctx context.Context,
-				opts *bind.TransactOpts,
-			) (*types.Transaction, error) {
-				tx, err := vrta15.DelegateStake(opts, subBatch, batch.stakeOriginator)
-				if err != nil {
-					return nil, fmt.Errorf("failed to stake: %w", err)
-				}
-				fmt.Println("DelegateStake tx sent. Transaction hash: ", tx.Hash().Hex())
-				return tx, nil
-			}
-
-			receipt, err := ec.WaitMinedWithRetry(context.Background(), tOpts, submitTx)
-			if err != nil {
-				if strings.Contains(err.Error(), "nonce too low") {
-					fmt.Println("Nonce too low. This likely means the tx was included while constructing a retry...")
-					receipt = &types.Receipt{Status: 1, BlockNumber: big.NewInt(0)}
-				} else {
-					log.Fatalf("Failed to wait for stake tx to be mined: %v", err)
-				}
-			}
-			fmt.Println("DelegateStake tx included in block: ", receipt.BlockNumber)
-
-			if receipt.Status != ethtypes.ReceiptStatusSuccessful {
-				revertReason := getRevertReason(context.Background(), receipt, client)
-				fmt.Printf("Transaction failed. Receipt status: %d, Revert reason: %s\n", receipt.Status, revertReason)
-				fmt.Printf("Stake originator: %s\n", batch.stakeOriginator.Hex())
-				fmt.Printf("Number of validators in this batch: %d\n", len(subBatch))
-				for _, pubKey := range subBatch {
-					fmt.Printf("Validator pubkey: %x\n", pubKey)
-				}
-				fmt.Printf("Total amount staked: %s wei\n", tOpts.Value.String())
-				continue
-			}
-
-			fmt.Println("-------------------")
-			fmt.Printf("Batch %s completed\n", idx)
-			fmt.Println("-------------------")
-		}
-	}
-	fmt.Println("All batches completed!")
-}
-
-func SuggestGasTipCapAndPrice(ctx context.Context, client *ethclient.Client) (
-	gasTip *big.Int, gasPrice *big.Int, err error) {
-
-	// Returns priority fee per gas
-	gasTip, err = client.SuggestGasTipCap(ctx)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get gas tip cap: %w", err)
-	}
-	// Returns priority fee per gas + base fee per gas
-	gasPrice, err = client.SuggestGasPrice(ctx)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get gas price: %w", err)
-	}
-	return gasTip, gasPrice, nil
-}
-
-func getRevertReason(ctx context.Context, receipt *types.Receipt, client *ethclient.Client) string {
-	tx, _, err := client.TransactionByHash(ctx, receipt.TxHash)
+	alreadyStaked, err := migrate.ExcludeAlreadyStaked(ctx, router, totEvents, 100)
 	if err != nil {
-		return fmt.Sprintf("failed to get transaction: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to check validators staked in new reg: %v", err)
 	}
+	skipped = append(skipped, alreadyStaked...)
+	fmt.Printf("Number of events deleted for validators already staked in new reg: %d\n", len(alreadyStaked))
 
-	msg := ethereum.CallMsg{
-		From:     common.HexToAddress("0x4535bd6fF24860b5fd2889857651a85fb3d3C6b1"),
-		To:       tx.To(),
-		Gas:      tx.Gas(),
-		GasPrice: tx.GasPrice(),
-		Value:    tx.Value(),
-		Data:     tx.Data(),
-	}
-
-	result, err := client.CallContract(ctx, msg, receipt.BlockNumber)
-	if err != nil {
-		return fmt.Sprintf("Revert reason: %v", err)
-	}
+	batches := migrate.BatchesByOriginator(totEvents)
+	migrate.Summarize(batches, skipped).Print(os.Stdout)
 
-	return fmt.Sprintf("No error, but transaction failed. Result: %x", result)
+	return batches, skipped
 }
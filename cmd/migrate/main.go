@@ -2,265 +2,264 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"log"
+	"maps"
 	"math/big"
-	"net/http"
 	"os"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
 	events "github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/migrate"
 	query "github.com/primevprotocol/validator-registry/pkg/query"
 	utils "github.com/primevprotocol/validator-registry/pkg/utils"
 	vrv1 "github.com/primevprotocol/validator-registry/pkg/validatorregistryv1"
 )
 
-type Batch struct {
-	pubKeys         [][]byte
-	stakeOriginator common.Address
-}
-
-func isValidatorRegisteredWithBeaconChain(pubKey string) (bool, error) {
+func main() {
+	privateKeyFile := flag.String("private-key-file", "", "file containing the hex private key; PRIVATE_KEY env var is used if empty")
+	fundingTimeout := flag.Duration("funding-timeout", 0, "if underfunded, wait up to this long for the signing account to be funded instead of exiting")
+	maxBatches := flag.Int("max-batches", 0, "stop after processing this many batches; 0 processes all")
+	receiptsOut := flag.String("receipts-out", "", "if set, append a JSON line per included DelegateStake tx with its originator, pubkeys, block and status")
+	checkpointPath := flag.String("checkpoint", "migrate_checkpoint.txt", "file recording completed batches, which later runs skip")
+	stakedSetPath := flag.String("staked-set", "", "file of pubkeys staked by earlier runs, excluded from the plan and appended to after each successful sub batch; disabled if empty")
+	sweepTo := flag.String("sweep-to", "", "after all batches complete, send the signing account's remaining balance to this address")
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	planOut := flag.String("plan-out", "", "write the migration plan (sub batch pubkeys and values per originator, skipped validators) to this JSON file")
+	planIn := flag.String("plan-in", "", "execute the migration plan in this JSON file, as written by --plan-out, instead of building one from stored events")
+	planOnly := flag.Bool("plan-only", false, "build the migration plan, then exit without sending any transaction")
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var sweepAddr common.Address
+	if *sweepTo != "" {
+		if !common.IsHexAddress(*sweepTo) {
+			cliutil.Fail(cliutil.ExitConfig, "invalid --sweep-to address %q", *sweepTo)
+		}
+		sweepAddr = common.HexToAddress(*sweepTo)
+	}
 
-	url := fmt.Sprintf("https://holesky.beaconcha.in/api/v1/validator/%s", strings.ToLower(pubKey))
-	resp, err := http.Get(url)
+	privateKey, err := utils.LoadPrivateKey("PRIVATE_KEY", *privateKeyFile)
 	if err != nil {
-		return false, err
+		cliutil.Fail(cliutil.ExitConfig, "Failed to load private key: %v", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	client, err := ethclient.Dial("https://ethereum-holesky-rpc.publicnode.com")
 	if err != nil {
-		return false, err
+		cliutil.Fail(cliutil.ExitRPC, "Failed to connect to the Ethereum client: %v", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		var errorResponse map[string]interface{}
-		if err := json.Unmarshal(body, &errorResponse); err != nil {
-			return false, err
-		}
-
-		if errorResponse["status"] == "ERROR: invalid validator argument, pubkey(s) did not resolve to a validator index" {
-			return false, nil
-		}
-
-		return false, fmt.Errorf("failed to get validator status: %s, response: %s", resp.Status, string(body))
+	chainID := config.Holesky.ChainID
+	if err := utils.EnsureChainID(ctx, client, chainID); err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Refusing to transact on unexpected network: %v", err)
 	}
+	fmt.Println("Chain ID: ", chainID)
 
-	var result struct {
-		Status string `json:"status"`
-		Data   struct {
-			Status string `json:"status"`
-		} `json:"data"`
+	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
+	minBalance := big.NewInt(params.Ether)
+	err = utils.EnsureMinBalance(ctx, client, fromAddress, minBalance)
+	var insufficient *utils.ErrInsufficientBalance
+	if errors.As(err, &insufficient) && *fundingTimeout > 0 {
+		fmt.Printf("%v, waiting up to %s\n", err, *fundingTimeout)
+		fundingCtx, cancel := context.WithTimeout(ctx, *fundingTimeout)
+		err = utils.WaitForMinBalance(fundingCtx, client, fromAddress, minBalance, 10*time.Second)
+		cancel()
 	}
+	if errors.As(err, &insufficient) {
+		cliutil.Fail(cliutil.ExitInsufficientFunds, "%v", err)
+	} else if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "%v", err)
+	}
+
+	contractAddress := common.HexToAddress("0x5d4fC7B5Aeea4CF4F0Ca6Be09A2F5AaDAd2F2803") // Holesky validator registry 6/13
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return false, err
+	vrt, err := vrv1.NewValidatorregistryv1Transactor(contractAddress, client)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry transactor: %v", err)
 	}
 
-	if result.Status != "OK" {
-		return false, nil
+	ec := utils.NewETHClient(client)
+
+	if !*planOnly {
+		ec.CancelPendingTxes(ctx, privateKey)
 	}
 
-	if result.Data.Status == "active_online" || result.Data.Status == "active_offline" {
-		return true, nil
+	amountPerValidator := new(big.Int)
+	// 0.0001 ether
+	amountPerValidator.SetString("100000000000000", 10)
+
+	cfg := migrate.Config{
+		Registry:           contractAddress,
+		SubBatchSize:       migrate.MaxDelegateStakeBatchSize,
+		AmountPerValidator: amountPerValidator,
+		MaxBatches:         *maxBatches,
 	}
 
-	return false, nil
-}
+	var plan migrate.Plan
+	if *planIn != "" {
+		plan, err = migrate.LoadPlanFile(*planIn)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to load migration plan: %v", err)
+		}
+		fmt.Printf("Loaded migration plan of %d batches staking %s wei from %s\n", len(plan.Batches), plan.TotalValue, *planIn)
+	} else {
+		batches, skipped := batchesFromEvents(ctx, client, chainID)
+		plan, err = migrate.NewPlan(batches, skipped, cfg)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to build migration plan: %v", err)
+		}
+	}
 
-func main() {
-	privateKeyString := os.Getenv("PRIVATE_KEY")
-	if privateKeyString == "" {
-		fmt.Println("PRIVATE_KEY env var not supplied")
-		os.Exit(1)
+	var stakedSet *migrate.StakedSet
+	if *stakedSetPath != "" {
+		stakedSet, err = migrate.OpenStakedSet(*stakedSetPath)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to open staked set: %v", err)
+		}
+		defer stakedSet.Close()
+		skipped := len(plan.Skipped)
+		plan, err = plan.ExcludeStaked(stakedSet)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to exclude staked set from migration plan: %v", err)
+		}
+		fmt.Printf("Excluded %d validators found in the staked set of %d pubkeys\n", len(plan.Skipped)-skipped, stakedSet.Len())
 	}
 
-	if privateKeyString[:2] == "0x" {
-		privateKeyString = privateKeyString[2:]
+	if *planOut != "" {
+		if err := migrate.WritePlanFile(*planOut, plan); err != nil {
+			cliutil.Fail(cliutil.ExitGeneric, "Failed to write migration plan: %v", err)
+		}
+		fmt.Printf("Wrote migration plan staking %s wei to %s\n", plan.TotalValue, *planOut)
 	}
-	privateKey, err := crypto.HexToECDSA(privateKeyString)
-	if err != nil {
-		fmt.Println("Failed to parse private key")
-		os.Exit(1)
+	if *planOnly {
+		fmt.Println("--plan-only set, exiting without sending any transaction")
+		return
 	}
 
-	client, err := ethclient.Dial("https://ethereum-holesky-rpc.publicnode.com")
+	opts, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
 	if err != nil {
-		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create transactor: %v", err)
 	}
+	opts.GasLimit = uint64(3000000)
 
-	chainID, err := client.ChainID(context.Background())
+	executor, err := migrate.NewExecutor(client, opts, vrt, cfg)
 	if err != nil {
-		log.Fatalf("Failed to get chain id: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Invalid migration config: %v", err)
 	}
-	fmt.Println("Chain ID: ", chainID)
-
-	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
-	balance, err := client.BalanceAt(context.Background(), fromAddress, nil)
+	checkpoint, err := migrate.OpenCheckpoint(*checkpointPath)
 	if err != nil {
-		log.Fatalf("Failed to get account balance: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to open checkpoint: %v", err)
 	}
-	if balance.Cmp(big.NewInt(1000000000000000000)) == -1 {
-		log.Fatalf("Insufficient balance. Please fund %v with at least 1 ETH", fromAddress.Hex())
+	defer checkpoint.Close()
+	executor.SetCheckpoint(checkpoint)
+	if stakedSet != nil {
+		executor.SetStakedSet(stakedSet)
 	}
-
-	contractAddress := common.HexToAddress("0x5d4fC7B5Aeea4CF4F0Ca6Be09A2F5AaDAd2F2803") // Holesky validator registry 6/13
-
-	vrt, err := vrv1.NewValidatorregistryv1Transactor(contractAddress, client)
+	if *receiptsOut != "" {
+		records, err := migrate.NewTxRecordWriter(*receiptsOut)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to open receipts file: %v", err)
+		}
+		defer records.Close()
+		executor.SetRecordWriter(records)
+	}
+	result, err := executor.ExecutePlan(ctx, plan)
 	if err != nil {
-		log.Fatalf("Failed to create Validator Registry transactor: %v", err)
+		cliutil.Fail(cliutil.ExitCode(err), "Failed to execute migration: %v", err)
 	}
+	for _, u := range result.Unconfirmed {
+		fmt.Printf("Transaction not confirmed, check its outcome on chain. Stake originator: %s\n", u.StakeOriginator.Hex())
+		for _, pubKey := range u.PubKeys {
+			fmt.Printf("Validator pubkey: %x\n", pubKey)
+		}
+	}
+	if len(result.Unconfirmed) > 0 {
+		cliutil.Fail(cliutil.ExitPartialFailure, "%d sub batches not confirmed", len(result.Unconfirmed))
+	}
+	if result.Remaining > 0 {
+		fmt.Printf("Stopped after %d batches, %d remaining. Rerun to continue.\n", result.Processed, result.Remaining)
+		return
+	}
+	fmt.Println("All batches completed!")
 
-	ec := utils.NewETHClient(client)
-
-	ec.CancelPendingTxes(context.Background(), privateKey)
-
-	e := make(map[string]events.Event)
+	if *sweepTo != "" {
+		receipt, err := ec.SweepBalance(ctx, privateKey, sweepAddr)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitCode(err), "Failed to sweep remaining balance: %v", err)
+		}
+		fmt.Printf("Swept remaining balance to %s in tx %s\n", sweepAddr.Hex(), receipt.TxHash.Hex())
+	}
+}
 
-	stakedEvents, err := events.ReadEvents("staked")
+// batchesFromEvents batches the validators staked in the stored events,
+// which must have been collected from chainID, that are neither staked in
+// the registry yet nor staked by the default dev account.
+func batchesFromEvents(ctx context.Context, client *ethclient.Client, chainID *big.Int) ([]migrate.Batch, []migrate.SkippedValidator) {
+	stakedEvents, err := events.ReadEventsForChain(events.EventStaked, chainID)
 	if err != nil {
-		log.Fatalf("Failed to read staked events: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read staked events: %v", err)
 	}
 
-	unstakedEvents, err := events.ReadEvents("unstaked")
+	unstakedEvents, err := events.ReadEventsForChain(events.EventUnstaked, chainID)
 	if err != nil {
-		log.Fatalf("Failed to read unstaked events: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read unstaked events: %v", err)
 	}
 
-	withdrawnEvents, err := events.ReadEvents("withdraw")
+	withdrawnEvents, err := events.ReadEventsForChain(events.EventWithdraw, chainID)
 	if err != nil {
-		log.Fatalf("Failed to read withdrawn events: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read withdrawn events: %v", err)
 	}
 
-	for _, event := range stakedEvents {
-		e[event.ValBLSPubKey] = event
-	}
-	for _, event := range unstakedEvents {
-		delete(e, event.ValBLSPubKey)
-	}
-	for _, event := range withdrawnEvents {
-		delete(e, event.ValBLSPubKey)
-	}
+	e := events.Reconstruct(stakedEvents, unstakedEvents, withdrawnEvents)
 
-	stakedVals, err := query.GetAllStakedValsFromRegistry()
+	stakedVals, err := query.GetAllStakedVals(ctx, client)
 	if err != nil {
-		log.Fatalf("Failed to get all staked validators: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to get all staked validators: %v", err)
 	}
 
+	var skipped []migrate.SkippedValidator
 	for _, stakedVal := range stakedVals {
-		// fmt.Println("Validator already staked: ", stakedVal)
-		delete(e, stakedVal)
+		if event, ok := e[stakedVal]; ok {
+			skipped = append(skipped, migrate.SkippedValidator{Event: event, Reason: migrate.SkipAlreadyStaked})
+			delete(e, stakedVal)
+		}
 	}
 
 	deletedFromDefault := 0
 	for _, event := range e {
 		if event.TxOriginator == "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+			skipped = append(skipped, migrate.SkippedValidator{Event: event, Reason: migrate.SkipExcludedOriginator})
 			delete(e, event.ValBLSPubKey)
 			deletedFromDefault++
 		}
 	}
 	fmt.Println("Number of events deleted from default account: ", deletedFromDefault)
 
-	// fmt.Println("Number of validators to check on beacon chain: ", len(e))
-
-	batches := make(map[string]Batch)
-	// skipped := 0
-	batched := 0
-	for _, event := range e {
-		// registered, err := isValidatorRegisteredWithBeaconChain(event.ValBLSPubKey)
-		// if err != nil {
-		// 	log.Fatalf("Failed to check validator registration with beacon chain: %v", err)
-		// }
-		// if registered {
-		// fmt.Println("Validator is registered with beacon chain: ", event.ValBLSPubKey)
-
-		batched++
-		if batch, exists := batches[event.TxOriginator]; exists {
-			batch.pubKeys = append(batch.pubKeys, common.Hex2Bytes(event.ValBLSPubKey))
-			batches[event.TxOriginator] = batch
-		} else {
-			batches[event.TxOriginator] = Batch{
-				pubKeys:         [][]byte{common.Hex2Bytes(event.ValBLSPubKey)},
-				stakeOriginator: common.HexToAddress(event.TxOriginator),
-			}
-		}
-
-		// } else {
-		// 	fmt.Printf("Skipping validator who is not registered with beacon chain: %s\n", event.ValBLSPubKey)
-		// 	skipped++
-		// }
-	}
-	// fmt.Println("Number of validators skipped for not being registered with beacon chain: ", skipped)
-	fmt.Println("Number of validators batched: ", batched)
+	batches := migrate.BatchesByOriginator(e)
+	fmt.Println("Number of validators batched: ", len(e))
 
 	// print lens of batches
 	fmt.Println("Number of batches: ", len(batches))
-	for _, batch := range batches {
-		fmt.Println("Batch size: ", len(batch.pubKeys))
+	counts := events.CountByOriginator(slices.Collect(maps.Values(e)))
+	for _, originator := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Println("Batch size: ", counts[originator])
 	}
 
-	biggestBatchSize := 20
-	for idx, batch := range batches {
-		// split into sub batches of 20 or less
-		for i := 0; i < len(batch.pubKeys); i += biggestBatchSize {
-			end := i + biggestBatchSize
-			if end > len(batch.pubKeys) {
-				end = len(batch.pubKeys)
-			}
-			subBatch := batch.pubKeys[i:end]
-
-			opts, err := ec.CreateTransactOpts(context.Background(), privateKey, chainID)
-			if err != nil {
-				log.Fatalf("Failed to create transact opts: %v", err)
-			}
-
-			amountPerValidator := new(big.Int)
-			// 0.0001 ether
-			amountPerValidator.SetString("100000000000000", 10)
-			totalAmount := new(big.Int).Mul(amountPerValidator, big.NewInt(int64(len(subBatch))))
-			opts.Value = totalAmount
-
-			submitTx := func(
-				ctx context.Context,
-				opts *bind.TransactOpts,
-			) (*types.Transaction, error) {
-
-				tx, err := vrt.DelegateStake(opts, subBatch, batch.stakeOriginator)
-				if err != nil {
-					return nil, fmt.Errorf("failed to stake: %w", err)
-				}
-				fmt.Println("DelegateStake tx sent. Transaction hash: ", tx.Hash().Hex())
-				return tx, nil
-			}
-
-			receipt, err := ec.WaitMinedWithRetry(context.Background(), opts, submitTx)
-			if err != nil {
-				if strings.Contains(err.Error(), "nonce too low") {
-					fmt.Println("Nonce too low. This likely means the tx was included while constructing a retry...")
-					receipt = &types.Receipt{Status: 1, BlockNumber: big.NewInt(0)}
-				} else {
-					log.Fatalf("Failed to wait for stake tx to be mined: %v", err)
-				}
-			}
-			fmt.Println("DelegateStake tx included in block: ", receipt.BlockNumber)
-
-			if receipt.Status == 0 {
-				fmt.Println("DelegateStake tx included, but failed. Exiting...")
-				os.Exit(1)
-			}
-
-			fmt.Println("-------------------")
-			fmt.Printf("Batch %s completed\n", idx)
-			fmt.Println("-------------------")
-		}
-	}
-	fmt.Println("All batches completed!")
+	migrate.Summarize(batches, skipped).Print(os.Stdout)
+
+	return batches, skipped
 }
@@ -1,191 +1,186 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/csv"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/preconf"
+	"github.com/primevprotocol/validator-registry/pkg/preconfmanager"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
 )
 
 type optedInSlot struct {
-	slot           uint64
-	blockNumber    uint64
-	pubKey         string
-	optInBlock     uint64
-	optInType      string
-	podOwner       common.Address
-	vault          common.Address
-	operator       common.Address
-	withdrawalAddr common.Address
+	optins.Slot
 	// Only populated at end of script
 	missed bool
 }
 
-type SentioResponse struct {
-	SyncSqlResponse struct {
-		RuntimeCost string `json:"runtimeCost"`
-		Result      struct {
-			Columns     []string            `json:"columns"`
-			ColumnTypes map[string]string   `json:"columnTypes"`
-			Rows        []map[string]string `json:"rows"`
-		} `json:"result"`
-	} `json:"syncSqlResponse"`
-}
+func main() {
+	slotsFile := flag.String("slots-file", filepath.Join("..", "opted-in-slots", "opted_in_slots.csv"), "path to the opted-in slots CSV produced by opted-in-slots")
+	outDir := flag.String("out-dir", ".", cliutil.OutDirUsage)
+	watchMode := flag.Bool("watch", false, "instead of a one-shot export, check each newly finalized epoch and serve missed and proposed slot counts as Prometheus metrics")
+	validatorsFile := flag.String("validators-file", filepath.Join("..", "all-mainnet-regs", "opted_in_validators.csv"), "opted in validators CSV produced by all-mainnet-regs, used by --watch")
+	beaconURL := flag.String("beacon-url", "https://ethereum-beacon-api.publicnode.com", "beacon node API used by --watch")
+	metricsAddr := flag.String("metrics-addr", ":9090", "address --watch serves /metrics on")
+	watchInterval := flag.Duration("watch-interval", time.Minute, "how often --watch polls for newly finalized epochs")
+	fromEpoch := flag.Uint64("from-epoch", 0, "first epoch --watch checks; the finalized epoch at startup if 0")
+	preconfManager := flag.String("preconf-manager", config.MevCommitMainnet.PreconfManager.Hex(), "preconf manager contract address on the mev-commit chain")
+	commitmentLookback := flag.Uint64("commitment-lookback", 50000, "mev-commit chain blocks before the latest scanned for commitments, by --watch at startup and by the export if --from-block is unset")
+	fromBlock := flag.Uint64("from-block", 0, "first mev-commit chain block scanned for opened commitments; --commitment-lookback blocks before the latest if 0")
+	windowSize := flag.Uint64("window-size", 100000, "number of blocks per event filter call when filtering by --committer")
+	var committers cliutil.AddressList
+	flag.Var(&committers, "committer", "only count commitments by this provider address and report its miss rate; may be repeated or comma separated")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if !common.IsHexAddress(*preconfManager) {
+		log.Fatalf("Invalid --preconf-manager address %q", *preconfManager)
+	}
+	if *watchMode {
+		watch(ctx, watchConfig{
+			validatorsFile:     *validatorsFile,
+			beaconURL:          *beaconURL,
+			metricsAddr:        *metricsAddr,
+			interval:           *watchInterval,
+			fromEpoch:          *fromEpoch,
+			preconfManager:     common.HexToAddress(*preconfManager),
+			commitmentLookback: *commitmentLookback,
+		})
+		return
+	}
 
-type OpenedCommit struct {
-	BlockNumber     uint64
-	BidAmt          string
-	TransactionHash string
-}
+	outputPath, err := cliutil.OutPath(*outDir, "missed_slots.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
 
-func main() {
-	optedInSlots, err := loadOptedInSlots()
+	optedInSlots, err := loadOptedInSlots(*slotsFile)
 	if err != nil {
 		log.Fatalf("Error loading opted-in slots: %v\n", err)
 	}
 
-	fmt.Printf("Loaded %d opted-in slots from CSV\n", len(optedInSlots))
-
-	openedCommits, err := fetchOpenedCommits()
+	client, err := config.MevCommitMainnet.Dial()
+	if err != nil {
+		log.Fatal(err)
+	}
+	filterer, err := preconfmanager.NewPreconfmanagerFilterer(common.HexToAddress(*preconfManager), client)
 	if err != nil {
-		log.Fatalf("Error fetching opened commits: %v\n", err)
+		log.Fatalf("Failed to create preconfmanager filterer: %v", err)
 	}
+	toBlock, err := utils.SafeTip(ctx, client, 0)
+	if err != nil {
+		log.Fatalf("Failed to get current mev-commit block: %v", err)
+	}
+	*fromBlock = scanStart(*fromBlock, toBlock, *commitmentLookback)
+
+	if len(committers) > 0 {
+		commitments, err := preconf.FilterOpenedCommitments(ctx, filterer, preconf.FilterConfig{
+			StartBlock: *fromBlock,
+			EndBlock:   toBlock,
+			WindowSize: *windowSize,
+			Committers: committers,
+		})
+		if err != nil {
+			log.Fatalf("Error fetching opened commits: %v\n", err)
+		}
+		fmt.Printf("Loaded %d opened commits by %d committers from mev-commit blocks %d to %d\n", len(commitments), len(committers), *fromBlock, toBlock)
 
-	fmt.Printf("Loaded %d opened commits from Sentio\n", len(openedCommits))
+		blocksByCommitter := preconf.BlocksByCommitter(commitments)
+		markCommitterMisses(committers, blocksByCommitter, optedInSlots)
+		printCommitterMisses(os.Stdout, committers, blocksByCommitter, optedInSlots)
+	} else {
+		openedCommits, err := preconf.CommitmentsByBlock(ctx, filterer, *fromBlock, toBlock)
+		if err != nil {
+			log.Fatalf("Error fetching opened commits: %v\n", err)
+		}
+		fmt.Printf("Loaded opened commits for %d blocks from mev-commit blocks %d to %d\n", len(openedCommits), *fromBlock, toBlock)
 
-	for blockNumber, slot := range optedInSlots {
-		if _, ok := openedCommits[blockNumber]; ok {
-			slot.missed = false
-		} else {
-			slot.missed = true
+		for blockNumber, slot := range optedInSlots {
+			_, ok := openedCommits[blockNumber]
+			slot.missed = !ok
 		}
 	}
 
 	fmt.Printf("Writing %d slots to CSV\n", len(optedInSlots))
 
-	err = writeToCsv(optedInSlots)
+	err = writeToCsv(outputPath, optedInSlots)
 	if err != nil {
 		log.Fatalf("Error writing to CSV: %v\n", err)
 	}
 }
 
-func fetchOpenedCommits() (map[uint64]OpenedCommit, error) {
-	url := "https://endpoint.sentio.xyz/primev/mevcommit/opened_commits_apr_22"
-	apiKey := "iFhXK2RmifCsv0quNQL38UrMMefVtTv1q"
-
-	reqBody := []byte("{}")
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
-	}
-
-	req.Header.Set("api-key", apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response: %v", err)
-	}
-
-	var sentioResp SentioResponse
-	if err := json.Unmarshal(body, &sentioResp); err != nil {
-		return nil, fmt.Errorf("error parsing JSON: %v", err)
+// markCommitterMisses marks the opted-in slots none of committers has a
+// commitment for as missed.
+func markCommitterMisses(committers []common.Address, blocksByCommitter map[common.Address]map[uint64]bool, optedInSlots map[uint64]*optedInSlot) {
+	for blockNumber, slot := range optedInSlots {
+		slot.missed = true
+		for _, committer := range committers {
+			if blocksByCommitter[committer][blockNumber] {
+				slot.missed = false
+			}
+		}
 	}
+}
 
-	commits := map[uint64]OpenedCommit{}
-	for _, row := range sentioResp.SyncSqlResponse.Result.Rows {
-		blockNum, _ := strconv.ParseUint(row["blockNumber"], 10, 64)
-		commit := OpenedCommit{
-			BlockNumber:     blockNum,
-			BidAmt:          row["bidAmt"],
-			TransactionHash: row["transaction_hash"],
+// printCommitterMisses prints to out, for each committer, how many opted-in
+// slots it has no commitment for.
+func printCommitterMisses(out io.Writer, committers []common.Address, blocksByCommitter map[common.Address]map[uint64]bool, optedInSlots map[uint64]*optedInSlot) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "committer\tslots\tcommitted\tmissed\tmiss rate")
+	for _, committer := range committers {
+		committed := 0
+		for blockNumber := range optedInSlots {
+			if blocksByCommitter[committer][blockNumber] {
+				committed++
+			}
+		}
+		missed := len(optedInSlots) - committed
+		rate := 0.0
+		if len(optedInSlots) > 0 {
+			rate = 100 * float64(missed) / float64(len(optedInSlots))
 		}
-		commits[blockNum] = commit
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.2f%%\n", committer.Hex(), len(optedInSlots), committed, missed, rate)
 	}
-
-	return commits, nil
+	w.Flush()
 }
 
-func loadOptedInSlots() (map[uint64]*optedInSlot, error) {
-
-	csvPath := filepath.Join("..", "opted-in-slots", "opted_in_slots.csv")
-
-	file, err := os.Open(csvPath)
+func loadOptedInSlots(csvPath string) (map[uint64]*optedInSlot, error) {
+	slots, duplicates, err := optins.ReadSlotsFileCountingDuplicates(csvPath)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-
-	header, err := reader.Read()
-	if err != nil {
-		return nil, err
+	if duplicates > 0 {
+		fmt.Printf("WARNING: %d rows of %s repeat the block number of an earlier row and were overwritten, the CSV may be malformed\n", duplicates, csvPath)
 	}
-	fmt.Printf("CSV Headers: %v\n", header)
-	optedInSlots := map[uint64]*optedInSlot{}
-
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			fmt.Printf("Error reading CSV record: %v\n", err)
-			continue
-		}
 
-		slot, err := strconv.ParseUint(record[0], 10, 64)
-		if err != nil {
-			log.Fatalf("Error parsing slot: %v\n", err)
-		}
-
-		blockNumber, err := strconv.ParseUint(record[1], 10, 64)
-		if err != nil {
-			log.Fatalf("Error parsing block number: %v\n", err)
-		}
-
-		optInBlock, err := strconv.ParseUint(record[3], 10, 64)
-		if err != nil {
-			log.Fatalf("Error parsing opt-in block: %v\n", err)
-		}
-
-		optedInSlots[blockNumber] = &optedInSlot{
-			slot:           slot,
-			blockNumber:    blockNumber,
-			pubKey:         record[2],
-			optInBlock:     optInBlock,
-			optInType:      record[4],
-			podOwner:       common.HexToAddress(record[5]),
-			vault:          common.HexToAddress(record[6]),
-			operator:       common.HexToAddress(record[7]),
-			withdrawalAddr: common.HexToAddress(record[8]),
-		}
+	optedInSlots := make(map[uint64]*optedInSlot, len(slots))
+	for blockNumber, slot := range slots {
+		optedInSlots[blockNumber] = &optedInSlot{Slot: slot}
 	}
 	fmt.Printf("Loaded %d opted-in slots from CSV\n", len(optedInSlots))
 	return optedInSlots, nil
 }
 
-func writeToCsv(optedInSlots map[uint64]*optedInSlot) error {
-	csvPath := filepath.Join("..", "missed-slots", "missed_slots.csv")
-
+func writeToCsv(csvPath string, optedInSlots map[uint64]*optedInSlot) error {
 	file, err := os.Create(csvPath)
 	if err != nil {
 		return err
@@ -197,25 +192,38 @@ func writeToCsv(optedInSlots map[uint64]*optedInSlot) error {
 		toWrite = append(toWrite, slot)
 	}
 	sort.Slice(toWrite, func(i, j int) bool {
-		return toWrite[i].optInBlock < toWrite[j].optInBlock
+		return toWrite[i].Validator.OptInBlock < toWrite[j].Validator.OptInBlock
 	})
 
 	writer := csv.NewWriter(file)
-	writer.Write([]string{"slot", "blockNumber", "pubKey", "optInBlock", "optInType", "podOwner", "vault", "operator", "withdrawalAddr", "missed"})
+	writer.Write(optins.MissedSlotsSchema.Columns)
 	for _, slot := range toWrite {
 		writer.Write([]string{
-			fmt.Sprintf("%d", slot.slot),
-			fmt.Sprintf("%d", slot.blockNumber),
-			slot.pubKey,
-			fmt.Sprintf("%d", slot.optInBlock),
-			slot.optInType,
-			slot.podOwner.Hex(),
-			slot.vault.Hex(),
-			slot.operator.Hex(),
-			slot.withdrawalAddr.Hex(),
+			fmt.Sprintf("%d", slot.Slot.Slot),
+			fmt.Sprintf("%d", slot.BlockNumber),
+			slot.Validator.PubKey,
+			fmt.Sprintf("%d", slot.Validator.OptInBlock),
+			slot.Validator.OptInType.String(),
+			slot.Validator.PodOwner.Hex(),
+			slot.Validator.Vault.Hex(),
+			slot.Validator.Operator.Hex(),
+			slot.Validator.WithdrawalAddr.Hex(),
 			fmt.Sprintf("%t", slot.missed),
 		})
 	}
 	writer.Flush()
-	return nil
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// scanStart returns fromBlock, or if it is 0 the block lookback blocks
+// before toBlock, so a run without --from-block doesn't scan the whole
+// chain.
+func scanStart(fromBlock, toBlock, lookback uint64) uint64 {
+	if fromBlock != 0 {
+		return fromBlock
+	}
+	return toBlock - min(toBlock, lookback)
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/preconf"
+)
+
+func testSlots() map[uint64]*optedInSlot {
+	return map[uint64]*optedInSlot{
+		101: {Slot: optins.Slot{Slot: 1, BlockNumber: 101, Validator: optins.Validator{PubKey: strings.Repeat("aa", 48), OptInBlock: 90, OptInType: optins.OptInTypeVanilla}}},
+		102: {Slot: optins.Slot{Slot: 2, BlockNumber: 102, Validator: optins.Validator{PubKey: strings.Repeat("bb", 48), OptInBlock: 80, OptInType: optins.OptInTypeEigen}}, missed: true},
+	}
+}
+
+func TestWriteToCsvMatchesSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missed_slots.csv")
+	if err := writeToCsv(path, testSlots()); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := optins.MissedSlotsSchema.ValidateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("written CSV has problems %v", problems)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[1], ",true") || !strings.HasSuffix(lines[2], ",false") {
+		t.Errorf("got rows %q, want the earlier opt-in first", lines[1:])
+	}
+}
+
+func TestWriteToCsvReportsWriteErrors(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full")
+	}
+	if err := writeToCsv("/dev/full", testSlots()); err == nil {
+		t.Error("write to a full device succeeded")
+	}
+}
+
+func TestScanStart(t *testing.T) {
+	for _, tc := range []struct {
+		fromBlock, toBlock, lookback, want uint64
+	}{
+		{fromBlock: 10, toBlock: 1000, lookback: 100, want: 10},
+		{fromBlock: 0, toBlock: 1000, lookback: 100, want: 900},
+		{fromBlock: 0, toBlock: 50, lookback: 100, want: 0},
+	} {
+		if got := scanStart(tc.fromBlock, tc.toBlock, tc.lookback); got != tc.want {
+			t.Errorf("scanStart(%d, %d, %d) = %d, want %d", tc.fromBlock, tc.toBlock, tc.lookback, got, tc.want)
+		}
+	}
+}
+
+func TestCommitterMissesAttributesEachCommitter(t *testing.T) {
+	committerA := common.HexToAddress("0x0a")
+	committerB := common.HexToAddress("0x0b")
+	blocksByCommitter := preconf.BlocksByCommitter([]preconf.Commitment{
+		{Committer: committerA, BlockNumber: 101},
+		{Committer: committerB, BlockNumber: 101},
+		{Committer: committerB, BlockNumber: 102},
+		// Not an opted-in slot.
+		{Committer: committerA, BlockNumber: 200},
+	})
+	slots := testSlots()
+	slots[103] = &optedInSlot{Slot: optins.Slot{Slot: 3, BlockNumber: 103}}
+
+	markCommitterMisses([]common.Address{committerA}, blocksByCommitter, slots)
+	if slots[101].missed || !slots[102].missed || !slots[103].missed {
+		t.Errorf("tracking A: got missed %t %t %t, want false true true", slots[101].missed, slots[102].missed, slots[103].missed)
+	}
+	markCommitterMisses([]common.Address{committerA, committerB}, blocksByCommitter, slots)
+	if slots[101].missed || slots[102].missed || !slots[103].missed {
+		t.Errorf("tracking A and B: got missed %t %t %t, want false false true", slots[101].missed, slots[102].missed, slots[103].missed)
+	}
+
+	var out bytes.Buffer
+	printCommitterMisses(&out, []common.Address{committerA, committerB}, blocksByCommitter, slots)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want a header and a row per committer:\n%s", len(lines), out.String())
+	}
+	for i, want := range [][]string{
+		{committerA.Hex(), "3", "1", "2", "66.67%"},
+		{committerB.Hex(), "3", "2", "1", "33.33%"},
+	} {
+		if got := strings.Fields(lines[i+1]); !slices.Equal(got, want) {
+			t.Errorf("row %d is %v, want %v", i, got, want)
+		}
+	}
+}
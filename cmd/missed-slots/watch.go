@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/primevprotocol/validator-registry/pkg/beacon"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/preconf"
+	"github.com/primevprotocol/validator-registry/pkg/preconfmanager"
+	"github.com/primevprotocol/validator-registry/pkg/proposals"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	missedSlots = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "missed_slots_opted_in_missed_total",
+		Help: "Opted-in slots with no block or no opened commitment.",
+	})
+	proposedSlots = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "missed_slots_opted_in_proposed_total",
+		Help: "Opted-in slots whose block has an opened commitment.",
+	})
+	checkedEpoch = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "missed_slots_last_checked_epoch",
+		Help: "Last finalized epoch whose opted-in slots were checked.",
+	})
+)
+
+type watchConfig struct {
+	validatorsFile     string
+	beaconURL          string
+	metricsAddr        string
+	interval           time.Duration
+	fromEpoch          uint64
+	preconfManager     common.Address
+	commitmentLookback uint64
+}
+
+// watch checks the opted-in slots of each newly finalized epoch, updating
+// the Prometheus metrics served on cfg.metricsAddr, until interrupted.
+func watch(ctx context.Context, cfg watchConfig) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	validators, err := optins.ReadValidatorsFile(cfg.validatorsFile)
+	if err != nil {
+		log.Fatalf("Failed to load opted in validators: %v", err)
+	}
+	fmt.Printf("Loaded %d opted in validators from %s\n", len(validators), cfg.validatorsFile)
+
+	client, err := config.MevCommitMainnet.Dial()
+	if err != nil {
+		log.Fatal(err)
+	}
+	commitments, err := newOnChainCommitments(ctx, client, cfg.preconfManager, cfg.commitmentLookback)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	beaconClient := beacon.NewClient(cfg.beaconURL)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(missedSlots, proposedSlots, checkedEpoch)
+	server := &http.Server{Addr: cfg.metricsAddr, Handler: promhttp.HandlerFor(registry, promhttp.HandlerOpts{})}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to serve metrics: %v", err)
+		}
+	}()
+	defer server.Close()
+	fmt.Printf("Serving metrics on %s/metrics\n", cfg.metricsAddr)
+
+	next := cfg.fromEpoch
+	if next == 0 {
+		finalized, err := beaconClient.FinalizedEpoch(ctx)
+		if err != nil {
+			log.Fatalf("Failed to get finalized epoch: %v", err)
+		}
+		next = finalized
+	}
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+	for {
+		finalized, err := beaconClient.FinalizedEpoch(ctx)
+		if err != nil {
+			fmt.Printf("Failed to get finalized epoch, retrying: %v\n", err)
+		}
+		for ; err == nil && next <= finalized; next++ {
+			var report proposals.EpochReport
+			report, err = proposals.CheckEpoch(ctx, beaconClient, validators, commitments, next)
+			if err != nil {
+				// The epoch is retried on the next tick.
+				fmt.Printf("Failed to check epoch %d, retrying: %v\n", next, err)
+				break
+			}
+			// Later epochs only target later blocks.
+			commitments.evictChecked()
+			missedSlots.Add(float64(report.Missed))
+			proposedSlots.Add(float64(report.Proposed))
+			checkedEpoch.Set(float64(next))
+			fmt.Printf("Epoch %d: %d opted-in slots proposed, %d missed %v\n", next, report.Proposed, report.Missed, report.MissedSlots)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// onChainCommitments indexes the preconf manager's opened commitments by
+// the L1 block they target, scanning new mev-commit chain blocks on
+// demand.
+type onChainCommitments struct {
+	client    *ethclient.Client
+	filterer  *preconfmanager.PreconfmanagerFilterer
+	nextBlock uint64
+	byBlock   map[uint64]preconf.Commitment
+	// checked is the highest block HasCommitment was asked about.
+	checked uint64
+}
+
+func newOnChainCommitments(ctx context.Context, client *ethclient.Client, preconfManager common.Address, lookback uint64) (*onChainCommitments, error) {
+	filterer, err := preconfmanager.NewPreconfmanagerFilterer(preconfManager, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create preconfmanager filterer: %w", err)
+	}
+	latest, err := utils.BlockNumberWithRetry(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest mev-commit block: %w", err)
+	}
+	return &onChainCommitments{
+		client:    client,
+		filterer:  filterer,
+		nextBlock: latest - min(latest, lookback),
+		byBlock:   make(map[uint64]preconf.Commitment),
+	}, nil
+}
+
+func (c *onChainCommitments) HasCommitment(ctx context.Context, blockNumber uint64) (bool, error) {
+	c.checked = max(c.checked, blockNumber)
+	if _, ok := c.byBlock[blockNumber]; ok {
+		return true, nil
+	}
+	latest, err := utils.BlockNumberWithRetry(ctx, c.client)
+	if err != nil {
+		return false, err
+	}
+	if latest < c.nextBlock {
+		return false, nil
+	}
+	commitments, err := preconf.CommitmentsByBlock(ctx, c.filterer, c.nextBlock, latest)
+	if err != nil {
+		return false, err
+	}
+	for block, commitment := range commitments {
+		if _, ok := c.byBlock[block]; !ok {
+			c.byBlock[block] = commitment
+		}
+	}
+	c.nextBlock = latest + 1
+	_, ok := c.byBlock[blockNumber]
+	return ok, nil
+}
+
+// evictChecked drops the commitments of blocks up to the highest one
+// checked, so the index doesn't grow for as long as the watch runs. Call it
+// only once an epoch has been fully checked.
+func (c *onChainCommitments) evictChecked() {
+	for block := range c.byBlock {
+		if block <= c.checked {
+			delete(c.byBlock, block)
+		}
+	}
+}
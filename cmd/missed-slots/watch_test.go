@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/preconf"
+)
+
+func TestOnChainCommitmentsEvictsCheckedBlocks(t *testing.T) {
+	c := &onChainCommitments{
+		byBlock: map[uint64]preconf.Commitment{100: {}, 101: {}, 150: {}},
+	}
+	// Both blocks are indexed, so HasCommitment makes no RPC call.
+	for _, block := range []uint64{101, 100} {
+		if ok, err := c.HasCommitment(context.Background(), block); err != nil || !ok {
+			t.Fatalf("block %d: got %v, %v, want a commitment", block, ok, err)
+		}
+	}
+	c.evictChecked()
+	if len(c.byBlock) != 1 {
+		t.Fatalf("got %d indexed blocks after eviction, want 1", len(c.byBlock))
+	}
+	if _, ok := c.byBlock[150]; !ok {
+		t.Error("unchecked block 150 evicted")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+func TestLoadValidatorsFromCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "validators.csv")
+	pubKey := strings.Repeat("aa", 48)
+	if err := optins.WriteValidatorsFile(path, []optins.Validator{{PubKey: pubKey, OptInBlock: 10, OptInType: optins.OptInTypeVanilla}}); err != nil {
+		t.Fatal(err)
+	}
+
+	validators, err := loadValidatorsFromCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validators) != 1 || validators[pubKey].OptInBlock != 10 {
+		t.Errorf("got %+v, want the validator written to %s", validators, path)
+	}
+}
+
+func TestLoadValidatorsFromCSVNamesMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.csv")
+	_, err := loadValidatorsFromCSV(path)
+	if err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("got error %v, want one naming %s", err, path)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// optin-durations prints how long each validator in an opt-in CSV has been
+// opted in, longest first.
+func main() {
+	input := flag.String("input", "opted_in_validators.csv", "opted in validators CSV, as written by all-mainnet-regs")
+	atBlock := flag.Uint64("at-block", 0, "block to measure durations at; the latest mainnet block if 0")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	validators, err := optins.ReadValidatorsFile(*input)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read opted in validators: %v", err)
+	}
+
+	currentBlock := *atBlock
+	if currentBlock == 0 {
+		client, err := config.Mainnet.Dial()
+		if err != nil {
+			cliutil.Fail(cliutil.ExitRPC, "Failed to connect to the Ethereum client: %v", err)
+		}
+		currentBlock, err = utils.BlockNumberWithRetry(ctx, client)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitRPC, "Failed to get latest block number: %v", err)
+		}
+	}
+
+	durations := optins.OptInDurations(validators, currentBlock)
+	pubKeys := slices.SortedFunc(maps.Keys(durations), func(a, b string) int {
+		return cmp.Or(cmp.Compare(durations[b], durations[a]), cmp.Compare(a, b))
+	})
+
+	fmt.Printf("Opt-in durations of %d validators as of block %d\n", len(pubKeys), currentBlock)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "pubkey\topt-in type\topt-in block\tblocks\tapprox. time")
+	for _, pubKey := range pubKeys {
+		validator := validators[pubKey]
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n",
+			pubKey, validator.OptInType, validator.OptInBlock, durations[pubKey], optins.BlocksToDuration(durations[pubKey]))
+	}
+	w.Flush()
+}
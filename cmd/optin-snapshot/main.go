@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitavs"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitmiddleware"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/query"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+	"github.com/primevprotocol/validator-registry/pkg/validatoroptinrouter"
+	"github.com/primevprotocol/validator-registry/pkg/vanillaregistry"
+)
+
+const deploymentBlock = uint64(21162202)
+
+func main() {
+	atBlock := flag.Uint64("at-block", 0, "block to snapshot the opted-in set at (required)")
+	output := flag.String("output", "", "output CSV path, defaults to opted_in_snapshot_<block>.csv")
+	outDir := flag.String("out-dir", ".", cliutil.OutDirUsage)
+	batchSize := flag.Int("batch-size", 50, "number of pubkeys per router call")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	if *atBlock == 0 {
+		cliutil.Fail(cliutil.ExitConfig, "--at-block is required")
+	}
+	if *atBlock < deploymentBlock {
+		cliutil.Fail(cliutil.ExitConfig, "--at-block %d is before the opt-in contracts were deployed at block %d", *atBlock, deploymentBlock)
+	}
+	if *batchSize <= 0 {
+		cliutil.Fail(cliutil.ExitConfig, "--batch-size must be positive, got %d", *batchSize)
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("opted_in_snapshot_%d.csv", *atBlock)
+	}
+	outputPath, err := cliutil.OutPath(*outDir, *output)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "%v", err)
+	}
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	client, err := config.Mainnet.Dial()
+	if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "Failed to connect to the Ethereum client: %v", err)
+	}
+	if err := utils.EnsureChainID(ctx, client, config.Mainnet.ChainID); err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Chain ID is not mainnet: %v", err)
+	}
+
+	avsFilterer, err := mevcommitavs.NewMevcommitavsFilterer(common.HexToAddress("0xBc77233855e3274E1903771675Eb71E602D9DC2e"), client)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create mev-commit AVS filterer: %v", err)
+	}
+	middlewareFilterer, err := mevcommitmiddleware.NewMevcommitmiddlewareFilterer(common.HexToAddress("0x21fD239311B050bbeE7F32850d99ADc224761382"), client)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create mev-commit middleware filterer: %v", err)
+	}
+	vanillaFilterer, err := vanillaregistry.NewVanillaregistryFilterer(common.HexToAddress("0x47afdcB2B089C16CEe354811EA1Bbe0DB7c335E9"), client)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create vanilla registry filterer: %v", err)
+	}
+	routerCaller, err := validatoroptinrouter.NewValidatoroptinrouterCaller(common.HexToAddress("0x821798d7b9d57dF7Ed7616ef9111A616aB19ed64"), client)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Opt-In Router caller: %v", err)
+	}
+
+	// Events up to the block give every validator that could be opted in at
+	// it; the router at that block decides which still were.
+	collector := optins.NewCollector(avsFilterer, middlewareFilterer, vanillaFilterer, 50000)
+	candidates, err := collector.Collect(ctx, deploymentBlock, *atBlock)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "Failed to collect opt-in events: %v", err)
+	}
+
+	snapshot, err := snapshotAt(ctx, routerCaller, candidates, *atBlock, *batchSize)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "Failed to query router at block %d: %v", *atBlock, err)
+	}
+
+	if err := writeSnapshot(outputPath, *atBlock, snapshot); err != nil {
+		cliutil.Fail(cliutil.ExitGeneric, "Failed to write snapshot: %v", err)
+	}
+	fmt.Printf("Wrote %d validators opted in at block %d to %s\n", len(snapshot), *atBlock, outputPath)
+}
+
+// snapshotAt returns the candidates the router reports as opted in at
+// atBlock, keeping the latest opt-in per pubkey.
+func snapshotAt(ctx context.Context, router optins.RouterCaller, candidates []optins.Validator, atBlock uint64, batchSize int) ([]optins.Validator, error) {
+	latest := make(map[string]optins.Validator, len(candidates))
+	for _, validator := range candidates {
+		if prev, ok := latest[validator.PubKey]; !ok || validator.OptInBlock >= prev.OptInBlock {
+			latest[validator.PubKey] = validator
+		}
+	}
+	unique := make([]optins.Validator, 0, len(latest))
+	for _, validator := range latest {
+		unique = append(unique, validator)
+	}
+	sort.Slice(unique, func(i, j int) bool {
+		if unique[i].OptInBlock != unique[j].OptInBlock {
+			return unique[i].OptInBlock < unique[j].OptInBlock
+		}
+		return unique[i].PubKey < unique[j].PubKey
+	})
+
+	pubkeys := make([][]byte, 0, len(unique))
+	for _, validator := range unique {
+		pubkey, err := hex.DecodeString(validator.PubKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pubkey %q: %w", validator.PubKey, err)
+		}
+		pubkeys = append(pubkeys, pubkey)
+	}
+
+	statuses, err := query.OptedInStatusAt(ctx, router, pubkeys, batchSize, new(big.Int).SetUint64(atBlock))
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make([]optins.Validator, 0, len(unique))
+	for i, status := range statuses {
+		if optins.IsOptedIn(status) {
+			snapshot = append(snapshot, unique[i])
+		}
+	}
+	return snapshot, nil
+}
+
+// writeSnapshot writes validators as an optins validators CSV preceded by
+// a comment line stamping the block, which optins readers skip.
+func writeSnapshot(path string, atBlock uint64, validators []optins.Validator) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintf(file, "# opted-in validators as of block %d\n", atBlock); err != nil {
+		return err
+	}
+	if err := optins.WriteValidators(file, validators); err != nil {
+		return err
+	}
+	return file.Close()
+}
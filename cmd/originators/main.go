@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+)
+
+// originators prints every address that ever staked a validator, with the
+// number of staked events it sent, from the stored staked events.
+func main() {
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	stakedEvents, err := events.ReadEvents(events.EventStaked)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read staked events: %v", err)
+	}
+
+	counts := make(map[common.Address]int)
+	for _, event := range stakedEvents {
+		counts[common.HexToAddress(event.TxOriginator)]++
+	}
+
+	originators := events.AllOriginators(stakedEvents)
+	fmt.Printf("%d originators across %d staked events\n", len(originators), len(stakedEvents))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "originator\tstaked")
+	for _, originator := range originators {
+		fmt.Fprintf(w, "%s\t%d\n", originator.Hex(), counts[originator])
+	}
+	w.Flush()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/primevprotocol/validator-registry/pkg/beacon"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/proposals"
+)
+
+func main() {
+	validatorsFile := flag.String("validators-file", filepath.Join("..", "all-mainnet-regs", "opted_in_validators.csv"), "path to the opted-in validators CSV produced by all-mainnet-regs")
+	beaconURL := flag.String("beacon-url", "https://ethereum-beacon-api.publicnode.com", "beacon node API to read proposer duties and blocks from")
+	startEpoch := flag.Uint64("start-epoch", 0, "first epoch to report on")
+	endEpoch := flag.Uint64("end-epoch", 0, "last epoch to report on")
+	outDir := flag.String("out-dir", ".", cliutil.OutDirUsage)
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if *endEpoch < *startEpoch {
+		log.Fatalf("--end-epoch %d is before --start-epoch %d", *endEpoch, *startEpoch)
+	}
+	outputPath, err := cliutil.OutPath(*outDir, "proposer_participation.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	validators, err := optins.ReadValidatorsFile(*validatorsFile)
+	if err != nil {
+		log.Fatalf("Failed to load opted in validators: %v", err)
+	}
+	fmt.Printf("Loaded %d opted in validators from %s\n", len(validators), *validatorsFile)
+
+	participation, err := proposals.ParticipationByEpoch(ctx, beacon.NewClient(*beaconURL), validators, *startEpoch, *endEpoch)
+	if err != nil {
+		log.Fatalf("Failed to compute proposer participation: %v", err)
+	}
+
+	if err := writeToCsv(outputPath, participation); err != nil {
+		log.Fatalf("Error writing to CSV: %v", err)
+	}
+	fmt.Printf("Wrote participation for %d epochs to %s\n", len(participation), outputPath)
+}
+
+func writeToCsv(path string, participation []proposals.EpochParticipation) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"epoch", "duties", "proposed", "missed"}); err != nil {
+		return err
+	}
+	for _, p := range participation {
+		record := []string{
+			strconv.FormatUint(p.Epoch, 10),
+			strconv.Itoa(p.Duties),
+			strconv.Itoa(p.Proposed),
+			strconv.Itoa(p.Missed),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
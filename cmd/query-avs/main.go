@@ -2,23 +2,45 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
+	"flag"
 	"fmt"
 	"log"
+	"slices"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
 	"github.com/primevprotocol/validator-registry/pkg/mevcommitavs"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/query"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
 )
 
 func main() {
+	since := flag.String("since", "", cliutil.SinceUsage)
+	var podOwners cliutil.AddressList
+	flag.Var(&podOwners, "pod-owner", "only report validators registered by this pod owner; repeatable, all pod owners if unset")
+	csvPath := flag.String("csv", "", "also write the validators to this CSV file in the opted in validators format")
+	outDir := flag.String("out-dir", ".", cliutil.OutDirUsage)
+	active := flag.Bool("active", false, "only print validators still registered with the AVS, instead of every ValidatorRegistered event")
+	byOwner := flag.Bool("by-owner", false, "print the validators registered by each --pod-owner, grouped by pod owner, and exit")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
 
 	client, err := ethclient.Dial("https://ethereum-holesky-rpc.publicnode.com")
 	if err != nil {
 		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
 	}
 
-	chainID, err := client.ChainID(context.Background())
+	chainID, err := utils.ChainIDWithRetry(ctx, client)
 	if err != nil {
 		log.Fatalf("Failed to get chain id: %v", err)
 	}
@@ -31,17 +53,35 @@ func main() {
 		log.Fatalf("Failed to create Validator Registry caller: %v", err)
 	}
 
-	podOwner := common.HexToAddress("0x90dC8493CF3676C46A5Df49B9febD891C0161AFD")
+	avsCaller, err := mevcommitavs.NewMevcommitavsCaller(mevCommitAVSAddress, client)
+	if err != nil {
+		log.Fatalf("Failed to create mev-commit AVS caller: %v", err)
+	}
 
 	// Get the latest block number
-	latestBlock, err := client.BlockNumber(context.Background())
+	latestBlock, err := utils.BlockNumberWithRetry(ctx, client)
 	if err != nil {
 		log.Fatalf("Failed to get latest block number: %v", err)
 	}
 
 	batchSize := uint64(50000)
 	startBlock := uint64(0)
+	if *since != "" {
+		startBlock, err = cliutil.ResolveStartBlock(ctx, client, *since)
+		if err != nil {
+			log.Fatalf("Failed to resolve --since: %v", err)
+		}
+	}
+
+	if *byOwner {
+		if len(podOwners) == 0 {
+			log.Fatal("--by-owner requires at least one --pod-owner")
+		}
+		printByOwner(ctx, avsFilterer, podOwners, startBlock)
+		return
+	}
 
+	var registered []*mevcommitavs.MevcommitavsValidatorRegistered
 	for startBlock <= latestBlock {
 		endBlock := startBlock + batchSize - 1
 		if endBlock > latestBlock {
@@ -51,21 +91,92 @@ func main() {
 		opts := &bind.FilterOpts{
 			Start:   startBlock,
 			End:     &endBlock,
-			Context: context.Background(),
+			Context: ctx,
 		}
 
-		events, err := avsFilterer.FilterValidatorRegistered(opts, []common.Address{podOwner})
+		events, err := avsFilterer.FilterValidatorRegistered(opts, podOwners)
 		if err != nil {
 			log.Fatalf("Failed to filter Validator Registered events for blocks %d to %d: %v", startBlock, endBlock, err)
 		}
 
-		for events.Next() {
-			fmt.Printf("Block: %d, Validator PubKey: %s, Pod Owner: %s\n",
-				events.Event.Raw.BlockNumber,
-				events.Event.ValidatorPubKey,
-				events.Event.PodOwner)
+		window, err := utils.CollectEvents(utils.IterEvents(events, func() *mevcommitavs.MevcommitavsValidatorRegistered {
+			return events.Event
+		}))
+		if err != nil {
+			log.Fatalf("Failed to iterate Validator Registered events for blocks %d to %d: %v", startBlock, endBlock, err)
 		}
+		registered = append(registered, window...)
 
 		startBlock = endBlock + 1
 	}
+
+	if *active {
+		pubKeys := make([][]byte, len(registered))
+		for i, event := range registered {
+			pubKeys[i] = event.ValidatorPubKey
+		}
+		// Events are already filtered by pod owner, so only a single one
+		// needs rechecking against the current registration.
+		var podOwner common.Address
+		if len(podOwners) == 1 {
+			podOwner = podOwners[0]
+		}
+		activePubKeys, err := query.ActiveAVSValidators(ctx, avsCaller, podOwner, pubKeys)
+		if err != nil {
+			log.Fatalf("Failed to check current AVS registrations: %v", err)
+		}
+		stillActive := make(map[string]bool, len(activePubKeys))
+		for _, pubKey := range activePubKeys {
+			stillActive[string(pubKey)] = true
+		}
+		registered = slices.DeleteFunc(registered, func(event *mevcommitavs.MevcommitavsValidatorRegistered) bool {
+			return !stillActive[string(event.ValidatorPubKey)]
+		})
+		fmt.Printf("%d of %d registered validators are still registered\n", len(registered), len(pubKeys))
+	}
+
+	for _, event := range registered {
+		fmt.Printf("Block: %d, Validator PubKey: %s, Pod Owner: %s\n",
+			event.Raw.BlockNumber,
+			event.ValidatorPubKey,
+			event.PodOwner)
+	}
+
+	if *csvPath != "" {
+		path, err := cliutil.OutPath(*outDir, *csvPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := optins.WriteValidatorsFile(path, toValidators(registered)); err != nil {
+			log.Fatalf("Failed to write CSV file: %v", err)
+		}
+		fmt.Printf("Wrote %d validators to %s\n", len(registered), path)
+	}
+}
+
+func printByOwner(ctx context.Context, avsFilterer *mevcommitavs.MevcommitavsFilterer, podOwners []common.Address, startBlock uint64) {
+	byOwner, err := query.ValidatorsByPodOwners(ctx, avsFilterer, podOwners, startBlock)
+	if err != nil {
+		log.Fatalf("Failed to get validators by pod owner: %v", err)
+	}
+	for _, podOwner := range podOwners {
+		pubKeys := byOwner[podOwner]
+		fmt.Printf("Pod Owner: %s, Validators: %d\n", podOwner, len(pubKeys))
+		for _, pubKey := range pubKeys {
+			fmt.Printf("  %x\n", pubKey)
+		}
+	}
+}
+
+func toValidators(registered []*mevcommitavs.MevcommitavsValidatorRegistered) []optins.Validator {
+	validators := make([]optins.Validator, len(registered))
+	for i, event := range registered {
+		validators[i] = optins.Validator{
+			PubKey:     hex.EncodeToString(event.ValidatorPubKey),
+			OptInType:  optins.OptInTypeEigen,
+			OptInBlock: event.Raw.BlockNumber,
+			PodOwner:   event.PodOwner,
+		}
+	}
+	return validators
 }
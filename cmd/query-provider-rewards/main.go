@@ -8,83 +8,96 @@ import (
 	"log"
 	"math/big"
 	"os"
+	"os/signal"
+	"text/tabwriter"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/primevprotocol/validator-registry/pkg/bidderregistry"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/preconf"
 	"github.com/primevprotocol/validator-registry/pkg/preconfmanager"
-)
-
-const (
-	PRECISION = 1e16
-)
-
-var (
-	BigOneHundredPercent = big.NewInt(100 * PRECISION)
+	"github.com/primevprotocol/validator-registry/pkg/utils"
 )
 
 func main() {
 
 	saveTxes := flag.Bool("save-txes", false, "save committed tx hashes to a file")
+	windowSize := flag.Uint64("window-size", 100000, "number of blocks per event filter call")
+	preconfManagerFlag := flag.String("preconf-manager", config.MevCommitMainnet.PreconfManager.Hex(), "preconf manager contract address")
+	checkTimestamps := flag.Bool("check-timestamps", false, "warn about commitments dispatched after the block that stored them")
+	outDir := flag.String("out-dir", ".", cliutil.OutDirUsage)
+	since := flag.String("since", "", cliutil.SinceUsage)
+	committerFlag := flag.String("committer", "", "only report on this provider address; all committers if empty")
+	bidderRegistryFlag := flag.String("bidder-registry", config.MevCommitMainnet.BidderRegistry.Hex(), "bidder registry contract address")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
 	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
 
 	client, err := ethclient.Dial("https://chainrpc.mev-commit.xyz/")
 	if err != nil {
 		log.Fatalf("Failed to connect to the mev-commit chain client: %v", err)
 	}
 
-	preconfManagerAddr := common.HexToAddress("0x3761bF3932cD22d684A7485002E1424c3aCCD69c")
-	preconfManager, err := preconfmanager.NewPreconfmanagerFilterer(preconfManagerAddr, client)
+	aggregator, err := newAggregator(client, *preconfManagerFlag, *bidderRegistryFlag, *windowSize)
 	if err != nil {
-		log.Fatalf("Failed to create preconfmanager: %v", err)
+		log.Fatal(err)
 	}
 
-	bidderRegistryAddr := common.HexToAddress("0xC973D09e51A20C9Ab0214c439e4B34Dbac52AD67")
-	bidderRegistry, err := bidderregistry.NewBidderregistryFilterer(bidderRegistryAddr, client)
+	endBlock, err := utils.SafeTip(ctx, client, 0)
 	if err != nil {
-		log.Fatalf("Failed to create bidderregistry: %v", err)
+		log.Fatalf("Failed to get current block: %v", err)
 	}
 
-	block, err := client.BlockByNumber(context.Background(), nil)
-	if err != nil {
-		log.Fatalf("Failed to get current block: %v", err)
+	startBlock := uint64(0)
+	if *since != "" {
+		startBlock, err = cliutil.ResolveStartBlock(ctx, client, *since)
+		if err != nil {
+			log.Fatalf("Failed to resolve --since: %v", err)
+		}
 	}
 
-	endBlock := block.Number().Uint64()
-	opts := &bind.FilterOpts{
-		Start: 0,
-		End:   &endBlock,
+	var committers []common.Address
+	if *committerFlag != "" {
+		committer, err := parseAddress("committer", *committerFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		committers = []common.Address{committer}
 	}
-	iter, err := preconfManager.FilterOpenedCommitmentStored(opts, nil)
+
+	commitments, err := aggregator.Commitments(ctx, startBlock, endBlock, committers)
 	if err != nil {
 		log.Fatalf("Failed to get opened commitment stored: %v", err)
 	}
 
-	providerInQuestion := common.HexToAddress("0xE3d71EF44D20917b93AA93e12Bd35b0859824A8F")
-
-	events := []preconfmanager.PreconfmanagerOpenedCommitmentStored{}
-	for iter.Next() {
-		events = append(events, *iter.Event)
+	if *checkTimestamps {
+		suspect, err := preconf.CheckDispatchTimestamps(ctx, client, commitments)
+		if err != nil {
+			log.Fatalf("Failed to check dispatch timestamps: %v", err)
+		}
+		for _, commitment := range suspect {
+			fmt.Printf("Commitment for tx %s dispatched at %d, after its block %d\n", commitment.TxnHash, commitment.DispatchTimestamp, commitment.Raw.BlockNumber)
+		}
+		fmt.Printf("%d of %d commitments have a dispatch timestamp after their block\n", len(suspect), len(commitments))
 	}
 
 	if *saveTxes {
-		txes := []string{}
-		decayedPayments := []*big.Int{}
-		for _, event := range events {
-			if event.Committer == providerInQuestion {
-				txes = append(txes, event.TxnHash)
-				resid := new(big.Int).Mul(event.BidAmt, computeResidualAfterDecay(
-					event.DecayStartTimeStamp,
-					event.DecayEndTimeStamp,
-					event.DispatchTimestamp,
-					false,
-				))
-				resid = new(big.Int).Div(resid, BigOneHundredPercent)
-				decayedPayments = append(decayedPayments, resid)
-			}
+		path, err := cliutil.OutPath(*outDir, "committed_txes.csv")
+		if err != nil {
+			log.Fatal(err)
 		}
-		file, err := os.Create("committed_txes.csv")
+		file, err := os.Create(path)
 		if err != nil {
 			log.Fatalf("Failed to create file: %v", err)
 		}
@@ -95,77 +108,96 @@ func main() {
 		if err := writer.Write([]string{"tx_hash", "decayed_payment"}); err != nil {
 			log.Fatalf("Failed to write header: %v", err)
 		}
-		for i, tx := range txes {
-			if err := writer.Write([]string{tx, decayedPayments[i].String()}); err != nil {
-				log.Fatalf("Failed to write tx: %v", err)
-			}
-		}
-		fmt.Println("Saved txes to committed_txes.csv")
-	}
-
-	totalBidAmt := big.NewInt(0)
-	totalDecayedBidAmtFixed := big.NewInt(0)
-	totalDecayedBidAmtWithBug := big.NewInt(0)
-	for _, event := range events {
-		commitment := event
-		if commitment.Committer == providerInQuestion {
-			totalBidAmt.Add(totalBidAmt, commitment.BidAmt)
-			decayPercentageFixed := computeResidualAfterDecay(
-				commitment.DecayStartTimeStamp,
-				commitment.DecayEndTimeStamp,
-				commitment.DispatchTimestamp,
-				true,
-			)
-			decayPercentageWithBug := computeResidualAfterDecay(
+		for _, commitment := range commitments {
+			residual, err := preconf.ComputeResidualAfterDecay(
 				commitment.DecayStartTimeStamp,
 				commitment.DecayEndTimeStamp,
 				commitment.DispatchTimestamp,
 				false,
 			)
-			decayedBidAmtFixed := new(big.Int).Mul(commitment.BidAmt, decayPercentageFixed)
-			decayedBidAmtWithBug := new(big.Int).Mul(commitment.BidAmt, decayPercentageWithBug)
-			decayedBidAmtFixed = new(big.Int).Div(decayedBidAmtFixed, BigOneHundredPercent)
-			decayedBidAmtWithBug = new(big.Int).Div(decayedBidAmtWithBug, BigOneHundredPercent)
-			totalDecayedBidAmtFixed.Add(totalDecayedBidAmtFixed, decayedBidAmtFixed)
-			totalDecayedBidAmtWithBug.Add(totalDecayedBidAmtWithBug, decayedBidAmtWithBug)
+			if err != nil {
+				log.Fatalf("Failed to compute decay for tx %s: %v", commitment.TxnHash, err)
+			}
+			decayedPayment := preconf.DecayedAmount(commitment.BidAmt, residual)
+			if err := writer.Write([]string{commitment.TxnHash, decayedPayment.String()}); err != nil {
+				log.Fatalf("Failed to write tx: %v", err)
+			}
 		}
+		fmt.Println("Saved txes to", path)
 	}
-	fmt.Println("Total bid amount: ", totalBidAmt)
-	fmt.Println("Total decayed bid amount (decay logic being post PR #673): ", totalDecayedBidAmtFixed)
-	fmt.Println("Total decayed bid amount (decay logic being pre PR #673): ", totalDecayedBidAmtWithBug)
 
-	iter2, err := bidderRegistry.FilterFundsRewarded(opts, nil, nil, []common.Address{providerInQuestion})
+	rewarded, err := aggregator.FundsRewarded(ctx, startBlock, endBlock, committers)
 	if err != nil {
 		log.Fatalf("Failed to get funds rewarded: %v", err)
 	}
 
-	totatlFundsRewarded := big.NewInt(0)
-	for iter2.Next() {
-		reward := iter2.Event
-		totatlFundsRewarded.Add(totatlFundsRewarded, reward.Amount)
+	if len(committers) == 0 {
+		reports, err := preconf.ReportByCommitter(commitments, rewarded)
+		if err != nil {
+			log.Fatalf("Failed to compute decayed bid amounts: %v", err)
+		}
+		printReports(reports)
+		return
 	}
-	fmt.Println("Total funds actually rewarded: ", totatlFundsRewarded)
+
+	totals, err := preconf.SumCommitments(commitments)
+	if err != nil {
+		log.Fatalf("Failed to compute decayed bid amounts: %v", err)
+	}
+	fmt.Println("Total bid amount: ", totals.BidAmt)
+	fmt.Println("Total decayed bid amount (decay logic being post PR #673): ", totals.DecayedBidAmtFixed)
+	fmt.Println("Total decayed bid amount (decay logic being pre PR #673): ", totals.DecayedBidAmtWithBug)
+
+	stats, err := preconf.DecayStats(commitments)
+	if err != nil {
+		log.Fatalf("Failed to compute decay stats: %v", err)
+	}
+	fmt.Println("Residual after decay (decay logic being post PR #673): ", stats.Fixed)
+	fmt.Println("Residual after decay (decay logic being pre PR #673): ", stats.WithBug)
+
+	totalFundsRewarded := rewarded[committers[0]]
+	if totalFundsRewarded == nil {
+		totalFundsRewarded = big.NewInt(0)
+	}
+	fmt.Println("Total funds actually rewarded: ", totalFundsRewarded)
 }
 
-// Copied from https://github.com/primev/mev-commit/blob/main/oracle/pkg/updater/updater.go
-func computeResidualAfterDecay(startTimestamp, endTimestamp, commitTimestamp uint64, fixedLogic bool) *big.Int {
-	if startTimestamp >= endTimestamp || endTimestamp <= commitTimestamp {
-		log.Fatalf("timestamp out of range: %v, %v, %v", startTimestamp, endTimestamp, commitTimestamp)
-		return big.NewInt(0)
+func printReports(reports []preconf.CommitterReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "committer\tcommitments\tbid amount\tdecayed (post #673)\tdecayed (pre #673)\trewarded")
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			r.Committer.Hex(), r.Commitments, r.Totals.BidAmt, r.Totals.DecayedBidAmtFixed, r.Totals.DecayedBidAmtWithBug, r.Rewarded)
 	}
-	if startTimestamp > commitTimestamp {
-		if fixedLogic {
-			return BigOneHundredPercent
-		}
-		return big.NewInt(0)
+	w.Flush()
+}
+
+// newAggregator binds the preconf manager and bidder registry filterers at
+// the given hex addresses.
+func newAggregator(client bind.ContractFilterer, preconfManagerHex, bidderRegistryHex string, windowSize uint64) (*preconf.Aggregator, error) {
+	preconfManagerAddr, err := parseAddress("preconf-manager", preconfManagerHex)
+	if err != nil {
+		return nil, err
+	}
+	bidderRegistryAddr, err := parseAddress("bidder-registry", bidderRegistryHex)
+	if err != nil {
+		return nil, err
+	}
+
+	preconfManager, err := preconfmanager.NewPreconfmanagerFilterer(preconfManagerAddr, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create preconfmanager: %w", err)
 	}
-	totalTime := new(big.Int).SetUint64(endTimestamp - startTimestamp)
-	timePassed := new(big.Int).SetUint64(commitTimestamp - startTimestamp)
-	timeRemaining := new(big.Int).Sub(totalTime, timePassed)
-	scaledRemaining := new(big.Int).Mul(timeRemaining, BigOneHundredPercent)
-	residualPercentage := new(big.Int).Div(scaledRemaining, totalTime)
-	if residualPercentage.Cmp(BigOneHundredPercent) > 0 {
-		residualPercentage = BigOneHundredPercent
+	bidderRegistry, err := bidderregistry.NewBidderregistryFilterer(bidderRegistryAddr, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bidderregistry: %w", err)
+	}
+	return preconf.NewAggregator(preconfManager, bidderRegistry, windowSize), nil
+}
+
+func parseAddress(flagName, value string) (common.Address, error) {
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("invalid --%s address %q", flagName, value)
 	}
-	return residualPercentage
+	return common.HexToAddress(value), nil
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/bidderregistry"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+)
+
+func TestNewAggregatorUsesFlagAddresses(t *testing.T) {
+	contractABI, err := bidderregistry.BidderregistryMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := common.HexToAddress("0x0a")
+	rewardedAt := func(registry common.Address, amount int64) types.Log {
+		return testutil.EventLog(t, contractABI, registry, "FundsRewarded", 1, [32]byte{}, common.Address{}, provider, big.NewInt(1), big.NewInt(amount))
+	}
+	registry := common.HexToAddress("0xbb")
+	backend := &testutil.LogFilterer{Logs: []types.Log{
+		rewardedAt(registry, 5),
+		rewardedAt(config.MevCommitMainnet.BidderRegistry, 100),
+	}}
+
+	aggregator, err := newAggregator(backend, config.MevCommitMainnet.PreconfManager.Hex(), registry.Hex(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewarded, err := aggregator.FundsRewarded(context.Background(), 0, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewarded[provider].Int64() != 5 {
+		t.Errorf("got %v rewarded, want only the 5 rewarded by the --bidder-registry contract", rewarded[provider])
+	}
+}
+
+func TestNewAggregatorRejectsInvalidAddresses(t *testing.T) {
+	valid := common.HexToAddress("0xaa").Hex()
+	tests := []struct {
+		preconfManager, bidderRegistry, wantFlag string
+	}{
+		{"0x123", valid, "--preconf-manager"},
+		{valid, "registry", "--bidder-registry"},
+	}
+	for _, tt := range tests {
+		_, err := newAggregator(&testutil.LogFilterer{}, tt.preconfManager, tt.bidderRegistry, 10)
+		if err == nil || !strings.Contains(err.Error(), tt.wantFlag) {
+			t.Errorf("got error %v, want one naming %s", err, tt.wantFlag)
+		}
+	}
+}
@@ -2,22 +2,41 @@ package main
 
 import (
 	"context"
+	"encoding/csv"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"slices"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
 	"github.com/primevprotocol/validator-registry/pkg/mevcommitmiddleware"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
 )
 
 func main() {
+	csvPath := flag.String("csv", "", "also write the unique operators and vaults to this CSV file")
+	outDir := flag.String("out-dir", ".", cliutil.OutDirUsage)
+	since := flag.String("since", "", cliutil.SinceUsage)
+	confirmations := flag.Uint64("confirmations", 0, "only scan up to this many blocks behind the latest block")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
 	client, err := ethclient.Dial("https://ethereum-rpc.publicnode.com")
 	if err != nil {
 		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
 	}
 
-	chainID, err := client.ChainID(context.Background())
+	chainID, err := utils.ChainIDWithRetry(ctx, client)
 	if err != nil {
 		log.Fatalf("Failed to get chain id: %v", err)
 	}
@@ -30,59 +49,119 @@ func main() {
 		log.Fatalf("Failed to create Validator Registry caller: %v", err)
 	}
 
-	currentBlock, err := client.BlockByNumber(context.Background(), nil)
+	endBlock, err := utils.SafeTip(ctx, client, *confirmations)
 	if err != nil {
-		log.Fatalf("Failed to get current block: %v", err)
+		log.Fatalf("Failed to get end block: %v", err)
 	}
 
 	startBlock := uint64(21633063)
+	if *since != "" {
+		sinceBlock, err := cliutil.ResolveStartBlock(ctx, client, *since)
+		if err != nil {
+			log.Fatalf("Failed to resolve --since: %v", err)
+		}
+		startBlock = max(startBlock, sinceBlock)
+	}
 	batchSize := uint64(50000)
 
-	for i := startBlock; i < currentBlock.NumberU64(); i += batchSize {
-		start := i
-		end := i + batchSize
-		if end > currentBlock.NumberU64() {
-			end = currentBlock.NumberU64()
-		}
-		opts := &bind.FilterOpts{
-			Start:   start,
-			End:     &end,
-			Context: context.Background(),
-		}
-		operators, err := middlewareFilterer.FilterOperatorRegistered(opts, nil)
+	var operators []common.Address
+	err = utils.FilterRange(ctx, startBlock, endBlock, batchSize, func(opts *bind.FilterOpts) error {
+		iter, err := middlewareFilterer.FilterOperatorRegistered(opts, nil)
 		if err != nil {
-			log.Fatalf("Failed to get registered operators for blocks %d to %d: %v", start, end, err)
+			return fmt.Errorf("failed to get registered operators for blocks %d to %d: %w", opts.Start, *opts.End, err)
 		}
-		for operators.Next() {
-			operator := operators.Event.Operator
-			fmt.Println("Operator: ", operator.Hex(), "Registered in tx hash: ", operators.Event.Raw.TxHash.Hex())
+		registered, err := utils.CollectEvents(utils.IterEvents(iter, func() *mevcommitmiddleware.MevcommitmiddlewareOperatorRegistered {
+			return iter.Event
+		}))
+		if err != nil {
+			return fmt.Errorf("failed to iterate through registered operators: %w", err)
 		}
-		if err := operators.Error(); err != nil {
-			log.Fatalf("Failed to iterate through registered operators: %v", err)
+		for _, event := range registered {
+			operators = append(operators, event.Operator)
 		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	for i := startBlock; i < currentBlock.NumberU64(); i += batchSize {
-		start := i
-		end := i + batchSize
-		if end > currentBlock.NumberU64() {
-			end = currentBlock.NumberU64()
+	var vaults []common.Address
+	err = utils.FilterRange(ctx, startBlock, endBlock, batchSize, func(opts *bind.FilterOpts) error {
+		iter, err := middlewareFilterer.FilterVaultRegistered(opts, nil)
+		if err != nil {
+			return fmt.Errorf("failed to get registered vaults for blocks %d to %d: %w", opts.Start, *opts.End, err)
+		}
+		registered, err := utils.CollectEvents(utils.IterEvents(iter, func() *mevcommitmiddleware.MevcommitmiddlewareVaultRegistered {
+			return iter.Event
+		}))
+		if err != nil {
+			return fmt.Errorf("failed to iterate through registered vaults: %w", err)
 		}
-		opts := &bind.FilterOpts{
-			Start:   start,
-			End:     &end,
-			Context: context.Background(),
+		for _, event := range registered {
+			vaults = append(vaults, event.Vault)
 		}
-		vaults, err := middlewareFilterer.FilterVaultRegistered(opts, nil)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	operators = uniqueSorted(operators)
+	vaults = uniqueSorted(vaults)
+
+	fmt.Printf("%d unique operators:\n", len(operators))
+	for _, operator := range operators {
+		fmt.Println("Operator: ", operator.Hex())
+	}
+	fmt.Printf("%d unique vaults:\n", len(vaults))
+	for _, vault := range vaults {
+		fmt.Println("Vault: ", vault.Hex())
+	}
+
+	if *csvPath != "" {
+		path, err := cliutil.OutPath(*outDir, *csvPath)
 		if err != nil {
-			log.Fatalf("Failed to get registered vaults for blocks %d to %d: %v", start, end, err)
+			log.Fatal(err)
 		}
-		for vaults.Next() {
-			vault := vaults.Event.Vault
-			fmt.Println("Vault: ", vault.Hex(), "Registered in tx hash: ", vaults.Event.Raw.TxHash.Hex())
+		if err := writeCSV(path, operators, vaults); err != nil {
+			log.Fatalf("Failed to write CSV file: %v", err)
 		}
-		if err := vaults.Error(); err != nil {
-			log.Fatalf("Failed to iterate through registered vaults: %v", err)
+		fmt.Println("Wrote operators and vaults to", path)
+	}
+}
+
+// uniqueSorted returns addrs sorted with duplicates removed, as an address
+// registered more than once emits an event per registration.
+func uniqueSorted(addrs []common.Address) []common.Address {
+	sorted := slices.Clone(addrs)
+	slices.SortFunc(sorted, func(a, b common.Address) int { return a.Cmp(b) })
+	return slices.Compact(sorted)
+}
+
+func writeCSV(path string, operators, vaults []common.Address) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"type", "address"}); err != nil {
+		return err
+	}
+	for _, operator := range operators {
+		if err := writer.Write([]string{"operator", operator.Hex()}); err != nil {
+			return err
 		}
 	}
+	for _, vault := range vaults {
+		if err := writer.Write([]string{"vault", vault.Hex()}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
 }
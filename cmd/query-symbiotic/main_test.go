@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestUniqueSorted(t *testing.T) {
+	a, b, c := common.HexToAddress("0x0a"), common.HexToAddress("0x0b"), common.HexToAddress("0x0c")
+	addrs := []common.Address{c, a, b, a, c}
+
+	got := uniqueSorted(addrs)
+	if want := []common.Address{a, b, c}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if addrs[0] != c || len(addrs) != 5 {
+		t.Errorf("uniqueSorted modified its input: %v", addrs)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "symbiotic.csv")
+	if err := writeCSV(path, []common.Address{common.HexToAddress("0x0a")}, []common.Address{common.HexToAddress("0x0b")}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "type,address\n" +
+		"operator,0x000000000000000000000000000000000000000A\n" +
+		"vault,0x000000000000000000000000000000000000000b\n"
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -2,65 +2,188 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/query"
+	"github.com/primevprotocol/validator-registry/pkg/registry"
 	utils "github.com/primevprotocol/validator-registry/pkg/utils"
 	vr "github.com/primevprotocol/validator-registry/pkg/validatorregistry"
 )
 
 func main() {
+	networkName := flag.String("network", config.MevCommitTestnet.Name, fmt.Sprintf("network to query, one of %v", config.Names()))
+	batchSize := flag.Int("batch-size", 1000, "number of validators fetched per call")
+	concurrency := flag.Int("concurrency", 1, "number of calls in flight at once")
+	source := flag.String("source", "view", "where to read the staked set from: view (the registry's GetStakedValidators), logs (reconstructed from Staked/Unstaked/Withdrawn logs) or both, which cross-checks them")
+	fromBlock := flag.Uint64("from-block", 0, "first block scanned for registry logs with -source logs or both")
+	countStep := flag.Uint64("count-step", 0, "if set, print the staked validator count as CSV every this many blocks from -count-from to the latest block, then exit; needs an archive node")
+	countFrom := flag.Uint64("count-from", 0, "first block sampled with -count-step")
+	sortFlag := flag.String("sort", string(query.SortPubKey), fmt.Sprintf("order staked validators are printed in, one of %v; staked and recent sort by stake event, so need -source logs or both", query.SortOrders))
+	limit := flag.Int("limit", 10, "print at most this many staked validators; 0 prints all")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
 
-	client := utils.InitClient()
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
 
-	chainID, err := client.ChainID(context.Background())
+	if *source != "view" && *source != "logs" && *source != "both" {
+		log.Fatalf("Invalid -source %q, must be view, logs or both", *source)
+	}
+	order, err := query.ParseSortOrder(*sortFlag)
+	if err != nil {
+		log.Fatalf("Invalid -sort: %v", err)
+	}
+	if *source == "view" && order != query.SortPubKey {
+		log.Fatalf("-sort %s needs stake events, use -source logs or both", order)
+	}
+
+	network, err := config.Lookup(*networkName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	contractAddress, err := network.ValidatorRegistryAddress()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := network.Dial()
+	if err != nil {
+		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+	}
+
+	chainID, err := utils.ChainIDWithRetry(ctx, client)
 	if err != nil {
 		log.Fatalf("Failed to get chain id: %v", err)
 	}
 	fmt.Println("Chain ID: ", chainID)
 
-	contractAddress := common.HexToAddress("0xF263483500e849Bd8d452c9A0F075B606ee64087") // Accurate as of 4/24/2024
+	if *countStep > 0 {
+		sampleStakedCount(ctx, client, contractAddress, *countFrom, *countStep)
+		return
+	}
+
+	var viewValset []query.StakedValidator
+	if *source != "logs" {
+		viewValset = queryView(ctx, client, contractAddress, *batchSize, *concurrency)
+		if *source == "view" {
+			printValidators(viewValset, order, *limit)
+		}
+	}
+
+	var logsValset []query.StakedValidator
+	if *source != "view" {
+		reg, err := registry.ForNetwork(network, network.ValidatorRegistryVersion, client)
+		if err != nil {
+			log.Fatalf("Failed to bind validator registry: %v", err)
+		}
+		fmt.Printf("Reconstructing staked validators from registry logs since block %d...\n", *fromBlock)
+		start := time.Now()
+		logsValset, err = query.StakedValidatorsFromLogs(ctx, reg, *fromBlock)
+		if err != nil {
+			log.Fatalf("Failed to reconstruct staked validators from logs: %v", err)
+		}
+		fmt.Println("Reconstructed validator set length: ", len(logsValset))
+		fmt.Printf("Time to reconstruct staked validators from logs: %s\n", time.Since(start))
+		printValidators(logsValset, order, *limit)
+	}
 
+	if *source == "both" {
+		missing, extra := events.Diff(toSet(query.PubKeys(logsValset)), toSet(query.PubKeys(viewValset)))
+		for _, key := range missing {
+			fmt.Printf("Key %s is staked per logs but not per the view function\n", key)
+		}
+		for _, key := range extra {
+			fmt.Printf("Key %s is staked per the view function but not per logs\n", key)
+		}
+		if len(missing) > 0 || len(extra) > 0 {
+			log.Fatalf("Staked validators from logs and the view function differ: %d only in logs, %d only in view", len(missing), len(extra))
+		}
+		fmt.Println("Staked validators from logs match the view function.")
+	}
+}
+
+// sampleStakedCount prints the registry's staked validator count every step
+// blocks from fromBlock to the latest block as block,staked_validators CSV.
+func sampleStakedCount(ctx context.Context, client *ethclient.Client, contractAddress common.Address, fromBlock, step uint64) {
 	vrc, err := vr.NewValidatorregistryCaller(contractAddress, client)
 	if err != nil {
 		log.Fatalf("Failed to create Validator Registry caller: %v", err)
 	}
+	latestBlock, err := utils.BlockNumberWithRetry(ctx, client)
+	if err != nil {
+		log.Fatalf("Failed to get latest block number: %v", err)
+	}
 
-	fmt.Println("-------------------")
-	fmt.Println("Querying full set of validators BLS pubkeys staked with the registry contract...")
-	fmt.Println("-------------------")
-
-	startBeforeLen := time.Now()
+	fmt.Println("block,staked_validators")
+	for block := fromBlock; block <= latestBlock; block += step {
+		count, err := query.StakedCountAtBlock(ctx, vrc, new(big.Int).SetUint64(block))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%d,%s\n", block, count)
+	}
+}
 
-	numStakedVals, valsetVersion, err := vrc.GetNumberOfStakedValidators(nil)
+// queryView returns the staked validators per the registry's
+// GetStakedValidators view function.
+func queryView(ctx context.Context, client *ethclient.Client, contractAddress common.Address, batchSize, concurrency int) []query.StakedValidator {
+	vrc, err := vr.NewValidatorregistryCaller(contractAddress, client)
 	if err != nil {
-		log.Fatalf("Failed to get number of staked validators: %v", err)
+		log.Fatalf("Failed to create Validator Registry caller: %v", err)
 	}
-	fmt.Println("Number of staked validators: ", numStakedVals)
 
-	elapsedToObtainLen := time.Since(startBeforeLen)
+	fmt.Println("-------------------")
+	fmt.Println("Querying full set of validators BLS pubkeys staked with the registry contract...")
+	fmt.Println("-------------------")
 
 	start := time.Now()
 
-	aggregatedValset := utils.GetStakedValidators(vrc, numStakedVals, valsetVersion)
-	fmt.Println("Aggregated validator set length: ", len(aggregatedValset))
-
-	startIndex := len(aggregatedValset) - 10
-	if startIndex < 0 {
-		startIndex = 0
-	}
-	lastVals := aggregatedValset[startIndex:]
-	fmt.Print("Up to last 10 of staked validator BLS pubkeys: \n[\n")
-	for _, v := range lastVals {
-		fmt.Print(common.Bytes2Hex(v))
-		fmt.Print(",\n")
+	valset, err := query.StakedValidatorsFromView(ctx, vrc, utils.GetStakedValidatorsOpts{
+		BatchSize:   batchSize,
+		Concurrency: concurrency,
+	})
+	if err != nil {
+		log.Fatalf("Failed to get staked validators: %v", err)
 	}
-	fmt.Print("]\n")
+	fmt.Println("Aggregated validator set length: ", len(valset))
 
 	elapsed := time.Since(start)
-	fmt.Printf("Time to query number of staked validators: %s\n", elapsedToObtainLen)
 	fmt.Printf("Time to query all staked validator BLS pubkeys: %s\n", elapsed)
 	fmt.Println("The above performance can be improved utilizing https://geth.ethereum.org/docs/interacting-with-geth/rpc/batch")
+	return valset
+}
+
+// printValidators prints up to limit of valset in order, with the block
+// each validator was staked in if known.
+func printValidators(valset []query.StakedValidator, order query.SortOrder, limit int) {
+	printed := query.LimitStakedValidators(query.SortStakedValidators(valset, order), limit)
+	fmt.Printf("%d of %d staked validator BLS pubkeys, by %s: \n[\n", len(printed), len(valset), order)
+	for _, v := range printed {
+		if v.StakedBlock == 0 {
+			fmt.Printf("%s,\n", v.PubKey)
+			continue
+		}
+		fmt.Printf("%s (staked in block %d),\n", v.PubKey, v.StakedBlock)
+	}
+	fmt.Print("]\n")
+}
+
+func toSet(pubKeys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(pubKeys))
+	for _, pubKey := range pubKeys {
+		set[pubKey] = struct{}{}
+	}
+	return set
 }
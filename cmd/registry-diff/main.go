@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/query"
+	"github.com/primevprotocol/validator-registry/pkg/registry"
+)
+
+func main() {
+	networkName := flag.String("network", config.Holesky.Name, fmt.Sprintf("network both registries are deployed on, one of %v", config.Names()))
+	oldVersion := flag.String("old-registry-version", "", "version of the registry migrated from; defaults to the network's validator registry version")
+	oldRegistry := flag.String("old-registry", "", "address of the registry migrated from; defaults to the network's registry of --old-registry-version")
+	newVersion := flag.String("new-registry-version", string(config.RegistryV1Aug15), "version of the registry migrated to")
+	newRegistry := flag.String("new-registry", "", "address of the registry migrated to; defaults to the network's registry of --new-registry-version")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	network, err := config.Lookup(*networkName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *oldVersion == "" {
+		*oldVersion = string(network.ValidatorRegistryVersion)
+	}
+
+	client, err := network.Dial()
+	if err != nil {
+		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+	}
+
+	oldReg, err := openRegistry(network, config.RegistryVersion(*oldVersion), "--old-registry", *oldRegistry, client)
+	if err != nil {
+		log.Fatalf("Failed to bind old registry: %v", err)
+	}
+	newReg, err := openRegistry(network, config.RegistryVersion(*newVersion), "--new-registry", *newRegistry, client)
+	if err != nil {
+		log.Fatalf("Failed to bind new registry: %v", err)
+	}
+
+	onlyInOld, onlyInNew, err := query.RegistryDiff(ctx, oldReg, newReg)
+	if err != nil {
+		log.Fatalf("Failed to diff registries: %v", err)
+	}
+	for _, pubKey := range onlyInOld {
+		fmt.Printf("Key %s is staked with the %s registry but not the %s one\n", pubKey, oldReg.Version(), newReg.Version())
+	}
+	for _, pubKey := range onlyInNew {
+		fmt.Printf("Key %s is staked with the %s registry but not the %s one\n", pubKey, newReg.Version(), oldReg.Version())
+	}
+	fmt.Printf("%d validators only in the old registry, %d only in the new registry\n", len(onlyInOld), len(onlyInNew))
+}
+
+// openRegistry binds the registry of version at address, or the network's
+// registry of version if address is empty.
+func openRegistry(network config.Network, version config.RegistryVersion, name, address string, client bind.ContractBackend) (registry.Registry, error) {
+	if address == "" {
+		return registry.ForNetwork(network, version, client)
+	}
+	addr, err := parseAddress(name, address)
+	if err != nil {
+		return nil, err
+	}
+	return registry.New(version, addr, client)
+}
+
+func parseAddress(name, s string) (common.Address, error) {
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("invalid %s address %q", name, s)
+	}
+	return common.HexToAddress(s), nil
+}
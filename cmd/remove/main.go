@@ -3,19 +3,22 @@ package main
 import (
 	"context"
 	"crypto/ecdsa"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"math/big"
 	"os"
-	"strings"
+	"time"
 
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
 	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/migrate"
 	"github.com/primevprotocol/validator-registry/pkg/query"
 	utils "github.com/primevprotocol/validator-registry/pkg/utils"
 	vrv1 "github.com/primevprotocol/validator-registry/pkg/validatorregistryv1"
@@ -24,133 +27,147 @@ import (
 func extractPrivateKey(keystoreFile string, passphrase string) *ecdsa.PrivateKey {
 	keyjson, err := os.ReadFile(keystoreFile)
 	if err != nil {
-		panic("failed to read keystore file")
+		cliutil.Fail(cliutil.ExitConfig, "failed to read keystore file")
 	}
 
 	key, err := keystore.DecryptKey(keyjson, passphrase)
 	if err != nil {
-		panic("failed to decrypt key")
+		cliutil.Fail(cliutil.ExitConfig, "failed to decrypt key")
 	}
 
 	return key.PrivateKey
 }
 
 func main() {
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	confirmationWait := flag.Duration("confirmation-wait", 2*time.Minute, "after each unstake tx is included, wait up to this long for the account's pending nonce to advance; 0 disables the wait")
+	subBatchSize := flag.Int("sub-batch-size", migrate.MaxDelegateStakeBatchSize, "maximum number of pubkeys per Unstake tx")
+	minGasTip := flag.Uint64("min-gas-tip", 0, "floor in wei for the suggested gas tip, for chains that suggest a zero tip")
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
 	// Now using owner keystore
 	keystoreFile := os.Getenv("KEYSTORE_FILE")
 	if keystoreFile == "" {
-		fmt.Println("KEYSTORE_FILE env var not supplied")
-		os.Exit(1)
+		cliutil.Fail(cliutil.ExitConfig, "KEYSTORE_FILE env var not supplied")
 	}
 	passphrase := os.Getenv("PASSPHRASE")
 	if passphrase == "" {
-		fmt.Println("PASSPHRASE env var not supplied")
-		os.Exit(1)
+		cliutil.Fail(cliutil.ExitConfig, "PASSPHRASE env var not supplied")
 	}
 	privateKey := extractPrivateKey(keystoreFile, passphrase)
 
 	client, err := ethclient.Dial("https://ethereum-holesky-rpc.publicnode.com")
 	if err != nil {
-		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to connect to the Ethereum client: %v", err)
 	}
 
-	chainID, err := client.ChainID(context.Background())
-	if err != nil {
-		log.Fatalf("Failed to get chain id: %v", err)
+	chainID := config.Holesky.ChainID
+	if err := utils.EnsureChainID(ctx, client, chainID); err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Refusing to transact on unexpected network: %v", err)
 	}
 	fmt.Println("Chain ID: ", chainID)
 
 	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
-	balance, err := client.BalanceAt(context.Background(), fromAddress, nil)
-	if err != nil {
-		log.Fatalf("Failed to get account balance: %v", err)
-	}
-	zeroPointTwoEth := big.NewInt(200000000000000000)
-	if balance.Cmp(zeroPointTwoEth) == -1 {
-		log.Fatalf("Insufficient balance. Please fund %v with at least 0.2 ETH", fromAddress.Hex())
+	err = utils.EnsureMinBalance(ctx, client, fromAddress, big.NewInt(2*params.Ether/10))
+	var insufficient *utils.ErrInsufficientBalance
+	if errors.As(err, &insufficient) {
+		cliutil.Fail(cliutil.ExitInsufficientFunds, "%v", err)
+	} else if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "%v", err)
 	}
 
 	contractAddress := common.HexToAddress("0x5d4fC7B5Aeea4CF4F0Ca6Be09A2F5AaDAd2F2803") // Holesky validator registry 6/13
 
-	vrt, err := vrv1.NewValidatorregistryv1Transactor(contractAddress, client)
+	vr, err := vrv1.NewValidatorregistryv1(contractAddress, client)
 	if err != nil {
-		log.Fatalf("Failed to create Validator Registry transactor: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry binding: %v", err)
 	}
 
 	ec := utils.NewETHClient(client)
 
-	ec.CancelPendingTxes(context.Background(), privateKey)
+	ec.CancelPendingTxes(ctx, privateKey)
 
-	opts, err := ec.CreateTransactOpts(context.Background(), privateKey, chainID)
+	opts, err := ec.CreateTransactOpts(ctx, privateKey, chainID)
 	if err != nil {
-		log.Fatalf("Failed to create transact opts: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to create transact opts: %v", err)
 	}
 
 	// obtain all validators staked under 0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266 and remove them
-	e := make(map[string]events.Event)
-	stakedEvents, err := events.ReadEvents("staked")
+	stakedEvents, err := events.ReadEventsForChain(events.EventStaked, chainID)
 	if err != nil {
-		log.Fatalf("Failed to read staked events: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read staked events: %v", err)
 	}
-	unstakedEvents, err := events.ReadEvents("unstaked")
+	unstakedEvents, err := events.ReadEventsForChain(events.EventUnstaked, chainID)
 	if err != nil {
-		log.Fatalf("Failed to read unstaked events: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read unstaked events: %v", err)
 	}
-	withdrawnEvents, err := events.ReadEvents("withdraw")
+	withdrawnEvents, err := events.ReadEventsForChain(events.EventWithdraw, chainID)
 	if err != nil {
-		log.Fatalf("Failed to read withdrawn events: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read withdrawn events: %v", err)
 	}
 
-	for _, event := range stakedEvents {
-		e[event.ValBLSPubKey] = event
-	}
-	for _, event := range unstakedEvents {
-		delete(e, event.ValBLSPubKey)
-	}
-	for _, event := range withdrawnEvents {
-		delete(e, event.ValBLSPubKey)
-	}
+	states := events.ReconstructWithOriginator(stakedEvents, unstakedEvents, withdrawnEvents)
 
-	stakedVals, err := query.GetAllStakedValsFromRegistry()
+	stakedVals, err := query.GetAllStakedVals(ctx, client)
 	if err != nil {
-		log.Fatalf("Failed to get all staked validators: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to get all staked validators: %v", err)
 	}
 
+	originator := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
 	toRemove := make([][]byte, 0)
 	for _, stakedVal := range stakedVals {
-		if e[stakedVal].TxOriginator == "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+		if common.HexToAddress(states[stakedVal].TxOriginator) == originator {
 			toRemove = append(toRemove, common.Hex2Bytes(stakedVal))
 		}
 	}
 
 	fmt.Println("Number of validators to unstake: ", len(toRemove))
 
-	submitTx := func(
-		ctx context.Context,
-		opts *bind.TransactOpts,
-	) (*types.Transaction, error) {
+	if len(toRemove) == 0 {
+		fmt.Println("Nothing to unstake")
+		return
+	}
 
-		tx, err := vrt.Unstake(opts, toRemove)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unstake: %w", err)
-		}
-		fmt.Println("Unstake tx sent. Transaction hash: ", tx.Hash().Hex())
-		return tx, nil
+	if err := migrate.ValidateSubBatchSize(*subBatchSize, migrate.MaxDelegateStakeBatchSize); err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Invalid --sub-batch-size: %v", err)
 	}
+	fmt.Printf("Unstaking in %d txs of at most %d validators\n", len(migrate.SplitSubBatches(toRemove, *subBatchSize)), *subBatchSize)
 
-	receipt, err := ec.WaitMinedWithRetry(context.Background(), opts, submitTx)
+	executor, err := migrate.NewExecutor(client, opts, &vr.Validatorregistryv1Transactor, migrate.Config{
+		Registry:         contractAddress,
+		SubBatchSize:     *subBatchSize,
+		ConfirmationWait: *confirmationWait,
+		MinGasTip:        new(big.Int).SetUint64(*minGasTip),
+		Unstake:          true,
+	})
 	if err != nil {
-		if strings.Contains(err.Error(), "nonce too low") {
-			fmt.Println("Nonce too low. This likely means the tx was included while constructing a retry...")
-			receipt = &types.Receipt{Status: 1, BlockNumber: big.NewInt(0)}
-		} else {
-			log.Fatalf("Failed to wait for stake tx to be mined: %v", err)
-		}
+		cliutil.Fail(cliutil.ExitConfig, "Invalid unstake config: %v", err)
+	}
+	result, err := executor.Execute(ctx, []migrate.Batch{{PubKeys: toRemove, StakeOriginator: originator}})
+	if err != nil {
+		cliutil.Fail(cliutil.ExitCode(err), "Failed to unstake: %v", err)
+	}
+	if len(result.Unconfirmed) > 0 {
+		fmt.Printf("%d unstake txs not confirmed, relying on verification\n", len(result.Unconfirmed))
 	}
-	fmt.Println("Unstake tx included in block: ", receipt.BlockNumber)
 
-	if receipt.Status == 0 {
-		fmt.Println("Unstake tx included, but failed. Exiting...")
-		os.Exit(1)
+	// The unstake is destructive, so its effect is checked on chain rather
+	// than trusted from receipts.
+	stillStaked, err := migrate.StillStaked(ctx, client, contractAddress, toRemove)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "Failed to verify unstake: %v", err)
+	}
+	for _, pubKey := range stillStaked {
+		fmt.Printf("Validator %x is still staked\n", pubKey)
+	}
+	if len(stillStaked) > 0 {
+		cliutil.Fail(cliutil.ExitPartialFailure, "%d of %d validators still staked after unstake", len(stillStaked), len(toRemove))
 	}
+	fmt.Printf("Verified %d validators are no longer staked\n", len(toRemove))
 }
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/migrate"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+	vrv1 "github.com/primevprotocol/validator-registry/pkg/validatorregistryv1"
+	vrv1_aug15 "github.com/primevprotocol/validator-registry/pkg/validatorregistryv1_aug15"
+)
+
+// replay-stake reconstructs the staked set from stored events and stakes it
+// into a target registry, e.g. after the registry is redeployed.
+func main() {
+	networkName := flag.String("network", config.Holesky.Name, fmt.Sprintf("network of the target registry, one of %v", config.Names()))
+	registryVersion := flag.String("registry-version", string(config.RegistryV1Aug15), fmt.Sprintf("version of the target registry, one of %s or %s", config.RegistryV1, config.RegistryV1Aug15))
+	registryFlag := flag.String("registry", "", "target registry address; defaults to the network's registry of --registry-version")
+	privateKeyFile := flag.String("private-key-file", "", "file containing the hex private key; PRIVATE_KEY env var is used if empty")
+	dryRun := flag.Bool("dry-run", false, "print the replay plan without sending any transaction")
+	maxBatches := flag.Int("max-batches", 0, "stop after processing this many batches; 0 processes all")
+	receiptsOut := flag.String("receipts-out", "", "if set, append a JSON line per included DelegateStake tx with its originator, pubkeys, block and status")
+	checkpointPath := flag.String("checkpoint", "replay_checkpoint.txt", "file recording completed batches, which later runs skip")
+	minGasTip := flag.Uint64("min-gas-tip", 0, "floor in wei for the suggested gas tip, for chains that suggest a zero tip")
+	useNonceManager := flag.Bool("nonce-manager", false, "allocate nonces locally instead of re-querying the pending nonce for each sub batch")
+	sweepTo := flag.String("sweep-to", "", "after all batches complete, send the signing account's remaining balance to this address")
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var sweepAddr common.Address
+	if *sweepTo != "" {
+		if !common.IsHexAddress(*sweepTo) {
+			cliutil.Fail(cliutil.ExitConfig, "invalid --sweep-to address %q", *sweepTo)
+		}
+		sweepAddr = common.HexToAddress(*sweepTo)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	network, err := config.Lookup(*networkName)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "%v", err)
+	}
+
+	stakedEvents, err := events.ReadEvents(events.EventStaked)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read staked events: %v", err)
+	}
+	unstakedEvents, err := events.ReadEvents(events.EventUnstaked)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read unstaked events: %v", err)
+	}
+	withdrawnEvents, err := events.ReadEvents(events.EventWithdraw)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read withdrawn events: %v", err)
+	}
+
+	states := events.ReconstructWithOriginator(stakedEvents, unstakedEvents, withdrawnEvents)
+	batches := migrate.BatchesByOriginatorAndAmount(states)
+	fmt.Println("Number of batches: ", len(batches))
+	for _, batch := range batches {
+		fmt.Printf("Stake originator %s: %d validators at %s wei each\n", batch.StakeOriginator.Hex(), len(batch.PubKeys), batch.AmountPerValidator)
+	}
+	migrate.Summarize(batches, nil).Print(os.Stdout)
+	if *dryRun {
+		return
+	}
+
+	privateKey, err := utils.LoadPrivateKey("PRIVATE_KEY", *privateKeyFile)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to load private key: %v", err)
+	}
+
+	client, err := network.Dial()
+	if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "Failed to connect to the Ethereum client: %v", err)
+	}
+	if err := utils.EnsureChainID(ctx, client, network.ChainID); err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Refusing to transact on unexpected network: %v", err)
+	}
+
+	version := config.RegistryVersion(*registryVersion)
+	var registryAddr common.Address
+	if *registryFlag != "" {
+		if !common.IsHexAddress(*registryFlag) {
+			cliutil.Fail(cliutil.ExitConfig, "invalid --registry address %q", *registryFlag)
+		}
+		registryAddr = common.HexToAddress(*registryFlag)
+	} else {
+		registryAddr, err = network.RegistryAddress(version)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "%v", err)
+		}
+	}
+
+	var transactor migrate.StakeTransactor
+	switch version {
+	case config.RegistryV1:
+		transactor, err = vrv1.NewValidatorregistryv1Transactor(registryAddr, client)
+	case config.RegistryV1Aug15:
+		transactor, err = vrv1_aug15.NewValidatorregistryv1Transactor(registryAddr, client)
+	default:
+		cliutil.Fail(cliutil.ExitConfig, "registry version %s does not support DelegateStake", version)
+	}
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry transactor: %v", err)
+	}
+
+	opts, err := bind.NewKeyedTransactorWithChainID(privateKey, network.ChainID)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create transactor: %v", err)
+	}
+	opts.GasLimit = uint64(3000000)
+
+	executor, err := migrate.NewExecutor(client, opts, transactor, migrate.Config{
+		Registry:        registryAddr,
+		SubBatchSize:    migrate.MaxDelegateStakeBatchSize,
+		UseNonceManager: *useNonceManager,
+		MinGasTip:       new(big.Int).SetUint64(*minGasTip),
+		MaxBatches:      *maxBatches,
+	})
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Invalid replay config: %v", err)
+	}
+
+	checkpoint, err := migrate.OpenCheckpoint(*checkpointPath)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to open checkpoint: %v", err)
+	}
+	defer checkpoint.Close()
+	executor.SetCheckpoint(checkpoint)
+	if *receiptsOut != "" {
+		records, err := migrate.NewTxRecordWriter(*receiptsOut)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to open receipts file: %v", err)
+		}
+		defer records.Close()
+		executor.SetRecordWriter(records)
+	}
+
+	result, err := executor.Execute(ctx, batches)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitCode(err), "Failed to replay stake: %v", err)
+	}
+	for _, u := range result.Unconfirmed {
+		fmt.Printf("Transaction not confirmed, check its outcome on chain. Stake originator: %s\n", u.StakeOriginator.Hex())
+		for _, pubKey := range u.PubKeys {
+			fmt.Printf("Validator pubkey: %x\n", pubKey)
+		}
+	}
+	if len(result.Unconfirmed) > 0 {
+		cliutil.Fail(cliutil.ExitPartialFailure, "%d sub batches not confirmed", len(result.Unconfirmed))
+	}
+	if result.Remaining > 0 {
+		fmt.Printf("Stopped after %d batches, %d remaining. Rerun to continue.\n", result.Processed, result.Remaining)
+		return
+	}
+	fmt.Println("All batches replayed!")
+
+	if *sweepTo != "" {
+		receipt, err := utils.NewETHClient(client).SweepBalance(ctx, privateKey, sweepAddr)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitCode(err), "Failed to sweep remaining balance: %v", err)
+		}
+		fmt.Printf("Swept remaining balance to %s in tx %s\n", sweepAddr.Hex(), receipt.TxHash.Hex())
+	}
+}
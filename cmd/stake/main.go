@@ -3,8 +3,9 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"math/big"
 	"os"
 
@@ -12,64 +13,98 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/migrate"
 	utils "github.com/primevprotocol/validator-registry/pkg/utils"
 	vr "github.com/primevprotocol/validator-registry/pkg/validatorregistry"
 )
 
 func main() {
-
-	privateKeyString := os.Getenv("PRIVATE_KEY")
-	if privateKeyString == "" {
-		fmt.Println("PRIVATE_KEY env var not supplied")
-		os.Exit(1)
+	networkName := flag.String("network", config.MevCommitTestnet.Name, fmt.Sprintf("network to stake on, one of %v", config.Names()))
+	privateKeyFile := flag.String("private-key-file", "", "file containing the hex private key; PRIVATE_KEY env var is used if empty")
+	minGasTip := flag.Uint64("min-gas-tip", 0, "floor in wei for the suggested gas tip, for chains that suggest a zero tip")
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	maxStake := flag.String("max-stake-per-validator", "3100000000000000000", "most wei to stake per validator; the registry's minStake is staked, and the run fails if it exceeds this")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	maxPerValidator, ok := new(big.Int).SetString(*maxStake, 10)
+	if !ok || maxPerValidator.Sign() <= 0 {
+		cliutil.Fail(cliutil.ExitConfig, "invalid --max-stake-per-validator %q", *maxStake)
 	}
 
-	if privateKeyString[:2] == "0x" {
-		privateKeyString = privateKeyString[2:]
+	network, err := config.Lookup(*networkName)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "%v", err)
 	}
-	privateKey, err := crypto.HexToECDSA(privateKeyString)
+	contractAddress, err := network.ValidatorRegistryAddress()
 	if err != nil {
-		fmt.Println("Failed to parse private key")
-		os.Exit(1)
+		cliutil.Fail(cliutil.ExitConfig, "%v", err)
 	}
 
-	client, err := ethclient.Dial("https://chainrpc.testnet.mev-commit.xyz")
+	privateKey, err := utils.LoadPrivateKey("PRIVATE_KEY", *privateKeyFile)
 	if err != nil {
-		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to load private key: %v", err)
 	}
 
-	chainID, err := client.ChainID(context.Background())
+	client, err := network.Dial()
 	if err != nil {
-		log.Fatalf("Failed to get chain id: %v", err)
+		cliutil.Fail(cliutil.ExitRPC, "Failed to connect to the Ethereum client: %v", err)
+	}
+
+	chainID := network.ChainID
+	if err := utils.EnsureChainID(ctx, client, chainID); err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Refusing to transact on unexpected network: %v", err)
 	}
 	fmt.Println("Chain ID: ", chainID)
 
-	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
-	balance, err := client.BalanceAt(context.Background(), fromAddress, nil)
+	publicKeyFilePath := "../../keys_example.txt"
+	pksAsBytes, err := readBLSPublicKeysFromFile(publicKeyFilePath)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "Failed to read public keys from file: %v", err)
+	}
+	pksAsBytes, dups := migrate.DedupWithinBatch(pksAsBytes)
+	for _, dup := range dups {
+		fmt.Printf("Skipping duplicate pubkey %x on line %d, first seen on line %d\n", dup.PubKey, dup.Index+1, dup.FirstIndex+1)
+	}
+
+	vrc, err := vr.NewValidatorregistryCaller(contractAddress, client)
 	if err != nil {
-		log.Fatalf("Failed to get account balance: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry caller: %v", err)
 	}
-	if balance.Cmp(big.NewInt(3100000000000000000)) == -1 {
-		log.Fatalf("Insufficient balance. Please fund %v with at least 3.1 ETH", fromAddress.Hex())
+	amountPerValidator, err := migrate.StakePerValidator(ctx, vrc, maxPerValidator)
+	if err != nil {
+		cliutil.Fail(cliutil.ExitConfig, "%v", err)
 	}
+	fmt.Printf("Staking %s wei per validator\n", amountPerValidator)
 
-	contractAddress := common.HexToAddress("0xF263483500e849Bd8d452c9A0F075B606ee64087") // Accurate as of 4/24/2024
+	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
+	minBalance := new(big.Int).Mul(amountPerValidator, big.NewInt(int64(len(pksAsBytes))))
+	err = utils.EnsureMinBalance(ctx, client, fromAddress, minBalance)
+	var insufficient *utils.ErrInsufficientBalance
+	if errors.As(err, &insufficient) {
+		cliutil.Fail(cliutil.ExitInsufficientFunds, "%v", err)
+	} else if err != nil {
+		cliutil.Fail(cliutil.ExitRPC, "%v", err)
+	}
 
 	vrt, err := vr.NewValidatorregistryTransactor(contractAddress, client)
 	if err != nil {
-		log.Fatalf("Failed to create Validator Registry transactor: %v", err)
+		cliutil.Fail(cliutil.ExitConfig, "Failed to create Validator Registry transactor: %v", err)
 	}
 
 	ec := utils.NewETHClient(client)
-
-	publicKeyFilePath := "../../keys_example.txt"
-	pksAsBytes, err := readBLSPublicKeysFromFile(publicKeyFilePath)
-	if err != nil {
-		log.Fatalf("Failed to read public keys from file: %v", err)
+	if *minGasTip > 0 {
+		ec.MinGasTip = new(big.Int).SetUint64(*minGasTip)
 	}
 
-	batchSize := 20
+	batchSize := migrate.MaxDelegateStakeBatchSize
 	type Batch struct {
 		pubKeys [][]byte
 	}
@@ -84,14 +119,12 @@ func main() {
 
 	for idx, batch := range batches {
 
-		opts, err := ec.CreateTransactOpts(context.Background(), privateKey, chainID)
+		opts, err := ec.CreateTransactOpts(ctx, privateKey, chainID)
 		if err != nil {
-			log.Fatalf("Failed to create transact opts: %v", err)
+			cliutil.Fail(cliutil.ExitRPC, "Failed to create transact opts: %v", err)
 		}
 
-		amountPerValidator := new(big.Int)
-		amountPerValidator.SetString("3100000000000000000", 10)
-		totalAmount := new(big.Int).Mul(amountPerValidator, big.NewInt(int64(batchSize)))
+		totalAmount := new(big.Int).Mul(amountPerValidator, big.NewInt(int64(len(batch.pubKeys))))
 		opts.Value = totalAmount
 
 		submitTx := func(
@@ -107,15 +140,14 @@ func main() {
 			return tx, nil
 		}
 
-		receipt, err := ec.WaitMinedWithRetry(context.Background(), opts, submitTx)
+		receipt, err := ec.WaitMinedWithRetry(ctx, opts, submitTx)
 		if err != nil {
-			log.Fatalf("Failed to wait for stake tx to be mined: %v", err)
+			cliutil.Fail(cliutil.ExitRPC, "Failed to wait for stake tx to be mined: %v", err)
 		}
 		fmt.Println("Stake tx included in block: ", receipt.BlockNumber)
 
 		if receipt.Status == 0 {
-			fmt.Println("Stake tx included, but failed. Exiting...")
-			os.Exit(1)
+			cliutil.Fail(cliutil.ExitRevert, "Stake tx included, but failed. Exiting...")
 		}
 
 		fmt.Println("-------------------")
@@ -3,19 +3,23 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"math/big"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
 	events "github.com/primevprotocol/validator-registry/pkg/events"
-	utils "github.com/primevprotocol/validator-registry/pkg/utils"
-	vr "github.com/primevprotocol/validator-registry/pkg/validatorregistry"
+	"github.com/primevprotocol/validator-registry/pkg/registry"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
 	"github.com/urfave/cli/v2"
 )
 
@@ -23,16 +27,74 @@ func main() {
 	app := &cli.App{
 		Name:  "store-events",
 		Usage: "Store and validate validator registry v1 events",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "network",
+				Usage: fmt.Sprintf("network of the registry, one of %v", config.Names()),
+				Value: config.MevCommitTestnet.Name,
+			},
+			&cli.StringFlag{
+				Name:  "registry-version",
+				Usage: fmt.Sprintf("registry deployment to read, one of %v; defaults to the network's validator registry", []config.RegistryVersion{config.RegistryOriginal, config.RegistryV1, config.RegistryV1Aug15}),
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: cliutil.TimeoutUsage,
+			},
+			&cli.StringFlag{
+				Name:  "log-level",
+				Usage: cliutil.LogLevelUsage,
+				Value: "info",
+			},
+			&cli.BoolFlag{
+				Name:  "log-json",
+				Usage: cliutil.LogJSONUsage,
+			},
+		},
+		Before: func(c *cli.Context) error {
+			cliutil.SetupLogging(c.String("log-level"), c.Bool("log-json"))
+			return nil
+		},
 		Commands: []*cli.Command{
 			{
 				Name:   "store",
 				Usage:  "Store all events related to validator registry v1 in artifacts directory",
 				Action: storeEvents,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "gzip",
+						Usage: "compress stored artifacts as .json.gz",
+					},
+				},
 			},
 			{
 				Name:   "validate",
 				Usage:  "Validate events from artifacts directory",
 				Action: validateEvents,
+				Flags: []cli.Flag{
+					&cli.Uint64Flag{
+						Name:  "from-block",
+						Usage: "ignore stored events before this block",
+					},
+					&cli.Uint64Flag{
+						Name:  "to-block",
+						Usage: "ignore stored events after this block, reconstructing the set as of it",
+						Value: math.MaxUint64,
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "print the validation report as JSON on stdout",
+					},
+					&cli.StringFlag{
+						Name:  "export-csv",
+						Usage: "write the reconstructed validators (pubkey, amount, originator) to this CSV file",
+					},
+					&cli.StringFlag{
+						Name:  "out-dir",
+						Usage: cliutil.OutDirUsage,
+						Value: ".",
+					},
+				},
 			},
 		},
 	}
@@ -43,123 +105,74 @@ func main() {
 	}
 }
 
-func initClientAndFilterer() (*ethclient.Client, *vr.ValidatorregistryFilterer, error) {
-	client, err := ethclient.Dial("https://chainrpc.testnet.mev-commit.xyz")
+// openRegistry dials the --network and binds its --registry-version
+// registry.
+func openRegistry(c *cli.Context) (*ethclient.Client, registry.Registry, error) {
+	network, err := config.Lookup(c.String("network"))
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to connect to the Ethereum client: %v", err)
+		return nil, nil, err
+	}
+	version := network.ValidatorRegistryVersion
+	if v := c.String("registry-version"); v != "" {
+		version = config.RegistryVersion(v)
 	}
 
-	contractAddress := common.HexToAddress("0xF263483500e849Bd8d452c9A0F075B606ee64087")
-	vrf, err := vr.NewValidatorregistryFilterer(contractAddress, client)
+	client, err := network.Dial()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create Validator Registry filterer: %v", err)
+		return nil, nil, err
 	}
-
-	return client, vrf, nil
-}
-
-func queryEvents(vrf *vr.ValidatorregistryFilterer, filterOpts *bind.FilterOpts, eventType string) ([]events.Event, error) {
-	var e []events.Event
-
-	switch eventType {
-	case "staked":
-		iter, err := vrf.FilterStaked(filterOpts, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get staked events: %v", err)
-		}
-		for iter.Next() {
-			event := iter.Event
-			e = append(e, events.NewEvent(
-				event.TxOriginator.Hex(),
-				common.Bytes2Hex(event.ValBLSPubKey),
-				event.Amount,
-				event.Raw.BlockNumber,
-			))
-		}
-		if err := iter.Error(); err != nil {
-			return nil, fmt.Errorf("error encountered during iteration: %v", err)
-		}
-	case "unstaked":
-		iter, err := vrf.FilterUnstaked(filterOpts, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get unstaked events: %v", err)
-		}
-		for iter.Next() {
-			event := iter.Event
-			e = append(e, events.NewEvent(
-				event.TxOriginator.Hex(),
-				common.Bytes2Hex(event.ValBLSPubKey),
-				event.Amount,
-				event.Raw.BlockNumber,
-			))
-		}
-		if err := iter.Error(); err != nil {
-			return nil, fmt.Errorf("error encountered during iteration: %v", err)
-		}
-	case "withdraw":
-		iter, err := vrf.FilterStakeWithdrawn(filterOpts, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get withdraw events: %v", err)
-		}
-		for iter.Next() {
-			event := iter.Event
-			e = append(e, events.NewEvent(
-				event.TxOriginator.Hex(),
-				common.Bytes2Hex(event.ValBLSPubKey),
-				event.Amount,
-				event.Raw.BlockNumber,
-			))
-		}
-		if err := iter.Error(); err != nil {
-			return nil, fmt.Errorf("error encountered during iteration: %v", err)
-		}
-	default:
-		return nil, fmt.Errorf("unknown event type: %s", eventType)
+	reg, err := registry.ForNetwork(network, version, client)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	return e, nil
+	return client, reg, nil
 }
 
 func storeEvents(c *cli.Context) error {
-	client, vrf, err := initClientAndFilterer()
+	ctx, cancel := cliutil.WithTimeout(c.Context, c.Duration("timeout"))
+	defer cancel()
+
+	client, reg, err := openRegistry(c)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	filterOpts := &bind.FilterOpts{Start: 0, End: nil}
+	filterOpts := &bind.FilterOpts{Start: 0, End: nil, Context: ctx}
 
 	if err := os.MkdirAll("../../artifacts", os.ModePerm); err != nil {
 		log.Fatalf("Failed to create artifacts directory: %v", err)
 	}
 
 	currentDate := time.Now().Format("2006-01-02_15-04-05")
-	blockNumber, err := client.BlockNumber(context.Background())
+	blockNumber, err := utils.BlockNumberWithRetry(ctx, client)
 	if err != nil {
 		log.Fatalf("Failed to get latest block number: %v", err)
 	}
 
-	serializeEvents := func(filename string, events []events.Event) {
-		file, err := os.Create(filepath.Join("../../artifacts", filename))
-		if err != nil {
-			log.Fatalf("Failed to create file: %v", err)
-		}
-		defer file.Close()
+	chainID, err := utils.ChainIDWithRetry(ctx, client)
+	if err != nil {
+		log.Fatalf("Failed to get chain id: %v", err)
+	}
 
-		encoder := json.NewEncoder(file)
-		encoder.SetIndent("", "  ")
-		if err := encoder.Encode(events); err != nil {
-			log.Fatalf("Failed to encode events to JSON: %v", err)
+	ext := "json"
+	if c.Bool("gzip") {
+		ext = "json.gz"
+	}
+
+	serializeEvents := func(filename string, e []events.Event) {
+		file := events.EventFile{ChainID: chainID, Events: e}
+		if err := events.WriteEventFile(filepath.Join("../../artifacts", filename), file); err != nil {
+			log.Fatal(err)
 		}
 	}
 
-	eventTypes := []string{"staked", "unstaked", "withdraw"}
-	for _, eventType := range eventTypes {
-		fmt.Printf("Querying all %s events from mev-commit chain genesis...\n", eventType)
-		events, err := queryEvents(vrf, filterOpts, eventType)
+	for _, eventType := range events.EventKinds {
+		fmt.Printf("Querying all %s events from %s registry genesis...\n", eventType, reg.Version())
+		events, err := reg.Events(filterOpts, eventType)
 		if err != nil {
 			log.Fatal(err)
 		}
-		serializeEvents(fmt.Sprintf("%s_events_%s_block_%d.json", eventType, currentDate, blockNumber), events)
+		serializeEvents(fmt.Sprintf("%s_events_%s_block_%d.%s", eventType, currentDate, blockNumber, ext), events)
 	}
 
 	fmt.Println("Events have been serialized to JSON files.")
@@ -167,53 +180,125 @@ func storeEvents(c *cli.Context) error {
 }
 
 func validateEvents(c *cli.Context) error {
-	stakedEvents, err := events.ReadEvents("staked")
+	ctx, cancel := cliutil.WithTimeout(c.Context, c.Duration("timeout"))
+	defer cancel()
+
+	fromBlock, toBlock := c.Uint64("from-block"), c.Uint64("to-block")
+
+	// With --json, progress output goes to stderr so stdout holds only the
+	// report.
+	var jsonOut io.Writer
+	if c.Bool("json") {
+		stdout := os.Stdout
+		jsonOut = stdout
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = stdout }()
+	}
+
+	stakedEvents, err := events.ReadEventsInRange(events.EventStaked, fromBlock, toBlock)
 	if err != nil {
 		return err
 	}
 
-	unstakedEvents, err := events.ReadEvents("unstaked")
+	unstakedEvents, err := events.ReadEventsInRange(events.EventUnstaked, fromBlock, toBlock)
 	if err != nil {
 		return err
 	}
 
-	withdrawnEvents, err := events.ReadEvents("withdraw")
+	withdrawnEvents, err := events.ReadEventsInRange(events.EventWithdraw, fromBlock, toBlock)
 	if err != nil {
 		return err
 	}
 
 	validators := reconstructValidators(stakedEvents, unstakedEvents, withdrawnEvents)
 
-	recentEventsValidators, err := queryValidatorsFromRecentEvents()
+	if name := c.String("export-csv"); name != "" {
+		path, err := cliutil.OutPath(c.String("out-dir"), name)
+		if err != nil {
+			return err
+		}
+		states := events.ReconstructWithOriginator(stakedEvents, unstakedEvents, withdrawnEvents)
+		if err := events.WriteValidatorStatesFile(path, states); err != nil {
+			return fmt.Errorf("failed to export reconstructed validators: %w", err)
+		}
+		fmt.Printf("Exported %d reconstructed validators to %s\n", len(states), path)
+	}
+
+	_, reg, err := openRegistry(c)
 	if err != nil {
 		return err
 	}
 
-	onChainValidators, err := queryOnChainValidators()
+	recentEventsValidators, err := queryValidatorsFromRecentEvents(ctx, reg)
 	if err != nil {
 		return err
 	}
 
-	if compareValidators(validators, recentEventsValidators) {
-		fmt.Println("Validator lists match with recent events.")
-	} else {
-		fmt.Println("Validator lists do not match with recent events.")
-		fmt.Printf("Reconstructed list length: %d\n", len(validators))
-		fmt.Printf("Recent events list length: %d\n", len(recentEventsValidators))
+	onChainValidators, err := queryOnChainValidators(ctx, reg)
+	if errors.Is(err, registry.ErrNotEnumerable) {
+		fmt.Printf("Skipping on-chain comparison: %v\n", err)
+	} else if err != nil {
+		return err
+	}
+
+	report := ValidationReport{
+		ReconstructedCount: len(validators),
+		RecentEvents:       compareValidators(validators, recentEventsValidators),
+	}
+	if onChainValidators != nil {
+		onChain := compareValidators(validators, onChainValidators)
+		report.OnChain = &onChain
+		// Validators staked on chain without a stored event usually mean the
+		// stored events are incomplete, so call them out on stderr with --json
+		// too. Without it printComparison lists them below.
+		if jsonOut != nil {
+			for _, key := range onChain.Unexpected {
+				fmt.Printf("WARNING: validator %s is staked on chain but has no stored event\n", key)
+			}
+		}
+		if len(onChain.Unexpected) > 0 {
+			fmt.Printf("WARNING: %d on-chain validators have no stored event, the stored events may be incomplete\n", len(onChain.Unexpected))
+		}
 	}
 
-	if compareValidators(validators, onChainValidators) {
-		fmt.Println("Validator lists match with on-chain data.")
-		fmt.Println("NOTE THIS ASSUMES NO VALIDATOR HAS GONE THROUGH A STAKE -> UNSTAKE -> WITHDRAW -> STAKE CYCLE")
+	if jsonOut != nil {
+		encoder := json.NewEncoder(jsonOut)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
 	} else {
-		fmt.Println("Validator lists do not match with on-chain data.")
-		fmt.Printf("Reconstructed list length: %d\n", len(validators))
-		fmt.Printf("On-chain list length: %d\n", len(onChainValidators))
+		printComparison("recent events", report.ReconstructedCount, report.RecentEvents)
+		if report.OnChain != nil {
+			printComparison("on-chain data", report.ReconstructedCount, *report.OnChain)
+		}
+		if report.OnChain != nil && report.OnChain.Match {
+			fmt.Println("NOTE THIS ASSUMES NO VALIDATOR HAS GONE THROUGH A STAKE -> UNSTAKE -> WITHDRAW -> STAKE CYCLE")
+		}
 	}
 
+	if !report.OK() {
+		return fmt.Errorf("reconstructed validators do not match")
+	}
 	return nil
 }
 
+func printComparison(source string, reconstructedCount int, c Comparison) {
+	if c.Match {
+		fmt.Printf("Validator lists match with %s.\n", source)
+		return
+	}
+	fmt.Printf("Validator lists do not match with %s.\n", source)
+	for _, key := range c.Missing {
+		fmt.Printf("Key %s is missing in actual validators\n", key)
+	}
+	for _, key := range c.Unexpected {
+		fmt.Printf("Key %s is not in reconstructed validators\n", key)
+	}
+	fmt.Printf("Reconstructed list length: %d\n", reconstructedCount)
+	fmt.Printf("%s list length: %d\n", source, c.Count)
+}
+
 func reconstructValidators(stakedEvents, unstakedEvents, withdrawnEvents []events.Event) map[string]*big.Int {
 	validators := make(map[string]*big.Int)
 
@@ -235,24 +320,19 @@ func reconstructValidators(stakedEvents, unstakedEvents, withdrawnEvents []event
 	return validators
 }
 
-func queryValidatorsFromRecentEvents() (map[string]*big.Int, error) {
-	_, vrf, err := initClientAndFilterer()
-	if err != nil {
-		return nil, err
-	}
-
-	filterOpts := &bind.FilterOpts{Start: 0, End: nil}
-	stakedEvents, err := queryEvents(vrf, filterOpts, "staked")
+func queryValidatorsFromRecentEvents(ctx context.Context, reg registry.Registry) (map[string]*big.Int, error) {
+	filterOpts := &bind.FilterOpts{Start: 0, End: nil, Context: ctx}
+	stakedEvents, err := reg.Events(filterOpts, events.EventStaked)
 	if err != nil {
 		return nil, err
 	}
 
-	unstakedEvents, err := queryEvents(vrf, filterOpts, "unstaked")
+	unstakedEvents, err := reg.Events(filterOpts, events.EventUnstaked)
 	if err != nil {
 		return nil, err
 	}
 
-	withdrawnEvents, err := queryEvents(vrf, filterOpts, "withdraw")
+	withdrawnEvents, err := reg.Events(filterOpts, events.EventWithdraw)
 	if err != nil {
 		return nil, err
 	}
@@ -260,46 +340,14 @@ func queryValidatorsFromRecentEvents() (map[string]*big.Int, error) {
 	return reconstructValidators(stakedEvents, unstakedEvents, withdrawnEvents), nil
 }
 
-func queryOnChainValidators() (map[string]*big.Int, error) {
-	client := utils.InitClient()
-	contractAddress := common.HexToAddress("0xF263483500e849Bd8d452c9A0F075B606ee64087")
-	vrc, err := vr.NewValidatorregistryCaller(contractAddress, client)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Validator Registry caller: %v", err)
-	}
-
-	numStakedVals, valsetVersion, err := vrc.GetNumberOfStakedValidators(nil)
+func queryOnChainValidators(ctx context.Context, reg registry.Registry) (map[string]*big.Int, error) {
+	pubKeys, err := reg.StakedValidators(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get number of staked validators: %v", err)
-	}
-
-	aggregatedValset := utils.GetStakedValidators(vrc, numStakedVals, valsetVersion)
-	validators := make(map[string]*big.Int)
-	for _, val := range aggregatedValset {
-		validators[common.Bytes2Hex(val)] = big.NewInt(0) // Assuming amount is not needed here
+		return nil, err
 	}
-
-	isOptedIn, err := vrc.IsStaked(nil, []byte("8ed81d776f9de04813920d48bd6f9f3804001e069b0867559a374f1de7d3d7371b4180524844655842cf5a9ffa9f4dcb"))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get is opted in: %v", err)
+	validators := make(map[string]*big.Int, len(pubKeys))
+	for _, pubKey := range pubKeys {
+		validators[pubKey] = big.NewInt(0) // Assuming amount is not needed here
 	}
-	fmt.Printf("Is opted in: %t\n", isOptedIn)
-
 	return validators, nil
 }
-
-func compareValidators(reconstructed, actual map[string]*big.Int) bool {
-	if len(reconstructed) != len(actual) {
-		return false
-	}
-
-	toReturn := true
-	for key := range reconstructed {
-		if _, exists := actual[key]; !exists {
-			fmt.Printf("Key %s is missing in actual validators\n", key)
-			toReturn = false
-		}
-	}
-
-	return toReturn
-}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math/big"
+
+	"github.com/primevprotocol/validator-registry/pkg/events"
+)
+
+// ValidationReport is the result of the validate command, emitted as JSON
+// with --json.
+type ValidationReport struct {
+	ReconstructedCount int        `json:"reconstructed_count"`
+	RecentEvents       Comparison `json:"recent_events"`
+	// OnChain is nil for registries that cannot enumerate their stake.
+	OnChain *Comparison `json:"on_chain,omitempty"`
+}
+
+// Comparison compares the reconstructed validator set against another
+// source.
+type Comparison struct {
+	Count int  `json:"count"`
+	Match bool `json:"match"`
+	// Missing are reconstructed validators absent from the other source.
+	Missing []string `json:"missing"`
+	// Unexpected are validators in the other source that were not
+	// reconstructed.
+	Unexpected []string `json:"unexpected"`
+}
+
+// OK reports whether every comparison matched.
+func (r ValidationReport) OK() bool {
+	return r.RecentEvents.Match && (r.OnChain == nil || r.OnChain.Match)
+}
+
+func compareValidators(reconstructed, actual map[string]*big.Int) Comparison {
+	c := Comparison{Count: len(actual)}
+	c.Missing, c.Unexpected = events.Diff(reconstructed, actual)
+	c.Match = len(c.Missing) == 0 && len(c.Unexpected) == 0
+	return c
+}
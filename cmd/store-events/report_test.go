@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"slices"
+	"testing"
+)
+
+func TestCompareValidators(t *testing.T) {
+	reconstructed := map[string]*big.Int{"aa": big.NewInt(1), "bb": big.NewInt(1)}
+	actual := map[string]*big.Int{"bb": big.NewInt(1), "cc": big.NewInt(1), "dd": big.NewInt(1)}
+
+	c := compareValidators(reconstructed, actual)
+	if c.Match || c.Count != 3 || !slices.Equal(c.Missing, []string{"aa"}) || !slices.Equal(c.Unexpected, []string{"cc", "dd"}) {
+		t.Errorf("got %+v, want 3 actual with aa missing and cc, dd unexpected", c)
+	}
+	if c := compareValidators(reconstructed, reconstructed); !c.Match {
+		t.Errorf("got %+v comparing a set with itself, want a match", c)
+	}
+}
+
+func TestValidationReportOK(t *testing.T) {
+	match, mismatch := Comparison{Match: true}, Comparison{}
+	tests := []struct {
+		report ValidationReport
+		want   bool
+	}{
+		{ValidationReport{RecentEvents: match}, true},
+		{ValidationReport{RecentEvents: match, OnChain: &match}, true},
+		{ValidationReport{RecentEvents: match, OnChain: &mismatch}, false},
+		{ValidationReport{RecentEvents: mismatch}, false},
+	}
+	for i, tt := range tests {
+		if got := tt.report.OK(); got != tt.want {
+			t.Errorf("report %d OK() = %t, want %t", i, got, tt.want)
+		}
+	}
+}
+
+func TestValidationReportJSON(t *testing.T) {
+	report := ValidationReport{
+		ReconstructedCount: 2,
+		RecentEvents:       compareValidators(map[string]*big.Int{"aa": nil}, map[string]*big.Int{"aa": nil}),
+	}
+	got, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Matching comparisons list empty arrays rather than null, and on_chain
+	// is omitted when not checked.
+	want := `{"reconstructed_count":2,"recent_events":{"count":1,"match":true,"missing":[],"unexpected":[]}}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
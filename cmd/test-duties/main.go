@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -11,8 +13,10 @@ import (
 	"os/signal"
 	"reflect"
 	"strconv"
-	"strings"
 	"time"
+
+	"github.com/primevprotocol/validator-registry/pkg/beacon"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
 )
 
 const (
@@ -129,12 +133,7 @@ func (c *Client) FetchProposerDuties(ctx context.Context, epoch uint64) (*Propos
 			return nil, fmt.Errorf("reading response body: %v", err)
 		}
 
-		bodyString := string(bodyBytes)
-		if strings.Contains(bodyString, "Proposer duties were requested for a future epoch") {
-			return nil, fmt.Errorf("proposer duties were requested for a future epoch")
-		}
-
-		return nil, fmt.Errorf("unexpected status code: %v, response: %s", resp.StatusCode, bodyString)
+		return nil, beacon.ParseAPIError(resp.StatusCode, bodyBytes)
 	}
 
 	var dutiesResp ProposerDutiesResponse
@@ -208,10 +207,16 @@ func PrintDuties(duties *ProposerDutiesResponse, changed bool) {
 }
 
 func main() {
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
 	client := NewClient(beaconAPIURL)
 	cache := NewDutiesCache()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 
 	sigCh := make(chan os.Signal, 1)
@@ -315,7 +320,7 @@ func fetchAndTrackDuties(ctx context.Context, client *Client, cache *DutiesCache
 	fmt.Printf("\nFetching proposer duties for next epoch %d\n", nextEpoch)
 	nextDuties, err := client.FetchProposerDuties(ctx, nextEpoch)
 	if err != nil {
-		if strings.Contains(err.Error(), "future epoch") {
+		if errors.Is(err, beacon.ErrFutureEpoch) {
 			fmt.Printf("Next epoch duties not yet available: %v\n", err)
 		} else {
 			fmt.Printf("Error fetching next epoch duties: %v\n", err)
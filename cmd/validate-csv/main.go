@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+// validate-csv checks CSV artifacts against their expected columns before
+// they are fed into opted-in-slots or missed-slots, reporting problems by
+// line number.
+func main() {
+	schemaName := flag.String("schema", "", fmt.Sprintf("schema to check the files against, one of %v; inferred from each file name if empty", optins.SchemaNames()))
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	if flag.NArg() == 0 {
+		cliutil.Fail(cliutil.ExitConfig, "usage: validate-csv [-schema name] file.csv...")
+	}
+
+	failed := 0
+	for _, path := range flag.Args() {
+		var schema optins.Schema
+		var err error
+		if *schemaName != "" {
+			schema, err = optins.LookupSchema(*schemaName)
+		} else {
+			schema, err = optins.SchemaForFile(path)
+		}
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "%v", err)
+		}
+
+		problems, err := schema.ValidateFile(path)
+		if err != nil {
+			cliutil.Fail(cliutil.ExitConfig, "Failed to read %s: %v", path, err)
+		}
+		for _, problem := range problems {
+			fmt.Printf("%s: %s\n", path, problem)
+		}
+		if len(problems) > 0 {
+			failed++
+			fmt.Printf("%s: %d problems against the %s schema\n", path, len(problems), schema.Name)
+			continue
+		}
+		fmt.Printf("%s: valid %s CSV\n", path, schema.Name)
+	}
+	if failed > 0 {
+		cliutil.Fail(cliutil.ExitGeneric, "%d of %d files failed validation", failed, flag.NArg())
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/query"
+	vr "github.com/primevprotocol/validator-registry/pkg/validatorregistry"
+)
+
+func main() {
+	networkName := flag.String("network", config.MevCommitTestnet.Name, fmt.Sprintf("network to query, one of %v", config.Names()))
+	watch := flag.Bool("watch", false, "keep polling and log whenever the valset version changes")
+	interval := flag.Duration("interval", 12*time.Second, "polling interval for --watch")
+	timeout := flag.Duration("timeout", 0, cliutil.TimeoutUsage)
+	logLevel := flag.String("log-level", "info", cliutil.LogLevelUsage)
+	logJSON := flag.Bool("log-json", false, cliutil.LogJSONUsage)
+	flag.Parse()
+	cliutil.SetupLogging(*logLevel, *logJSON)
+
+	ctx, cancel := cliutil.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	network, err := config.Lookup(*networkName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	contractAddress, err := network.ValidatorRegistryAddress()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := network.Dial()
+	if err != nil {
+		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
+	}
+
+	vrc, err := vr.NewValidatorregistryCaller(contractAddress, client)
+	if err != nil {
+		log.Fatalf("Failed to create Validator Registry caller: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	cur, err := query.GetValsetVersion(ctx, vrc)
+	if err != nil {
+		log.Fatalf("Failed to get valset version: %v", err)
+	}
+	fmt.Printf("Valset version: %v (%v staked validators)\n", cur.Version, cur.NumStakedVals)
+
+	if !*watch {
+		return
+	}
+
+	fmt.Printf("Watching for valset version changes every %s\n", *interval)
+	err = query.WatchValsetVersion(ctx, vrc, cur, *interval, func(prev, cur query.ValsetVersion) error {
+		fmt.Printf("%s valset version changed: %v -> %v (%v -> %v staked validators)\n",
+			time.Now().Format(time.RFC3339), prev.Version, cur.Version, prev.NumStakedVals, cur.NumStakedVals)
+		return nil
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("Failed to watch valset version: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Package beacon looks up validator status from beacon chain APIs.
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// Status is a validator status as reported by beaconcha.in.
+type Status string
+
+const (
+	StatusActiveOnline  Status = "active_online"
+	StatusActiveOffline Status = "active_offline"
+	// StatusNotFound means the pubkey does not resolve to a validator index,
+	// i.e. no deposit for it has been processed.
+	StatusNotFound Status = "not_found"
+)
+
+// IsActive reports whether the validator is active, whether or not it is
+// currently attesting.
+func (s Status) IsActive() bool {
+	return s == StatusActiveOnline || s == StatusActiveOffline
+}
+
+// BeaconchainClient queries the beaconcha.in v1 API.
+type BeaconchainClient struct {
+	apiURL     string
+	httpClient *http.Client
+	retries    int
+	// Clock times the backoff between retries. Defaults to utils.RealClock.
+	Clock utils.Clock
+}
+
+// NewBeaconchainClient returns a client for the beaconcha.in API at apiURL,
+// e.g. https://holesky.beaconcha.in.
+func NewBeaconchainClient(apiURL string) *BeaconchainClient {
+	return &BeaconchainClient{
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		httpClient: utils.NewTracingHTTPClient(),
+		retries:    defaultRetries,
+		Clock:      utils.RealClock{},
+	}
+}
+
+type beaconchainResponse struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// IsRegistered returns the beaconcha.in status of the validator with BLS
+// pubkey, or StatusNotFound if it is not known to the beacon chain.
+func (c *BeaconchainClient) IsRegistered(ctx context.Context, pubkey string) (Status, error) {
+	url := fmt.Sprintf("%s/api/v1/validator/%s", c.apiURL, normalizePubkey(pubkey))
+	resp, err := doWithRetry(ctx, c.Clock, nil, c.httpClient, c.retries, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("accept", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("querying validator %s: %w", pubkey, err)
+	}
+
+	var result beaconchainResponse
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return "", fmt.Errorf("decoding response with status code %d: %w", resp.statusCode, err)
+	}
+	if result.Status != "OK" {
+		if strings.Contains(result.Status, "did not resolve to a validator index") {
+			return StatusNotFound, nil
+		}
+		return "", fmt.Errorf("unexpected response status %q with status code %d", result.Status, resp.statusCode)
+	}
+
+	var data struct {
+		Status string `json:"status"`
+	}
+	if len(result.Data) == 0 || string(result.Data) == "null" {
+		return StatusNotFound, nil
+	}
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		return "", fmt.Errorf("decoding validator data: %w", err)
+	}
+	if data.Status == "" {
+		return "", fmt.Errorf("response for validator %s has no status", pubkey)
+	}
+	return Status(data.Status), nil
+}
+
+func normalizePubkey(pubkey string) string {
+	return "0x" + strings.ToLower(strings.TrimPrefix(pubkey, "0x"))
+}
@@ -0,0 +1,137 @@
+package beacon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+)
+
+// newBeaconchainServer answers validator lookups with the responses in
+// order, repeating the last, and records the path of the latest request.
+func newBeaconchainServer(t *testing.T, requests *atomic.Int32, lastPath *atomic.Value, responses ...func(w http.ResponseWriter)) *BeaconchainClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		lastPath.Store(r.URL.Path)
+		responses[min(n, len(responses))-1](w)
+	}))
+	t.Cleanup(server.Close)
+	return NewBeaconchainClient(server.URL + "/")
+}
+
+func respond(statusCode int, body string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(statusCode)
+		w.Write([]byte(body))
+	}
+}
+
+func TestIsRegisteredRetriesServerErrors(t *testing.T) {
+	var requests atomic.Int32
+	var lastPath atomic.Value
+	client := newBeaconchainServer(t, &requests, &lastPath,
+		respond(http.StatusServiceUnavailable, "busy"),
+		respond(http.StatusTooManyRequests, "slow down"),
+		respond(http.StatusOK, `{"status":"OK","data":{"status":"active_online"}}`),
+	)
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	client.Clock = clock
+
+	result := make(chan Status, 1)
+	go func() {
+		status, err := client.IsRegistered(context.Background(), "0xABCD")
+		if err != nil {
+			t.Error(err)
+		}
+		result <- status
+	}()
+	// Backoff grows by a second per attempt.
+	for attempt := 1; attempt <= 2; attempt++ {
+		eventually(t, "the client to back off", func() bool { return clock.Waiters() == 1 })
+		clock.Advance(time.Duration(attempt) * time.Second)
+	}
+
+	if status := <-result; status != StatusActiveOnline || !status.IsActive() {
+		t.Errorf("got status %q, want active_online", status)
+	}
+	if requests.Load() != 3 {
+		t.Errorf("got %d requests, want 3", requests.Load())
+	}
+	if path := lastPath.Load(); path != "/api/v1/validator/0xabcd" {
+		t.Errorf("requested %v, want the lowercased 0x prefixed pubkey", path)
+	}
+}
+
+func TestIsRegisteredNotFound(t *testing.T) {
+	for name, body := range map[string]string{
+		"unresolved index": `{"status":"ERROR: pubkey did not resolve to a validator index","data":null}`,
+		"null data":        `{"status":"OK","data":null}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var requests atomic.Int32
+			var lastPath atomic.Value
+			client := newBeaconchainServer(t, &requests, &lastPath, respond(http.StatusOK, body))
+			status, err := client.IsRegistered(context.Background(), "abcd")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != StatusNotFound || status.IsActive() {
+				t.Errorf("got status %q, want not_found", status)
+			}
+		})
+	}
+}
+
+func TestIsRegisteredGivesUpAfterRetries(t *testing.T) {
+	var requests atomic.Int32
+	var lastPath atomic.Value
+	client := newBeaconchainServer(t, &requests, &lastPath, respond(http.StatusBadGateway, "down"))
+	client.retries = 1
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	client.Clock = clock
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := client.IsRegistered(context.Background(), "abcd")
+		errc <- err
+	}()
+	eventually(t, "the client to back off", func() bool { return clock.Waiters() == 1 })
+	clock.Advance(time.Second)
+
+	if err := <-errc; err == nil {
+		t.Fatal("got no error after every attempt failed")
+	}
+	if requests.Load() != 2 {
+		t.Errorf("got %d requests, want 2", requests.Load())
+	}
+}
+
+func TestIsRegisteredStopsBackingOffWhenCancelled(t *testing.T) {
+	var requests atomic.Int32
+	var lastPath atomic.Value
+	client := newBeaconchainServer(t, &requests, &lastPath, respond(http.StatusServiceUnavailable, "busy"))
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	client.Clock = clock
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := client.IsRegistered(ctx, "abcd")
+		errc <- err
+	}()
+	eventually(t, "the client to back off", func() bool { return clock.Waiters() == 1 })
+	cancel()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("got %d requests, want 1", requests.Load())
+	}
+}
@@ -0,0 +1,92 @@
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// SlotsPerEpoch is the number of slots in an epoch on mainnet and Holesky.
+const SlotsPerEpoch = 32
+
+// BlockNumberForSlot returns the number of the execution block proposed in
+// slot, or an error matching ErrNotFound if the slot was missed. It reads
+// the blinded block, whose execution payload header carries the number
+// without the full payload's transactions.
+func (c *Client) BlockNumberForSlot(ctx context.Context, slot uint64) (uint64, error) {
+	url := fmt.Sprintf("%s/eth/v1/beacon/blinded_blocks/%d", c.apiURL, slot)
+	resp, err := doWithRetry(ctx, c.Clock, c.limiter, c.httpClient, c.retries, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("accept", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("querying block for slot %d: %w", slot, err)
+	}
+	if resp.statusCode != http.StatusOK {
+		return 0, fmt.Errorf("fetching block for slot %d: %w", slot, ParseAPIError(resp.statusCode, resp.body))
+	}
+
+	var result struct {
+		Data struct {
+			Message struct {
+				Body struct {
+					ExecutionPayloadHeader struct {
+						BlockNumber string `json:"block_number"`
+					} `json:"execution_payload_header"`
+				} `json:"body"`
+			} `json:"message"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return 0, fmt.Errorf("decoding response: %w", err)
+	}
+	blockNumber, err := strconv.ParseUint(result.Data.Message.Body.ExecutionPayloadHeader.BlockNumber, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing block number for slot %d: %w", slot, err)
+	}
+	return blockNumber, nil
+}
+
+// BlockRangeForEpochs returns the numbers of the first and last execution
+// blocks proposed in [startEpoch, endEpoch], skipping missed slots at
+// either end. It returns an error matching ErrNotFound if every slot in the
+// span was missed.
+func (c *Client) BlockRangeForEpochs(ctx context.Context, startEpoch, endEpoch uint64) (firstBlock, lastBlock uint64, err error) {
+	if endEpoch < startEpoch {
+		return 0, 0, fmt.Errorf("end epoch %d is before start epoch %d", endEpoch, startEpoch)
+	}
+	startSlot := startEpoch * SlotsPerEpoch
+	endSlot := (endEpoch+1)*SlotsPerEpoch - 1
+
+	firstSlot := startSlot
+	for ; firstSlot <= endSlot; firstSlot++ {
+		firstBlock, err = c.BlockNumberForSlot(ctx, firstSlot)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return 0, 0, err
+		}
+	}
+	if firstSlot > endSlot {
+		return 0, 0, fmt.Errorf("no block proposed in epochs %d to %d: %w", startEpoch, endEpoch, ErrNotFound)
+	}
+
+	for lastSlot := endSlot; lastSlot > firstSlot; lastSlot-- {
+		lastBlock, err = c.BlockNumberForSlot(ctx, lastSlot)
+		if err == nil {
+			return firstBlock, lastBlock, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return 0, 0, err
+		}
+	}
+	return firstBlock, firstBlock, nil
+}
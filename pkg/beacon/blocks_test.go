@@ -0,0 +1,82 @@
+package beacon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newBlocksServer serves the blinded blocks of slots in blocks, keyed by
+// slot, and 404s every other slot as missed.
+func newBlocksServer(t *testing.T, blocks map[uint64]uint64) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slot, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/eth/v1/beacon/blinded_blocks/"), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		blockNumber, ok := blocks[slot]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":404,"message":"NOT_FOUND: beacon block at slot"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"message":{"slot":"%d","body":{"execution_payload_header":{"block_number":"%d"}}}}}`, slot, blockNumber)
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient(server.URL)
+	client.SetRateLimit(0)
+	return client
+}
+
+func TestBlockNumberForSlot(t *testing.T) {
+	client := newBlocksServer(t, map[uint64]uint64{100: 2000})
+	blockNumber, err := client.BlockNumberForSlot(context.Background(), 100)
+	if err != nil || blockNumber != 2000 {
+		t.Fatalf("got %d, %v, want 2000", blockNumber, err)
+	}
+	if _, err := client.BlockNumberForSlot(context.Background(), 101); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v for a missed slot, want ErrNotFound", err)
+	}
+}
+
+func TestBlockRangeForEpochsSkipsMissedBoundarySlots(t *testing.T) {
+	blocks := make(map[uint64]uint64)
+	// Epochs 2 and 3 span slots 64 to 127; 64, 65 and 127 are missed.
+	for slot := uint64(66); slot < 127; slot++ {
+		blocks[slot] = 1000 + slot
+	}
+	client := newBlocksServer(t, blocks)
+
+	first, last, err := client.BlockRangeForEpochs(context.Background(), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != 1066 || last != 1126 {
+		t.Errorf("got blocks %d to %d, want 1066 to 1126", first, last)
+	}
+}
+
+func TestBlockRangeForEpochsSingleBlock(t *testing.T) {
+	client := newBlocksServer(t, map[uint64]uint64{40: 500})
+	first, last, err := client.BlockRangeForEpochs(context.Background(), 1, 1)
+	if err != nil || first != 500 || last != 500 {
+		t.Fatalf("got %d to %d, %v, want 500 to 500", first, last, err)
+	}
+}
+
+func TestBlockRangeForEpochsAllMissed(t *testing.T) {
+	client := newBlocksServer(t, nil)
+	if _, _, err := client.BlockRangeForEpochs(context.Background(), 1, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	if _, _, err := client.BlockRangeForEpochs(context.Background(), 3, 2); err == nil {
+		t.Error("reversed epoch range accepted")
+	}
+}
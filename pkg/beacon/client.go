@@ -0,0 +1,217 @@
+package beacon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// Canonical validator statuses returned by the beacon node API, see
+// https://hackmd.io/ofFJ5gOmQpu1jjHilHbdQQ
+const (
+	ValidatorStatusPendingInitialized = "pending_initialized"
+	ValidatorStatusPendingQueued      = "pending_queued"
+	ValidatorStatusActiveOngoing      = "active_ongoing"
+	ValidatorStatusActiveExiting      = "active_exiting"
+	ValidatorStatusActiveSlashed      = "active_slashed"
+	ValidatorStatusExitedUnslashed    = "exited_unslashed"
+	ValidatorStatusExitedSlashed      = "exited_slashed"
+	ValidatorStatusWithdrawalPossible = "withdrawal_possible"
+	ValidatorStatusWithdrawalDone     = "withdrawal_done"
+)
+
+// ErrValidatorNotFound is returned when the beacon node knows no validator
+// with the requested pubkey.
+var ErrValidatorNotFound = errors.New("validator not found")
+
+// IsExitedStatus reports whether a validator with status has exited or is
+// past exit, so can no longer propose.
+func IsExitedStatus(status string) bool {
+	switch status {
+	case ValidatorStatusExitedUnslashed, ValidatorStatusExitedSlashed,
+		ValidatorStatusWithdrawalPossible, ValidatorStatusWithdrawalDone:
+		return true
+	}
+	return false
+}
+
+// Client queries the standard beacon node API. It is safe for concurrent
+// use, and all its requests share one rate limit.
+type Client struct {
+	apiURL     string
+	httpClient *http.Client
+	retries    int
+	limiter    *rateLimiter
+	// Clock times the backoff between retries and the rate limit. Defaults
+	// to utils.RealClock.
+	Clock utils.Clock
+}
+
+func NewClient(apiURL string) *Client {
+	return &Client{
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		httpClient: utils.NewTracingHTTPClient(),
+		retries:    defaultRetries,
+		limiter:    newRateLimiter(defaultRequestsPerSecond),
+		Clock:      utils.RealClock{},
+	}
+}
+
+// SetRateLimit limits the client to requestsPerSecond requests, counting
+// retries, or removes the limit if requestsPerSecond is not positive.
+func (c *Client) SetRateLimit(requestsPerSecond int) {
+	c.limiter = newRateLimiter(requestsPerSecond)
+}
+
+type validatorData struct {
+	Index     string `json:"index"`
+	Status    string `json:"status"`
+	Validator struct {
+		Pubkey string `json:"pubkey"`
+	} `json:"validator"`
+}
+
+// ValidatorStatus returns the canonical status of the validator with BLS
+// pubkey at the head state, or ErrValidatorNotFound.
+func (c *Client) ValidatorStatus(ctx context.Context, pubkey string) (string, error) {
+	url := fmt.Sprintf("%s/eth/v1/beacon/states/head/validators/%s", c.apiURL, normalizePubkey(pubkey))
+	resp, err := doWithRetry(ctx, c.Clock, c.limiter, c.httpClient, c.retries, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("accept", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("querying validator %s: %w", pubkey, err)
+	}
+	if resp.statusCode == http.StatusNotFound {
+		// This endpoint only 404s for an unknown validator, whatever the
+		// message says.
+		return "", fmt.Errorf("%s: %w", pubkey, ErrValidatorNotFound)
+	}
+	if resp.statusCode != http.StatusOK {
+		return "", ParseAPIError(resp.statusCode, resp.body)
+	}
+
+	var result struct {
+		Data validatorData `json:"data"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	if result.Data.Status == "" {
+		return "", fmt.Errorf("response for validator %s has no status", pubkey)
+	}
+	return result.Data.Status, nil
+}
+
+// maxIDsPerRequest bounds the id list of a single ValidatorStatuses request,
+// as beacon nodes limit request sizes.
+const maxIDsPerRequest = 1000
+
+// ValidatorStatuses returns the canonical head state status of each of
+// pubkeys, keyed by the pubkey as passed in. Pubkeys unknown to the beacon
+// node are absent from the result.
+func (c *Client) ValidatorStatuses(ctx context.Context, pubkeys []string) (map[string]string, error) {
+	statuses := make(map[string]string, len(pubkeys))
+	for i := 0; i < len(pubkeys); i += maxIDsPerRequest {
+		end := min(i+maxIDsPerRequest, len(pubkeys))
+		if err := c.validatorStatusesBatch(ctx, pubkeys[i:end], statuses); err != nil {
+			return nil, fmt.Errorf("querying validators %d to %d: %w", i, end, err)
+		}
+	}
+	return statuses, nil
+}
+
+func (c *Client) validatorStatusesBatch(ctx context.Context, pubkeys []string, statuses map[string]string) error {
+	byNormalized := make(map[string]string, len(pubkeys))
+	ids := make([]string, 0, len(pubkeys))
+	for _, pubkey := range pubkeys {
+		normalized := normalizePubkey(pubkey)
+		byNormalized[normalized] = pubkey
+		ids = append(ids, normalized)
+	}
+	body, err := json.Marshal(struct {
+		IDs []string `json:"ids"`
+	}{IDs: ids})
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/eth/v1/beacon/states/head/validators", c.apiURL)
+	resp, err := doWithRetry(ctx, c.Clock, c.limiter, c.httpClient, c.retries, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	if resp.statusCode != http.StatusOK {
+		return ParseAPIError(resp.statusCode, resp.body)
+	}
+
+	var result struct {
+		Data []validatorData `json:"data"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	for _, data := range result.Data {
+		pubkey, ok := byNormalized[normalizePubkey(data.Validator.Pubkey)]
+		if !ok {
+			return fmt.Errorf("response contains unrequested validator %s", data.Validator.Pubkey)
+		}
+		statuses[pubkey] = data.Status
+	}
+	return nil
+}
+
+// FinalizedEpoch returns the epoch of the head state's finalized
+// checkpoint.
+func (c *Client) FinalizedEpoch(ctx context.Context) (uint64, error) {
+	url := fmt.Sprintf("%s/eth/v1/beacon/states/head/finality_checkpoints", c.apiURL)
+	resp, err := doWithRetry(ctx, c.Clock, c.limiter, c.httpClient, c.retries, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("accept", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("querying finality checkpoints: %w", err)
+	}
+	if resp.statusCode != http.StatusOK {
+		return 0, ParseAPIError(resp.statusCode, resp.body)
+	}
+
+	var result struct {
+		Data struct {
+			Finalized struct {
+				Epoch string `json:"epoch"`
+			} `json:"finalized"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return 0, fmt.Errorf("decoding response: %w", err)
+	}
+	epoch, err := strconv.ParseUint(result.Data.Finalized.Epoch, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing finalized epoch %q: %w", result.Data.Finalized.Epoch, err)
+	}
+	return epoch, nil
+}
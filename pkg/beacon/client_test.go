@@ -0,0 +1,135 @@
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newValidatorServer serves the head state statuses of the validators in
+// statuses, keyed by lowercase 0x prefixed pubkey.
+func newValidatorServer(t *testing.T, statuses map[string]string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pubkey := strings.TrimPrefix(r.URL.Path, "/eth/v1/beacon/states/head/validators/")
+		status, ok := statuses[pubkey]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":404,"message":"Validator not found"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"index":"1","status":%q,"validator":{"pubkey":%q}}}`, status, pubkey)
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient(server.URL)
+	client.SetRateLimit(0)
+	return client
+}
+
+func TestValidatorStatus(t *testing.T) {
+	client := newValidatorServer(t, map[string]string{"0xaa": ValidatorStatusActiveOngoing, "0xbb": ValidatorStatusWithdrawalDone})
+
+	status, err := client.ValidatorStatus(context.Background(), "AA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != ValidatorStatusActiveOngoing || IsExitedStatus(status) {
+		t.Errorf("got status %q, want active_ongoing", status)
+	}
+	status, err = client.ValidatorStatus(context.Background(), "0xbb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsExitedStatus(status) {
+		t.Errorf("status %q is not exited, want withdrawal_done to be", status)
+	}
+}
+
+func TestValidatorStatusNotFound(t *testing.T) {
+	client := newValidatorServer(t, nil)
+	if _, err := client.ValidatorStatus(context.Background(), "0xcc"); !errors.Is(err, ErrValidatorNotFound) {
+		t.Errorf("got error %v, want ErrValidatorNotFound", err)
+	}
+}
+
+// newBatchValidatorServer answers POSTed id lists with the statuses of the
+// known ids, plus extra if not empty, recording the size of each request.
+func newBatchValidatorServer(t *testing.T, statuses map[string]string, extra string, sizes *[]int) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		*sizes = append(*sizes, len(req.IDs))
+		ids := req.IDs
+		if extra != "" {
+			ids = append(ids, extra)
+		}
+		data := []validatorData{}
+		for _, id := range ids {
+			status, ok := statuses[id]
+			if !ok && id != extra {
+				continue
+			}
+			var v validatorData
+			v.Status = status
+			v.Validator.Pubkey = id
+			data = append(data, v)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient(server.URL)
+	client.SetRateLimit(0)
+	return client
+}
+
+func TestValidatorStatusesBatchesAndKeysByInput(t *testing.T) {
+	known := map[string]string{}
+	pubkeys := make([]string, 0, maxIDsPerRequest+1)
+	for i := 0; i <= maxIDsPerRequest; i++ {
+		pubkey := fmt.Sprintf("%04x", i)
+		pubkeys = append(pubkeys, pubkey)
+		// Every other validator is unknown to the beacon node.
+		if i%2 == 0 {
+			known["0x"+pubkey] = ValidatorStatusActiveOngoing
+		}
+	}
+	pubkeys[0] = "0x0000"
+	var sizes []int
+	client := newBatchValidatorServer(t, known, "", &sizes)
+
+	statuses, err := client.ValidatorStatuses(context.Background(), pubkeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 2 || sizes[0] != maxIDsPerRequest || sizes[1] != 1 {
+		t.Errorf("got requests of %v ids, want [%d 1]", sizes, maxIDsPerRequest)
+	}
+	if len(statuses) != len(known) {
+		t.Errorf("got %d statuses, want %d", len(statuses), len(known))
+	}
+	if statuses["0x0000"] != ValidatorStatusActiveOngoing || statuses["03e8"] != ValidatorStatusActiveOngoing {
+		t.Error("statuses are not keyed by the pubkeys as passed in")
+	}
+	if _, ok := statuses["0001"]; ok {
+		t.Error("got a status for an unknown validator")
+	}
+}
+
+func TestValidatorStatusesRejectsUnrequestedValidator(t *testing.T) {
+	var sizes []int
+	client := newBatchValidatorServer(t, map[string]string{"0xaa": ValidatorStatusActiveOngoing}, "0xff", &sizes)
+	if _, err := client.ValidatorStatuses(context.Background(), []string{"aa"}); err == nil || !strings.Contains(err.Error(), "unrequested validator") {
+		t.Errorf("got error %v, want an unrequested validator error", err)
+	}
+}
@@ -0,0 +1,108 @@
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ProposerDuty is a validator's assignment to propose the block of a slot.
+type ProposerDuty struct {
+	// Pubkey is the hex BLS pubkey without 0x prefix.
+	Pubkey string
+	Slot   uint64
+}
+
+// ProposerDuties returns the proposer duties of epoch, or an error matching
+// ErrFutureEpoch if the beacon node can't compute them yet.
+func (c *Client) ProposerDuties(ctx context.Context, epoch uint64) ([]ProposerDuty, error) {
+	url := fmt.Sprintf("%s/eth/v1/validator/duties/proposer/%d", c.apiURL, epoch)
+	resp, err := doWithRetry(ctx, c.Clock, c.limiter, c.httpClient, c.retries, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("accept", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying proposer duties for epoch %d: %w", epoch, err)
+	}
+	if resp.statusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching proposer duties for epoch %d: %w", epoch, ParseAPIError(resp.statusCode, resp.body))
+	}
+
+	var result struct {
+		Data []struct {
+			Pubkey string `json:"pubkey"`
+			Slot   string `json:"slot"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp.body, &result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	duties := make([]ProposerDuty, len(result.Data))
+	for i, data := range result.Data {
+		slot, err := strconv.ParseUint(data.Slot, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing slot %q in epoch %d: %w", data.Slot, epoch, err)
+		}
+		duties[i] = ProposerDuty{Pubkey: strings.TrimPrefix(data.Pubkey, "0x"), Slot: slot}
+	}
+	return duties, nil
+}
+
+// ProposerDutiesRange returns the proposer duties of every epoch in
+// [startEpoch, endEpoch] keyed by epoch, fetching up to concurrency epochs
+// at once. Requests share the client's rate limit and retry policy, which
+// backs off on 429s. Epochs from the first one the beacon node reports as in
+// the future are left out, so the range stops at the last available epoch.
+func (c *Client) ProposerDutiesRange(ctx context.Context, startEpoch, endEpoch uint64, concurrency int) (map[uint64][]ProposerDuty, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var mu sync.Mutex
+	byEpoch := make(map[uint64][]ProposerDuty)
+	firstFuture := endEpoch + 1
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for epoch := startEpoch; epoch <= endEpoch; epoch++ {
+		mu.Lock()
+		past := epoch >= firstFuture
+		mu.Unlock()
+		if past {
+			break
+		}
+		g.Go(func() error {
+			duties, err := c.ProposerDuties(ctx, epoch)
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, ErrFutureEpoch) {
+				firstFuture = min(firstFuture, epoch)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			byEpoch[epoch] = duties
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	for epoch := range byEpoch {
+		if epoch >= firstFuture {
+			delete(byEpoch, epoch)
+		}
+	}
+	return byEpoch, nil
+}
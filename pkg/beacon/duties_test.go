@@ -0,0 +1,102 @@
+package beacon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newDutiesServer serves one duty per epoch, for slot epoch*32, and reports
+// epochs from future on as in the future like Lighthouse does.
+func newDutiesServer(t *testing.T, future uint64, requests *atomic.Int32) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		epoch, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/eth/v1/validator/duties/proposer/"), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if epoch >= future {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":400,"message":"Proposer duties were requested for a future epoch"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"data":[{"pubkey":"0x%02x","slot":"%d"}]}`, epoch, epoch*SlotsPerEpoch)
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient(server.URL)
+	client.SetRateLimit(0)
+	return client
+}
+
+func TestProposerDuties(t *testing.T) {
+	var requests atomic.Int32
+	client := newDutiesServer(t, 10, &requests)
+	duties, err := client.ProposerDuties(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(duties) != 1 || duties[0].Pubkey != "03" || duties[0].Slot != 96 {
+		t.Errorf("got duties %+v, want pubkey 03 in slot 96", duties)
+	}
+	if _, err := client.ProposerDuties(context.Background(), 10); !errors.Is(err, ErrFutureEpoch) {
+		t.Errorf("got %v for a future epoch, want ErrFutureEpoch", err)
+	}
+}
+
+func TestProposerDutiesRangeStopsAtFutureEpoch(t *testing.T) {
+	var requests atomic.Int32
+	client := newDutiesServer(t, 5, &requests)
+
+	byEpoch, err := client.ProposerDutiesRange(context.Background(), 2, 8, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byEpoch) != 3 {
+		t.Fatalf("got duties for %d epochs, want epochs 2 to 4", len(byEpoch))
+	}
+	for epoch := uint64(2); epoch <= 4; epoch++ {
+		duties := byEpoch[epoch]
+		if len(duties) != 1 || duties[0].Slot != epoch*SlotsPerEpoch {
+			t.Errorf("epoch %d: got duties %+v", epoch, duties)
+		}
+	}
+}
+
+func TestProposerDutiesRangeAllAvailable(t *testing.T) {
+	var requests atomic.Int32
+	client := newDutiesServer(t, 100, &requests)
+
+	byEpoch, err := client.ProposerDutiesRange(context.Background(), 0, 9, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byEpoch) != 10 || requests.Load() != 10 {
+		t.Errorf("got %d epochs from %d requests, want 10 from 10", len(byEpoch), requests.Load())
+	}
+}
+
+func TestProposerDutiesEmptyData(t *testing.T) {
+	for _, body := range []string{`{"data":[]}`, `{}`} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		}))
+		client := NewClient(server.URL)
+		client.SetRateLimit(0)
+		duties, err := client.ProposerDuties(context.Background(), 1)
+		server.Close()
+		if err != nil {
+			t.Errorf("%s: got error %v, want none", body, err)
+		}
+		if len(duties) != 0 {
+			t.Errorf("%s: got duties %+v, want none", body, duties)
+		}
+	}
+}
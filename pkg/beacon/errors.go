@@ -0,0 +1,72 @@
+package beacon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrFutureEpoch is returned when duties are requested for an epoch the
+	// beacon node can't compute yet.
+	ErrFutureEpoch = errors.New("requested epoch is in the future")
+	// ErrNotFound is returned for a 404 response not about a validator,
+	// e.g. a missed slot's block.
+	ErrNotFound = errors.New("not found")
+	// ErrBadRequest is returned for a 400 response not matching a more
+	// specific error.
+	ErrBadRequest = errors.New("bad request")
+)
+
+// APIError is a non-200 beacon API response, decoded from the standard
+// {"code", "message"} error envelope when the body holds one. errors.Is
+// matches it against ErrFutureEpoch, ErrValidatorNotFound, ErrNotFound and
+// ErrBadRequest.
+type APIError struct {
+	StatusCode int
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+
+	kind error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("beacon API status %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// ParseAPIError decodes the error response with statusCode and body into
+// an *APIError. A body that isn't an error envelope becomes the message.
+func ParseAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	apiErr.StatusCode = statusCode
+	apiErr.kind = classify(statusCode, apiErr.Message)
+	return apiErr
+}
+
+func classify(statusCode int, message string) error {
+	lower := strings.ToLower(message)
+	switch statusCode {
+	case http.StatusBadRequest:
+		// Lighthouse: "Proposer duties were requested for a future epoch";
+		// Prysm: "Request epoch N can not be greater than next epoch".
+		if strings.Contains(lower, "future epoch") || strings.Contains(lower, "greater than next epoch") {
+			return ErrFutureEpoch
+		}
+		return ErrBadRequest
+	case http.StatusNotFound:
+		if strings.Contains(lower, "validator") {
+			return ErrValidatorNotFound
+		}
+		return ErrNotFound
+	}
+	return nil
+}
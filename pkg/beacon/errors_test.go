@@ -0,0 +1,38 @@
+package beacon
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestParseAPIError(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		body        string
+		wantKind    error
+		wantMessage string
+	}{
+		{"lighthouse future epoch", http.StatusBadRequest, `{"code":400,"message":"BAD_REQUEST: Proposer duties were requested for a future epoch"}`, ErrFutureEpoch, "BAD_REQUEST: Proposer duties were requested for a future epoch"},
+		{"prysm future epoch", http.StatusBadRequest, `{"code":400,"message":"Request epoch 12 can not be greater than next epoch 10"}`, ErrFutureEpoch, "Request epoch 12 can not be greater than next epoch 10"},
+		{"other bad request", http.StatusBadRequest, `{"code":400,"message":"invalid slot"}`, ErrBadRequest, "invalid slot"},
+		{"unknown validator", http.StatusNotFound, `{"code":404,"message":"Validator not found"}`, ErrValidatorNotFound, "Validator not found"},
+		{"missed block", http.StatusNotFound, `{"code":404,"message":"NOT_FOUND: beacon block at slot 5"}`, ErrNotFound, "NOT_FOUND: beacon block at slot 5"},
+		{"not an envelope", http.StatusBadGateway, " upstream timed out\n", nil, "upstream timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParseAPIError(tt.statusCode, []byte(tt.body))
+			if err.StatusCode != tt.statusCode || err.Message != tt.wantMessage {
+				t.Errorf("got status %d and message %q, want %d and %q", err.StatusCode, err.Message, tt.statusCode, tt.wantMessage)
+			}
+			if tt.wantKind != nil && !errors.Is(err, tt.wantKind) {
+				t.Errorf("got %v, want it to match %v", err, tt.wantKind)
+			}
+			if tt.wantKind == nil && errors.Unwrap(err) != nil {
+				t.Errorf("got %v matching %v, want no kind", err, errors.Unwrap(err))
+			}
+		})
+	}
+}
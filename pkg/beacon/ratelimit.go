@@ -0,0 +1,51 @@
+package beacon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// defaultRequestsPerSecond is the rate NewClient limits requests to, below
+// what public beacon endpoints throttle at.
+const defaultRequestsPerSecond = 10
+
+// rateLimiter spaces requests at least interval apart. It is shared by
+// every request of a Client, including retries and concurrent range
+// fetches, so their combined rate stays under the limit. A nil limiter
+// doesn't limit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(requestsPerSecond int) *rateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Second / time.Duration(requestsPerSecond)}
+}
+
+// wait reserves the next free request slot and blocks on clock until it
+// comes or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, clock utils.Clock) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := clock.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	if at.After(now) {
+		return sleepCtx(ctx, clock, at.Sub(now))
+	}
+	return nil
+}
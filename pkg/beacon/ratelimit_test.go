@@ -0,0 +1,83 @@
+package beacon
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+)
+
+// eventually fails t unless cond holds within a few seconds.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRateLimiterSpacesRequests(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	limiter := newRateLimiter(10)
+	ctx := context.Background()
+
+	if err := limiter.wait(ctx, clock); err != nil {
+		t.Fatal(err)
+	}
+	var done atomic.Int32
+	for i := 0; i < 2; i++ {
+		go func() {
+			limiter.wait(ctx, clock)
+			done.Add(1)
+		}()
+	}
+	eventually(t, "both waits to block", func() bool { return clock.Waiters() == 2 })
+
+	clock.Advance(99 * time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if done.Load() != 0 {
+		t.Fatal("request allowed before the interval elapsed")
+	}
+	clock.Advance(time.Millisecond)
+	eventually(t, "the second request", func() bool { return done.Load() == 1 })
+	clock.Advance(100 * time.Millisecond)
+	eventually(t, "the third request", func() bool { return done.Load() == 2 })
+}
+
+func TestRateLimiterIsSharedByRangeFetches(t *testing.T) {
+	var requests atomic.Int32
+	client := newDutiesServer(t, 100, &requests)
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	client.Clock = clock
+	client.SetRateLimit(10)
+
+	result := make(chan int, 1)
+	go func() {
+		byEpoch, err := client.ProposerDutiesRange(context.Background(), 0, 3, 4)
+		if err != nil {
+			t.Error(err)
+		}
+		result <- len(byEpoch)
+	}()
+	for want := int32(1); want <= 4; want++ {
+		eventually(t, "the next request", func() bool { return requests.Load() == want })
+		if want < 4 {
+			eventually(t, "the remaining fetches to wait", func() bool { return clock.Waiters() == int(4-want) })
+			clock.Advance(100 * time.Millisecond)
+		}
+	}
+	if got := <-result; got != 4 {
+		t.Errorf("got %d epochs, want 4", got)
+	}
+}
+
+func TestNilRateLimiterDoesNotWait(t *testing.T) {
+	if err := newRateLimiter(0).wait(context.Background(), testutil.NewFakeClock(time.Unix(0, 0))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,70 @@
+package beacon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+const defaultRetries = 5
+
+// response is a fully read HTTP response.
+type response struct {
+	statusCode int
+	body       []byte
+}
+
+// doWithRetry sends the request built by newReq, retrying transport errors,
+// 429s and 5xx responses with linear backoff. Every attempt first waits for
+// limiter. Other responses, successful or not, are returned for the caller
+// to interpret.
+func doWithRetry(ctx context.Context, clock utils.Clock, limiter *rateLimiter, httpClient *http.Client, retries int, newReq func(ctx context.Context) (*http.Request, error)) (*response, error) {
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			if err := sleepCtx(ctx, clock, time.Duration(attempt)*time.Second); err != nil {
+				return nil, err
+			}
+		}
+		if err := limiter.wait(ctx, clock); err != nil {
+			return nil, err
+		}
+		req, err := newReq(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("executing request: %w", err)
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("reading response body: %w", err)
+			continue
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, body)
+			continue
+		}
+		return &response{statusCode: resp.StatusCode, body: body}, nil
+	}
+	return nil, fmt.Errorf("giving up after %d retries: %w", retries, lastErr)
+}
+
+func sleepCtx(ctx context.Context, clock utils.Clock, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clock.After(d):
+		return nil
+	}
+}
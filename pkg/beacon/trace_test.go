@@ -0,0 +1,37 @@
+package beacon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestBeaconCallsAreTraced(t *testing.T) {
+	tracer := &testutil.Tracer{}
+	utils.SetTracer(tracer)
+	t.Cleanup(func() { utils.SetTracer(noop.NewTracerProvider().Tracer("")) })
+
+	client := newValidatorServer(t, map[string]string{"0xaa": ValidatorStatusActiveOngoing})
+	if _, err := client.ValidatorStatus(context.Background(), "0xaa"); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "HTTP GET" || !span.Ended() || span.Status() == codes.Error {
+		t.Errorf("got span %q (ended %t, status %v), want an ended, successful HTTP GET", span.Name(), span.Ended(), span.Status())
+	}
+	if path, _ := span.Attribute("url.path"); path.AsString() != "/eth/v1/beacon/states/head/validators/0xaa" {
+		t.Errorf("got url.path %q, want the validator endpoint", path.AsString())
+	}
+	if code, _ := span.Attribute("http.response.status_code"); code.AsInt64() != 200 {
+		t.Errorf("got status code attribute %d, want 200", code.AsInt64())
+	}
+}
@@ -0,0 +1,34 @@
+package cliutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AddressList is a flag.Value collecting addresses from a flag that may be
+// repeated, each value holding one or more comma separated addresses.
+type AddressList []common.Address
+
+func (l *AddressList) String() string {
+	if l == nil {
+		return ""
+	}
+	hexes := make([]string, len(*l))
+	for i, addr := range *l {
+		hexes[i] = addr.Hex()
+	}
+	return strings.Join(hexes, ",")
+}
+
+func (l *AddressList) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if !common.IsHexAddress(part) {
+			return fmt.Errorf("invalid address %q", part)
+		}
+		*l = append(*l, common.HexToAddress(part))
+	}
+	return nil
+}
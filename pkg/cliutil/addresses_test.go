@@ -0,0 +1,44 @@
+package cliutil
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAddressListFlag(t *testing.T) {
+	var addrs AddressList
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&addrs, "pod-owner", "")
+	err := fs.Parse([]string{
+		"--pod-owner", "0x00000000000000000000000000000000000000aa",
+		"--pod-owner", "0x00000000000000000000000000000000000000bb, 0x00000000000000000000000000000000000000cc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []common.Address{common.HexToAddress("0xaa"), common.HexToAddress("0xbb"), common.HexToAddress("0xcc")}
+	if len(addrs) != len(want) {
+		t.Fatalf("got %v, want %v", addrs, want)
+	}
+	for i := range want {
+		if addrs[i] != want[i] {
+			t.Errorf("address %d is %s, want %s", i, addrs[i], want[i])
+		}
+	}
+	if got := addrs.String(); got != want[0].Hex()+","+want[1].Hex()+","+want[2].Hex() {
+		t.Errorf("got String() %s", got)
+	}
+}
+
+func TestAddressListRejectsInvalidAddress(t *testing.T) {
+	var addrs AddressList
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&addrs, "pod-owner", "")
+	if err := fs.Parse([]string{"--pod-owner", "0x00000000000000000000000000000000000000aa,nope"}); err == nil {
+		t.Error("got no error for an invalid address")
+	}
+}
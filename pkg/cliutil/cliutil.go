@@ -0,0 +1,63 @@
+package cliutil
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Exit codes shared by all commands so scripts can tell failure classes apart.
+const (
+	ExitOK                = 0
+	ExitGeneric           = 1
+	ExitConfig            = 2
+	ExitRPC               = 3
+	ExitInsufficientFunds = 4
+	ExitRevert            = 5
+	ExitPartialFailure    = 6
+)
+
+// Error attaches an exit code to an error.
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func Errorf(code int, format string, args ...any) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// ExitCode returns the exit code for err, ExitGeneric if none is attached.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var cliErr *Error
+	if errors.As(err, &cliErr) {
+		return cliErr.Code
+	}
+	return ExitGeneric
+}
+
+// Fail logs the formatted message and exits with code.
+func Fail(code int, format string, args ...any) {
+	log.Printf(format, args...)
+	os.Exit(code)
+}
+
+// Exit logs err and exits with its exit code. It is a no-op for a nil error.
+func Exit(err error) {
+	if err == nil {
+		return
+	}
+	Fail(ExitCode(err), "%v", err)
+}
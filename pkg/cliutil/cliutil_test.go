@@ -0,0 +1,36 @@
+package cliutil
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	rpcErr := Errorf(ExitRPC, "failed to dial: %w", errors.New("refused"))
+	for name, tc := range map[string]struct {
+		err  error
+		want int
+	}{
+		"nil":           {nil, ExitOK},
+		"plain":         {errors.New("boom"), ExitGeneric},
+		"coded":         {rpcErr, ExitRPC},
+		"wrapped coded": {fmt.Errorf("running migration: %w", rpcErr), ExitRPC},
+		"revert":        {Errorf(ExitRevert, "tx reverted"), ExitRevert},
+	} {
+		if got := ExitCode(tc.err); got != tc.want {
+			t.Errorf("%s: ExitCode = %d, want %d", name, got, tc.want)
+		}
+	}
+}
+
+func TestErrorfUnwraps(t *testing.T) {
+	cause := errors.New("refused")
+	err := Errorf(ExitRPC, "failed to dial: %w", cause)
+	if !errors.Is(err, cause) {
+		t.Error("Errorf result does not wrap its %w argument")
+	}
+	if err.Error() != "failed to dial: refused" {
+		t.Errorf("got message %q", err.Error())
+	}
+}
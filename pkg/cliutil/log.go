@@ -0,0 +1,55 @@
+package cliutil
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LogLevelUsage and LogJSONUsage are the usage strings for --log-level and
+// --log-json flags passed to SetupLogging.
+const (
+	LogLevelUsage = "minimum level logged, one of debug, info, warn or error"
+	LogJSONUsage  = "log as JSON lines instead of text"
+)
+
+// ParseLogLevel parses debug, info, warn or error, case insensitively.
+func ParseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", level)
+	}
+	return l, nil
+}
+
+// NewLogger returns a logger writing to stderr at level and above, as JSON
+// lines if json is set. An invalid level falls back to info.
+func NewLogger(level string, json bool) *slog.Logger {
+	return newLogger(os.Stderr, level, json)
+}
+
+func newLogger(w io.Writer, level string, json bool) *slog.Logger {
+	l, err := ParseLogLevel(level)
+	if err != nil {
+		l = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	if json {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// SetupLogging validates level, then installs NewLogger(level, json) as
+// the slog default used by the shared packages. It exits with ExitConfig on
+// an invalid level.
+func SetupLogging(level string, json bool) *slog.Logger {
+	if _, err := ParseLogLevel(level); err != nil {
+		Fail(ExitConfig, "%v", err)
+	}
+	logger := NewLogger(level, json)
+	slog.SetDefault(logger)
+	return logger
+}
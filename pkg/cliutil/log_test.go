@@ -0,0 +1,37 @@
+package cliutil
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLoggerSuppressesDebugAtInfo(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, "info", false)
+	logger.Debug("polling balance")
+	logger.Info("tx sent")
+
+	out := buf.String()
+	if strings.Contains(out, "polling balance") {
+		t.Errorf("debug record logged at info level: %q", out)
+	}
+	if !strings.Contains(out, "tx sent") {
+		t.Errorf("info record not logged: %q", out)
+	}
+
+	buf.Reset()
+	newLogger(&buf, "debug", false).Debug("polling balance")
+	if !strings.Contains(buf.String(), "polling balance") {
+		t.Errorf("debug record not logged at debug level")
+	}
+}
+
+func TestParseLogLevelRejectsUnknownLevel(t *testing.T) {
+	if _, err := ParseLogLevel("verbose"); err == nil {
+		t.Error("parsed unknown level verbose")
+	}
+	if _, err := ParseLogLevel(" WARN "); err != nil {
+		t.Errorf("failed to parse WARN: %v", err)
+	}
+}
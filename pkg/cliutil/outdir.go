@@ -0,0 +1,26 @@
+package cliutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// OutDirUsage is the usage string for --out-dir flags resolved with
+// OutPath.
+const OutDirUsage = "directory CSV and JSON output files are written to"
+
+// OutPath returns the path of the output file name within dir, creating dir
+// if needed. An absolute name is returned unchanged.
+func OutPath(dir, name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create output directory %s: %w", dir, err)
+	}
+	return filepath.Join(dir, name), nil
+}
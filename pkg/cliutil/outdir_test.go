@@ -0,0 +1,56 @@
+package cliutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutPathCreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out", "nested")
+	path, err := OutPath(dir, "slots.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dir, "slots.csv") {
+		t.Errorf("got path %s, want slots.csv in %s", path, dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("output directory not created: %v", err)
+	}
+}
+
+func TestOutPathKeepsAbsoluteName(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "slots.csv")
+	dir := filepath.Join(t.TempDir(), "unused")
+	path, err := OutPath(dir, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != name {
+		t.Errorf("got path %s, want %s", path, name)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("created %s for an absolute name", dir)
+	}
+}
+
+func TestOutPathDefaultsToWorkingDir(t *testing.T) {
+	path, err := OutPath("", "slots.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "slots.csv" {
+		t.Errorf("got path %s, want slots.csv", path)
+	}
+}
+
+func TestOutPathFailsWhenDirIsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OutPath(file, "slots.csv"); err == nil {
+		t.Error("got no error for an output directory that is a file")
+	}
+}
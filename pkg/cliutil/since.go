@@ -0,0 +1,59 @@
+package cliutil
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// HeaderSource is implemented by *ethclient.Client.
+type HeaderSource interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// SinceUsage is the usage string for --since flags resolved with
+// ResolveStartBlock.
+const SinceUsage = "only scan from this block number or RFC3339 date (e.g. 2025-04-01T00:00:00Z)"
+
+// ResolveStartBlock turns a --since value into a block number. A number is
+// taken as a block; an RFC3339 date resolves to the first block with a
+// timestamp at or after it, found by binary search over headers.
+func ResolveStartBlock(ctx context.Context, client HeaderSource, since string) (uint64, error) {
+	if block, err := strconv.ParseUint(since, 10, 64); err == nil {
+		return block, nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return 0, fmt.Errorf("--since %q is neither a block number nor an RFC3339 date", since)
+	}
+	return blockAtOrAfter(ctx, client, uint64(t.Unix()))
+}
+
+func blockAtOrAfter(ctx context.Context, client HeaderSource, timestamp uint64) (uint64, error) {
+	latest, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest header: %w", err)
+	}
+	if latest.Time < timestamp {
+		return 0, fmt.Errorf("timestamp %d is after the latest block %d at %d", timestamp, latest.Number, latest.Time)
+	}
+
+	lo, hi := uint64(0), latest.Number.Uint64()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get header %d: %w", mid, err)
+		}
+		if header.Time < timestamp {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
@@ -0,0 +1,49 @@
+package cliutil
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// chainHeaders serves headers for blocks 0 through 100, block n mined at
+// 1000 + 12n.
+type chainHeaders struct{}
+
+func (chainHeaders) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		number = big.NewInt(100)
+	}
+	return &types.Header{Number: number, Time: 1000 + 12*number.Uint64()}, nil
+}
+
+func TestResolveStartBlock(t *testing.T) {
+	date := func(unix int64) string { return time.Unix(unix, 0).UTC().Format(time.RFC3339) }
+	tests := []struct {
+		since   string
+		want    uint64
+		wantErr bool
+	}{
+		{since: "12345", want: 12345},
+		{since: date(1000), want: 0},
+		{since: date(1120), want: 10},
+		// Between blocks 10 and 11 resolves to the later one.
+		{since: date(1121), want: 11},
+		{since: date(2200), want: 100},
+		{since: date(2201), wantErr: true},
+		{since: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveStartBlock(context.Background(), chainHeaders{}, tt.since)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ResolveStartBlock(%q) got error %v, want error %t", tt.since, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ResolveStartBlock(%q) = %d, want %d", tt.since, got, tt.want)
+		}
+	}
+}
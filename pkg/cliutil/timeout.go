@@ -0,0 +1,20 @@
+package cliutil
+
+import (
+	"context"
+	"time"
+)
+
+// TimeoutUsage is the usage string for --timeout flags applied with
+// WithTimeout.
+const TimeoutUsage = "abort the command if it runs longer than this, e.g. 30m; no deadline if 0"
+
+// WithTimeout returns parent with a deadline timeout from now, or parent with
+// only a cancel func if timeout is 0, so commands bound unattended runs
+// without forcing a deadline on interactive ones.
+func WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
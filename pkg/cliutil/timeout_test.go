@@ -0,0 +1,36 @@
+package cliutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTimeoutSetsDeadline(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		t.Fatal("got no deadline for a 1ms timeout")
+	}
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", ctx.Err())
+	}
+}
+
+func TestWithTimeoutZeroHasNoDeadline(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), 0)
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("got a deadline for a zero timeout")
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("context done before cancel")
+	default:
+	}
+	cancel()
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("got %v after cancel, want context.Canceled", ctx.Err())
+	}
+}
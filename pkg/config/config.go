@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// RegistryVersion identifies a validator registry deployment's ABI.
+type RegistryVersion string
+
+const (
+	// RegistryOriginal is the original registry on the mev-commit chain.
+	RegistryOriginal RegistryVersion = "original"
+	// RegistryV1 is the 6/13 v1 registry on holesky.
+	RegistryV1 RegistryVersion = "v1"
+	// RegistryV1Aug15 is the 8/15 v1 registry on holesky that v1 stake is
+	// migrated to.
+	RegistryV1Aug15 RegistryVersion = "v1-aug15"
+)
+
+// Network describes a chain the scripts talk to and the contracts deployed
+// on it. Unknown contract addresses are left as the zero address.
+type Network struct {
+	Name    string
+	RPCURL  string
+	ChainID *big.Int
+
+	// ValidatorRegistry is the simple stake registry: the original registry
+	// on the mev-commit chain, or the 6/13 v1 registry on holesky.
+	ValidatorRegistry common.Address
+	// ValidatorRegistryVersion is the version of ValidatorRegistry.
+	ValidatorRegistryVersion RegistryVersion
+	// ValidatorRegistryV1Aug15 is the 8/15 v1 registry, if deployed.
+	ValidatorRegistryV1Aug15 common.Address
+	PreconfManager           common.Address
+	BidderRegistry           common.Address
+}
+
+var (
+	MevCommitTestnet = Network{
+		Name:                     "mev-commit-testnet",
+		RPCURL:                   "https://chainrpc.testnet.mev-commit.xyz",
+		ChainID:                  big.NewInt(17864),
+		ValidatorRegistry:        common.HexToAddress("0xF263483500e849Bd8d452c9A0F075B606ee64087"), // Accurate as of 4/24/2024
+		ValidatorRegistryVersion: RegistryOriginal,
+	}
+	MevCommitMainnet = Network{
+		Name:           "mev-commit-mainnet",
+		RPCURL:         "https://chainrpc.mev-commit.xyz/",
+		ChainID:        big.NewInt(57173),
+		PreconfManager: common.HexToAddress("0x3761bF3932cD22d684A7485002E1424c3aCCD69c"),
+		BidderRegistry: common.HexToAddress("0xC973D09e51A20C9Ab0214c439e4B34Dbac52AD67"),
+	}
+	Holesky = Network{
+		Name:                     "holesky",
+		RPCURL:                   "https://ethereum-holesky-rpc.publicnode.com",
+		ChainID:                  big.NewInt(17000),
+		ValidatorRegistry:        common.HexToAddress("0x5d4fC7B5Aeea4CF4F0Ca6Be09A2F5AaDAd2F2803"), // Holesky validator registry 6/13
+		ValidatorRegistryVersion: RegistryV1,
+		ValidatorRegistryV1Aug15: common.HexToAddress("0x87D5F694fAD0b6C8aaBCa96277DE09451E277Bcf"),
+	}
+	Mainnet = Network{
+		Name:    "mainnet",
+		RPCURL:  "https://ethereum-rpc.publicnode.com",
+		ChainID: big.NewInt(1),
+	}
+)
+
+var networks = map[string]Network{
+	MevCommitTestnet.Name: MevCommitTestnet,
+	MevCommitMainnet.Name: MevCommitMainnet,
+	Holesky.Name:          Holesky,
+	Mainnet.Name:          Mainnet,
+}
+
+// Names returns the names of all known networks, sorted.
+func Names() []string {
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func Lookup(name string) (Network, error) {
+	network, ok := networks[name]
+	if !ok {
+		return Network{}, fmt.Errorf("unknown network %q, expected one of %v", name, Names())
+	}
+	return network, nil
+}
+
+// ValidatorRegistryAddress returns the validator registry address or an
+// error if none is known for the network.
+func (n Network) ValidatorRegistryAddress() (common.Address, error) {
+	if n.ValidatorRegistry == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("no validator registry address configured for network %s", n.Name)
+	}
+	return n.ValidatorRegistry, nil
+}
+
+// RegistryAddress returns the address of the validator registry of the
+// given version, or an error if the network has none.
+func (n Network) RegistryAddress(version RegistryVersion) (common.Address, error) {
+	var addr common.Address
+	switch version {
+	case n.ValidatorRegistryVersion:
+		addr = n.ValidatorRegistry
+	case RegistryV1Aug15:
+		addr = n.ValidatorRegistryV1Aug15
+	}
+	if addr == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("no %s validator registry configured for network %s", version, n.Name)
+	}
+	return addr, nil
+}
+
+// Dial connects to the network's RPC endpoint, tracing each HTTP request
+// with utils.Tracer.
+func (n Network) Dial() (*ethclient.Client, error) {
+	rpcClient, err := rpc.DialOptions(context.Background(), n.RPCURL, rpc.WithHTTPClient(utils.NewTracingHTTPClient()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s at %s: %w", n.Name, n.RPCURL, err)
+	}
+	return ethclient.NewClient(rpcClient), nil
+}
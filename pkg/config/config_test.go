@@ -0,0 +1,57 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	for _, name := range Names() {
+		network, err := Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if network.Name != name || network.ChainID == nil || network.RPCURL == "" {
+			t.Errorf("network %s is incomplete: %+v", name, network)
+		}
+	}
+	if _, err := Lookup("goerli"); err == nil || !strings.Contains(err.Error(), "holesky") {
+		t.Errorf("got error %v for an unknown network, want one listing the known ones", err)
+	}
+}
+
+func TestNetworksHaveDistinctChainIDs(t *testing.T) {
+	seen := make(map[string]string)
+	for _, name := range Names() {
+		network, _ := Lookup(name)
+		if other, ok := seen[network.ChainID.String()]; ok {
+			t.Errorf("%s and %s share chain ID %s", name, other, network.ChainID)
+		}
+		seen[network.ChainID.String()] = name
+	}
+}
+
+func TestValidatorRegistryAddress(t *testing.T) {
+	addr, err := MevCommitTestnet.ValidatorRegistryAddress()
+	if err != nil || addr != MevCommitTestnet.ValidatorRegistry {
+		t.Errorf("got %s, %v for the mev-commit testnet registry", addr, err)
+	}
+	// The registry lives on the mev-commit chain or holesky, never on L1.
+	if _, err := Mainnet.ValidatorRegistryAddress(); err == nil {
+		t.Error("expected an error for a network without a registry")
+	}
+}
+
+func TestRegistryAddress(t *testing.T) {
+	addr, err := MevCommitTestnet.RegistryAddress(MevCommitTestnet.ValidatorRegistryVersion)
+	if err != nil || addr != MevCommitTestnet.ValidatorRegistry {
+		t.Errorf("got %s, %v for the current registry version, want %s", addr, err, MevCommitTestnet.ValidatorRegistry)
+	}
+	addr, err = Holesky.RegistryAddress(RegistryV1Aug15)
+	if err != nil || addr != Holesky.ValidatorRegistryV1Aug15 {
+		t.Errorf("got %s, %v for the holesky v1-aug15 registry, want %s", addr, err, Holesky.ValidatorRegistryV1Aug15)
+	}
+	if _, err := Mainnet.RegistryAddress(RegistryV1Aug15); err == nil {
+		t.Error("got no error for a registry version the network has no deployment of")
+	}
+}
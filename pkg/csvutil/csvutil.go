@@ -0,0 +1,197 @@
+// Package csvutil decodes CSV files with a header row into structs.
+package csvutil
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal reads a CSV with a header row from r into v, which must be a
+// pointer to a slice of structs. Columns are matched to fields by their
+// `csv:"name"` tag, so column order does not matter. Untagged struct fields
+// are flattened into their parent, and fields tagged "-" are ignored.
+//
+// Lines starting with # are skipped, so files may carry comment lines such
+// as a snapshot stamp. Every tagged field must have a column and every
+// column must have a field. Supported field types are strings, integers,
+// bools, *big.Int and anything implementing encoding.TextUnmarshaler; an
+// empty cell leaves a TextUnmarshaler field at its zero value. Errors name
+// the offending line and column.
+func Unmarshal(r io.Reader, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice || rv.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("csvutil: Unmarshal needs a pointer to a slice of structs, got %T", v)
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+
+	fields, err := fieldsOf(elemType)
+	if err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+	columns, err := mapHeader(header, fields)
+	if err != nil {
+		return err
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading line %d: %w", line, err)
+		}
+		elem := reflect.New(elemType).Elem()
+		for i, value := range record {
+			f := columns[i]
+			if err := setField(elem.FieldByIndex(f.index), value); err != nil {
+				return fmt.Errorf("line %d: column %q: %w", line, f.name, err)
+			}
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+}
+
+type field struct {
+	name  string
+	index []int
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// fieldsOf returns the tagged fields of t, descending into untagged
+// struct fields.
+func fieldsOf(t reflect.Type) ([]field, error) {
+	var fields []field
+	seen := map[string]bool{}
+	var walk func(t reflect.Type, prefix []int) error
+	walk = func(t reflect.Type, prefix []int) error {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			index := append(append([]int{}, prefix...), i)
+			tag, tagged := sf.Tag.Lookup("csv")
+			if tag == "-" {
+				continue
+			}
+			if !tagged {
+				if sf.Type.Kind() == reflect.Struct && !reflect.PointerTo(sf.Type).Implements(textUnmarshalerType) {
+					if err := walk(sf.Type, index); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if seen[tag] {
+				return fmt.Errorf("csvutil: column %q is tagged on more than one field of %v", tag, t)
+			}
+			seen[tag] = true
+			fields = append(fields, field{name: tag, index: index})
+		}
+		return nil
+	}
+	if err := walk(t, nil); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// mapHeader maps each header position to its field, checking the header
+// has exactly one column per field.
+func mapHeader(header []string, fields []field) ([]field, error) {
+	byName := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byName[f.name] = f
+	}
+
+	columns := make([]field, len(header))
+	present := make(map[string]bool, len(header))
+	var unexpected []string
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if present[name] {
+			return nil, fmt.Errorf("duplicate column %q in header %v", name, header)
+		}
+		present[name] = true
+		f, ok := byName[name]
+		if !ok {
+			unexpected = append(unexpected, name)
+			continue
+		}
+		columns[i] = f
+	}
+
+	var missing []string
+	for _, f := range fields {
+		if !present[f.name] {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("header %v is missing columns %v", header, missing)
+	}
+	if len(unexpected) > 0 {
+		return nil, fmt.Errorf("header %v has unexpected columns %v", header, unexpected)
+	}
+	return columns, nil
+}
+
+func setField(v reflect.Value, value string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if value == "" {
+			return nil
+		}
+		return u.UnmarshalText([]byte(value))
+	}
+	if v.Type() == reflect.TypeFor[*big.Int]() {
+		n, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.Set(reflect.ValueOf(n))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported field type %v", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package csvutil
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type row struct {
+	Name  string `csv:"name"`
+	Count uint64 `csv:"count"`
+}
+
+func TestUnmarshalSkipsCommentLines(t *testing.T) {
+	input := "# opted-in validators as of block 100\nname,count\na,1\n# trailing note\nb,2\n"
+	var rows []row
+	if err := Unmarshal(strings.NewReader(input), &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0] != (row{"a", 1}) || rows[1] != (row{"b", 2}) {
+		t.Errorf("got %+v, want rows a and b", rows)
+	}
+}
+
+type Owned struct {
+	Owner common.Address `csv:"owner"`
+}
+
+type fullRow struct {
+	Owned
+	Name    string   `csv:"name"`
+	Active  bool     `csv:"active"`
+	Delta   int32    `csv:"delta"`
+	Amount  *big.Int `csv:"amount"`
+	Ignored string   `csv:"-"`
+}
+
+func TestUnmarshalAllFieldTypes(t *testing.T) {
+	// Columns are in a different order from the fields.
+	input := "amount,active,owner,name,delta\n" +
+		"123456789012345678901234567890,true,0x00000000000000000000000000000000000000aa,a,-3\n" +
+		"0,false,,b,4\n"
+	var rows []fullRow
+	if err := Unmarshal(strings.NewReader(input), &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	want, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	first := rows[0]
+	if first.Name != "a" || !first.Active || first.Delta != -3 || first.Amount.Cmp(want) != 0 || first.Owner != common.HexToAddress("0xaa") {
+		t.Errorf("got first row %+v", first)
+	}
+	// An empty cell leaves a TextUnmarshaler at its zero value.
+	if rows[1].Owner != (common.Address{}) || rows[1].Active || rows[1].Delta != 4 {
+		t.Errorf("got second row %+v", rows[1])
+	}
+}
+
+func TestUnmarshalHeaderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"missing column", "name", "missing columns [count]"},
+		{"unexpected column", "name,count,extra", "unexpected columns [extra]"},
+		{"duplicate column", "name,count,name", "duplicate column \"name\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []row
+			err := Unmarshal(strings.NewReader(tt.header+"\n"), &rows)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalNamesBadCell(t *testing.T) {
+	var rows []row
+	err := Unmarshal(strings.NewReader("name,count\na,1\nb,x\n"), &rows)
+	if err == nil || !strings.Contains(err.Error(), `line 3: column "count"`) {
+		t.Errorf("got error %v, want one naming line 3 and column count", err)
+	}
+}
+
+func TestUnmarshalRejectsNonSlice(t *testing.T) {
+	var r row
+	if err := Unmarshal(strings.NewReader("name,count\n"), &r); err == nil {
+		t.Error("got no error decoding into a struct pointer")
+	}
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadEventsFromVerifiesChainID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "staked_events.json")
+	written := []Event{NewEvent("0xA", "01", big.NewInt(1), 1)}
+	if err := WriteEventFile(path, EventFile{ChainID: big.NewInt(17000), Events: written}); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadEventsFrom(path, big.NewInt(17000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 {
+		t.Errorf("got %d events, want 1", len(read))
+	}
+	if _, err := ReadEventsFrom(path, nil); err != nil {
+		t.Errorf("got %v reading without a chain ID to verify", err)
+	}
+
+	_, err = ReadEventsFrom(path, big.NewInt(1))
+	if !errors.Is(err, ErrChainIDMismatch) {
+		t.Errorf("got %v reading a Holesky artifact for mainnet, want ErrChainIDMismatch", err)
+	}
+}
+
+func TestReadEventsFromAcceptsArtifactWithoutChainID(t *testing.T) {
+	dir := t.TempDir()
+	// Written before the EventFile envelope, as a bare array.
+	legacy := filepath.Join(dir, "legacy.json")
+	if err := os.WriteFile(legacy, []byte(`[{"tx_originator":"0xA","val_bls_pub_key":"01","amount":1,"block":1}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Written in the envelope without a chain ID.
+	unscoped := filepath.Join(dir, "unscoped.json")
+	if err := WriteEventsFile(unscoped, []Event{NewEvent("0xA", "01", big.NewInt(1), 1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{legacy, unscoped} {
+		read, err := ReadEventsFrom(path, big.NewInt(17000))
+		if err != nil {
+			t.Fatalf("%s: %v", filepath.Base(path), err)
+		}
+		if len(read) != 1 || read[0].ValBLSPubKey != "01" {
+			t.Errorf("%s: got %+v, want the stored event", filepath.Base(path), read)
+		}
+	}
+}
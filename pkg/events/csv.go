@@ -0,0 +1,46 @@
+package events
+
+import (
+	"encoding/csv"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+)
+
+var ValidatorStateColumns = []string{"pubKey", "amount", "originator", "lastStakeBlock"}
+
+// WriteValidatorStates writes reconstructed validator states sorted by
+// pubkey, so exports from different runs can be diffed.
+func WriteValidatorStates(w io.Writer, states map[string]ValidatorState) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ValidatorStateColumns); err != nil {
+		return err
+	}
+	for _, pubKey := range slices.Sorted(maps.Keys(states)) {
+		state := states[pubKey]
+		amount := ""
+		if state.Amount != nil {
+			amount = state.Amount.String()
+		}
+		record := []string{pubKey, amount, state.TxOriginator, strconv.FormatUint(state.LastStakeBlock, 10)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteValidatorStatesFile(path string, states map[string]ValidatorState) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := WriteValidatorStates(file, states); err != nil {
+		return err
+	}
+	return file.Close()
+}
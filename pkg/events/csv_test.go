@@ -0,0 +1,36 @@
+package events
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteValidatorStatesSortsByPubKey(t *testing.T) {
+	states := map[string]ValidatorState{
+		"bb": {Amount: big.NewInt(64), TxOriginator: "0xB", LastStakeBlock: 20},
+		"aa": {Amount: big.NewInt(32), TxOriginator: "0xA", LastStakeBlock: 10},
+		"cc": {TxOriginator: "0xC", LastStakeBlock: 30},
+	}
+	path := filepath.Join(t.TempDir(), "states.csv")
+	if err := WriteValidatorStatesFile(path, states); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"pubKey,amount,originator,lastStakeBlock",
+		"aa,32,0xA,10",
+		"bb,64,0xB,20",
+		"cc,,0xC,30",
+		"",
+	}, "\n")
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
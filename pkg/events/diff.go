@@ -0,0 +1,24 @@
+package events
+
+import "slices"
+
+// Diff compares a validator set reconstructed from stored events against
+// another set, e.g. the one on chain, both keyed by pubkey. missing holds
+// the reconstructed keys absent from actual and extra the keys of actual
+// with no reconstructed entry. Both are sorted.
+func Diff[R, A any](reconstructed map[string]R, actual map[string]A) (missing, extra []string) {
+	missing, extra = []string{}, []string{}
+	for key := range reconstructed {
+		if _, exists := actual[key]; !exists {
+			missing = append(missing, key)
+		}
+	}
+	for key := range actual {
+		if _, exists := reconstructed[key]; !exists {
+			extra = append(extra, key)
+		}
+	}
+	slices.Sort(missing)
+	slices.Sort(extra)
+	return missing, extra
+}
@@ -0,0 +1,22 @@
+package events
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	reconstructed := map[string]ValidatorState{"aa": {}, "bb": {}, "dd": {}}
+	actual := map[string]*big.Int{"ee": nil, "bb": nil, "cc": nil}
+
+	missing, extra := Diff(reconstructed, actual)
+	if !slices.Equal(missing, []string{"aa", "dd"}) || !slices.Equal(extra, []string{"cc", "ee"}) {
+		t.Errorf("got missing %v and extra %v, want [aa dd] and [cc ee]", missing, extra)
+	}
+
+	missing, extra = Diff(map[string]bool{}, map[string]bool{})
+	if missing == nil || extra == nil || len(missing)+len(extra) != 0 {
+		t.Errorf("got %#v and %#v for empty sets, want empty non-nil slices", missing, extra)
+	}
+}
@@ -1,13 +1,20 @@
 package events
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 type Event struct {
@@ -15,20 +22,56 @@ type Event struct {
 	ValBLSPubKey string   `json:"val_bls_pub_key"`
 	Amount       *big.Int `json:"amount"`
 	Block        uint64   `json:"block"`
+	// LogIndex is the index of the event's log in its block. Artifacts
+	// written before it was recorded read back 0.
+	LogIndex uint `json:"log_index,omitempty"`
+}
+
+// EventFile is the envelope an events artifact is stored in. Artifacts
+// written before it existed are a bare JSON array of events, and read back
+// with a nil ChainID.
+type EventFile struct {
+	// ChainID is the chain the events were collected from.
+	ChainID *big.Int `json:"chain_id"`
+	Events  []Event  `json:"events"`
 }
 
+// ErrChainIDMismatch is returned when an artifact was collected from a
+// different chain than the one it is read for.
+var ErrChainIDMismatch = errors.New("events artifact chain ID mismatch")
+
 func NewEvent(txOriginator string, valBLSPubKey string, amount *big.Int, block uint64) Event {
 	return Event{TxOriginator: txOriginator, ValBLSPubKey: valBLSPubKey, Amount: amount, Block: block}
 }
 
-func ReadEvents(eventType string) ([]Event, error) {
+func ReadEvents(eventType EventKind) ([]Event, error) {
+	return ReadEventsForChain(eventType, nil)
+}
+
+// ReadEventsForChain is ReadEvents, verifying the artifact was collected
+// from chainID as ReadEventsFrom does.
+func ReadEventsForChain(eventType EventKind, chainID *big.Int) ([]Event, error) {
+	path, err := latestEventsFile(eventType)
+	if err != nil {
+		return nil, err
+	}
+	return ReadEventsFrom(path, chainID)
+}
+
+// latestEventsFile returns the most recently modified artifact of eventType.
+func latestEventsFile(eventType EventKind) (string, error) {
 	files, err := filepath.Glob(fmt.Sprintf("../../artifacts/%s_events_*.json", eventType))
 	if err != nil {
-		return nil, fmt.Errorf("failed to list %s event files: %v", eventType, err)
+		return "", fmt.Errorf("failed to list %s event files: %v", eventType, err)
 	}
+	gzFiles, err := filepath.Glob(fmt.Sprintf("../../artifacts/%s_events_*.json.gz", eventType))
+	if err != nil {
+		return "", fmt.Errorf("failed to list %s event files: %v", eventType, err)
+	}
+	files = append(files, gzFiles...)
 
 	if len(files) == 0 {
-		return nil, fmt.Errorf("no %s event files found", eventType)
+		return "", fmt.Errorf("no %s event files found", eventType)
 	}
 
 	sort.Slice(files, func(i, j int) bool {
@@ -45,17 +88,290 @@ func ReadEvents(eventType string) ([]Event, error) {
 
 	recentFile := files[0]
 	fmt.Printf("Using artifact file: %s\n", recentFile)
+	return recentFile, nil
+}
 
-	f, err := os.Open(recentFile)
+// StreamEventsLatest is ReadEvents, passing each event to fn instead of
+// collecting them.
+func StreamEventsLatest(eventType EventKind, fn func(Event) error) error {
+	path, err := latestEventsFile(eventType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %v", recentFile, err)
+		return err
 	}
-	defer f.Close()
+	return StreamEventsFile(path, fn)
+}
+
+// ReadEventsFile decodes events from path, through gzip if it ends in .gz.
+func ReadEventsFile(path string) ([]Event, error) {
+	return ReadEventsFrom(path, nil)
+}
 
+// ReadEventsFrom is ReadEventsFile, returning an error wrapping
+// ErrChainIDMismatch if chainID is non-nil and the artifact records a
+// different chain ID. Artifacts without a chain ID can't be verified and
+// are read as is.
+func ReadEventsFrom(path string, chainID *big.Int) ([]Event, error) {
 	var events []Event
-	if err := json.NewDecoder(f).Decode(&events); err != nil {
-		return nil, fmt.Errorf("failed to decode events from file %s: %v", recentFile, err)
+	fileChainID, err := streamEventsFile(path, func(event Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if chainID != nil {
+		if fileChainID == nil {
+			fmt.Printf("Artifact %s has no chain ID, not verifying it is from chain %s\n", path, chainID)
+		} else if fileChainID.Cmp(chainID) != 0 {
+			return nil, fmt.Errorf("%w: %s was collected from chain %s, expected %s", ErrChainIDMismatch, path, fileChainID, chainID)
+		}
 	}
-
 	return events, nil
 }
+
+// StreamEventsFile is ReadEventsFile, passing each event to fn instead of
+// collecting them.
+func StreamEventsFile(path string, fn func(Event) error) error {
+	_, err := streamEventsFile(path, fn)
+	return err
+}
+
+// streamEventsFile is StreamEventsFile, also returning the artifact's chain
+// ID.
+func streamEventsFile(path string, fn func(Event) error) (*big.Int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip stream %s: %v", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	chainID, err := streamEventFile(r, fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode events from file %s: %w", path, err)
+	}
+	return chainID, nil
+}
+
+// StreamEvents decodes an EventFile, or a bare JSON array of events, from r
+// one event at a time, passing each to fn, so large artifacts need not fit
+// in memory. It stops at the first error returned by fn.
+func StreamEvents(r io.Reader, fn func(Event) error) error {
+	_, err := streamEventFile(r, fn)
+	return err
+}
+
+// streamEventFile is StreamEvents, also returning the EventFile's chain ID,
+// or nil for a bare array.
+func streamEventFile(r io.Reader, fn func(Event) error) (*big.Int, error) {
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		// A null document, as written for a nil slice.
+		return nil, nil
+	}
+	delim, ok := token.(json.Delim)
+	if ok && delim == '[' {
+		return nil, streamEventArray(decoder, fn)
+	}
+	if !ok || delim != '{' {
+		return nil, fmt.Errorf("expected an events file or a JSON array of events, got %v", token)
+	}
+
+	var chainID *big.Int
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch token {
+		case "chain_id":
+			if err := decoder.Decode(&chainID); err != nil {
+				return nil, fmt.Errorf("decoding chain_id: %w", err)
+			}
+		case "events":
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			if token == nil {
+				continue
+			}
+			if delim, ok := token.(json.Delim); !ok || delim != '[' {
+				return nil, fmt.Errorf("expected a JSON array of events, got %v", token)
+			}
+			if err := streamEventArray(decoder, fn); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown events file field %v", token)
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return chainID, nil
+}
+
+// streamEventArray decodes the elements of a JSON array whose opening
+// bracket decoder has already consumed, through the closing bracket.
+func streamEventArray(decoder *json.Decoder, fn func(Event) error) error {
+	for decoder.More() {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteEventsFile encodes events as indented JSON to path, through gzip if
+// it ends in .gz, in an EventFile without a chain ID.
+func WriteEventsFile(path string, events []Event) error {
+	return WriteEventFile(path, EventFile{Events: events})
+}
+
+// WriteEventFile encodes file as indented JSON to path, through gzip if it
+// ends in .gz.
+func WriteEventFile(path string, file EventFile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file); err != nil {
+		return fmt.Errorf("failed to encode events to JSON: %v", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("failed to finish gzip stream %s: %v", path, err)
+		}
+	}
+	return f.Close()
+}
+
+// Reconstruct replays staked, unstaked and withdrawn events and returns the
+// currently staked validators keyed by BLS pubkey.
+func Reconstruct(stakedEvents, unstakedEvents, withdrawnEvents []Event) map[string]Event {
+	e := make(map[string]Event)
+	for _, event := range stakedEvents {
+		e[event.ValBLSPubKey] = event
+	}
+	for _, event := range unstakedEvents {
+		delete(e, event.ValBLSPubKey)
+	}
+	for _, event := range withdrawnEvents {
+		delete(e, event.ValBLSPubKey)
+	}
+	return e
+}
+
+// ValidatorState is the stake of a currently staked validator as of its
+// most recent staking event.
+type ValidatorState struct {
+	Amount         *big.Int
+	TxOriginator   string
+	LastStakeBlock uint64
+}
+
+// ReconstructWithOriginator is like Reconstruct but orders events by block
+// rather than by slice position, so a validator restaked under a new
+// originator reports the latest one. Unstake and withdraw events only
+// remove a validator if they are not older than its latest stake.
+func ReconstructWithOriginator(stakedEvents, unstakedEvents, withdrawnEvents []Event) map[string]ValidatorState {
+	states := make(map[string]ValidatorState)
+	for _, event := range stakedEvents {
+		if prev, ok := states[event.ValBLSPubKey]; ok && prev.LastStakeBlock > event.Block {
+			continue
+		}
+		states[event.ValBLSPubKey] = ValidatorState{
+			Amount:         event.Amount,
+			TxOriginator:   event.TxOriginator,
+			LastStakeBlock: event.Block,
+		}
+	}
+	for _, removed := range [][]Event{unstakedEvents, withdrawnEvents} {
+		for _, event := range removed {
+			if state, ok := states[event.ValBLSPubKey]; ok && event.Block >= state.LastStakeBlock {
+				delete(states, event.ValBLSPubKey)
+			}
+		}
+	}
+	return states
+}
+
+// AllOriginators returns every distinct tx originator of events, sorted by
+// address.
+func AllOriginators(events []Event) []common.Address {
+	seen := make(map[common.Address]struct{})
+	for _, event := range events {
+		seen[common.HexToAddress(event.TxOriginator)] = struct{}{}
+	}
+	originators := make([]common.Address, 0, len(seen))
+	for originator := range seen {
+		originators = append(originators, originator)
+	}
+	sort.Slice(originators, func(i, j int) bool {
+		return bytes.Compare(originators[i][:], originators[j][:]) < 0
+	})
+	return originators
+}
+
+// CountByOriginator returns the number of validators staked by each tx
+// originator. Pass reconstructed events to count only currently staked ones.
+func CountByOriginator(events []Event) map[string]int {
+	counts := make(map[string]int)
+	for _, event := range events {
+		counts[event.TxOriginator]++
+	}
+	return counts
+}
+
+// FilterByBlock returns the events with from <= Block <= to.
+func FilterByBlock(events []Event, from, to uint64) []Event {
+	filtered := make([]Event, 0, len(events))
+	for _, event := range events {
+		if event.Block >= from && event.Block <= to {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// ReadEventsInRange reads the most recent artifact of eventType and keeps
+// only events with from <= Block <= to.
+func ReadEventsInRange(eventType EventKind, from, to uint64) ([]Event, error) {
+	events, err := ReadEvents(eventType)
+	if err != nil {
+		return nil, err
+	}
+	return FilterByBlock(events, from, to), nil
+}
@@ -0,0 +1,198 @@
+package events
+
+import (
+	"compress/gzip"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestCountByOriginator(t *testing.T) {
+	staked := []Event{
+		NewEvent("0xA", "01", big.NewInt(1), 1),
+		NewEvent("0xB", "02", big.NewInt(1), 2),
+		NewEvent("0xA", "03", big.NewInt(1), 3),
+	}
+	unstaked := []Event{NewEvent("0xA", "03", big.NewInt(1), 4)}
+
+	counts := CountByOriginator(staked)
+	if counts["0xA"] != 2 || counts["0xB"] != 1 || len(counts) != 2 {
+		t.Errorf("got counts %v for all stake events, want 0xA:2 0xB:1", counts)
+	}
+
+	// Counting reconstructed events leaves out unstaked validators.
+	var current []Event
+	for _, event := range Reconstruct(staked, unstaked, nil) {
+		current = append(current, event)
+	}
+	counts = CountByOriginator(current)
+	if counts["0xA"] != 1 || counts["0xB"] != 1 {
+		t.Errorf("got counts %v for reconstructed events, want 0xA:1 0xB:1", counts)
+	}
+}
+
+func TestAllOriginators(t *testing.T) {
+	events := []Event{
+		NewEvent("0x00000000000000000000000000000000000000Bb", "01", big.NewInt(1), 1),
+		NewEvent("0x00000000000000000000000000000000000000aa", "02", big.NewInt(1), 2),
+		NewEvent("0x00000000000000000000000000000000000000bb", "03", big.NewInt(1), 3),
+		NewEvent("0x00000000000000000000000000000000000000AA", "04", big.NewInt(1), 4),
+		NewEvent("0x0000000000000000000000000000000000000001", "05", big.NewInt(1), 5),
+	}
+
+	got := AllOriginators(events)
+	want := []common.Address{
+		common.HexToAddress("0x01"),
+		common.HexToAddress("0xaa"),
+		common.HexToAddress("0xbb"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got originators %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("originator %d is %s, want %s", i, got[i].Hex(), want[i].Hex())
+		}
+	}
+	if got := AllOriginators(nil); len(got) != 0 {
+		t.Errorf("got originators %v for no events, want none", got)
+	}
+}
+
+func TestFilterByBlockIsInclusive(t *testing.T) {
+	events := []Event{
+		NewEvent("0xA", "01", big.NewInt(1), 9),
+		NewEvent("0xA", "02", big.NewInt(1), 10),
+		NewEvent("0xA", "03", big.NewInt(1), 15),
+		NewEvent("0xA", "04", big.NewInt(1), 20),
+		NewEvent("0xA", "05", big.NewInt(1), 21),
+	}
+	filtered := FilterByBlock(events, 10, 20)
+	if len(filtered) != 3 || filtered[0].Block != 10 || filtered[2].Block != 20 {
+		t.Errorf("got %+v, want the events of blocks 10, 15 and 20", filtered)
+	}
+	if got := FilterByBlock(events, 30, 40); len(got) != 0 {
+		t.Errorf("got %+v for a window past every event, want none", got)
+	}
+}
+
+func TestEventFileRoundTrip(t *testing.T) {
+	written := []Event{
+		NewEvent("0xA", "01", big.NewInt(32), 1),
+		NewEvent("0xB", "02", big.NewInt(64), 2),
+	}
+	for _, name := range []string{"staked_events.json", "staked_events.json.gz"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := WriteEventsFile(path, written); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadEventsFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(read) != len(written) {
+			t.Fatalf("%s: read %d events, want %d", name, len(read), len(written))
+		}
+		for i := range written {
+			if read[i].ValBLSPubKey != written[i].ValBLSPubKey || read[i].Amount.Cmp(written[i].Amount) != 0 || read[i].Block != written[i].Block {
+				t.Errorf("%s: read %+v, want %+v", name, read[i], written[i])
+			}
+		}
+	}
+}
+
+func TestWriteEventsFileCompressesGzPaths(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "staked_events.json.gz")
+	if err := WriteEventsFile(path, []Event{NewEvent("0xA", "01", big.NewInt(1), 1)}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := gzip.NewReader(f); err != nil {
+		t.Errorf("%s is not gzip compressed: %v", path, err)
+	}
+}
+
+func TestReconstructWithOriginatorKeepsLatestStake(t *testing.T) {
+	staked := []Event{
+		// Out of block order, as when artifacts are concatenated.
+		NewEvent("0xNew", "01", big.NewInt(64), 20),
+		NewEvent("0xOld", "01", big.NewInt(32), 10),
+		NewEvent("0xA", "02", big.NewInt(32), 5),
+		NewEvent("0xB", "03", big.NewInt(32), 30),
+	}
+	unstaked := []Event{
+		// Older than 01's latest stake, so it doesn't remove it.
+		NewEvent("0xOld", "01", big.NewInt(32), 15),
+		NewEvent("0xA", "02", big.NewInt(32), 6),
+	}
+	withdrawn := []Event{NewEvent("0xB", "03", big.NewInt(32), 30)}
+
+	states := ReconstructWithOriginator(staked, unstaked, withdrawn)
+	if len(states) != 1 {
+		t.Fatalf("got %d staked validators, want 1: %+v", len(states), states)
+	}
+	state := states["01"]
+	if state.TxOriginator != "0xNew" || state.Amount.Int64() != 64 || state.LastStakeBlock != 20 {
+		t.Errorf("got state %+v, want the block 20 stake by 0xNew", state)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{"bare array", `[{"tx_originator":"0xA","val_bls_pub_key":"01","amount":1,"block":1},{"tx_originator":"0xB","val_bls_pub_key":"02","amount":2,"block":2}]`, 2},
+		{"events file", `{"chain_id":17000,"events":[{"tx_originator":"0xA","val_bls_pub_key":"01","amount":1,"block":1}]}`, 1},
+		{"null", `null`, 0},
+		{"null events", `{"events":null}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Event
+			err := StreamEvents(strings.NewReader(tt.input), func(e Event) error {
+				got = append(got, e)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("got %d events, want %d", len(got), tt.want)
+			}
+			if tt.want > 0 && (got[0].TxOriginator != "0xA" || got[0].ValBLSPubKey != "01" || got[0].Amount.Int64() != 1) {
+				t.Errorf("got first event %+v", got[0])
+			}
+		})
+	}
+}
+
+func TestStreamEventsStopsAtCallbackError(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	err := StreamEvents(strings.NewReader(`[{"block":1},{"block":2},{"block":3}]`), func(Event) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("got error %v after %d calls, want %v after 1", err, calls, stop)
+	}
+}
+
+func TestStreamEventsRejectsMalformedInput(t *testing.T) {
+	for _, input := range []string{`"events"`, `{"unknown":1}`, `[{"block":1},`} {
+		if err := StreamEvents(strings.NewReader(input), func(Event) error { return nil }); err == nil {
+			t.Errorf("got no error for %s", input)
+		}
+	}
+}
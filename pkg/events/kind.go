@@ -0,0 +1,30 @@
+package events
+
+import "fmt"
+
+// EventKind is a validator registry event type. Its value is the artifact
+// file name prefix events of the kind are stored under.
+type EventKind string
+
+const (
+	EventStaked   EventKind = "staked"
+	EventUnstaked EventKind = "unstaked"
+	EventWithdraw EventKind = "withdraw"
+)
+
+// EventKinds lists every EventKind, in the order artifacts are stored.
+var EventKinds = []EventKind{EventStaked, EventUnstaked, EventWithdraw}
+
+func (k EventKind) String() string {
+	return string(k)
+}
+
+// ParseEventKind returns the EventKind named s, or an error if s names none.
+func ParseEventKind(s string) (EventKind, error) {
+	for _, kind := range EventKinds {
+		if string(kind) == s {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type %q, expected one of %v", s, EventKinds)
+}
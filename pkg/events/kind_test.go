@@ -0,0 +1,23 @@
+package events
+
+import "testing"
+
+func TestParseEventKindRoundTrip(t *testing.T) {
+	for _, kind := range EventKinds {
+		parsed, err := ParseEventKind(kind.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != kind {
+			t.Errorf("ParseEventKind(%q) = %q", kind.String(), parsed)
+		}
+	}
+}
+
+func TestParseEventKindRejectsUnknown(t *testing.T) {
+	for _, s := range []string{"", "Staked", "withdrawn", "stake"} {
+		if kind, err := ParseEventKind(s); err == nil {
+			t.Errorf("ParseEventKind(%q) = %q, want an error", s, kind)
+		}
+	}
+}
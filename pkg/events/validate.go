@@ -0,0 +1,71 @@
+package events
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BLSPubKeyLength is the length in bytes of a validator's BLS pubkey.
+const BLSPubKeyLength = 48
+
+// IssueKind says what is wrong with an event flagged by ValidateAll.
+type IssueKind string
+
+const (
+	// IssueNonHexPubKey marks pubkeys that aren't plain hex, including
+	// ones with a 0x prefix, which common.Hex2Bytes silently mis-decodes.
+	IssueNonHexPubKey IssueKind = "non-hex pubkey"
+	// IssueBadPubKeyLength marks pubkeys that don't decode to
+	// BLSPubKeyLength bytes.
+	IssueBadPubKeyLength IssueKind = "bad pubkey length"
+	// IssueEmptyOriginator marks events without a tx originator.
+	IssueEmptyOriginator IssueKind = "empty originator"
+	// IssueInvalidOriginator marks tx originators that aren't addresses.
+	IssueInvalidOriginator IssueKind = "invalid originator"
+	// IssueNilAmount marks events without an amount.
+	IssueNilAmount IssueKind = "nil amount"
+)
+
+// ValidationIssue is a problem found in the event at Index of the slice
+// passed to ValidateAll.
+type ValidationIssue struct {
+	Index  int
+	Event  Event
+	Kind   IssueKind
+	Detail string
+}
+
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("event %d (block %d, pubkey %q): %s: %s", i.Index, i.Event.Block, i.Event.ValBLSPubKey, i.Kind, i.Detail)
+}
+
+// ValidateAll returns every issue found in events that would make a stake
+// call built from them revert or misbehave, in event order. An event can
+// have more than one issue.
+func ValidateAll(events []Event) []ValidationIssue {
+	var issues []ValidationIssue
+	for i, event := range events {
+		add := func(kind IssueKind, format string, args ...any) {
+			issues = append(issues, ValidationIssue{Index: i, Event: event, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+		}
+
+		if pubKey, err := hex.DecodeString(event.ValBLSPubKey); err != nil {
+			add(IssueNonHexPubKey, "%v", err)
+		} else if len(pubKey) != BLSPubKeyLength {
+			add(IssueBadPubKeyLength, "%d bytes, want %d", len(pubKey), BLSPubKeyLength)
+		}
+
+		if event.TxOriginator == "" {
+			add(IssueEmptyOriginator, "no tx originator")
+		} else if !common.IsHexAddress(event.TxOriginator) {
+			add(IssueInvalidOriginator, "%q is not an address", event.TxOriginator)
+		}
+
+		if event.Amount == nil {
+			add(IssueNilAmount, "no amount")
+		}
+	}
+	return issues
+}
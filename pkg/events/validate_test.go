@@ -0,0 +1,57 @@
+package events
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestValidateAll(t *testing.T) {
+	validPubKey := strings.Repeat("ab", BLSPubKeyLength)
+	originator := "0x4535bd6ff24860b5fd2889857651a85fb3d3c6b1"
+	events := []Event{
+		NewEvent(originator, validPubKey, big.NewInt(1), 1),
+		NewEvent(originator, strings.Repeat("ab", 47), big.NewInt(1), 2),
+		NewEvent(originator, "0x"+validPubKey, big.NewInt(1), 3),
+		NewEvent("", validPubKey, big.NewInt(1), 4),
+		NewEvent("0xnotanaddress", validPubKey, nil, 5),
+		NewEvent(originator, "", big.NewInt(1), 6),
+		NewEvent(originator, validPubKey, big.NewInt(1), 7),
+	}
+
+	want := []struct {
+		index int
+		kind  IssueKind
+	}{
+		{1, IssueBadPubKeyLength},
+		{2, IssueNonHexPubKey},
+		{3, IssueEmptyOriginator},
+		{4, IssueInvalidOriginator},
+		{4, IssueNilAmount},
+		{5, IssueBadPubKeyLength},
+	}
+	issues := ValidateAll(events)
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d: %v", len(issues), len(want), issues)
+	}
+	for i, w := range want {
+		if issues[i].Index != w.index || issues[i].Kind != w.kind {
+			t.Errorf("issue %d is %s of event %d, want %s of event %d", i, issues[i].Kind, issues[i].Index, w.kind, w.index)
+		}
+		if issues[i].Event.Block != events[w.index].Block {
+			t.Errorf("issue %d carries the event of block %d, want %d", i, issues[i].Event.Block, events[w.index].Block)
+		}
+	}
+	if got := issues[0].String(); !strings.Contains(got, "47 bytes, want 48") || !strings.Contains(got, "block 2") {
+		t.Errorf("got issue %q, want one naming the block and length", got)
+	}
+}
+
+func TestValidateAllValidEvents(t *testing.T) {
+	events := []Event{
+		NewEvent("0x4535bd6ff24860b5fd2889857651a85fb3d3c6b1", strings.Repeat("0A", BLSPubKeyLength), big.NewInt(0), 1),
+	}
+	if issues := ValidateAll(events); len(issues) != 0 {
+		t.Errorf("got issues %v for valid events", issues)
+	}
+}
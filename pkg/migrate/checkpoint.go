@@ -0,0 +1,73 @@
+package migrate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Checkpoint records completed batches by Batch.Key in an append-only file,
+// one per line, so a later run can skip them.
+type Checkpoint struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]bool
+}
+
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	done := make(map[string]bool)
+
+	existing, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("opening checkpoint: %w", err)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			// A line truncated by a crash matches no batch key, so it is
+			// harmless to load.
+			done[line] = true
+		}
+		existing.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading checkpoint: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening checkpoint: %w", err)
+	}
+	return &Checkpoint{file: file, done: done}, nil
+}
+
+func (c *Checkpoint) Done(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done[key]
+}
+
+func (c *Checkpoint) MarkDone(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Leading newline keeps a record from merging with a line truncated by a crash.
+	if _, err := fmt.Fprintf(c.file, "\n%s\n", key); err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	if err := c.file.Sync(); err != nil {
+		return fmt.Errorf("syncing checkpoint: %w", err)
+	}
+	c.done[key] = true
+	return nil
+}
+
+func (c *Checkpoint) Close() error {
+	return c.file.Close()
+}
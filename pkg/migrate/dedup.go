@@ -0,0 +1,31 @@
+package migrate
+
+import "encoding/hex"
+
+// DuplicatePubKey is a pubkey dropped by DedupWithinBatch.
+type DuplicatePubKey struct {
+	PubKey []byte
+	// Index is the position of the dropped copy and FirstIndex that of the
+	// copy that was kept.
+	Index      int
+	FirstIndex int
+}
+
+// DedupWithinBatch returns pubKeys with repeated keys removed, keeping the
+// first occurrence, along with the removed copies. The registry reverts a
+// whole stake call that includes a pubkey twice.
+func DedupWithinBatch(pubKeys [][]byte) ([][]byte, []DuplicatePubKey) {
+	firstIndex := make(map[string]int, len(pubKeys))
+	deduped := make([][]byte, 0, len(pubKeys))
+	var dups []DuplicatePubKey
+	for i, pubKey := range pubKeys {
+		key := hex.EncodeToString(pubKey)
+		if first, seen := firstIndex[key]; seen {
+			dups = append(dups, DuplicatePubKey{PubKey: pubKey, Index: i, FirstIndex: first})
+			continue
+		}
+		firstIndex[key] = i
+		deduped = append(deduped, pubKey)
+	}
+	return deduped, dups
+}
@@ -0,0 +1,44 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestDedupWithinBatch(t *testing.T) {
+	pubKeys := [][]byte{testPubKey(1), testPubKey(2), testPubKey(1), testPubKey(3), testPubKey(2), testPubKey(1)}
+
+	deduped, dups := DedupWithinBatch(pubKeys)
+	want := [][]byte{testPubKey(1), testPubKey(2), testPubKey(3)}
+	if len(deduped) != len(want) {
+		t.Fatalf("got %d pubkeys, want %d", len(deduped), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(deduped[i], want[i]) {
+			t.Errorf("pubkey %d is %x, want %x", i, deduped[i], want[i])
+		}
+	}
+	wantDups := [][2]int{{2, 0}, {4, 1}, {5, 0}}
+	if len(dups) != len(wantDups) {
+		t.Fatalf("got duplicates %+v, want %v", dups, wantDups)
+	}
+	for i, dup := range dups {
+		if dup.Index != wantDups[i][0] || dup.FirstIndex != wantDups[i][1] || !bytes.Equal(dup.PubKey, pubKeys[dup.Index]) {
+			t.Errorf("duplicate %d is %+v, want index %d first seen at %d", i, dup, wantDups[i][0], wantDups[i][1])
+		}
+	}
+}
+
+func TestExecuteDropsDuplicatePubKeys(t *testing.T) {
+	executor, transactor := newTestExecutor(t, testConfig())
+	batch := Batch{StakeOriginator: common.Address{1}, PubKeys: [][]byte{testPubKey(1), testPubKey(1), testPubKey(2)}}
+	if _, err := executor.Execute(context.Background(), []Batch{batch}); err != nil {
+		t.Fatal(err)
+	}
+	if len(transactor.calls) != 1 || len(transactor.calls[0].pubKeys) != 2 || transactor.calls[0].value.Int64() != 20 {
+		t.Errorf("got calls %+v, want one call staking 2 pubkeys for 20", transactor.calls)
+	}
+}
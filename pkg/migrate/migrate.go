@@ -0,0 +1,718 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/primevprotocol/validator-registry/pkg/cliutil"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/query"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+type Batch struct {
+	PubKeys         [][]byte
+	StakeOriginator common.Address
+	// AmountPerValidator, if set, overrides Config.AmountPerValidator for
+	// this batch.
+	AmountPerValidator *big.Int
+	// States holds the state of the validator at the same index of PubKeys.
+	// Config.AmountFor requires it.
+	States []events.ValidatorState
+}
+
+// Key identifies the batch in a Checkpoint by the registry it is submitted
+// to, its originator, its amount if set and a hash of its pubkeys, so runs
+// against another registry, or with validators since added for the same
+// originator, don't skip it.
+func (b Batch) Key(registry common.Address) string {
+	pubKeys := slices.CompactFunc(slices.SortedFunc(slices.Values(b.PubKeys), bytes.Compare), bytes.Equal)
+	key := registry.Hex() + "/" + b.StakeOriginator.Hex()
+	if b.AmountPerValidator != nil {
+		key += "/" + b.AmountPerValidator.String()
+	}
+	return key + "/" + hex.EncodeToString(crypto.Keccak256(pubKeys...)[:8])
+}
+
+// BatchesByOriginator groups events into one batch per tx originator,
+// sorted by originator so runs are deterministic.
+func BatchesByOriginator(e map[string]events.Event) []Batch {
+	byOriginator := make(map[string]*Batch)
+	for _, event := range e {
+		batch, exists := byOriginator[event.TxOriginator]
+		if !exists {
+			batch = &Batch{StakeOriginator: common.HexToAddress(event.TxOriginator)}
+			byOriginator[event.TxOriginator] = batch
+		}
+		batch.PubKeys = append(batch.PubKeys, common.Hex2Bytes(event.ValBLSPubKey))
+		batch.States = append(batch.States, events.ValidatorState{
+			Amount:         event.Amount,
+			TxOriginator:   event.TxOriginator,
+			LastStakeBlock: event.Block,
+		})
+	}
+
+	batches := make([]Batch, 0, len(byOriginator))
+	for _, batch := range byOriginator {
+		batches = append(batches, *batch)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].StakeOriginator.Hex() < batches[j].StakeOriginator.Hex()
+	})
+	return batches
+}
+
+// ExcludeAlreadyStaked deletes from e the validators router reports as
+// vanilla opted in, i.e. staked in the registry the router wraps, checking
+// batchSize pubkeys per call, and returns them as skipped with
+// SkipAlreadyStaked.
+func ExcludeAlreadyStaked(ctx context.Context, router optins.RouterCaller, e map[string]events.Event, batchSize int) ([]SkippedValidator, error) {
+	keys := slices.Sorted(maps.Keys(e))
+	pubKeys := make([][]byte, len(keys))
+	for i, key := range keys {
+		pubKey, err := hex.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("decoding pubkey %s: %w", key, err)
+		}
+		pubKeys[i] = pubKey
+	}
+	statuses, err := query.OptedInStatus(ctx, router, pubKeys, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	var skipped []SkippedValidator
+	for i, status := range statuses {
+		if status.IsVanillaOptedIn {
+			skipped = append(skipped, SkippedValidator{Event: e[keys[i]], Reason: SkipAlreadyStaked})
+			delete(e, keys[i])
+		}
+	}
+	return skipped, nil
+}
+
+// BatchesByOriginatorAndAmount groups validators into one batch per tx
+// originator and staked amount, so a replay stakes each validator with the
+// amount it had. Batches are sorted by originator then amount, and pubkeys
+// within a batch are sorted.
+func BatchesByOriginatorAndAmount(states map[string]events.ValidatorState) []Batch {
+	type batchKey struct {
+		originator string
+		amount     string
+	}
+	byKey := make(map[batchKey]*Batch)
+	for _, pubKey := range slices.Sorted(maps.Keys(states)) {
+		state := states[pubKey]
+		key := batchKey{originator: state.TxOriginator, amount: state.Amount.String()}
+		batch, exists := byKey[key]
+		if !exists {
+			batch = &Batch{
+				StakeOriginator:    common.HexToAddress(state.TxOriginator),
+				AmountPerValidator: new(big.Int).Set(state.Amount),
+			}
+			byKey[key] = batch
+		}
+		batch.PubKeys = append(batch.PubKeys, common.Hex2Bytes(pubKey))
+		batch.States = append(batch.States, state)
+	}
+
+	batches := make([]Batch, 0, len(byKey))
+	for _, batch := range byKey {
+		batches = append(batches, *batch)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		if batches[i].StakeOriginator != batches[j].StakeOriginator {
+			return batches[i].StakeOriginator.Hex() < batches[j].StakeOriginator.Hex()
+		}
+		return batches[i].AmountPerValidator.Cmp(batches[j].AmountPerValidator) < 0
+	})
+	return batches
+}
+
+// StakeTransactor is implemented by the validatorregistryv1 and
+// validatorregistryv1_aug15 transactor bindings.
+type StakeTransactor interface {
+	DelegateStake(opts *bind.TransactOpts, blsPubKeys [][]byte, stakeOriginator common.Address) (*types.Transaction, error)
+	Unstake(opts *bind.TransactOpts, blsPubKeys [][]byte) (*types.Transaction, error)
+}
+
+// MaxDelegateStakeBatchSize is the most pubkeys the registry contracts
+// accept in a single DelegateStake or Stake call.
+const MaxDelegateStakeBatchSize = 20
+
+type Config struct {
+	// Registry is the address of the registry txs are submitted to. It
+	// scopes checkpoint keys.
+	Registry common.Address
+	// SubBatchSize is the maximum number of pubkeys per DelegateStake tx.
+	SubBatchSize int
+	// MaxSubBatchSize is the contract's limit SubBatchSize is checked
+	// against. Zero means MaxDelegateStakeBatchSize.
+	MaxSubBatchSize int
+	// AmountPerValidator is the stake attached for each pubkey.
+	AmountPerValidator *big.Int
+	// AmountFor, if set, returns the stake attached for a validator instead
+	// of AmountPerValidator, so validators can be staked with different
+	// amounts. Validators are grouped by amount before being split into
+	// sub batches, as the registry splits a tx's value evenly over its
+	// pubkeys.
+	AmountFor func(v events.ValidatorState) *big.Int
+	// UseNonceManager allocates nonces locally instead of re-querying the
+	// pending nonce before every sub batch.
+	UseNonceManager bool
+	// ConfirmationWait bounds how long to wait, after a sub batch is
+	// included, for the account's pending nonce to advance before the next
+	// sub batch is submitted. Zero disables the wait.
+	ConfirmationWait time.Duration
+	// MinGasTip, if set, is the floor suggested gas tips are raised to.
+	MinGasTip *big.Int
+	// ContinueOnRevert keeps going after a reverted sub batch instead of
+	// returning an error.
+	ContinueOnRevert bool
+	// MaxBatches, if positive, stops Execute after that many batches have
+	// been processed. Batches already recorded in the checkpoint don't count.
+	MaxBatches int
+	// Unstake submits an Unstake tx with no value for each sub batch instead
+	// of DelegateStake. Batches' StakeOriginator is then only used in logs
+	// and records.
+	Unstake bool
+}
+
+// Validate checks that Registry is set and that SubBatchSize is positive and within the contract's
+// maximum batch size.
+func (c Config) Validate() error {
+	if c.Registry == (common.Address{}) {
+		return fmt.Errorf("no registry address configured")
+	}
+	maxSize := c.MaxSubBatchSize
+	if maxSize == 0 {
+		maxSize = MaxDelegateStakeBatchSize
+	}
+	return ValidateSubBatchSize(c.SubBatchSize, maxSize)
+}
+
+// ValidateSubBatchSize returns an error if size is not in [1, maxSize].
+func ValidateSubBatchSize(size, maxSize int) error {
+	if size <= 0 {
+		return fmt.Errorf("sub batch size must be positive, got %d", size)
+	}
+	if size > maxSize {
+		return fmt.Errorf("sub batch size %d exceeds the contract's max batch size of %d", size, maxSize)
+	}
+	return nil
+}
+
+// SplitSubBatches splits pubKeys into consecutive sub batches of size
+// pubkeys, the last holding the remainder. size must be positive.
+func SplitSubBatches(pubKeys [][]byte, size int) [][][]byte {
+	subBatches := make([][][]byte, 0, (len(pubKeys)+size-1)/size)
+	for i := 0; i < len(pubKeys); i += size {
+		subBatches = append(subBatches, pubKeys[i:min(i+size, len(pubKeys))])
+	}
+	return subBatches
+}
+
+type FailedSubBatch struct {
+	StakeOriginator common.Address
+	PubKeys         [][]byte
+	Receipt         *types.Receipt
+}
+
+// UnconfirmedSubBatch is a sub batch whose tx was rejected with "nonce too
+// low" on a resubmission, so an earlier attempt was likely included but no
+// receipt was seen. Callers should check its outcome on chain.
+type UnconfirmedSubBatch struct {
+	StakeOriginator common.Address
+	PubKeys         [][]byte
+}
+
+// Result is the outcome of an Execute call.
+type Result struct {
+	// Failed holds the sub batches whose tx was included but reverted.
+	Failed []FailedSubBatch
+	// Unconfirmed holds the sub batches whose inclusion couldn't be
+	// confirmed.
+	Unconfirmed []UnconfirmedSubBatch
+	// Records holds one entry per sub batch tx included by this call.
+	Records []TxRecord
+	// OptedIn holds the pubkeys dropped from their sub batch because the
+	// opt-in router reported them opted in just before it was submitted.
+	OptedIn [][]byte
+	// Processed is the number of batches submitted by this call.
+	Processed int
+	// Remaining is the number of batches neither completed in this call nor
+	// recorded in the checkpoint, e.g. because MaxBatches was reached.
+	Remaining int
+}
+
+type Executor struct {
+	client     utils.Backend
+	ec         *utils.ETHClient
+	baseOpts   *bind.TransactOpts
+	transactor StakeTransactor
+	cfg        Config
+	nonces     *utils.NonceManager
+	checkpoint *Checkpoint
+	records    *TxRecordWriter
+	router     optins.RouterCaller
+	staked     *StakedSet
+	clock      utils.Clock
+}
+
+// NewExecutor creates an executor submitting DelegateStake, or with
+// cfg.Unstake Unstake, txs signed by baseOpts. Nonce, value and gas price are filled in per sub batch. It
+// returns an error if cfg is invalid.
+func NewExecutor(
+	client utils.Backend,
+	baseOpts *bind.TransactOpts,
+	transactor StakeTransactor,
+	cfg Config,
+) (*Executor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	e := &Executor{
+		client:     client,
+		ec:         utils.NewETHClient(client),
+		baseOpts:   baseOpts,
+		transactor: transactor,
+		cfg:        cfg,
+		clock:      utils.RealClock{},
+	}
+	e.ec.MinGasTip = cfg.MinGasTip
+	if cfg.UseNonceManager {
+		e.nonces = utils.NewNonceManager(client, baseOpts.From)
+	}
+	return e, nil
+}
+
+// SetCheckpoint makes Execute skip batches already recorded in checkpoint
+// and record each batch whose sub batches all succeeded.
+func (e *Executor) SetCheckpoint(checkpoint *Checkpoint) {
+	e.checkpoint = checkpoint
+}
+
+// SetRecordWriter makes Execute write a TxRecord to w as soon as each sub
+// batch tx is included.
+func (e *Executor) SetRecordWriter(w *TxRecordWriter) {
+	e.records = w
+}
+
+// SetStakedSet makes Execute add the pubkeys of each successful stake sub
+// batch to set.
+func (e *Executor) SetStakedSet(set *StakedSet) {
+	e.staked = set
+}
+
+// SetClock makes the executor time its inclusion and nonce waits on clock
+// instead of the real clock.
+func (e *Executor) SetClock(clock utils.Clock) {
+	e.clock = clock
+	e.ec.Clock = clock
+}
+
+// SetOptInRouter makes Execute recheck each stake sub batch against router
+// just before submitting it and drop the validators already opted in via
+// any source, as they may have opted in since the plan was built and
+// staking them would revert.
+func (e *Executor) SetOptInRouter(router optins.RouterCaller) {
+	e.router = router
+}
+
+// Execute stakes, or with cfg.Unstake unstakes, every batch in sub batches
+// of at most cfg.SubBatchSize, stopping early once cfg.MaxBatches batches
+// have been processed. It is ExecutePlan of the plan NewPlan builds.
+func (e *Executor) Execute(ctx context.Context, batches []Batch) (Result, error) {
+	plan, err := NewPlan(batches, nil, e.cfg)
+	if err != nil {
+		return Result{Failed: []FailedSubBatch{}}, err
+	}
+	return e.ExecutePlan(ctx, plan)
+}
+
+// ExecutePlan submits exactly the sub batches of plan, with their planned
+// values, stopping early once cfg.MaxBatches batches have been processed.
+// The plan is checked against cfg before anything is submitted.
+func (e *Executor) ExecutePlan(ctx context.Context, plan Plan) (Result, error) {
+	result := Result{Failed: []FailedSubBatch{}}
+	if err := e.checkPlan(plan); err != nil {
+		return result, err
+	}
+	for i, batch := range plan.Batches {
+		if e.checkpoint != nil && e.checkpoint.Done(batch.Key) {
+			fmt.Printf("Skipping batch %s, already completed\n", batch.Originator.Hex())
+			continue
+		}
+		if e.cfg.MaxBatches > 0 && result.Processed >= e.cfg.MaxBatches {
+			result.Remaining = e.countRemaining(plan.Batches[i:])
+			fmt.Printf("Reached max batches (%d), %d batches remaining\n", e.cfg.MaxBatches, result.Remaining)
+			return result, nil
+		}
+		result.Processed++
+
+		done, err := e.executeBatch(ctx, batch, &result)
+		if err != nil {
+			return result, err
+		}
+		if e.checkpoint != nil && done {
+			if err := e.checkpoint.MarkDone(batch.Key); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// checkPlan returns an error if a sub batch of plan exceeds the contract's
+// max batch size, has a malformed pubkey or has amounts and a value that
+// don't match cfg.Unstake.
+func (e *Executor) checkPlan(plan Plan) error {
+	maxSize := e.cfg.MaxSubBatchSize
+	if maxSize == 0 {
+		maxSize = MaxDelegateStakeBatchSize
+	}
+	for _, batch := range plan.Batches {
+		for _, subBatch := range batch.SubBatches {
+			if err := ValidateSubBatchSize(len(subBatch.PubKeys), maxSize); err != nil {
+				return fmt.Errorf("batch %s: %w", batch.Key, err)
+			}
+			if _, err := subBatch.decodePubKeys(); err != nil {
+				return fmt.Errorf("batch %s: %w", batch.Key, err)
+			}
+			if e.cfg.Unstake && (subBatch.Value != nil || subBatch.Amounts != nil) {
+				return fmt.Errorf("batch %s: unstake sub batch has value %s", batch.Key, subBatch.Value)
+			}
+			if !e.cfg.Unstake {
+				if err := subBatch.checkAmounts(); err != nil {
+					return fmt.Errorf("batch %s: %w", batch.Key, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Executor) countRemaining(batches []PlanBatch) int {
+	remaining := 0
+	for _, batch := range batches {
+		if e.checkpoint == nil || !e.checkpoint.Done(batch.Key) {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// dedupBatch drops repeated pubkeys from batch, along with their states,
+// logging each one dropped.
+func (c Config) dedupBatch(batch Batch) ([][]byte, []events.ValidatorState, error) {
+	if c.AmountFor != nil && len(batch.States) != len(batch.PubKeys) {
+		return nil, nil, fmt.Errorf("batch %s has %d validator states for %d pubkeys", batch.StakeOriginator.Hex(), len(batch.States), len(batch.PubKeys))
+	}
+	pubKeys, dups := DedupWithinBatch(batch.PubKeys)
+	states := batch.States
+	if len(dups) > 0 && states != nil {
+		states = make([]events.ValidatorState, 0, len(pubKeys))
+		dropped := make(map[int]bool, len(dups))
+		for _, dup := range dups {
+			dropped[dup.Index] = true
+		}
+		for i, state := range batch.States {
+			if !dropped[i] {
+				states = append(states, state)
+			}
+		}
+	}
+	for _, dup := range dups {
+		fmt.Printf("Dropping duplicate pubkey %x at index %d of batch %s, first seen at index %d\n",
+			dup.PubKey, dup.Index, batch.StakeOriginator.Hex(), dup.FirstIndex)
+	}
+	return pubKeys, states, nil
+}
+
+// groupByAmount splits pubKeys, whose validators have states, into groups
+// of validators AmountFor gives the same amount, in order of first
+// appearance, so no sub batch mixes amounts: the registry splits a tx's
+// value evenly over its pubkeys. Without AmountFor there is a single group.
+func (c Config) groupByAmount(pubKeys [][]byte, states []events.ValidatorState) ([][][]byte, [][]events.ValidatorState, error) {
+	if c.AmountFor == nil || c.Unstake {
+		return [][][]byte{pubKeys}, [][]events.ValidatorState{states}, nil
+	}
+	var groupKeys [][][]byte
+	var groupStates [][]events.ValidatorState
+	groups := make(map[string]int)
+	for i, state := range states {
+		amount := c.AmountFor(state)
+		if amount == nil || amount.Sign() <= 0 {
+			return nil, nil, fmt.Errorf("no positive stake amount for validator staked by %s at block %d", state.TxOriginator, state.LastStakeBlock)
+		}
+		g, ok := groups[amount.String()]
+		if !ok {
+			g = len(groupKeys)
+			groups[amount.String()] = g
+			groupKeys = append(groupKeys, nil)
+			groupStates = append(groupStates, nil)
+		}
+		groupKeys[g] = append(groupKeys[g], pubKeys[i])
+		groupStates[g] = append(groupStates[g], state)
+	}
+	return groupKeys, groupStates, nil
+}
+
+// subBatchAmounts returns the stake attached for each validator of
+// subBatch, a sub batch of batch whose validators have states: nil for
+// Unstake, AmountFor of each state if set, else the batch's or config's
+// amount per validator.
+func (c Config) subBatchAmounts(batch Batch, subBatch [][]byte, states []events.ValidatorState) ([]*big.Int, error) {
+	if c.Unstake {
+		return nil, nil
+	}
+	amounts := make([]*big.Int, len(subBatch))
+	for i := range subBatch {
+		switch {
+		case c.AmountFor != nil:
+			amount := c.AmountFor(states[i])
+			if amount == nil || amount.Sign() <= 0 {
+				return nil, fmt.Errorf("no positive stake amount for validator staked by %s at block %d", states[i].TxOriginator, states[i].LastStakeBlock)
+			}
+			amounts[i] = new(big.Int).Set(amount)
+		case batch.AmountPerValidator != nil:
+			amounts[i] = new(big.Int).Set(batch.AmountPerValidator)
+		default:
+			amounts[i] = new(big.Int).Set(c.AmountPerValidator)
+		}
+	}
+	return amounts, nil
+}
+
+// subBatchStates returns the states of the n-th sub batch of size pubkeys,
+// or nil if the batch has no states.
+func subBatchStates(states []events.ValidatorState, n, size, subBatchLen int) []events.ValidatorState {
+	if states == nil {
+		return nil
+	}
+	return states[n*size : n*size+subBatchLen]
+}
+
+// executeBatch submits every sub batch of batch, appending reverted ones to
+// result.Failed and unconfirmed ones to result.Unconfirmed, and reports
+// whether every sub batch was included successfully, so the batch can be
+// checkpointed.
+func (e *Executor) executeBatch(ctx context.Context, batch PlanBatch, result *Result) (bool, error) {
+	done := true
+	for _, planned := range batch.SubBatches {
+		if e.router != nil && !e.cfg.Unstake {
+			var optedIn [][]byte
+			var err error
+			planned, optedIn, err = e.dropOptedIn(ctx, planned)
+			if err != nil {
+				return false, fmt.Errorf("batch %s: %w", batch.Key, err)
+			}
+			for _, pubKey := range optedIn {
+				fmt.Printf("Dropping pubkey %x from batch %s, opted in since the plan was built\n", pubKey, batch.Originator.Hex())
+			}
+			result.OptedIn = append(result.OptedIn, optedIn...)
+			if len(planned.PubKeys) == 0 {
+				fmt.Printf("Skipping sub batch of batch %s, every validator is already opted in\n", batch.Originator.Hex())
+				continue
+			}
+		}
+		subBatch, err := planned.decodePubKeys()
+		if err != nil {
+			return false, fmt.Errorf("batch %s: %w", batch.Key, err)
+		}
+		receipt, err := e.executeSubBatch(ctx, batch.Originator, subBatch, planned.Value)
+		if err != nil {
+			return false, err
+		}
+		if receipt == nil {
+			fmt.Printf("%s tx for %d pubkeys of batch %s not confirmed, check its outcome on chain\n", e.txName(), len(subBatch), batch.Originator.Hex())
+			result.Unconfirmed = append(result.Unconfirmed, UnconfirmedSubBatch{
+				StakeOriginator: batch.Originator,
+				PubKeys:         subBatch,
+			})
+			done = false
+			continue
+		}
+		fmt.Printf("%s tx included in block: %v\n", e.txName(), receipt.BlockNumber)
+
+		record := newTxRecord(batch.Originator, subBatch, receipt)
+		result.Records = append(result.Records, record)
+		if e.records != nil {
+			if err := e.records.Write(record); err != nil {
+				return false, err
+			}
+		}
+
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			done = false
+			result.Failed = append(result.Failed, FailedSubBatch{
+				StakeOriginator: batch.Originator,
+				PubKeys:         subBatch,
+				Receipt:         receipt,
+			})
+			if !e.cfg.ContinueOnRevert {
+				return false, cliutil.Errorf(cliutil.ExitRevert, "%s tx %s included, but failed", e.txName(), receipt.TxHash.Hex())
+			}
+			continue
+		}
+
+		if e.staked != nil && !e.cfg.Unstake {
+			if err := e.staked.Add(subBatch); err != nil {
+				return false, err
+			}
+		}
+
+		fmt.Println("-------------------")
+		fmt.Printf("Batch %s completed\n", batch.Originator.Hex())
+		fmt.Println("-------------------")
+	}
+	return done, nil
+}
+
+// dropOptedIn returns planned without the validators the router reports
+// as opted in, its value recomputed from the remaining amounts, and the
+// pubkeys of those dropped.
+func (e *Executor) dropOptedIn(ctx context.Context, planned PlanSubBatch) (PlanSubBatch, [][]byte, error) {
+	pubKeys, err := planned.decodePubKeys()
+	if err != nil {
+		return PlanSubBatch{}, nil, err
+	}
+	statuses, err := query.OptedInStatus(ctx, e.router, pubKeys, len(pubKeys))
+	if err != nil {
+		return PlanSubBatch{}, nil, fmt.Errorf("failed to recheck opt-in status: %w", err)
+	}
+	var optedIn [][]byte
+	kept := planned.filter(func(i int) bool {
+		if optins.IsOptedIn(statuses[i]) {
+			optedIn = append(optedIn, pubKeys[i])
+			return false
+		}
+		return true
+	})
+	return kept, optedIn, nil
+}
+
+func (e *Executor) txName() string {
+	if e.cfg.Unstake {
+		return "Unstake"
+	}
+	return "DelegateStake"
+}
+
+// executeSubBatch submits subBatch and waits for its receipt. The receipt is
+// nil if the tx was rejected with "nonce too low" on a resubmission, as the
+// outcome of the earlier attempt is then unknown.
+func (e *Executor) executeSubBatch(
+	ctx context.Context,
+	stakeOriginator common.Address,
+	subBatch [][]byte,
+	value *big.Int,
+) (*types.Receipt, error) {
+	opts := *e.baseOpts
+	opts.Context = ctx
+	opts.Value = value
+
+	nonce, err := e.nextNonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.Nonce = new(big.Int).SetUint64(nonce)
+
+	gasTip, gasPrice, err := e.ec.SuggestGasTipCapAndPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest gas tip cap and price: %w", err)
+	}
+	opts.GasFeeCap = gasPrice
+	opts.GasTipCap = gasTip
+
+	submitTx := func(
+		ctx context.Context,
+		opts *bind.TransactOpts,
+	) (*types.Transaction, error) {
+		var tx *types.Transaction
+		var err error
+		if e.cfg.Unstake {
+			tx, err = e.transactor.Unstake(opts, subBatch)
+		} else {
+			tx, err = e.transactor.DelegateStake(opts, subBatch, stakeOriginator)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to submit %s: %w", e.txName(), err)
+		}
+		fmt.Printf("%s tx sent. Transaction hash: %s\n", e.txName(), tx.Hash().Hex())
+		return tx, nil
+	}
+
+	receipt, err := e.ec.WaitMinedWithRetry(ctx, &opts, submitTx)
+	if err != nil {
+		if !strings.Contains(err.Error(), "nonce too low") {
+			// The tx may never have reached the mempool, so the nonce is
+			// re-queried rather than left as a gap stalling later txs.
+			e.resetNonce()
+			return nil, cliutil.Errorf(cliutil.ExitRPC, "failed to wait for %s tx to be mined: %w", e.txName(), err)
+		}
+		fmt.Println("Nonce too low. This likely means the tx was included while constructing a retry...")
+		receipt = nil
+	}
+
+	if err := e.waitForNonceAdvance(ctx, nonce); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+func (e *Executor) nextNonce(ctx context.Context) (uint64, error) {
+	if e.nonces != nil {
+		return e.nonces.Next(ctx)
+	}
+	nonce, err := e.client.PendingNonceAt(ctx, e.baseOpts.From)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+	return nonce, nil
+}
+
+func (e *Executor) resetNonce() {
+	if e.nonces != nil {
+		e.nonces.Reset()
+	}
+}
+
+// waitForNonceAdvance blocks until the pending nonce reported by the node is
+// past usedNonce, so the next sub batch doesn't reuse it.
+func (e *Executor) waitForNonceAdvance(ctx context.Context, usedNonce uint64) error {
+	if e.cfg.ConfirmationWait <= 0 {
+		return nil
+	}
+	deadline := e.clock.Now().Add(e.cfg.ConfirmationWait)
+	for {
+		pending, err := e.client.PendingNonceAt(ctx, e.baseOpts.From)
+		if err != nil {
+			return fmt.Errorf("failed to get pending nonce: %w", err)
+		}
+		if pending > usedNonce {
+			return nil
+		}
+		if e.clock.Now().After(deadline) {
+			return fmt.Errorf("pending nonce still %d after waiting %s for nonce %d to confirm",
+				pending, e.cfg.ConfirmationWait, usedNonce)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.clock.After(time.Second):
+		}
+	}
+}
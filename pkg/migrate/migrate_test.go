@@ -0,0 +1,769 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+	"github.com/primevprotocol/validator-registry/pkg/validatoroptinrouter"
+)
+
+// fakeBackend mines every tx sent through fakeTransactor into a receipt
+// with the status the transactor chose. If lagging, its pending nonce
+// never advances, like a node slow to see the account's new txs.
+type fakeBackend struct {
+	mu       sync.Mutex
+	nonce    uint64
+	lagging  bool
+	receipts map[common.Hash]*types.Receipt
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{receipts: make(map[common.Hash]*types.Receipt)}
+}
+
+func (b *fakeBackend) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	receipt, ok := b.receipts[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (b *fakeBackend) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nonce, nil
+}
+
+func (b *fakeBackend) NonceAt(context.Context, common.Address, *big.Int) (uint64, error) {
+	return b.PendingNonceAt(context.Background(), common.Address{})
+}
+
+func (b *fakeBackend) BalanceAt(context.Context, common.Address, *big.Int) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+func (b *fakeBackend) ChainID(context.Context) (*big.Int, error) { return big.NewInt(1), nil }
+
+func (b *fakeBackend) SuggestGasTipCap(context.Context) (*big.Int, error) { return big.NewInt(1), nil }
+
+func (b *fakeBackend) SuggestGasPrice(context.Context) (*big.Int, error) { return big.NewInt(2), nil }
+
+func (b *fakeBackend) SendTransaction(context.Context, *types.Transaction) error { return nil }
+
+func (b *fakeBackend) mine(tx *types.Transaction, status uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.lagging {
+		b.nonce++
+	}
+	b.receipts[tx.Hash()] = &types.Receipt{TxHash: tx.Hash(), Status: status, BlockNumber: big.NewInt(int64(tx.Nonce()) + 1)}
+}
+
+type stakeCall struct {
+	pubKeys    [][]byte
+	originator common.Address
+	value      *big.Int
+	nonce      uint64
+	unstake    bool
+}
+
+// fakeTransactor records every DelegateStake and Unstake call. status and
+// submitErr, if set, choose the receipt status and submission error of the
+// call with the given index.
+type fakeTransactor struct {
+	backend   *fakeBackend
+	calls     []stakeCall
+	status    func(call int) uint64
+	submitErr func(call int) error
+	// beforeCall, if set, runs before each call is recorded.
+	beforeCall func(call int)
+}
+
+func (t *fakeTransactor) DelegateStake(opts *bind.TransactOpts, blsPubKeys [][]byte, stakeOriginator common.Address) (*types.Transaction, error) {
+	return t.submit(opts, stakeCall{pubKeys: blsPubKeys, originator: stakeOriginator, value: opts.Value})
+}
+
+func (t *fakeTransactor) Unstake(opts *bind.TransactOpts, blsPubKeys [][]byte) (*types.Transaction, error) {
+	return t.submit(opts, stakeCall{pubKeys: blsPubKeys, value: opts.Value, unstake: true})
+}
+
+func (t *fakeTransactor) submit(opts *bind.TransactOpts, sc stakeCall) (*types.Transaction, error) {
+	call := len(t.calls)
+	if t.beforeCall != nil {
+		t.beforeCall(call)
+	}
+	sc.nonce = opts.Nonce.Uint64()
+	t.calls = append(t.calls, sc)
+	if t.submitErr != nil {
+		if err := t.submitErr(call); err != nil {
+			return nil, err
+		}
+	}
+	status := types.ReceiptStatusSuccessful
+	if t.status != nil {
+		status = t.status(call)
+	}
+	tx := types.NewTx(&types.LegacyTx{Nonce: opts.Nonce.Uint64(), Value: opts.Value, Data: bytes.Join(sc.pubKeys, nil)})
+	t.backend.mine(tx, status)
+	return tx, nil
+}
+
+// nonces returns the nonce every call was submitted with.
+func (t *fakeTransactor) nonces() []uint64 {
+	nonces := make([]uint64, len(t.calls))
+	for i, call := range t.calls {
+		nonces[i] = call.nonce
+	}
+	return nonces
+}
+
+var testRegistry = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+func testPubKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, events.BLSPubKeyLength)
+}
+
+func testConfig() Config {
+	return Config{
+		Registry:           testRegistry,
+		SubBatchSize:       2,
+		AmountPerValidator: big.NewInt(10),
+	}
+}
+
+func newTestExecutor(t *testing.T, cfg Config) (*Executor, *fakeTransactor) {
+	t.Helper()
+	backend := newFakeBackend()
+	transactor := &fakeTransactor{backend: backend}
+	executor, err := NewExecutor(backend, &bind.TransactOpts{From: common.Address{1}}, transactor, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return executor, transactor
+}
+
+func openTestCheckpoint(t *testing.T, path string) *Checkpoint {
+	t.Helper()
+	checkpoint, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { checkpoint.Close() })
+	return checkpoint
+}
+
+// fiveBatches returns five single-validator batches with distinct
+// originators.
+func fiveBatches() []Batch {
+	batches := make([]Batch, 5)
+	for i := range batches {
+		batches[i] = Batch{PubKeys: [][]byte{testPubKey(byte(i + 1))}, StakeOriginator: common.Address{byte(i + 1)}}
+	}
+	return batches
+}
+
+func TestExecuteAllocatesIncreasingNonces(t *testing.T) {
+	// The node's pending nonce lags behind the submitted txs, so only
+	// locally allocated nonces keep the sub batches from colliding.
+	cfg := testConfig()
+	cfg.UseNonceManager = true
+	executor, transactor := newTestExecutor(t, cfg)
+	transactor.backend.nonce = 5
+	transactor.backend.lagging = true
+
+	batches := []Batch{
+		{PubKeys: [][]byte{testPubKey(1), testPubKey(2), testPubKey(3)}, StakeOriginator: common.HexToAddress("0x0a")},
+		{PubKeys: [][]byte{testPubKey(4)}, StakeOriginator: common.HexToAddress("0x0b")},
+	}
+	result, err := executor.Execute(context.Background(), batches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Failed) != 0 {
+		t.Errorf("got failed sub batches %+v, want none", result.Failed)
+	}
+	want := []uint64{5, 6, 7}
+	nonces := transactor.nonces()
+	if len(nonces) != len(want) {
+		t.Fatalf("submitted with nonces %v, want %v", nonces, want)
+	}
+	for i := range want {
+		if nonces[i] != want[i] {
+			t.Errorf("submitted with nonces %v, want %v", nonces, want)
+			break
+		}
+	}
+}
+
+func TestExecuteRequeriesNonceAfterFailedSubmission(t *testing.T) {
+	cfg := testConfig()
+	cfg.UseNonceManager = true
+	executor, transactor := newTestExecutor(t, cfg)
+	transactor.backend.nonce = 5
+	sendErr := errors.New("insufficient funds for gas * price + value")
+	transactor.submitErr = func(call int) error {
+		if call == 0 {
+			return sendErr
+		}
+		return nil
+	}
+	batch := []Batch{{PubKeys: [][]byte{testPubKey(1)}, StakeOriginator: common.HexToAddress("0x0a")}}
+
+	if _, err := executor.Execute(context.Background(), batch); !errors.Is(err, sendErr) {
+		t.Fatalf("got error %v, want %v", err, sendErr)
+	}
+	if _, err := executor.Execute(context.Background(), batch); err != nil {
+		t.Fatal(err)
+	}
+	// The failed tx never reached the node, so its nonce is reused rather
+	// than leaving a gap.
+	if nonces := transactor.nonces(); len(nonces) != 2 || nonces[1] != 5 {
+		t.Errorf("submitted with nonces %v, want [5 5]", nonces)
+	}
+}
+
+func TestExecuteSplitsBatchesIntoSubBatches(t *testing.T) {
+	executor, transactor := newTestExecutor(t, testConfig())
+	originator := common.Address{9}
+	batch := Batch{
+		PubKeys:         [][]byte{testPubKey(1), testPubKey(2), testPubKey(3)},
+		StakeOriginator: originator,
+	}
+
+	result, err := executor.Execute(context.Background(), []Batch{batch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactor.calls) != 2 {
+		t.Fatalf("got %d txs, want 2", len(transactor.calls))
+	}
+	for i, want := range []struct {
+		pubKeys int
+		value   int64
+	}{{2, 20}, {1, 10}} {
+		call := transactor.calls[i]
+		if len(call.pubKeys) != want.pubKeys || call.value.Int64() != want.value || call.originator != originator {
+			t.Errorf("tx %d staked %d pubkeys with value %s for %s, want %d with %d for %s",
+				i, len(call.pubKeys), call.value, call.originator, want.pubKeys, want.value, originator)
+		}
+	}
+	if result.Processed != 1 || len(result.Records) != 2 || len(result.Failed) != 0 {
+		t.Errorf("got result %+v, want 1 batch processed in 2 recorded txs", result)
+	}
+}
+
+func TestExecuteStopsAtRevertUnlessContinuing(t *testing.T) {
+	reverted := func(call int) uint64 {
+		if call == 0 {
+			return types.ReceiptStatusFailed
+		}
+		return types.ReceiptStatusSuccessful
+	}
+
+	executor, transactor := newTestExecutor(t, testConfig())
+	transactor.status = reverted
+	result, err := executor.Execute(context.Background(), fiveBatches())
+	if err == nil {
+		t.Fatal("expected an error for a reverted sub batch")
+	}
+	if len(transactor.calls) != 1 || len(result.Failed) != 1 {
+		t.Errorf("got %d txs and %d failed sub batches, want 1 and 1", len(transactor.calls), len(result.Failed))
+	}
+
+	cfg := testConfig()
+	cfg.ContinueOnRevert = true
+	executor, transactor = newTestExecutor(t, cfg)
+	transactor.status = reverted
+	result, err = executor.Execute(context.Background(), fiveBatches())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactor.calls) != 5 || len(result.Failed) != 1 || result.Failed[0].StakeOriginator != (common.Address{1}) {
+		t.Errorf("got %d txs and failed sub batches %+v, want 5 txs and only the first failed", len(transactor.calls), result.Failed)
+	}
+}
+
+func TestExecuteStopsAtMaxBatchesAndResumesFromCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.txt")
+	cfg := testConfig()
+	cfg.MaxBatches = 2
+
+	executor, transactor := newTestExecutor(t, cfg)
+	executor.SetCheckpoint(openTestCheckpoint(t, path))
+	result, err := executor.Execute(context.Background(), fiveBatches())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Processed != 2 || result.Remaining != 3 {
+		t.Fatalf("got %d processed and %d remaining, want 2 and 3", result.Processed, result.Remaining)
+	}
+	if len(transactor.calls) != 2 {
+		t.Fatalf("got %d txs, want 2", len(transactor.calls))
+	}
+
+	executor, transactor = newTestExecutor(t, cfg)
+	executor.SetCheckpoint(openTestCheckpoint(t, path))
+	result, err = executor.Execute(context.Background(), fiveBatches())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Processed != 2 || result.Remaining != 1 {
+		t.Fatalf("rerun got %d processed and %d remaining, want 2 and 1", result.Processed, result.Remaining)
+	}
+	for i, call := range transactor.calls {
+		if want := (common.Address{byte(i + 3)}); call.originator != want {
+			t.Errorf("rerun tx %d staked for %s, want %s", i, call.originator, want)
+		}
+	}
+}
+
+func TestExecuteCheckpointsOnlySuccessfulBatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    func(int) uint64
+		submitErr func(int) error
+		wantDone  []bool
+	}{
+		{
+			name:     "all succeed",
+			wantDone: []bool{true, true, true, true, true},
+		},
+		{
+			name: "reverted",
+			status: func(call int) uint64 {
+				if call == 1 {
+					return types.ReceiptStatusFailed
+				}
+				return types.ReceiptStatusSuccessful
+			},
+			wantDone: []bool{true, false, true, true, true},
+		},
+		{
+			name: "unconfirmed after nonce too low",
+			submitErr: func(call int) error {
+				if call == 2 {
+					return errors.New("nonce too low")
+				}
+				return nil
+			},
+			wantDone: []bool{true, true, false, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ContinueOnRevert = true
+			executor, transactor := newTestExecutor(t, cfg)
+			transactor.status = tt.status
+			transactor.submitErr = tt.submitErr
+			checkpoint := openTestCheckpoint(t, filepath.Join(t.TempDir(), "checkpoint.txt"))
+			executor.SetCheckpoint(checkpoint)
+
+			batches := fiveBatches()
+			plan, err := NewPlan(batches, nil, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := executor.ExecutePlan(context.Background(), plan); err != nil {
+				t.Fatal(err)
+			}
+			for i, batch := range plan.Batches {
+				if got := checkpoint.Done(batch.Key); got != tt.wantDone[i] {
+					t.Errorf("batch %d checkpointed = %v, want %v", i, got, tt.wantDone[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOpenCheckpointSkipsTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.txt")
+	done := Batch{StakeOriginator: common.HexToAddress("0x0a")}
+	truncated := Batch{StakeOriginator: common.HexToAddress("0x0b"), AmountPerValidator: big.NewInt(32)}
+	key := truncated.Key(testRegistry)
+	if err := os.WriteFile(path, []byte(done.Key(testRegistry)+"\n"+key[:len(key)-1]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint := openTestCheckpoint(t, path)
+	if !checkpoint.Done(done.Key(testRegistry)) {
+		t.Errorf("%s not recorded as done", done.Key(testRegistry))
+	}
+	if checkpoint.Done(truncated.Key(testRegistry)) {
+		t.Error("batch with a truncated line recorded as done")
+	}
+}
+
+func TestBatchesByOriginator(t *testing.T) {
+	e := map[string]events.Event{
+		hex.EncodeToString(testPubKey(1)): events.NewEvent(common.Address{2}.Hex(), hex.EncodeToString(testPubKey(1)), big.NewInt(1), 1),
+		hex.EncodeToString(testPubKey(2)): events.NewEvent(common.Address{1}.Hex(), hex.EncodeToString(testPubKey(2)), big.NewInt(1), 2),
+		hex.EncodeToString(testPubKey(3)): events.NewEvent(common.Address{2}.Hex(), hex.EncodeToString(testPubKey(3)), big.NewInt(1), 3),
+	}
+	batches := BatchesByOriginator(e)
+	if len(batches) != 2 {
+		t.Fatalf("got %d batches, want 2", len(batches))
+	}
+	if batches[0].StakeOriginator != (common.Address{1}) || len(batches[0].PubKeys) != 1 {
+		t.Errorf("first batch is %+v, want originator 0x01 with 1 pubkey", batches[0])
+	}
+	if batches[1].StakeOriginator != (common.Address{2}) || len(batches[1].PubKeys) != 2 || len(batches[1].States) != 2 {
+		t.Errorf("second batch is %+v, want originator 0x02 with 2 pubkeys and states", batches[1])
+	}
+}
+
+func TestConfigValidateSubBatchSize(t *testing.T) {
+	tests := []struct {
+		cfg     Config
+		wantErr bool
+	}{
+		{Config{Registry: testRegistry, SubBatchSize: 1}, false},
+		{Config{Registry: testRegistry, SubBatchSize: MaxDelegateStakeBatchSize}, false},
+		{Config{Registry: testRegistry, SubBatchSize: MaxDelegateStakeBatchSize + 1}, true},
+		{Config{Registry: testRegistry, SubBatchSize: 0}, true},
+		{Config{Registry: testRegistry, SubBatchSize: -5}, true},
+		{Config{Registry: testRegistry, SubBatchSize: 50, MaxSubBatchSize: 50}, false},
+		{Config{Registry: testRegistry, SubBatchSize: 51, MaxSubBatchSize: 50}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("sub batch size %d with max %d got error %v, want error %t", tt.cfg.SubBatchSize, tt.cfg.MaxSubBatchSize, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBatchesByOriginatorAndAmount(t *testing.T) {
+	a, b := common.HexToAddress("0x0a"), common.HexToAddress("0x0b")
+	state := func(originator common.Address, amount int64) events.ValidatorState {
+		return events.ValidatorState{TxOriginator: originator.Hex(), Amount: big.NewInt(amount)}
+	}
+	states := map[string]events.ValidatorState{
+		hex.EncodeToString(testPubKey(4)): state(b, 10),
+		hex.EncodeToString(testPubKey(3)): state(a, 20),
+		hex.EncodeToString(testPubKey(2)): state(a, 10),
+		hex.EncodeToString(testPubKey(1)): state(a, 10),
+	}
+
+	batches := BatchesByOriginatorAndAmount(states)
+	want := []struct {
+		originator common.Address
+		amount     int64
+		pubKeys    [][]byte
+	}{
+		{a, 10, [][]byte{testPubKey(1), testPubKey(2)}},
+		{a, 20, [][]byte{testPubKey(3)}},
+		{b, 10, [][]byte{testPubKey(4)}},
+	}
+	if len(batches) != len(want) {
+		t.Fatalf("got %d batches, want %d", len(batches), len(want))
+	}
+	for i, w := range want {
+		got := batches[i]
+		if got.StakeOriginator != w.originator || got.AmountPerValidator.Int64() != w.amount || len(got.PubKeys) != len(w.pubKeys) {
+			t.Errorf("batch %d is %s staking %d x %v, want %s staking %d x %d", i, got.StakeOriginator, len(got.PubKeys), got.AmountPerValidator, w.originator, len(w.pubKeys), w.amount)
+			continue
+		}
+		for j := range w.pubKeys {
+			if !bytes.Equal(got.PubKeys[j], w.pubKeys[j]) {
+				t.Errorf("batch %d pubkey %d is %x, want %x", i, j, got.PubKeys[j], w.pubKeys[j])
+			}
+		}
+	}
+}
+
+func TestExecuteUsesBatchAmount(t *testing.T) {
+	executor, transactor := newTestExecutor(t, testConfig())
+	batches := []Batch{
+		{StakeOriginator: common.Address{1}, PubKeys: [][]byte{testPubKey(1), testPubKey(2)}, AmountPerValidator: big.NewInt(32)},
+		{StakeOriginator: common.Address{2}, PubKeys: [][]byte{testPubKey(3)}},
+	}
+	if _, err := executor.Execute(context.Background(), batches); err != nil {
+		t.Fatal(err)
+	}
+	if len(transactor.calls) != 2 || transactor.calls[0].value.Int64() != 64 || transactor.calls[1].value.Int64() != 10 {
+		t.Errorf("got calls %+v, want values 64 from the batch amount and 10 from the config", transactor.calls)
+	}
+}
+
+func TestBatchKey(t *testing.T) {
+	batch := Batch{PubKeys: [][]byte{testPubKey(1), testPubKey(2)}, StakeOriginator: common.Address{1}}
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	reordered := Batch{PubKeys: [][]byte{testPubKey(2), testPubKey(1), testPubKey(2)}, StakeOriginator: common.Address{1}}
+	if batch.Key(testRegistry) != reordered.Key(testRegistry) {
+		t.Errorf("key depends on pubkey order or duplicates")
+	}
+	if batch.Key(testRegistry) == batch.Key(other) {
+		t.Errorf("key doesn't depend on the registry")
+	}
+	grown := Batch{PubKeys: [][]byte{testPubKey(1), testPubKey(2), testPubKey(3)}, StakeOriginator: common.Address{1}}
+	if batch.Key(testRegistry) == grown.Key(testRegistry) {
+		t.Errorf("key doesn't change when validators are added")
+	}
+	withAmount := batch
+	withAmount.AmountPerValidator = big.NewInt(10)
+	if batch.Key(testRegistry) == withAmount.Key(testRegistry) {
+		t.Errorf("key doesn't depend on the amount")
+	}
+}
+
+func TestCheckpointIsScopedToRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.txt")
+	executor, _ := newTestExecutor(t, testConfig())
+	executor.SetCheckpoint(openTestCheckpoint(t, path))
+	if _, err := executor.Execute(context.Background(), fiveBatches()); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.Registry = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	executor, transactor := newTestExecutor(t, cfg)
+	executor.SetCheckpoint(openTestCheckpoint(t, path))
+	result, err := executor.Execute(context.Background(), fiveBatches())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Processed != 5 || len(transactor.calls) != 5 {
+		t.Fatalf("got %d processed batches and %d txs against another registry, want 5 and 5", result.Processed, len(transactor.calls))
+	}
+}
+
+func TestConfigRequiresRegistry(t *testing.T) {
+	cfg := testConfig()
+	cfg.Registry = common.Address{}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("config without registry validated")
+	}
+}
+
+func TestNewPlanSplitsMixedAmounts(t *testing.T) {
+	cfg := testConfig()
+	cfg.AmountFor = func(v events.ValidatorState) *big.Int { return v.Amount }
+	amounts := []int64{10, 20, 10, 20, 10}
+	batch := Batch{StakeOriginator: common.Address{1}}
+	for i, amount := range amounts {
+		batch.PubKeys = append(batch.PubKeys, testPubKey(byte(i+1)))
+		batch.States = append(batch.States, events.ValidatorState{Amount: big.NewInt(amount), TxOriginator: common.Address{1}.Hex()})
+	}
+
+	plan, err := NewPlan([]Batch{batch}, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantKeys := [][]byte{{1, 3}, {5}, {2, 4}}
+	wantValues := []int64{20, 10, 40}
+	subBatches := plan.Batches[0].SubBatches
+	if len(subBatches) != len(wantKeys) {
+		t.Fatalf("got %d sub batches, want %d", len(subBatches), len(wantKeys))
+	}
+	for i, subBatch := range subBatches {
+		pubKeys, err := subBatch.decodePubKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pubKeys) != len(wantKeys[i]) {
+			t.Fatalf("sub batch %d has %d pubkeys, want %d", i, len(pubKeys), len(wantKeys[i]))
+		}
+		for j, pubKey := range pubKeys {
+			if !bytes.Equal(pubKey, testPubKey(wantKeys[i][j])) {
+				t.Errorf("sub batch %d pubkey %d is %x, want the key of validator %d", i, j, pubKey[:1], wantKeys[i][j])
+			}
+		}
+		if subBatch.Value.Int64() != wantValues[i] {
+			t.Errorf("sub batch %d value %s, want %d", i, subBatch.Value, wantValues[i])
+		}
+	}
+	if plan.TotalValue.Int64() != 70 {
+		t.Errorf("total value %s, want 70", plan.TotalValue)
+	}
+
+	executor, transactor := newTestExecutor(t, cfg)
+	if _, err := executor.ExecutePlan(context.Background(), plan); err != nil {
+		t.Fatal(err)
+	}
+	for i, call := range transactor.calls {
+		perValidator := new(big.Int).Div(call.value, big.NewInt(int64(len(call.pubKeys))))
+		if perValidator.Int64() != amounts[call.pubKeys[0][0]-1] {
+			t.Errorf("tx %d stakes %s per validator, want %d", i, perValidator, amounts[call.pubKeys[0][0]-1])
+		}
+	}
+}
+
+// fakeRouter reports the validators in optedIn as AVS opted in and those
+// in vanilla as vanilla opted in.
+type fakeRouter struct {
+	optedIn map[string]bool
+	vanilla map[string]bool
+}
+
+func (r *fakeRouter) AreValidatorsOptedIn(_ *bind.CallOpts, pubKeys [][]byte) ([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, error) {
+	statuses := make([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		statuses[i].IsAvsOptedIn = r.optedIn[string(pubKey)]
+		statuses[i].IsVanillaOptedIn = r.vanilla[string(pubKey)]
+	}
+	return statuses, nil
+}
+
+func TestExecuteDropsValidatorsOptedInBetweenSubBatches(t *testing.T) {
+	cfg := testConfig()
+	cfg.AmountFor = func(v events.ValidatorState) *big.Int { return v.Amount }
+	batch := Batch{StakeOriginator: common.Address{1}}
+	for i, amount := range []int64{10, 10, 30, 30} {
+		batch.PubKeys = append(batch.PubKeys, testPubKey(byte(i+1)))
+		batch.States = append(batch.States, events.ValidatorState{Amount: big.NewInt(amount)})
+	}
+	plan, err := NewPlan([]Batch{batch}, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executor, transactor := newTestExecutor(t, cfg)
+	router := &fakeRouter{optedIn: make(map[string]bool)}
+	executor.SetOptInRouter(router)
+	// Validator 3 opts in while the first sub batch is being submitted,
+	// after the plan was built.
+	transactor.beforeCall = func(call int) {
+		if call == 0 {
+			router.optedIn[string(testPubKey(3))] = true
+		}
+	}
+	checkpoint := openTestCheckpoint(t, filepath.Join(t.TempDir(), "checkpoint.txt"))
+	executor.SetCheckpoint(checkpoint)
+
+	result, err := executor.ExecutePlan(context.Background(), plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactor.calls) != 2 {
+		t.Fatalf("got %d txs, want 2", len(transactor.calls))
+	}
+	second := transactor.calls[1]
+	if len(second.pubKeys) != 1 || !bytes.Equal(second.pubKeys[0], testPubKey(4)) {
+		t.Fatalf("second tx staked %d pubkeys, want only validator 4", len(second.pubKeys))
+	}
+	if second.value.Int64() != 30 {
+		t.Errorf("second tx value %s, want 30", second.value)
+	}
+	if len(result.OptedIn) != 1 || !bytes.Equal(result.OptedIn[0], testPubKey(3)) {
+		t.Errorf("got %d opted in pubkeys, want validator 3", len(result.OptedIn))
+	}
+	if !checkpoint.Done(plan.Batches[0].Key) {
+		t.Errorf("batch not checkpointed")
+	}
+}
+
+func TestExcludeAlreadyStaked(t *testing.T) {
+	e := make(map[string]events.Event)
+	for i := byte(1); i <= 5; i++ {
+		event := events.NewEvent(common.Address{i}.Hex(), hex.EncodeToString(testPubKey(i)), big.NewInt(10), uint64(i))
+		e[event.ValBLSPubKey] = event
+	}
+	router := &fakeRouter{
+		vanilla: map[string]bool{string(testPubKey(2)): true, string(testPubKey(5)): true},
+		// Only validators staked in the wrapped registry are already staked.
+		optedIn: map[string]bool{string(testPubKey(3)): true},
+	}
+
+	skipped, err := ExcludeAlreadyStaked(context.Background(), router, e, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 2 || len(e) != 3 {
+		t.Fatalf("got %d skipped and %d remaining, want 2 and 3", len(skipped), len(e))
+	}
+	for _, key := range []byte{2, 5} {
+		if _, ok := e[hex.EncodeToString(testPubKey(key))]; ok {
+			t.Errorf("validator %d not excluded", key)
+		}
+	}
+	summary := Summarize(BatchesByOriginator(e), skipped)
+	if summary.Skipped[SkipAlreadyStaked] != 2 || summary.ToStake != 3 {
+		t.Errorf("summary skips %d as already staked and stakes %d, want 2 and 3", summary.Skipped[SkipAlreadyStaked], summary.ToStake)
+	}
+}
+
+// waitForWaiter blocks until the code under test is waiting on clock.
+func waitForWaiter(t *testing.T, clock *testutil.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a timer")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWaitForNonceAdvancePollsOnClock(t *testing.T) {
+	cfg := testConfig()
+	cfg.ConfirmationWait = 5 * time.Second
+	executor, transactor := newTestExecutor(t, cfg)
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	executor.SetClock(clock)
+	transactor.backend.nonce = 3
+
+	done := make(chan error, 1)
+	go func() { done <- executor.waitForNonceAdvance(context.Background(), 3) }()
+	for i := 0; i < 2; i++ {
+		waitForWaiter(t, clock)
+		clock.Advance(time.Second)
+	}
+	waitForWaiter(t, clock)
+	transactor.backend.mu.Lock()
+	transactor.backend.nonce = 4
+	transactor.backend.mu.Unlock()
+	clock.Advance(time.Second)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForNonceAdvanceGivesUpAfterConfirmationWait(t *testing.T) {
+	cfg := testConfig()
+	cfg.ConfirmationWait = 3 * time.Second
+	executor, transactor := newTestExecutor(t, cfg)
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	executor.SetClock(clock)
+	transactor.backend.nonce = 3
+
+	done := make(chan error, 1)
+	go func() { done <- executor.waitForNonceAdvance(context.Background(), 3) }()
+	for i := 0; i < 3; i++ {
+		waitForWaiter(t, clock)
+		select {
+		case err := <-done:
+			t.Fatalf("gave up after %d of 3 seconds: %v", i, err)
+		default:
+		}
+		clock.Advance(time.Second)
+	}
+	// A poll exactly at the deadline isn't past it, so one more is made.
+	waitForWaiter(t, clock)
+	clock.Advance(time.Second)
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "pending nonce still 3") {
+		t.Fatalf("got %v, want a nonce timeout", err)
+	}
+}
@@ -0,0 +1,275 @@
+package migrate
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+)
+
+// Plan is everything a migration will do, for review before it is
+// executed: each batch's sub batch txs with their pubkeys and value, and
+// the validators left out.
+type Plan struct {
+	Batches []PlanBatch `json:"batches"`
+	Skipped []PlanSkip  `json:"skipped"`
+	// TotalValue is the sum of the value of every sub batch tx.
+	TotalValue *big.Int `json:"total_value"`
+}
+
+type PlanBatch struct {
+	// Key identifies the batch in a Checkpoint, as Batch.Key does.
+	Key        string         `json:"key"`
+	Originator common.Address `json:"originator"`
+	SubBatches []PlanSubBatch `json:"sub_batches"`
+}
+
+// PlanSubBatch is a single DelegateStake or Unstake tx. Amounts holds the
+// stake of the validator at the same index of PubKeys and Value their sum.
+// Both are nil for Unstake.
+type PlanSubBatch struct {
+	PubKeys []string   `json:"pub_keys"`
+	Amounts []*big.Int `json:"amounts,omitempty"`
+	Value   *big.Int   `json:"value"`
+}
+
+type PlanSkip struct {
+	PubKey     string     `json:"pub_key"`
+	Originator string     `json:"originator"`
+	Reason     SkipReason `json:"reason"`
+}
+
+// NewPlan splits batches into sub batches and computes their values the way
+// an Executor with cfg would.
+func NewPlan(batches []Batch, skipped []SkippedValidator, cfg Config) (Plan, error) {
+	if err := cfg.Validate(); err != nil {
+		return Plan{}, err
+	}
+	plan := Plan{
+		Batches:    make([]PlanBatch, 0, len(batches)),
+		Skipped:    make([]PlanSkip, 0, len(skipped)),
+		TotalValue: new(big.Int),
+	}
+	for _, batch := range batches {
+		pubKeys, states, err := cfg.dedupBatch(batch)
+		if err != nil {
+			return Plan{}, err
+		}
+		planBatch := PlanBatch{Key: batch.Key(cfg.Registry), Originator: batch.StakeOriginator, SubBatches: []PlanSubBatch{}}
+		groupKeys, groupStates, err := cfg.groupByAmount(pubKeys, states)
+		if err != nil {
+			return Plan{}, err
+		}
+		for g := range groupKeys {
+			for n, subBatch := range SplitSubBatches(groupKeys[g], cfg.SubBatchSize) {
+				amounts, err := cfg.subBatchAmounts(batch, subBatch, subBatchStates(groupStates[g], n, cfg.SubBatchSize, len(subBatch)))
+				if err != nil {
+					return Plan{}, err
+				}
+				hexKeys := make([]string, len(subBatch))
+				for i, pubKey := range subBatch {
+					hexKeys[i] = hex.EncodeToString(pubKey)
+				}
+				planned := PlanSubBatch{PubKeys: hexKeys, Amounts: amounts, Value: sumAmounts(amounts)}
+				planBatch.SubBatches = append(planBatch.SubBatches, planned)
+				if planned.Value != nil {
+					plan.TotalValue.Add(plan.TotalValue, planned.Value)
+				}
+			}
+		}
+		plan.Batches = append(plan.Batches, planBatch)
+	}
+	for _, s := range skipped {
+		plan.Skipped = append(plan.Skipped, PlanSkip{
+			PubKey:     s.Event.ValBLSPubKey,
+			Originator: s.Event.TxOriginator,
+			Reason:     s.Reason,
+		})
+	}
+	return plan, nil
+}
+
+// WritePlan writes plan to w as indented JSON.
+func WritePlan(w io.Writer, plan Plan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
+
+func WritePlanFile(path string, plan Plan) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := WritePlan(file, plan); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// LoadPlan decodes a plan written by WritePlan, possibly edited since,
+// checking that every batch has a key and originator and every pubkey is a
+// hex BLS pubkey. TotalValue is recomputed from the sub batch values, as
+// an edit that drops validators rarely updates it.
+func LoadPlan(r io.Reader) (Plan, error) {
+	var plan Plan
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&plan); err != nil {
+		return Plan{}, fmt.Errorf("decoding plan: %w", err)
+	}
+	plan.TotalValue = new(big.Int)
+	keys := make(map[string]bool, len(plan.Batches))
+	for i, batch := range plan.Batches {
+		if batch.Key == "" {
+			return Plan{}, fmt.Errorf("batch %d has no key", i)
+		}
+		if keys[batch.Key] {
+			return Plan{}, fmt.Errorf("batch key %s appears more than once", batch.Key)
+		}
+		keys[batch.Key] = true
+		if batch.Originator == (common.Address{}) {
+			return Plan{}, fmt.Errorf("batch %s has no originator", batch.Key)
+		}
+		for _, subBatch := range batch.SubBatches {
+			if _, err := subBatch.decodePubKeys(); err != nil {
+				return Plan{}, fmt.Errorf("batch %s: %w", batch.Key, err)
+			}
+			if subBatch.Value != nil {
+				plan.TotalValue.Add(plan.TotalValue, subBatch.Value)
+			}
+		}
+	}
+	return plan, nil
+}
+
+func LoadPlanFile(path string) (Plan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Plan{}, err
+	}
+	defer file.Close()
+	return LoadPlan(file)
+}
+
+// PubKeys returns the decoded pubkeys of every sub batch of the plan, in
+// plan order.
+func (p Plan) PubKeys() ([][]byte, error) {
+	var pubKeys [][]byte
+	for _, batch := range p.Batches {
+		for _, subBatch := range batch.SubBatches {
+			decoded, err := subBatch.decodePubKeys()
+			if err != nil {
+				return nil, fmt.Errorf("batch %s: %w", batch.Key, err)
+			}
+			pubKeys = append(pubKeys, decoded...)
+		}
+	}
+	return pubKeys, nil
+}
+
+// Without returns plan without the validators in pubKeys, such as those an
+// Executor dropped for opting in after the plan was built, so that
+// VerifyTotals doesn't expect stake for them. Sub batch values and
+// TotalValue are recomputed as in ExcludeStaked.
+func (p Plan) Without(pubKeys [][]byte) (Plan, error) {
+	drop := make(map[string]bool, len(pubKeys))
+	for _, pubKey := range pubKeys {
+		drop[string(pubKey)] = true
+	}
+	out := Plan{
+		Batches:    make([]PlanBatch, 0, len(p.Batches)),
+		Skipped:    p.Skipped,
+		TotalValue: new(big.Int),
+	}
+	for _, batch := range p.Batches {
+		filtered := PlanBatch{Key: batch.Key, Originator: batch.Originator, SubBatches: []PlanSubBatch{}}
+		for _, subBatch := range batch.SubBatches {
+			decoded, err := subBatch.decodePubKeys()
+			if err != nil {
+				return Plan{}, fmt.Errorf("batch %s: %w", batch.Key, err)
+			}
+			kept := subBatch.filter(func(i int) bool { return !drop[string(decoded[i])] })
+			if len(kept.PubKeys) == 0 {
+				continue
+			}
+			filtered.SubBatches = append(filtered.SubBatches, kept)
+			if kept.Value != nil {
+				out.TotalValue.Add(out.TotalValue, kept.Value)
+			}
+		}
+		out.Batches = append(out.Batches, filtered)
+	}
+	return out, nil
+}
+
+// filter returns the sub batch of the validators of s keep reports true
+// for, with its value recomputed from their amounts.
+func (s PlanSubBatch) filter(keep func(i int) bool) PlanSubBatch {
+	var filtered PlanSubBatch
+	for i, pubKey := range s.PubKeys {
+		if !keep(i) {
+			continue
+		}
+		filtered.PubKeys = append(filtered.PubKeys, pubKey)
+		if s.Amounts != nil {
+			filtered.Amounts = append(filtered.Amounts, s.Amounts[i])
+		}
+	}
+	filtered.Value = sumAmounts(filtered.Amounts)
+	return filtered
+}
+
+// checkAmounts returns an error unless s has one positive amount per
+// pubkey, all equal as the registry splits a tx's value evenly over its
+// pubkeys, summing to its value.
+func (s PlanSubBatch) checkAmounts() error {
+	if len(s.Amounts) != len(s.PubKeys) {
+		return fmt.Errorf("stake sub batch has %d amounts for %d pubkeys", len(s.Amounts), len(s.PubKeys))
+	}
+	for _, amount := range s.Amounts {
+		if amount == nil || amount.Sign() <= 0 {
+			return fmt.Errorf("stake sub batch has a non-positive amount")
+		}
+		if amount.Cmp(s.Amounts[0]) != 0 {
+			return fmt.Errorf("stake sub batch mixes amounts %s and %s", s.Amounts[0], amount)
+		}
+	}
+	if s.Value == nil || sumAmounts(s.Amounts).Cmp(s.Value) != 0 {
+		return fmt.Errorf("stake sub batch value %v doesn't match the sum of its amounts", s.Value)
+	}
+	return nil
+}
+
+// sumAmounts returns the sum of amounts, or nil if amounts is nil.
+func sumAmounts(amounts []*big.Int) *big.Int {
+	if amounts == nil {
+		return nil
+	}
+	sum := new(big.Int)
+	for _, amount := range amounts {
+		sum.Add(sum, amount)
+	}
+	return sum
+}
+
+func (s PlanSubBatch) decodePubKeys() ([][]byte, error) {
+	pubKeys := make([][]byte, len(s.PubKeys))
+	for i, hexKey := range s.PubKeys {
+		pubKey, err := hex.DecodeString(hexKey)
+		if err != nil {
+			return nil, fmt.Errorf("pubkey %q is not hex: %w", hexKey, err)
+		}
+		if len(pubKey) != events.BLSPubKeyLength {
+			return nil, fmt.Errorf("pubkey %s is %d bytes, want %d", hexKey, len(pubKey), events.BLSPubKeyLength)
+		}
+		pubKeys[i] = pubKey
+	}
+	return pubKeys, nil
+}
@@ -0,0 +1,156 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+)
+
+// testPlan plans three validators of one originator, skipping a fourth.
+func testPlan(t *testing.T) Plan {
+	t.Helper()
+	batch := Batch{
+		PubKeys:         [][]byte{testPubKey(1), testPubKey(2), testPubKey(3)},
+		StakeOriginator: common.Address{1},
+	}
+	skipped := []SkippedValidator{{
+		Event:  events.NewEvent(common.Address{2}.Hex(), hex.EncodeToString(testPubKey(4)), big.NewInt(10), 1),
+		Reason: SkipAlreadyStaked,
+	}}
+	plan, err := NewPlan([]Batch{batch}, skipped, testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+func TestPlanJSONRoundTrip(t *testing.T) {
+	plan := testPlan(t)
+	if len(plan.Batches) != 1 || len(plan.Batches[0].SubBatches) != 2 || plan.TotalValue.Int64() != 30 {
+		t.Fatalf("got plan %+v, want one batch of two sub batches staking 30", plan)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePlan(&buf, plan); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPlan(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, plan) {
+		t.Errorf("round trip changed the plan:\ngot  %+v\nwant %+v", loaded, plan)
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := WritePlanFile(path, plan); err != nil {
+		t.Fatal(err)
+	}
+	fromFile, err := LoadPlanFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromFile, plan) {
+		t.Errorf("file round trip changed the plan")
+	}
+	if len(fromFile.Skipped) != 1 || fromFile.Skipped[0].Reason != SkipAlreadyStaked {
+		t.Errorf("got skipped %+v, want the already staked validator", fromFile.Skipped)
+	}
+}
+
+func TestExecuteLoadedPlanSubmitsExactBatches(t *testing.T) {
+	plan := testPlan(t)
+	var buf bytes.Buffer
+	if err := WritePlan(&buf, plan); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPlan(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An operator drops validator 2 from the reviewed plan.
+	first := &loaded.Batches[0].SubBatches[0]
+	first.PubKeys = first.PubKeys[:1]
+	first.Amounts = first.Amounts[:1]
+	first.Value = big.NewInt(10)
+
+	executor, transactor := newTestExecutor(t, testConfig())
+	if _, err := executor.ExecutePlan(context.Background(), loaded); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		pubKeys []byte
+		value   int64
+	}{
+		{[]byte{1}, 10},
+		{[]byte{3}, 10},
+	}
+	if len(transactor.calls) != len(want) {
+		t.Fatalf("got %d txs, want %d", len(transactor.calls), len(want))
+	}
+	for i, call := range transactor.calls {
+		if len(call.pubKeys) != len(want[i].pubKeys) || call.value.Int64() != want[i].value || call.originator != (common.Address{1}) {
+			t.Fatalf("tx %d stakes %d pubkeys for %s with %s, want %d for the originator with %d", i, len(call.pubKeys), call.originator.Hex(), call.value, len(want[i].pubKeys), want[i].value)
+		}
+		for j, b := range want[i].pubKeys {
+			if !bytes.Equal(call.pubKeys[j], testPubKey(b)) {
+				t.Errorf("tx %d pubkey %d is not validator %d", i, j, b)
+			}
+		}
+	}
+}
+
+func TestLoadPlanRejectsMalformedPlans(t *testing.T) {
+	pubKey := hex.EncodeToString(testPubKey(1))
+	for name, plan := range map[string]string{
+		"unknown field":      `{"batches": [], "extra": 1}`,
+		"missing key":        `{"batches": [{"originator": "0x0000000000000000000000000000000000000001", "sub_batches": []}]}`,
+		"duplicate key":      `{"batches": [{"key": "k", "originator": "0x0000000000000000000000000000000000000001"}, {"key": "k", "originator": "0x0000000000000000000000000000000000000001"}]}`,
+		"missing originator": `{"batches": [{"key": "k", "sub_batches": []}]}`,
+		"short pubkey":       `{"batches": [{"key": "k", "originator": "0x0000000000000000000000000000000000000001", "sub_batches": [{"pub_keys": ["aabb"]}]}]}`,
+		"non-hex pubkey":     `{"batches": [{"key": "k", "originator": "0x0000000000000000000000000000000000000001", "sub_batches": [{"pub_keys": ["0x` + pubKey + `"]}]}]}`,
+	} {
+		if _, err := LoadPlan(strings.NewReader(plan)); err == nil {
+			t.Errorf("%s: loaded without error", name)
+		}
+	}
+}
+
+func TestLoadPlanRecomputesTotalValue(t *testing.T) {
+	plan := testPlan(t)
+	// An edit drops validator 3's sub batch but leaves the total as it was.
+	plan.Batches[0].SubBatches = plan.Batches[0].SubBatches[:1]
+
+	var buf bytes.Buffer
+	if err := WritePlan(&buf, plan); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPlan(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.TotalValue.Int64() != 20 {
+		t.Errorf("got total value %s, want 20 from the remaining sub batch", loaded.TotalValue)
+	}
+}
+
+func TestExecutePlanRejectsInconsistentValue(t *testing.T) {
+	plan := testPlan(t)
+	plan.Batches[0].SubBatches[0].Value = big.NewInt(1)
+
+	executor, transactor := newTestExecutor(t, testConfig())
+	if _, err := executor.ExecutePlan(context.Background(), plan); err == nil {
+		t.Fatal("executed a plan whose value doesn't match its amounts")
+	}
+	if len(transactor.calls) != 0 {
+		t.Errorf("got %d txs, want none before the plan is checked", len(transactor.calls))
+	}
+}
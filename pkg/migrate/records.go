@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TxRecord ties a submitted DelegateStake tx to the sub batch it staked.
+type TxRecord struct {
+	TxHash      common.Hash    `json:"tx_hash"`
+	Originator  common.Address `json:"originator"`
+	PubKeys     []string       `json:"pub_keys"`
+	BlockNumber uint64         `json:"block_number"`
+	Status      uint64         `json:"status"`
+}
+
+func newTxRecord(originator common.Address, subBatch [][]byte, receipt *types.Receipt) TxRecord {
+	pubKeys := make([]string, len(subBatch))
+	for i, pubKey := range subBatch {
+		pubKeys[i] = hex.EncodeToString(pubKey)
+	}
+	return TxRecord{
+		TxHash:      receipt.TxHash,
+		Originator:  originator,
+		PubKeys:     pubKeys,
+		BlockNumber: receipt.BlockNumber.Uint64(),
+		Status:      receipt.Status,
+	}
+}
+
+// TxRecordWriter appends TxRecords to a file as JSON lines, syncing after
+// each so records survive a crash mid-run.
+type TxRecordWriter struct {
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func NewTxRecordWriter(path string) (*TxRecordWriter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening receipts file: %w", err)
+	}
+	return &TxRecordWriter{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+func (w *TxRecordWriter) Write(record TxRecord) error {
+	if err := w.encoder.Encode(record); err != nil {
+		return fmt.Errorf("writing tx record: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("syncing receipts file: %w", err)
+	}
+	return nil
+}
+
+func (w *TxRecordWriter) Close() error {
+	return w.file.Close()
+}
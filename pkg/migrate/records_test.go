@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestExecuteWritesTxRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "receipts.jsonl")
+	writer, err := NewTxRecordWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.ContinueOnRevert = true
+	executor, transactor := newTestExecutor(t, cfg)
+	transactor.status = func(call int) uint64 {
+		if call == 1 {
+			return types.ReceiptStatusFailed
+		}
+		return types.ReceiptStatusSuccessful
+	}
+	executor.SetRecordWriter(writer)
+
+	result, err := executor.Execute(context.Background(), fiveBatches()[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var written []TxRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record TxRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q is not a TxRecord: %v", scanner.Text(), err)
+		}
+		written = append(written, record)
+	}
+
+	if len(written) != 3 || len(result.Records) != 3 {
+		t.Fatalf("wrote %d records and returned %d, want 3 each", len(written), len(result.Records))
+	}
+	for i, record := range written {
+		batch := fiveBatches()[i]
+		wantStatus := types.ReceiptStatusSuccessful
+		if i == 1 {
+			wantStatus = types.ReceiptStatusFailed
+		}
+		if record.Originator != batch.StakeOriginator || len(record.PubKeys) != 1 || record.PubKeys[0] != hex.EncodeToString(batch.PubKeys[0]) ||
+			record.Status != wantStatus || record.BlockNumber != uint64(i+1) || record.TxHash != result.Records[i].TxHash {
+			t.Errorf("record %d is %+v", i, record)
+		}
+	}
+}
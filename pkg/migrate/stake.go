@@ -0,0 +1,34 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// MinStakeCaller is implemented by the validatorregistry,
+// validatorregistryv1 and validatorregistryv1_aug15 caller bindings.
+type MinStakeCaller interface {
+	MinStake(opts *bind.CallOpts) (*big.Int, error)
+}
+
+// StakePerValidator returns the registry's minimum stake, the amount a
+// stake tx sends per pubkey. It errors if the minimum exceeds
+// maxPerValidator, the most the operator agreed to stake per validator, so
+// a raised contract parameter stops the run rather than under-staking or
+// silently staking more.
+func StakePerValidator(ctx context.Context, caller MinStakeCaller, maxPerValidator *big.Int) (*big.Int, error) {
+	minStake, err := caller.MinStake(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registry minimum stake: %w", err)
+	}
+	if minStake == nil || minStake.Sign() <= 0 {
+		return nil, fmt.Errorf("registry reports non-positive minimum stake %v", minStake)
+	}
+	if minStake.Cmp(maxPerValidator) > 0 {
+		return nil, fmt.Errorf("registry minimum stake %s wei exceeds the configured maximum of %s wei per validator", minStake, maxPerValidator)
+	}
+	return minStake, nil
+}
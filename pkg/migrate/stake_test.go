@@ -0,0 +1,53 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+type fakeMinStake struct {
+	minStake *big.Int
+	err      error
+}
+
+func (f fakeMinStake) MinStake(*bind.CallOpts) (*big.Int, error) {
+	return f.minStake, f.err
+}
+
+func TestStakePerValidator(t *testing.T) {
+	maxPerValidator := big.NewInt(3100)
+
+	for _, minStake := range []int64{1, 3000, 3100} {
+		got, err := StakePerValidator(context.Background(), fakeMinStake{minStake: big.NewInt(minStake)}, maxPerValidator)
+		if err != nil {
+			t.Fatalf("min stake %d: %v", minStake, err)
+		}
+		if got.Int64() != minStake {
+			t.Errorf("got stake %v per validator, want the registry minimum of %d", got, minStake)
+		}
+	}
+}
+
+func TestStakePerValidatorRejects(t *testing.T) {
+	maxPerValidator := big.NewInt(3100)
+	callErr := errors.New("execution reverted")
+
+	for name, caller := range map[string]fakeMinStake{
+		"above maximum": {minStake: big.NewInt(3200)},
+		"zero":          {minStake: big.NewInt(0)},
+		"nil":           {},
+		"call error":    {err: callErr},
+	} {
+		got, err := StakePerValidator(context.Background(), caller, maxPerValidator)
+		if err == nil {
+			t.Errorf("%s: got stake %v per validator, want an error", name, got)
+		}
+		if caller.err != nil && !errors.Is(err, caller.err) {
+			t.Errorf("%s: got error %v, want %v", name, err, caller.err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package migrate
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+)
+
+// StakedSet records the hex pubkeys of validators a migration has already
+// staked in an append-only file, one per line, so later runs can exclude
+// them from their plan without re-querying the registry. It is lighter
+// than a Checkpoint for migrations whose batches change between runs.
+type StakedSet struct {
+	mu     sync.Mutex
+	file   *os.File
+	staked map[string]bool
+}
+
+func OpenStakedSet(path string) (*StakedSet, error) {
+	staked := make(map[string]bool)
+
+	existing, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("opening staked set: %w", err)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			line := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(scanner.Text())), "0x")
+			if line == "" {
+				continue
+			}
+			// A line truncated by a crash matches no pubkey, so it is
+			// harmless to load.
+			staked[line] = true
+		}
+		existing.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading staked set: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening staked set: %w", err)
+	}
+	return &StakedSet{file: file, staked: staked}, nil
+}
+
+func (s *StakedSet) Contains(pubKey []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.staked[hex.EncodeToString(pubKey)]
+}
+
+func (s *StakedSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.staked)
+}
+
+// Add records pubKeys as staked, syncing the file before returning.
+func (s *StakedSet) Add(pubKeys [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var b strings.Builder
+	// Leading newline keeps a record from merging with a line truncated by a crash.
+	b.WriteString("\n")
+	for _, pubKey := range pubKeys {
+		b.WriteString(hex.EncodeToString(pubKey))
+		b.WriteString("\n")
+	}
+	if _, err := s.file.WriteString(b.String()); err != nil {
+		return fmt.Errorf("writing staked set: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("syncing staked set: %w", err)
+	}
+	for _, pubKey := range pubKeys {
+		s.staked[hex.EncodeToString(pubKey)] = true
+	}
+	return nil
+}
+
+func (s *StakedSet) Close() error {
+	return s.file.Close()
+}
+
+// ExcludeStaked returns a copy of p without the validators in set, each
+// recorded as skipped with SkipInStakedSet. Sub batch values are recomputed
+// from the remaining validators' amounts, and sub batches left empty are
+// dropped.
+func (p Plan) ExcludeStaked(set *StakedSet) (Plan, error) {
+	out := Plan{
+		Batches:    make([]PlanBatch, 0, len(p.Batches)),
+		Skipped:    append([]PlanSkip{}, p.Skipped...),
+		TotalValue: new(big.Int),
+	}
+	for _, batch := range p.Batches {
+		filtered := PlanBatch{Key: batch.Key, Originator: batch.Originator, SubBatches: []PlanSubBatch{}}
+		for _, subBatch := range batch.SubBatches {
+			pubKeys, err := subBatch.decodePubKeys()
+			if err != nil {
+				return Plan{}, fmt.Errorf("batch %s: %w", batch.Key, err)
+			}
+			kept := subBatch.filter(func(i int) bool {
+				if !set.Contains(pubKeys[i]) {
+					return true
+				}
+				out.Skipped = append(out.Skipped, PlanSkip{
+					PubKey:     hex.EncodeToString(pubKeys[i]),
+					Originator: batch.Originator.Hex(),
+					Reason:     SkipInStakedSet,
+				})
+				return false
+			})
+			if len(kept.PubKeys) == 0 {
+				continue
+			}
+			filtered.SubBatches = append(filtered.SubBatches, kept)
+			if kept.Value != nil {
+				out.TotalValue.Add(out.TotalValue, kept.Value)
+			}
+		}
+		out.Batches = append(out.Batches, filtered)
+	}
+	return out, nil
+}
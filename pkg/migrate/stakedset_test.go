@@ -0,0 +1,132 @@
+package migrate
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+)
+
+func openTestStakedSet(t *testing.T, path string) *StakedSet {
+	t.Helper()
+	set, err := OpenStakedSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { set.Close() })
+	return set
+}
+
+func TestStakedSetPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "staked.txt")
+	set := openTestStakedSet(t, path)
+	if err := set.Add([][]byte{testPubKey(1), testPubKey(2)}); err != nil {
+		t.Fatal(err)
+	}
+	set.Close()
+
+	set = openTestStakedSet(t, path)
+	if set.Len() != 2 || !set.Contains(testPubKey(1)) || !set.Contains(testPubKey(2)) || set.Contains(testPubKey(3)) {
+		t.Fatalf("reopened set has %d pubkeys, want exactly the two added", set.Len())
+	}
+}
+
+func TestExcludeStakedRecomputesValues(t *testing.T) {
+	cfg := testConfig()
+	cfg.AmountFor = func(v events.ValidatorState) *big.Int { return v.Amount }
+	batch := Batch{StakeOriginator: common.Address{1}}
+	for i, amount := range []int64{10, 10, 10, 30, 30} {
+		batch.PubKeys = append(batch.PubKeys, testPubKey(byte(i+1)))
+		batch.States = append(batch.States, events.ValidatorState{Amount: big.NewInt(amount)})
+	}
+	plan, err := NewPlan([]Batch{batch}, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := openTestStakedSet(t, filepath.Join(t.TempDir(), "staked.txt"))
+	// Validator 3 is alone in its sub batch, so that sub batch is dropped.
+	if err := set.Add([][]byte{testPubKey(1), testPubKey(3), testPubKey(4)}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := plan.ExcludeStaked(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subBatches := out.Batches[0].SubBatches
+	if len(subBatches) != 2 {
+		t.Fatalf("got %d sub batches, want 2", len(subBatches))
+	}
+	for i, want := range []int64{10, 30} {
+		if err := subBatches[i].checkAmounts(); err != nil {
+			t.Errorf("sub batch %d: %v", i, err)
+		}
+		if len(subBatches[i].PubKeys) != 1 || subBatches[i].Value.Int64() != want {
+			t.Errorf("sub batch %d has %d pubkeys and value %s, want 1 and %d", i, len(subBatches[i].PubKeys), subBatches[i].Value, want)
+		}
+	}
+	if out.TotalValue.Int64() != 40 {
+		t.Errorf("total value %s, want 40", out.TotalValue)
+	}
+	if len(out.Skipped) != 3 {
+		t.Fatalf("got %d skipped validators, want 3", len(out.Skipped))
+	}
+	for _, skip := range out.Skipped {
+		if skip.Reason != SkipInStakedSet {
+			t.Errorf("validator %s skipped for %q, want %q", skip.PubKey, skip.Reason, SkipInStakedSet)
+		}
+	}
+	if plan.TotalValue.Int64() != 90 {
+		t.Errorf("original plan modified, total value %s", plan.TotalValue)
+	}
+}
+
+func TestExecuteAddsSuccessfulSubBatchesToStakedSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "staked.txt")
+	cfg := testConfig()
+	cfg.ContinueOnRevert = true
+	executor, transactor := newTestExecutor(t, cfg)
+	transactor.status = func(call int) uint64 {
+		if call == 1 {
+			return types.ReceiptStatusFailed
+		}
+		return types.ReceiptStatusSuccessful
+	}
+	executor.SetStakedSet(openTestStakedSet(t, path))
+
+	batches := []Batch{{
+		PubKeys:         [][]byte{testPubKey(1), testPubKey(2), testPubKey(3), testPubKey(4), testPubKey(5)},
+		StakeOriginator: common.Address{1},
+	}}
+	if _, err := executor.Execute(context.Background(), batches); err != nil {
+		t.Fatal(err)
+	}
+
+	set := openTestStakedSet(t, path)
+	for i, want := range []bool{true, true, false, false, true} {
+		if got := set.Contains(testPubKey(byte(i + 1))); got != want {
+			t.Errorf("validator %d in staked set = %v, want %v", i+1, got, want)
+		}
+	}
+
+	plan, err := NewPlan(batches, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err = plan.ExcludeStaked(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKeys, err := plan.PubKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pubKeys) != 2 {
+		t.Fatalf("next run plans %d validators, want the 2 that reverted", len(pubKeys))
+	}
+}
@@ -0,0 +1,102 @@
+package migrate
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+)
+
+// SkipReason says why a candidate validator was left out of the plan.
+type SkipReason string
+
+const (
+	// SkipAlreadyStaked marks validators already staked (opted in) in the
+	// target registry.
+	SkipAlreadyStaked SkipReason = "already staked"
+	// SkipExcludedOriginator marks validators staked by an originator that
+	// is deliberately not migrated, e.g. the default dev account.
+	SkipExcludedOriginator SkipReason = "excluded originator"
+	// SkipNotStaked marks validators with a stored event that are no longer
+	// staked in the source registry.
+	SkipNotStaked SkipReason = "not staked in source registry"
+	// SkipInStakedSet marks validators a previous run recorded in its
+	// StakedSet.
+	SkipInStakedSet SkipReason = "in staked set"
+)
+
+type SkippedValidator struct {
+	Event  events.Event
+	Reason SkipReason
+}
+
+type OriginatorSummary struct {
+	Originator common.Address
+	ToStake    int
+	Skipped    map[SkipReason]int
+}
+
+// PlanSummary counts, overall and per originator, how many candidate
+// validators a migration will stake and how many it skips and why.
+type PlanSummary struct {
+	Candidates   int
+	ToStake      int
+	Skipped      map[SkipReason]int
+	ByOriginator []OriginatorSummary
+}
+
+// Summarize builds a PlanSummary from the batches to execute and the
+// validators filtered out while building them. ByOriginator is sorted by
+// originator.
+func Summarize(plan []Batch, skipped []SkippedValidator) PlanSummary {
+	summary := PlanSummary{Skipped: make(map[SkipReason]int)}
+	byOriginator := make(map[common.Address]*OriginatorSummary)
+	originator := func(addr common.Address) *OriginatorSummary {
+		s, ok := byOriginator[addr]
+		if !ok {
+			s = &OriginatorSummary{Originator: addr, Skipped: make(map[SkipReason]int)}
+			byOriginator[addr] = s
+		}
+		return s
+	}
+
+	for _, batch := range plan {
+		summary.ToStake += len(batch.PubKeys)
+		originator(batch.StakeOriginator).ToStake += len(batch.PubKeys)
+	}
+	for _, s := range skipped {
+		summary.Skipped[s.Reason]++
+		originator(common.HexToAddress(s.Event.TxOriginator)).Skipped[s.Reason]++
+	}
+	summary.Candidates = summary.ToStake + len(skipped)
+
+	summary.ByOriginator = make([]OriginatorSummary, 0, len(byOriginator))
+	for _, s := range byOriginator {
+		summary.ByOriginator = append(summary.ByOriginator, *s)
+	}
+	sort.Slice(summary.ByOriginator, func(i, j int) bool {
+		return summary.ByOriginator[i].Originator.Hex() < summary.ByOriginator[j].Originator.Hex()
+	})
+	return summary
+}
+
+// Print writes a human readable report of s to w.
+func (s PlanSummary) Print(w io.Writer) {
+	reasons := slices.Sorted(maps.Keys(s.Skipped))
+	fmt.Fprintln(w, "Candidate validators: ", s.Candidates)
+	for _, reason := range reasons {
+		fmt.Fprintf(w, "Skipped (%s): %d\n", reason, s.Skipped[reason])
+	}
+	fmt.Fprintln(w, "To stake: ", s.ToStake)
+	for _, o := range s.ByOriginator {
+		fmt.Fprintf(w, "Originator %s: %d to stake", o.Originator.Hex(), o.ToStake)
+		for _, reason := range slices.Sorted(maps.Keys(o.Skipped)) {
+			fmt.Fprintf(w, ", %d skipped (%s)", o.Skipped[reason], reason)
+		}
+		fmt.Fprintln(w)
+	}
+}
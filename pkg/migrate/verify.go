@@ -0,0 +1,62 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/query"
+)
+
+// stillStakedBatchSize is the number of pubkeys StillStaked checks per
+// multicall.
+const stillStakedBatchSize = 100
+
+// StillStaked returns the pubkeys of pubKeys that the registry at registry
+// reports as staked, so an unstake run can verify on chain that it took
+// effect.
+func StillStaked(ctx context.Context, caller bind.ContractCaller, registry common.Address, pubKeys [][]byte) ([][]byte, error) {
+	isStaked, err := query.AreStaked(ctx, caller, registry, pubKeys, stillStakedBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check whether validators are staked: %w", err)
+	}
+	var staked [][]byte
+	for i, pubKey := range pubKeys {
+		if isStaked[i] {
+			staked = append(staked, pubKey)
+		}
+	}
+	return staked, nil
+}
+
+// StakedAmountReader is implemented by the validatorregistryv1 and
+// validatorregistryv1_aug15 caller bindings.
+type StakedAmountReader interface {
+	GetStakedAmount(opts *bind.CallOpts, valBLSPubKey []byte) (*big.Int, error)
+}
+
+// VerifyTotals sums the amount registry reports as staked for every pubkey
+// in plan, to reconcile a completed migration against plan.TotalValue.
+// Callers compare onChain with expected; a shortfall means some sub batch
+// did not land.
+func VerifyTotals(ctx context.Context, registry StakedAmountReader, plan Plan) (onChain, expected *big.Int, err error) {
+	pubKeys, err := plan.PubKeys()
+	if err != nil {
+		return nil, nil, err
+	}
+	onChain = new(big.Int)
+	for _, pubKey := range pubKeys {
+		amount, err := registry.GetStakedAmount(&bind.CallOpts{Context: ctx}, pubKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get staked amount of %x: %w", pubKey, err)
+		}
+		onChain.Add(onChain, amount)
+	}
+	expected = new(big.Int)
+	if plan.TotalValue != nil {
+		expected.Set(plan.TotalValue)
+	}
+	return onChain, expected, nil
+}
@@ -0,0 +1,176 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+)
+
+func TestExecuteUnstakes(t *testing.T) {
+	cfg := testConfig()
+	cfg.Unstake = true
+	executor, transactor := newTestExecutor(t, cfg)
+	transactor.submitErr = func(call int) error {
+		if call == 1 {
+			return errors.New("nonce too low")
+		}
+		return nil
+	}
+
+	batch := Batch{PubKeys: [][]byte{testPubKey(1), testPubKey(2), testPubKey(3)}, StakeOriginator: common.Address{1}}
+	result, err := executor.Execute(context.Background(), []Batch{batch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactor.calls) != 2 {
+		t.Fatalf("got %d txs, want 2", len(transactor.calls))
+	}
+	for i, call := range transactor.calls {
+		if !call.unstake || (call.value != nil && call.value.Sign() != 0) {
+			t.Errorf("tx %d: unstake %t with value %v, want an Unstake with no value", i, call.unstake, call.value)
+		}
+	}
+	// The second tx's "nonce too low" leaves its outcome unknown, so it is
+	// reported rather than recorded as a success.
+	if len(result.Records) != 1 || len(result.Unconfirmed) != 1 {
+		t.Fatalf("got %d records and %d unconfirmed, want 1 and 1", len(result.Records), len(result.Unconfirmed))
+	}
+	if unconfirmed := result.Unconfirmed[0].PubKeys; len(unconfirmed) != 1 || !bytes.Equal(unconfirmed[0], testPubKey(3)) {
+		t.Errorf("got unconfirmed pubkeys %x, want validator 3", unconfirmed)
+	}
+}
+
+func TestStillStakedFlagsRemainingValidators(t *testing.T) {
+	caller := &testutil.StakedMulticall{
+		T:        t,
+		Registry: testRegistry,
+		Staked:   map[string]bool{string(testPubKey(2)): true},
+	}
+	pubKeys := [][]byte{testPubKey(1), testPubKey(2), testPubKey(3)}
+
+	staked, err := StillStaked(context.Background(), caller, testRegistry, pubKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(staked) != 1 || !bytes.Equal(staked[0], testPubKey(2)) {
+		t.Errorf("got still staked %x, want validator 2", staked)
+	}
+
+	caller.Staked = nil
+	if staked, err := StillStaked(context.Background(), caller, testRegistry, pubKeys); err != nil || len(staked) != 0 {
+		t.Errorf("got %x, %v once all are unstaked, want none", staked, err)
+	}
+}
+
+func TestExecuteSplitsLargeUnstakeIntoSubBatches(t *testing.T) {
+	var pubKeys [][]byte
+	for i := range 45 {
+		pubKeys = append(pubKeys, testPubKey(byte(i)))
+	}
+	if subBatches := SplitSubBatches(pubKeys, MaxDelegateStakeBatchSize); len(subBatches) != 3 {
+		t.Fatalf("split 45 pubkeys into %d sub batches, want 3", len(subBatches))
+	}
+
+	cfg := testConfig()
+	cfg.Unstake = true
+	cfg.SubBatchSize = MaxDelegateStakeBatchSize
+	executor, transactor := newTestExecutor(t, cfg)
+	if _, err := executor.Execute(context.Background(), []Batch{{PubKeys: pubKeys, StakeOriginator: common.Address{1}}}); err != nil {
+		t.Fatal(err)
+	}
+	want := []int{20, 20, 5}
+	if len(transactor.calls) != len(want) {
+		t.Fatalf("got %d Unstake txs, want %d", len(transactor.calls), len(want))
+	}
+	next := 0
+	for i, call := range transactor.calls {
+		if len(call.pubKeys) != want[i] {
+			t.Errorf("tx %d unstakes %d validators, want %d", i, len(call.pubKeys), want[i])
+		}
+		for _, pubKey := range call.pubKeys {
+			if !bytes.Equal(pubKey, pubKeys[next]) {
+				t.Fatalf("tx %d unstakes validators out of order", i)
+			}
+			next++
+		}
+	}
+}
+
+// stakedAmounts reports the staked amount of each pubkey, zero if absent.
+type stakedAmounts map[string]int64
+
+func (s stakedAmounts) GetStakedAmount(_ *bind.CallOpts, pubKey []byte) (*big.Int, error) {
+	return big.NewInt(s[string(pubKey)]), nil
+}
+
+func TestVerifyTotalsFindsShortfall(t *testing.T) {
+	plan := testPlan(t)
+	// Validator 3's sub batch didn't land.
+	registry := stakedAmounts{string(testPubKey(1)): 10, string(testPubKey(2)): 10}
+
+	onChain, expected, err := VerifyTotals(context.Background(), registry, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected.Int64() != 30 || onChain.Int64() != 20 {
+		t.Errorf("got %s on chain of %s expected, want 20 of 30", onChain, expected)
+	}
+
+	registry[string(testPubKey(3))] = 10
+	onChain, expected, err = VerifyTotals(context.Background(), registry, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onChain.Cmp(expected) != 0 {
+		t.Errorf("got %s on chain of %s expected once every sub batch landed", onChain, expected)
+	}
+}
+
+func TestVerifyTotalsWithoutValidatorsOptedInDuringExecution(t *testing.T) {
+	plan := testPlan(t)
+	executor, transactor := newTestExecutor(t, testConfig())
+	router := &fakeRouter{optedIn: make(map[string]bool)}
+	executor.SetOptInRouter(router)
+	// Validator 3 opts in before its sub batch is rechecked, so it is
+	// never staked.
+	transactor.beforeCall = func(call int) {
+		if call == 0 {
+			router.optedIn[string(testPubKey(3))] = true
+		}
+	}
+	result, err := executor.ExecutePlan(context.Background(), plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := stakedAmounts{}
+	for _, call := range transactor.calls {
+		for _, pubKey := range call.pubKeys {
+			registry[string(pubKey)] = new(big.Int).Div(call.value, big.NewInt(int64(len(call.pubKeys)))).Int64()
+		}
+	}
+
+	onChain, expected, err := VerifyTotals(context.Background(), registry, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onChain.Cmp(expected) == 0 {
+		t.Fatalf("got %s on chain matching the full plan, want a shortfall for validator 3", onChain)
+	}
+	remaining, err := plan.Without(result.OptedIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onChain, expected, err = VerifyTotals(context.Background(), registry, remaining)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected.Int64() != 20 || onChain.Cmp(expected) != 0 {
+		t.Errorf("got %s on chain of %s expected, want 20 of 20", onChain, expected)
+	}
+}
@@ -0,0 +1,194 @@
+package optins
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitavs"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitmiddleware"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+	"github.com/primevprotocol/validator-registry/pkg/vanillaregistry"
+	"golang.org/x/sync/errgroup"
+)
+
+// AVSEvents is implemented by mevcommitavs.MevcommitavsFilterer.
+type AVSEvents interface {
+	FilterValidatorRegistered(opts *bind.FilterOpts, podOwner []common.Address) (*mevcommitavs.MevcommitavsValidatorRegisteredIterator, error)
+	WatchValidatorRegistered(opts *bind.WatchOpts, sink chan<- *mevcommitavs.MevcommitavsValidatorRegistered, podOwner []common.Address) (event.Subscription, error)
+}
+
+// MiddlewareEvents is implemented by
+// mevcommitmiddleware.MevcommitmiddlewareFilterer.
+type MiddlewareEvents interface {
+	FilterValRecordAdded(opts *bind.FilterOpts, operator []common.Address, vault []common.Address, position []*big.Int) (*mevcommitmiddleware.MevcommitmiddlewareValRecordAddedIterator, error)
+	WatchValRecordAdded(opts *bind.WatchOpts, sink chan<- *mevcommitmiddleware.MevcommitmiddlewareValRecordAdded, operator []common.Address, vault []common.Address, position []*big.Int) (event.Subscription, error)
+}
+
+// VanillaEvents is implemented by vanillaregistry.VanillaregistryFilterer.
+type VanillaEvents interface {
+	FilterStaked(opts *bind.FilterOpts, msgSender []common.Address, withdrawalAddress []common.Address) (*vanillaregistry.VanillaregistryStakedIterator, error)
+	WatchStaked(opts *bind.WatchOpts, sink chan<- *vanillaregistry.VanillaregistryStaked, msgSender []common.Address, withdrawalAddress []common.Address) (event.Subscription, error)
+}
+
+// Collector gathers opt-in events from the Eigen AVS, the Symbiotic
+// middleware and the vanilla registry.
+type Collector struct {
+	avs        AVSEvents
+	middleware MiddlewareEvents
+	vanilla    VanillaEvents
+	batchSize  uint64
+}
+
+func NewCollector(
+	avs AVSEvents,
+	middleware MiddlewareEvents,
+	vanilla VanillaEvents,
+	batchSize uint64,
+) *Collector {
+	return &Collector{avs: avs, middleware: middleware, vanilla: vanilla, batchSize: batchSize}
+}
+
+// Collect returns the opt-ins in [startBlock, endBlock], querying at most
+// batchSize blocks per filter call.
+func (c *Collector) Collect(ctx context.Context, startBlock, endBlock uint64) ([]Validator, error) {
+	optedInValidators := make([]Validator, 0, 1000)
+	for startBlock <= endBlock {
+		batchEnd := startBlock + c.batchSize - 1
+		if batchEnd > endBlock {
+			batchEnd = endBlock
+		}
+		fmt.Printf("Processing blocks %d to %d\n", startBlock, batchEnd)
+
+		validators, err := c.collectBatch(ctx, startBlock, batchEnd)
+		if err != nil {
+			return nil, err
+		}
+		optedInValidators = append(optedInValidators, validators...)
+		startBlock = batchEnd + 1
+	}
+	return optedInValidators, nil
+}
+
+// collectBatch filters the three opt-in sources concurrently, as they are
+// independent contracts, and returns their opt-ins in source order.
+func (c *Collector) collectBatch(ctx context.Context, startBlock, endBlock uint64) ([]Validator, error) {
+	sources := []func(opts *bind.FilterOpts) ([]Validator, error){
+		c.collectAVS,
+		c.collectMiddleware,
+		c.collectVanilla,
+	}
+	results := make([][]Validator, len(sources))
+
+	g, ctx := errgroup.WithContext(ctx)
+	for i, collect := range sources {
+		g.Go(func() error {
+			opts := &bind.FilterOpts{
+				Start:   startBlock,
+				End:     &endBlock,
+				Context: ctx,
+			}
+			validators, err := collect(opts)
+			if err != nil {
+				return err
+			}
+			results[i] = validators
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	optedInValidators := []Validator{}
+	for _, validators := range results {
+		optedInValidators = append(optedInValidators, validators...)
+	}
+	return optedInValidators, nil
+}
+
+func (c *Collector) collectAVS(opts *bind.FilterOpts) ([]Validator, error) {
+	var validators []Validator
+	events, err := c.avs.FilterValidatorRegistered(opts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter ValidatorRegistered events for blocks %d to %d: %w", opts.Start, *opts.End, err)
+	}
+	registered, err := utils.CollectEvents(utils.IterEvents(events, func() *mevcommitavs.MevcommitavsValidatorRegistered {
+		return events.Event
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate ValidatorRegistered events for blocks %d to %d: %w", opts.Start, *opts.End, err)
+	}
+	for _, event := range registered {
+		validators = append(validators, avsOptIn(event))
+	}
+	return validators, nil
+}
+
+func (c *Collector) collectMiddleware(opts *bind.FilterOpts) ([]Validator, error) {
+	var validators []Validator
+	events, err := c.middleware.FilterValRecordAdded(opts, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter ValRecordAdded events for blocks %d to %d: %w", opts.Start, *opts.End, err)
+	}
+	registered, err := utils.CollectEvents(utils.IterEvents(events, func() *mevcommitmiddleware.MevcommitmiddlewareValRecordAdded {
+		return events.Event
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate ValRecordAdded events for blocks %d to %d: %w", opts.Start, *opts.End, err)
+	}
+	for _, event := range registered {
+		validators = append(validators, middlewareOptIn(event))
+	}
+	return validators, nil
+}
+
+func (c *Collector) collectVanilla(opts *bind.FilterOpts) ([]Validator, error) {
+	var validators []Validator
+	events, err := c.vanilla.FilterStaked(opts, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter Staked events for blocks %d to %d: %w", opts.Start, *opts.End, err)
+	}
+	registered, err := utils.CollectEvents(utils.IterEvents(events, func() *vanillaregistry.VanillaregistryStaked {
+		return events.Event
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate Staked events for blocks %d to %d: %w", opts.Start, *opts.End, err)
+	}
+	for _, event := range registered {
+		validators = append(validators, vanillaOptIn(event))
+	}
+	return validators, nil
+}
+
+func avsOptIn(event *mevcommitavs.MevcommitavsValidatorRegistered) Validator {
+	return Validator{
+		PubKey:     hex.EncodeToString(event.ValidatorPubKey),
+		OptInType:  OptInTypeEigen,
+		OptInBlock: event.Raw.BlockNumber,
+		PodOwner:   event.PodOwner,
+	}
+}
+
+func middlewareOptIn(event *mevcommitmiddleware.MevcommitmiddlewareValRecordAdded) Validator {
+	return Validator{
+		PubKey:     hex.EncodeToString(event.BlsPubkey),
+		OptInType:  OptInTypeSymbiotic,
+		OptInBlock: event.Raw.BlockNumber,
+		Vault:      event.Vault,
+		Operator:   event.Operator,
+	}
+}
+
+func vanillaOptIn(event *vanillaregistry.VanillaregistryStaked) Validator {
+	return Validator{
+		PubKey:         hex.EncodeToString(event.ValBLSPubKey),
+		OptInType:      OptInTypeVanilla,
+		OptInBlock:     event.Raw.BlockNumber,
+		WithdrawalAddr: event.WithdrawalAddress,
+	}
+}
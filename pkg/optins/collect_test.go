@@ -0,0 +1,167 @@
+package optins
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitavs"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitmiddleware"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+	"github.com/primevprotocol/validator-registry/pkg/vanillaregistry"
+)
+
+var (
+	testAVS        = common.HexToAddress("0xa1")
+	testMiddleware = common.HexToAddress("0xa2")
+	testVanilla    = common.HexToAddress("0xa3")
+)
+
+// optInLogs returns a vanilla opt-in of c in block 3 ahead of an eigen opt-in
+// of a in block 1 and a symbiotic opt-in of b in block 2, and a second
+// vanilla opt-in of d in block 12.
+func optInLogs(t *testing.T) []types.Log {
+	t.Helper()
+	avsABI, err := mevcommitavs.MevcommitavsMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	middlewareABI, err := mevcommitmiddleware.MevcommitmiddlewareMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vanillaABI, err := vanillaregistry.VanillaregistryMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	staker := common.HexToAddress("0x01")
+	return []types.Log{
+		testutil.EventLog(t, vanillaABI, testVanilla, "Staked", 3, staker, common.HexToAddress("0x0c"), testPubKey('c'), big.NewInt(1)),
+		testutil.EventLog(t, avsABI, testAVS, "ValidatorRegistered", 1, testPubKey('a'), common.HexToAddress("0x0a")),
+		testutil.EventLog(t, middlewareABI, testMiddleware, "ValRecordAdded", 2, testPubKey('b'), common.HexToAddress("0x0b"), common.HexToAddress("0x0d"), big.NewInt(0)),
+		testutil.EventLog(t, vanillaABI, testVanilla, "Staked", 12, staker, common.HexToAddress("0x0c"), testPubKey('d'), big.NewInt(1)),
+	}
+}
+
+func newLogCollector(t *testing.T, backend bind.ContractFilterer, batchSize uint64) *Collector {
+	t.Helper()
+	avs, err := mevcommitavs.NewMevcommitavsFilterer(testAVS, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleware, err := mevcommitmiddleware.NewMevcommitmiddlewareFilterer(testMiddleware, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vanilla, err := vanillaregistry.NewVanillaregistryFilterer(testVanilla, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewCollector(avs, middleware, vanilla, batchSize)
+}
+
+func TestCollectReturnsOptInsInSourceOrder(t *testing.T) {
+	backend := &testutil.LogFilterer{Logs: optInLogs(t)}
+	validators, err := newLogCollector(t, backend, 10).Collect(context.Background(), 0, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Validator{
+		{PubKey: hex.EncodeToString(testPubKey('a')), OptInType: OptInTypeEigen, OptInBlock: 1, PodOwner: common.HexToAddress("0x0a")},
+		{PubKey: hex.EncodeToString(testPubKey('b')), OptInType: OptInTypeSymbiotic, OptInBlock: 2, Operator: common.HexToAddress("0x0b"), Vault: common.HexToAddress("0x0d")},
+		{PubKey: hex.EncodeToString(testPubKey('c')), OptInType: OptInTypeVanilla, OptInBlock: 3, WithdrawalAddr: common.HexToAddress("0x0c")},
+		{PubKey: hex.EncodeToString(testPubKey('d')), OptInType: OptInTypeVanilla, OptInBlock: 12, WithdrawalAddr: common.HexToAddress("0x0c")},
+	}
+	if len(validators) != len(want) {
+		t.Fatalf("got %d opt-ins, want %d: %+v", len(validators), len(want), validators)
+	}
+	for i := range want {
+		if validators[i] != want[i] {
+			t.Errorf("opt-in %d is %+v, want %+v", i, validators[i], want[i])
+		}
+	}
+	// Blocks 0-9 and 10-15, each filtered once per source.
+	if len(backend.Queries) != 6 {
+		t.Errorf("got %d filter calls, want 6", len(backend.Queries))
+	}
+}
+
+// barrierFilterer holds each FilterLogs call until all sources have called
+// it, failing if they don't within a second.
+type barrierFilterer struct {
+	*testutil.LogFilterer
+	arrived sync.WaitGroup
+	all     chan struct{}
+}
+
+func newBarrierFilterer(logs []types.Log, sources int) *barrierFilterer {
+	f := &barrierFilterer{LogFilterer: &testutil.LogFilterer{Logs: logs}, all: make(chan struct{})}
+	f.arrived.Add(sources)
+	go func() {
+		f.arrived.Wait()
+		close(f.all)
+	}()
+	return f
+}
+
+func (f *barrierFilterer) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	f.arrived.Done()
+	select {
+	case <-f.all:
+	case <-time.After(time.Second):
+		return nil, errors.New("sources filtered one at a time")
+	}
+	return f.LogFilterer.FilterLogs(ctx, q)
+}
+
+func TestCollectFiltersSourcesConcurrently(t *testing.T) {
+	backend := newBarrierFilterer(optInLogs(t), 3)
+	validators, err := newLogCollector(t, backend, 100).Collect(context.Background(), 0, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validators) != 4 {
+		t.Errorf("got %d opt-ins, want 4", len(validators))
+	}
+}
+
+// failingFilterer fails the FilterLogs calls for fail's logs.
+type failingFilterer struct {
+	*testutil.LogFilterer
+	fail common.Address
+	err  error
+}
+
+func (f *failingFilterer) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	for _, address := range q.Addresses {
+		if address == f.fail {
+			return nil, f.err
+		}
+	}
+	return f.LogFilterer.FilterLogs(ctx, q)
+}
+
+func TestCollectReturnsSourceError(t *testing.T) {
+	filterErr := errors.New("rate limited")
+	backend := &failingFilterer{
+		LogFilterer: &testutil.LogFilterer{Logs: optInLogs(t)},
+		fail:        testMiddleware,
+		err:         filterErr,
+	}
+	validators, err := newLogCollector(t, backend, 10).Collect(context.Background(), 0, 15)
+	if !errors.Is(err, filterErr) {
+		t.Fatalf("got error %v, want %v", err, filterErr)
+	}
+	if validators != nil {
+		t.Errorf("got opt-ins %+v alongside the error", validators)
+	}
+}
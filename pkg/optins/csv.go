@@ -0,0 +1,312 @@
+package optins
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/primevprotocol/validator-registry/pkg/csvutil"
+)
+
+var ValidatorColumns = []string{"pubKey", "optInBlock", "optInType", "podOwner", "vault", "operator", "withdrawalAddr"}
+
+var SlotColumns = []string{"slot", "blockNumber", "pubKey", "optInBlock", "optInType", "podOwner", "vault", "operator", "withdrawalAddr"}
+
+// ReadValidators reads opted-in validators keyed by pubkey.
+func ReadValidators(r io.Reader) (map[string]Validator, error) {
+	var rows []Validator
+	if err := csvutil.Unmarshal(r, &rows); err != nil {
+		return nil, err
+	}
+	validators := make(map[string]Validator, len(rows))
+	for _, validator := range rows {
+		validators[validator.PubKey] = validator
+	}
+	return validators, nil
+}
+
+func ReadValidatorsFile(path string) (map[string]Validator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadValidators(file)
+}
+
+// ReadSlots reads opted-in slots keyed by block number. A row with the
+// same block number as an earlier one replaces it.
+func ReadSlots(r io.Reader) (map[uint64]Slot, error) {
+	slots, _, err := readSlots(r)
+	return slots, err
+}
+
+func ReadSlotsFile(path string) (map[uint64]Slot, error) {
+	slots, _, err := readSlotsFile(path)
+	return slots, err
+}
+
+// ReadSlotsFileCountingDuplicates is ReadSlotsFile, also returning how many
+// rows were replaced by a later row with the same block number. A well
+// formed slots CSV has none, so callers can warn about malformed input.
+func ReadSlotsFileCountingDuplicates(path string) (map[uint64]Slot, int, error) {
+	return readSlotsFile(path)
+}
+
+func readSlots(r io.Reader) (map[uint64]Slot, int, error) {
+	var rows []Slot
+	if err := csvutil.Unmarshal(r, &rows); err != nil {
+		return nil, 0, err
+	}
+	slots := make(map[uint64]Slot, len(rows))
+	for _, slot := range rows {
+		slots[slot.BlockNumber] = slot
+	}
+	return slots, len(rows) - len(slots), nil
+}
+
+func readSlotsFile(path string) (map[uint64]Slot, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	return readSlots(file)
+}
+
+// sourceColumns are the source specific columns written per opt-in type
+// when exporting grouped by type.
+var sourceColumns = map[Type][]string{
+	OptInTypeEigen:     {"podOwner"},
+	OptInTypeSymbiotic: {"vault", "operator"},
+	OptInTypeVanilla:   {"withdrawalAddr"},
+}
+
+// GroupedFileNames maps each opt-in type to the file it is written to by
+// WriteValidatorsGroupedByType.
+var GroupedFileNames = map[Type]string{
+	OptInTypeEigen:     "opted_in_eigen.csv",
+	OptInTypeSymbiotic: "opted_in_symbiotic.csv",
+	OptInTypeVanilla:   "opted_in_vanilla.csv",
+}
+
+func validatorFields(v Validator) map[string]string {
+	return map[string]string{
+		"pubKey":         v.PubKey,
+		"optInBlock":     strconv.FormatUint(v.OptInBlock, 10),
+		"optInType":      v.OptInType.String(),
+		"podOwner":       v.PodOwner.Hex(),
+		"vault":          v.Vault.Hex(),
+		"operator":       v.Operator.Hex(),
+		"withdrawalAddr": v.WithdrawalAddr.Hex(),
+	}
+}
+
+func writeValidators(w io.Writer, columns []string, validators []Validator) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, validator := range validators {
+		fields := validatorFields(validator)
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = fields[column]
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteValidators writes validators with all ValidatorColumns.
+func WriteValidators(w io.Writer, validators []Validator) error {
+	return writeValidators(w, ValidatorColumns, validators)
+}
+
+func WriteValidatorsFile(path string, validators []Validator) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := WriteValidators(file, validators); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// WriteValidatorsGroupedByType writes one CSV per opt-in type into dir,
+// each holding only the columns relevant to that source.
+func WriteValidatorsGroupedByType(dir string, validators []Validator) error {
+	byType := make(map[Type][]Validator, len(GroupedFileNames))
+	for _, validator := range validators {
+		if _, ok := GroupedFileNames[validator.OptInType]; !ok {
+			return fmt.Errorf("unknown opt-in type %q for pubkey %s", validator.OptInType, validator.PubKey)
+		}
+		byType[validator.OptInType] = append(byType[validator.OptInType], validator)
+	}
+
+	for optInType, fileName := range GroupedFileNames {
+		columns := append([]string{"pubKey", "optInBlock"}, sourceColumns[optInType]...)
+		file, err := os.Create(filepath.Join(dir, fileName))
+		if err != nil {
+			return err
+		}
+		if err := writeValidators(file, columns, byType[optInType]); err != nil {
+			file.Close()
+			return fmt.Errorf("writing %s: %w", fileName, err)
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func slotRecord(slot Slot) []string {
+	fields := validatorFields(slot.Validator)
+	fields["slot"] = strconv.FormatUint(slot.Slot, 10)
+	fields["blockNumber"] = strconv.FormatUint(slot.BlockNumber, 10)
+	record := make([]string, len(SlotColumns))
+	for i, column := range SlotColumns {
+		record[i] = fields[column]
+	}
+	return record
+}
+
+func WriteSlots(w io.Writer, slots []Slot) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(SlotColumns); err != nil {
+		return err
+	}
+	for _, slot := range slots {
+		if err := writer.Write(slotRecord(slot)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteSlotsFile(path string, slots []Slot) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := WriteSlots(file, slots); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// appendWriter appends records to a CSV file, writing the header only when
+// the file is new or empty.
+type appendWriter struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *csv.Writer
+}
+
+func newAppendWriter(path string, columns []string) (*appendWriter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	w := &appendWriter{file: file, writer: csv.NewWriter(file)}
+	if info.Size() == 0 {
+		if err := w.writer.Write(columns); err != nil {
+			file.Close()
+			return nil, err
+		}
+		w.writer.Flush()
+	}
+	return w, w.writer.Error()
+}
+
+func (w *appendWriter) write(records [][]string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, record := range records {
+		if err := w.writer.Write(record); err != nil {
+			return err
+		}
+	}
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+func (w *appendWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// SlotWriter appends slots to a CSV file as they are found, so partial
+// results survive a crash. It is safe for concurrent use.
+type SlotWriter struct {
+	*appendWriter
+}
+
+func NewSlotWriter(path string) (*SlotWriter, error) {
+	w, err := newAppendWriter(path, SlotColumns)
+	if err != nil {
+		return nil, err
+	}
+	return &SlotWriter{w}, nil
+}
+
+func (w *SlotWriter) Write(slots []Slot) error {
+	records := make([][]string, 0, len(slots))
+	for _, slot := range slots {
+		records = append(records, slotRecord(slot))
+	}
+	return w.write(records)
+}
+
+// ValidatorWriter appends validators to a CSV file. It is safe for
+// concurrent use.
+type ValidatorWriter struct {
+	*appendWriter
+}
+
+func NewValidatorWriter(path string) (*ValidatorWriter, error) {
+	w, err := newAppendWriter(path, ValidatorColumns)
+	if err != nil {
+		return nil, err
+	}
+	return &ValidatorWriter{w}, nil
+}
+
+func (w *ValidatorWriter) Write(validators []Validator) error {
+	records := make([][]string, 0, len(validators))
+	for _, validator := range validators {
+		fields := validatorFields(validator)
+		record := make([]string, len(ValidatorColumns))
+		for i, column := range ValidatorColumns {
+			record[i] = fields[column]
+		}
+		records = append(records, record)
+	}
+	return w.write(records)
+}
@@ -0,0 +1,135 @@
+package optins
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	testEigenPubKey   = strings.Repeat("aa", 48)
+	testVanillaPubKey = strings.Repeat("bb", 48)
+)
+
+func TestReadValidatorsRoundTrip(t *testing.T) {
+	written := []Validator{
+		{PubKey: testEigenPubKey, OptInBlock: 10, OptInType: OptInTypeEigen, PodOwner: common.HexToAddress("0x01")},
+		{PubKey: testVanillaPubKey, OptInBlock: 20, OptInType: OptInTypeVanilla, WithdrawalAddr: common.HexToAddress("0x02")},
+	}
+	var buf bytes.Buffer
+	if err := WriteValidators(&buf, written); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadValidators(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range written {
+		if got := read[want.PubKey]; got != want {
+			t.Errorf("read %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestReadValidatorsMatchesColumnsByName(t *testing.T) {
+	csv := "optInType,pubKey,optInBlock,withdrawalAddr,operator,vault,podOwner\n" +
+		"Vanilla," + testVanillaPubKey + ",20,0x0000000000000000000000000000000000000002,,,\n"
+	read, err := ReadValidators(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := read[testVanillaPubKey]
+	if got.OptInType != OptInTypeVanilla || got.OptInBlock != 20 || got.WithdrawalAddr != common.HexToAddress("0x02") {
+		t.Errorf("read %+v from reordered columns", got)
+	}
+}
+
+func TestReadValidatorsRejectsBadHeaders(t *testing.T) {
+	for name, header := range map[string]string{
+		"missing column":    "pubKey,optInBlock,optInType,podOwner,vault,operator",
+		"unexpected column": "pubKey,optInBlock,optInType,podOwner,vault,operator,withdrawalAddr,extra",
+		"slots header":      strings.Join(SlotColumns, ","),
+	} {
+		if _, err := ReadValidators(strings.NewReader(header + "\n")); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestReadValidatorsNamesBadRow(t *testing.T) {
+	csv := strings.Join(ValidatorColumns, ",") + "\n" +
+		testEigenPubKey + ",10,Eigen,,,,\n" +
+		testVanillaPubKey + ",20,Unknown,,,,\n"
+	_, err := ReadValidators(strings.NewReader(csv))
+	if err == nil || !strings.Contains(err.Error(), "line 3") || !strings.Contains(err.Error(), "optInType") {
+		t.Fatalf("got error %v, want one naming line 3 and column optInType", err)
+	}
+}
+
+func TestWriteValidatorsGroupedByType(t *testing.T) {
+	dir := t.TempDir()
+	validators := []Validator{
+		{PubKey: testEigenPubKey, OptInBlock: 10, OptInType: OptInTypeEigen, PodOwner: common.HexToAddress("0x01")},
+		{PubKey: testVanillaPubKey, OptInBlock: 20, OptInType: OptInTypeVanilla, WithdrawalAddr: common.HexToAddress("0x02")},
+	}
+	if err := WriteValidatorsGroupedByType(dir, validators); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[Type]string{
+		OptInTypeEigen:     "pubKey,optInBlock,podOwner\n" + testEigenPubKey + ",10,0x0000000000000000000000000000000000000001\n",
+		OptInTypeSymbiotic: "pubKey,optInBlock,vault,operator\n",
+		OptInTypeVanilla:   "pubKey,optInBlock,withdrawalAddr\n" + testVanillaPubKey + ",20,0x0000000000000000000000000000000000000002\n",
+	}
+	for optInType, fileName := range GroupedFileNames {
+		got, err := os.ReadFile(filepath.Join(dir, fileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want[optInType] {
+			t.Errorf("%s holds\n%s\nwant\n%s", fileName, got, want[optInType])
+		}
+		schema, err := SchemaForFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if problems, err := schema.ValidateFile(filepath.Join(dir, fileName)); err != nil || len(problems) != 0 {
+			t.Errorf("%s fails its schema: %v %v", fileName, problems, err)
+		}
+	}
+}
+
+func TestWriteValidatorsGroupedByTypeRejectsUnknownType(t *testing.T) {
+	err := WriteValidatorsGroupedByType(t.TempDir(), []Validator{{PubKey: testEigenPubKey, OptInType: "Other"}})
+	if err == nil {
+		t.Fatal("expected an error for an unknown opt-in type")
+	}
+}
+
+func TestReadSlotsCountsDuplicateBlockNumbers(t *testing.T) {
+	slots := []Slot{
+		{Slot: 1, BlockNumber: 100, Validator: Validator{PubKey: testEigenPubKey, OptInType: OptInTypeEigen}},
+		{Slot: 2, BlockNumber: 101, Validator: Validator{PubKey: testVanillaPubKey, OptInType: OptInTypeVanilla}},
+		{Slot: 3, BlockNumber: 100, Validator: Validator{PubKey: testVanillaPubKey, OptInType: OptInTypeVanilla}},
+	}
+	var buf bytes.Buffer
+	if err := WriteSlots(&buf, slots); err != nil {
+		t.Fatal(err)
+	}
+
+	read, duplicates, err := readSlots(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 2 || duplicates != 1 {
+		t.Fatalf("read %d slots with %d duplicates, want 2 with 1", len(read), duplicates)
+	}
+	if read[100].Slot != 3 {
+		t.Errorf("block 100 holds slot %d, want the later row's slot 3", read[100].Slot)
+	}
+}
@@ -0,0 +1,28 @@
+package optins
+
+import "time"
+
+// SlotDuration is the Ethereum mainnet slot time, used to approximate the
+// wall clock time spanned by a number of blocks.
+const SlotDuration = 12 * time.Second
+
+// OptInDurations returns how many blocks each validator has been opted in
+// as of currentBlock, keyed by pubkey. A validator opted in at or after
+// currentBlock has a duration of 0.
+func OptInDurations(validators map[string]Validator, currentBlock uint64) map[string]uint64 {
+	durations := make(map[string]uint64, len(validators))
+	for pubKey, validator := range validators {
+		if validator.OptInBlock >= currentBlock {
+			durations[pubKey] = 0
+			continue
+		}
+		durations[pubKey] = currentBlock - validator.OptInBlock
+	}
+	return durations
+}
+
+// BlocksToDuration approximates the time spanned by blocks, assuming one
+// block per slot.
+func BlocksToDuration(blocks uint64) time.Duration {
+	return time.Duration(blocks) * SlotDuration
+}
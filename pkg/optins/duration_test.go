@@ -0,0 +1,30 @@
+package optins
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOptInDurations(t *testing.T) {
+	validators := map[string]Validator{
+		"aa": {OptInBlock: 100},
+		"bb": {OptInBlock: 1000},
+		"cc": {OptInBlock: 1500},
+	}
+	durations := OptInDurations(validators, 1000)
+	want := map[string]uint64{"aa": 900, "bb": 0, "cc": 0}
+	if len(durations) != len(want) {
+		t.Fatalf("got %v, want %v", durations, want)
+	}
+	for pubKey, blocks := range want {
+		if durations[pubKey] != blocks {
+			t.Errorf("%s opted in for %d blocks, want %d", pubKey, durations[pubKey], blocks)
+		}
+	}
+}
+
+func TestBlocksToDuration(t *testing.T) {
+	if got := BlocksToDuration(300); got != time.Hour {
+		t.Errorf("got %s for 300 blocks, want 1h", got)
+	}
+}
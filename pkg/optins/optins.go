@@ -0,0 +1,64 @@
+package optins
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Validator is a validator opted in to mev-commit through one of the
+// Eigen, Symbiotic or Vanilla opt-in sources.
+type Validator struct {
+	// PubKey is the hex encoded BLS pubkey without 0x prefix.
+	PubKey         string         `csv:"pubKey"`
+	OptInBlock     uint64         `csv:"optInBlock"`
+	OptInType      Type           `csv:"optInType"`
+	PodOwner       common.Address `csv:"podOwner"`
+	Vault          common.Address `csv:"vault"`
+	Operator       common.Address `csv:"operator"`
+	WithdrawalAddr common.Address `csv:"withdrawalAddr"`
+}
+
+// Slot is a proposer slot assigned to an opted-in validator.
+type Slot struct {
+	Slot        uint64 `csv:"slot"`
+	BlockNumber uint64 `csv:"blockNumber"`
+	Validator   Validator
+}
+
+// Type is the source a validator opted in through.
+type Type string
+
+const (
+	OptInTypeEigen     Type = "Eigen"
+	OptInTypeSymbiotic Type = "Symbiotic"
+	OptInTypeVanilla   Type = "Vanilla"
+)
+
+// Types lists every opt-in Type.
+var Types = []Type{OptInTypeEigen, OptInTypeSymbiotic, OptInTypeVanilla}
+
+func (t Type) String() string {
+	return string(t)
+}
+
+// ParseType returns the Type named s, or an error if s names none.
+func ParseType(s string) (Type, error) {
+	for _, t := range Types {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown opt-in type %q, expected one of %v", s, Types)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, so CSVs with an
+// unknown optInType are rejected when read.
+func (t *Type) UnmarshalText(text []byte) error {
+	parsed, err := ParseType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
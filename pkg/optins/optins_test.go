@@ -0,0 +1,32 @@
+package optins
+
+import "testing"
+
+func TestParseTypeRoundTrip(t *testing.T) {
+	for _, optInType := range Types {
+		parsed, err := ParseType(optInType.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != optInType {
+			t.Errorf("ParseType(%q) = %q", optInType.String(), parsed)
+		}
+
+		var unmarshaled Type
+		if err := unmarshaled.UnmarshalText([]byte(optInType.String())); err != nil || unmarshaled != optInType {
+			t.Errorf("UnmarshalText(%q) = %q, %v", optInType.String(), unmarshaled, err)
+		}
+	}
+}
+
+func TestParseTypeRejectsUnknown(t *testing.T) {
+	for _, s := range []string{"", "eigen", "EigenLayer", "vanilla "} {
+		if optInType, err := ParseType(s); err == nil {
+			t.Errorf("ParseType(%q) = %q, want an error", s, optInType)
+		}
+		var unmarshaled Type
+		if err := unmarshaled.UnmarshalText([]byte(s)); err == nil {
+			t.Errorf("UnmarshalText(%q) accepted an unknown type", s)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package optins
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/validatoroptinrouter"
+	"golang.org/x/sync/errgroup"
+)
+
+// RouterCaller is implemented by validatoroptinrouter.ValidatoroptinrouterCaller.
+type RouterCaller interface {
+	AreValidatorsOptedIn(opts *bind.CallOpts, valBLSPubKeys [][]byte) ([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, error)
+}
+
+// IsOptedIn reports whether any opt-in source reports the validator opted in.
+func IsOptedIn(status validatoroptinrouter.IValidatorOptInRouterOptInStatus) bool {
+	return status.IsAvsOptedIn || status.IsMiddlewareOptedIn || status.IsVanillaOptedIn
+}
+
+// SanityCheck queries the router in batches of batchSize, up to concurrency
+// batches at once, and returns the pubkeys of validators the router does
+// not report as opted in, in the order of validators. Event derived sets can
+// legitimately contain since-deregistered validators, so mismatches are
+// returned for the caller to judge rather than treated as errors.
+func SanityCheck(
+	ctx context.Context,
+	router RouterCaller,
+	validators []Validator,
+	batchSize int,
+	concurrency int,
+) ([][]byte, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	numBatches := (len(validators) + batchSize - 1) / batchSize
+	// Each batch writes only its own slot, so no locking is needed.
+	batchMismatches := make([][][]byte, numBatches)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for b := 0; b < numBatches; b++ {
+		start := b * batchSize
+		end := min(start+batchSize, len(validators))
+		g.Go(func() error {
+			fmt.Printf("Checking batch %d to %d against router\n", start, end)
+
+			batch := make([][]byte, 0, end-start)
+			for _, validator := range validators[start:end] {
+				batch = append(batch, common.FromHex(validator.PubKey))
+			}
+			statuses, err := router.AreValidatorsOptedIn(&bind.CallOpts{Context: ctx}, batch)
+			if err != nil {
+				return fmt.Errorf("checking batch %d to %d: %w", start, end, err)
+			}
+			if len(statuses) != len(batch) {
+				return fmt.Errorf("router returned %d statuses for batch of %d", len(statuses), len(batch))
+			}
+			for idx, status := range statuses {
+				if !IsOptedIn(status) {
+					batchMismatches[b] = append(batchMismatches[b], batch[idx])
+				}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	mismatches := [][]byte{}
+	for _, m := range batchMismatches {
+		mismatches = append(mismatches, m...)
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,177 @@
+package optins
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/primevprotocol/validator-registry/pkg/validatoroptinrouter"
+)
+
+// fakeRouter reports the hex pubkeys in optedIn as AVS opted in and
+// records the size of each call. If err is set, every call fails with it.
+type fakeRouter struct {
+	optedIn map[string]bool
+	err     error
+
+	mu    sync.Mutex
+	calls []int
+}
+
+func (r *fakeRouter) AreValidatorsOptedIn(opts *bind.CallOpts, pubKeys [][]byte) ([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, len(pubKeys))
+	r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	statuses := make([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		statuses[i].IsAvsOptedIn = r.optedIn[hex.EncodeToString(pubKey)]
+	}
+	return statuses, nil
+}
+
+func TestSanityCheckReturnsMismatchesInOrder(t *testing.T) {
+	validators := []Validator{{PubKey: "01"}, {PubKey: "02"}, {PubKey: "03"}, {PubKey: "04"}, {PubKey: "05"}}
+	router := &fakeRouter{optedIn: map[string]bool{"01": true, "03": true, "04": true}}
+
+	mismatches, err := SanityCheck(context.Background(), router, validators, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 2 || hex.EncodeToString(mismatches[0]) != "02" || hex.EncodeToString(mismatches[1]) != "05" {
+		t.Errorf("got mismatches %x, want [02 05]", mismatches)
+	}
+}
+
+func TestSanityCheckRejectsNonPositiveBatchSize(t *testing.T) {
+	if _, err := SanityCheck(context.Background(), &fakeRouter{}, []Validator{{PubKey: "01"}}, 0, 1); err == nil {
+		t.Error("batch size 0 accepted")
+	}
+}
+
+func TestSanityCheckBatchesCalls(t *testing.T) {
+	validators := []Validator{{PubKey: "01"}, {PubKey: "02"}, {PubKey: "03"}, {PubKey: "04"}, {PubKey: "05"}}
+	router := &fakeRouter{}
+
+	if _, err := SanityCheck(context.Background(), router, validators, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(router.calls, []int{2, 2, 1}) {
+		t.Errorf("got calls of sizes %v, want [2 2 1]", router.calls)
+	}
+}
+
+// concurrentRouter holds the first limit calls until all of them are in
+// flight and records the most calls ever in flight at once.
+type concurrentRouter struct {
+	*fakeRouter
+	limit int
+	full  chan struct{}
+
+	mu          sync.Mutex
+	started     int
+	inFlight    int
+	maxInFlight int
+}
+
+func (r *concurrentRouter) AreValidatorsOptedIn(opts *bind.CallOpts, pubKeys [][]byte) ([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, error) {
+	r.mu.Lock()
+	r.started++
+	started := r.started
+	r.inFlight++
+	r.maxInFlight = max(r.maxInFlight, r.inFlight)
+	if started == r.limit {
+		close(r.full)
+	}
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.inFlight--
+		r.mu.Unlock()
+	}()
+
+	if started <= r.limit {
+		select {
+		case <-r.full:
+		case <-opts.Context.Done():
+			return nil, opts.Context.Err()
+		}
+	}
+	return r.fakeRouter.AreValidatorsOptedIn(opts, pubKeys)
+}
+
+func TestSanityCheckChecksAllBatchesConcurrently(t *testing.T) {
+	const concurrency = 4
+	var validators []Validator
+	optedIn := map[string]bool{}
+	var want []string
+	for i := 0; i < 100; i++ {
+		pubKey := hex.EncodeToString([]byte{byte(i)})
+		validators = append(validators, Validator{PubKey: pubKey})
+		if i%3 == 0 {
+			want = append(want, pubKey)
+		} else {
+			optedIn[pubKey] = true
+		}
+	}
+	router := &concurrentRouter{
+		fakeRouter: &fakeRouter{optedIn: optedIn},
+		limit:      concurrency,
+		full:       make(chan struct{}),
+	}
+
+	mismatches, err := SanityCheck(context.Background(), router, validators, 7, concurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range mismatches {
+		got = append(got, hex.EncodeToString(m))
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got mismatches %v, want %v", got, want)
+	}
+
+	if len(router.calls) != 15 {
+		t.Errorf("made %d router calls, want 15", len(router.calls))
+	}
+	checked := 0
+	for _, size := range router.calls {
+		checked += size
+	}
+	if checked != len(validators) {
+		t.Errorf("checked %d validators, want %d", checked, len(validators))
+	}
+	if router.maxInFlight != concurrency {
+		t.Errorf("at most %d calls in flight, want %d", router.maxInFlight, concurrency)
+	}
+}
+
+func TestSanityCheckReturnsRouterError(t *testing.T) {
+	routerErr := errors.New("execution reverted")
+	_, err := SanityCheck(context.Background(), &fakeRouter{err: routerErr}, []Validator{{PubKey: "01"}}, 50, 1)
+	if !errors.Is(err, routerErr) {
+		t.Errorf("got error %v, want %v", err, routerErr)
+	}
+}
+
+func TestIsOptedIn(t *testing.T) {
+	for _, status := range []validatoroptinrouter.IValidatorOptInRouterOptInStatus{
+		{IsAvsOptedIn: true},
+		{IsMiddlewareOptedIn: true},
+		{IsVanillaOptedIn: true},
+	} {
+		if !IsOptedIn(status) {
+			t.Errorf("%+v not reported as opted in", status)
+		}
+	}
+	if IsOptedIn(validatoroptinrouter.IValidatorOptInRouterOptInStatus{}) {
+		t.Error("empty status reported as opted in")
+	}
+}
@@ -0,0 +1,203 @@
+package optins
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+)
+
+// Schema is the expected header of a CSV artifact.
+type Schema struct {
+	Name    string
+	Columns []string
+}
+
+// Schemas of the CSVs written by all-mainnet-regs, opted-in-slots and
+// missed-slots, and of the files WriteValidatorsGroupedByType writes.
+var (
+	ValidatorsSchema  = Schema{Name: "validators", Columns: ValidatorColumns}
+	SlotsSchema       = Schema{Name: "slots", Columns: SlotColumns}
+	MissedSlotsSchema = Schema{Name: "missed-slots", Columns: append(slices.Clone(SlotColumns), "missed")}
+	EigenSchema       = groupedSchema(OptInTypeEigen)
+	SymbioticSchema   = groupedSchema(OptInTypeSymbiotic)
+	VanillaSchema     = groupedSchema(OptInTypeVanilla)
+)
+
+var schemas = []Schema{ValidatorsSchema, SlotsSchema, MissedSlotsSchema, EigenSchema, SymbioticSchema, VanillaSchema}
+
+// schemaFileNames maps the default file name of each artifact to its
+// schema.
+var schemaFileNames = map[string]Schema{
+	"opted_in_validators.csv":            ValidatorsSchema,
+	"opted_in_slots.csv":                 SlotsSchema,
+	"missed_slots.csv":                   MissedSlotsSchema,
+	GroupedFileNames[OptInTypeEigen]:     EigenSchema,
+	GroupedFileNames[OptInTypeSymbiotic]: SymbioticSchema,
+	GroupedFileNames[OptInTypeVanilla]:   VanillaSchema,
+}
+
+func groupedSchema(t Type) Schema {
+	return Schema{
+		Name:    string(t),
+		Columns: append([]string{"pubKey", "optInBlock"}, sourceColumns[t]...),
+	}
+}
+
+// SchemaNames returns the names of all schemas.
+func SchemaNames() []string {
+	names := make([]string, len(schemas))
+	for i, schema := range schemas {
+		names[i] = schema.Name
+	}
+	return names
+}
+
+// LookupSchema returns the schema named name.
+func LookupSchema(name string) (Schema, error) {
+	for _, schema := range schemas {
+		if schema.Name == name {
+			return schema, nil
+		}
+	}
+	return Schema{}, fmt.Errorf("unknown schema %q, expected one of %v", name, SchemaNames())
+}
+
+// SchemaForFile returns the schema of the artifact written to path under
+// its default file name.
+func SchemaForFile(path string) (Schema, error) {
+	schema, ok := schemaFileNames[filepath.Base(path)]
+	if !ok {
+		return Schema{}, fmt.Errorf("no schema known for file %s", filepath.Base(path))
+	}
+	return schema, nil
+}
+
+// CSVProblem is a problem with the header or a row of a CSV. Line is the
+// 1-based line number in the file, counting comment lines.
+type CSVProblem struct {
+	Line   int
+	Column string
+	Detail string
+}
+
+func (p CSVProblem) String() string {
+	if p.Column == "" {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Detail)
+	}
+	return fmt.Sprintf("line %d: column %q: %s", p.Line, p.Column, p.Detail)
+}
+
+// Validate checks that r has exactly s's columns and that every row has a
+// well-formed value in each of them, returning the problems found in line
+// order. Lines starting with # are skipped, as csvutil.Unmarshal skips
+// them, so files may carry a snapshot stamp. An error is returned only if r
+// cannot be read as CSV.
+func (s Schema) Validate(r io.Reader) ([]CSVProblem, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	// Rows with the wrong number of fields are reported, not fatal.
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []CSVProblem{{Line: 1, Detail: "missing header"}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	headerLine, _ := reader.FieldPos(0)
+
+	var problems []CSVProblem
+	seen := make(map[string]bool, len(header))
+	for _, column := range header {
+		switch {
+		case seen[column]:
+			problems = append(problems, CSVProblem{Line: headerLine, Column: column, Detail: "duplicate column"})
+		case !slices.Contains(s.Columns, column):
+			problems = append(problems, CSVProblem{Line: headerLine, Column: column, Detail: "unexpected column"})
+		}
+		seen[column] = true
+	}
+	for _, column := range s.Columns {
+		if !seen[column] {
+			problems = append(problems, CSVProblem{Line: headerLine, Column: column, Detail: "missing column"})
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			problems = append(problems, CSVProblem{Line: parseErr.StartLine, Detail: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) != len(header) {
+			problems = append(problems, CSVProblem{Line: line, Detail: fmt.Sprintf("%d fields, want %d", len(record), len(header))})
+			continue
+		}
+		for i, value := range record {
+			if detail := checkValue(header[i], value); detail != "" {
+				problems = append(problems, CSVProblem{Line: line, Column: header[i], Detail: detail})
+			}
+		}
+	}
+	return problems, nil
+}
+
+// ValidateFile is Validate on the file at path.
+func (s Schema) ValidateFile(path string) ([]CSVProblem, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return s.Validate(file)
+}
+
+// checkValue returns what is wrong with value in column, or "" if it is
+// well-formed or the column is unknown.
+func checkValue(column, value string) string {
+	switch column {
+	case "slot", "blockNumber", "optInBlock":
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return fmt.Sprintf("%q is not a block or slot number", value)
+		}
+	case "pubKey":
+		pubKey, err := hex.DecodeString(value)
+		if err != nil {
+			return fmt.Sprintf("%q is not a hex pubkey without 0x prefix", value)
+		}
+		if len(pubKey) != events.BLSPubKeyLength {
+			return fmt.Sprintf("pubkey is %d bytes, want %d", len(pubKey), events.BLSPubKeyLength)
+		}
+	case "optInType":
+		if _, err := ParseType(value); err != nil {
+			return err.Error()
+		}
+	case "podOwner", "vault", "operator", "withdrawalAddr":
+		if !common.IsHexAddress(value) {
+			return fmt.Sprintf("%q is not an address", value)
+		}
+	case "missed":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Sprintf("%q is not a bool", value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package optins
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestValidateSkipsSnapshotStamp(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("# opted-in validators as of block 21200000\n")
+	validators := []Validator{{
+		PubKey:     strings.Repeat("ab", 48),
+		OptInBlock: 21162202,
+		OptInType:  OptInTypeVanilla,
+		PodOwner:   common.Address{1},
+	}}
+	if err := WriteValidators(&buf, validators); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := ValidatorsSchema.Validate(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("stamped snapshot has problems %v", problems)
+	}
+	if _, err := ReadValidators(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Errorf("stamped snapshot does not decode: %v", err)
+	}
+}
+
+func TestValidateReportsFileLines(t *testing.T) {
+	input := "# stamp\nslot,blockNumber,pubKey,optInBlock,optInType,podOwner,vault,operator,withdrawalAddr,extra\n" +
+		"# note\nx," + strings.Repeat(",", 8) + "\n"
+	problems, err := SlotsSchema.Validate(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) < 2 {
+		t.Fatalf("got problems %v, want a header and a row problem", problems)
+	}
+	if problems[0].Line != 2 || problems[0].Column != "extra" {
+		t.Errorf("first problem %v, want unexpected column extra on line 2", problems[0])
+	}
+	if problems[1].Line != 4 || problems[1].Column != "slot" {
+		t.Errorf("second problem %v, want bad slot on line 4", problems[1])
+	}
+}
@@ -0,0 +1,82 @@
+package optins
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema holds the columns of ValidatorColumns. Rows are unique per
+// opt-in, so rewriting validators that are already stored is a no-op.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS validators (
+	pubKey         TEXT    NOT NULL,
+	optInBlock     INTEGER NOT NULL,
+	optInType      TEXT    NOT NULL,
+	podOwner       TEXT    NOT NULL,
+	vault          TEXT    NOT NULL,
+	operator       TEXT    NOT NULL,
+	withdrawalAddr TEXT    NOT NULL,
+	UNIQUE (pubKey, optInType, optInBlock)
+);
+CREATE INDEX IF NOT EXISTS validators_pubKey ON validators (pubKey);
+CREATE INDEX IF NOT EXISTS validators_optInType ON validators (optInType);
+`
+
+// SQLiteWriter writes validators to the validators table of a SQLite
+// database, for ad-hoc SQL queries over the opted-in set.
+type SQLiteWriter struct {
+	db *sql.DB
+}
+
+// NewSQLiteWriter opens the SQLite database at path, creating it and the
+// validators table if needed. A path of ":memory:" opens an in-memory
+// database.
+func NewSQLiteWriter(path string) (*SQLiteWriter, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	// Each connection to ":memory:" is a separate database.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating validators table in %s: %w", path, err)
+	}
+	return &SQLiteWriter{db: db}, nil
+}
+
+// DB returns the underlying database.
+func (w *SQLiteWriter) DB() *sql.DB {
+	return w.db
+}
+
+// Write inserts validators in a single transaction, skipping those already
+// stored.
+func (w *SQLiteWriter) Write(validators []Validator) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO validators
+		(pubKey, optInBlock, optInType, podOwner, vault, operator, withdrawalAddr)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, v := range validators {
+		_, err := stmt.Exec(v.PubKey, int64(v.OptInBlock), v.OptInType.String(),
+			v.PodOwner.Hex(), v.Vault.Hex(), v.Operator.Hex(), v.WithdrawalAddr.Hex())
+		if err != nil {
+			return fmt.Errorf("inserting validator %s: %w", v.PubKey, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (w *SQLiteWriter) Close() error {
+	return w.db.Close()
+}
@@ -0,0 +1,81 @@
+package optins
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSQLiteWriterInsertsQueryableRows(t *testing.T) {
+	w, err := NewSQLiteWriter(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	validators := []Validator{
+		{PubKey: "aa", OptInBlock: 1, OptInType: OptInTypeEigen, PodOwner: common.HexToAddress("0x01")},
+		{PubKey: "bb", OptInBlock: 2, OptInType: OptInTypeSymbiotic, Vault: common.HexToAddress("0x02"), Operator: common.HexToAddress("0x03")},
+		{PubKey: "cc", OptInBlock: 3, OptInType: OptInTypeVanilla, WithdrawalAddr: common.HexToAddress("0x04")},
+		{PubKey: "dd", OptInBlock: 4, OptInType: OptInTypeVanilla, WithdrawalAddr: common.HexToAddress("0x04")},
+	}
+	if err := w.Write(validators); err != nil {
+		t.Fatal(err)
+	}
+	// Rewriting stored opt-ins, as a resumed run does, adds no rows.
+	if err := w.Write(validators[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := w.DB().QueryRow(`SELECT COUNT(*) FROM validators WHERE optInType = ?`, "Vanilla").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("got %d vanilla rows, want 2", count)
+	}
+	if err := w.DB().QueryRow(`SELECT COUNT(*) FROM validators`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != len(validators) {
+		t.Errorf("got %d rows, want %d", count, len(validators))
+	}
+
+	var optInBlock uint64
+	var vault, operator string
+	err = w.DB().QueryRow(`SELECT optInBlock, vault, operator FROM validators WHERE pubKey = ?`, "bb").Scan(&optInBlock, &vault, &operator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if optInBlock != 2 || vault != common.HexToAddress("0x02").Hex() || operator != common.HexToAddress("0x03").Hex() {
+		t.Errorf("got row %d, %s, %s for bb, want 2, 0x..02, 0x..03", optInBlock, vault, operator)
+	}
+}
+
+func TestSQLiteWriterReopensExistingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "validators.db")
+	w, err := NewSQLiteWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]Validator{{PubKey: "aa", OptInBlock: 1, OptInType: OptInTypeEigen}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = NewSQLiteWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	var count int
+	if err := w.DB().QueryRow(`SELECT COUNT(*) FROM validators`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("got %d rows after reopening, want 1", count)
+	}
+}
@@ -0,0 +1,104 @@
+package optins
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitavs"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitmiddleware"
+	"github.com/primevprotocol/validator-registry/pkg/vanillaregistry"
+)
+
+type BlockNumberer interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// Watch subscribes to new ValidatorRegistered, ValRecordAdded and Staked
+// events and passes each new opt-in, with the block it was emitted in, to
+// onNew. Subscriptions only deliver events from blocks mined after they are
+// made, so once subscribed Watch first collects the opt-ins from fromBlock
+// through the latest block and passes them to onNew in one call. It returns
+// when ctx is cancelled, a subscription fails or onNew returns an error.
+// Subscribing needs a websocket or IPC backend.
+func (c *Collector) Watch(
+	ctx context.Context,
+	client BlockNumberer,
+	fromBlock uint64,
+	onNew func(validators []Validator, throughBlock uint64) error,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	opts := &bind.WatchOpts{Context: ctx}
+
+	avsSink := make(chan *mevcommitavs.MevcommitavsValidatorRegistered)
+	avsSub, err := c.avs.WatchValidatorRegistered(opts, avsSink, nil)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to ValidatorRegistered events: %w", err)
+	}
+	defer avsSub.Unsubscribe()
+
+	middlewareSink := make(chan *mevcommitmiddleware.MevcommitmiddlewareValRecordAdded)
+	middlewareSub, err := c.middleware.WatchValRecordAdded(opts, middlewareSink, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to ValRecordAdded events: %w", err)
+	}
+	defer middlewareSub.Unsubscribe()
+
+	vanillaSink := make(chan *vanillaregistry.VanillaregistryStaked)
+	vanillaSub, err := c.vanilla.WatchStaked(opts, vanillaSink, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to Staked events: %w", err)
+	}
+	defer vanillaSub.Unsubscribe()
+
+	// Events up to latestBlock are collected here, so the subscriptions'
+	// events from those blocks are dropped below.
+	latestBlock, err := client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest block number: %w", err)
+	}
+	if latestBlock >= fromBlock {
+		validators, err := c.Collect(ctx, fromBlock, latestBlock)
+		if err != nil {
+			return fmt.Errorf("failed to collect opt-ins for blocks %d to %d: %w", fromBlock, latestBlock, err)
+		}
+		if err := onNew(validators, latestBlock); err != nil {
+			return err
+		}
+		fromBlock = latestBlock + 1
+	}
+
+	handle := func(validator Validator, raw types.Log) error {
+		if raw.BlockNumber < fromBlock {
+			return nil
+		}
+		if raw.Removed {
+			fmt.Printf("WARNING: opt-in of %s in block %d was removed by a reorg and may already be appended\n", validator.PubKey, raw.BlockNumber)
+			return nil
+		}
+		return onNew([]Validator{validator}, raw.BlockNumber)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-avsSub.Err():
+			return fmt.Errorf("ValidatorRegistered subscription failed: %w", err)
+		case err := <-middlewareSub.Err():
+			return fmt.Errorf("ValRecordAdded subscription failed: %w", err)
+		case err := <-vanillaSub.Err():
+			return fmt.Errorf("Staked subscription failed: %w", err)
+		case event := <-avsSink:
+			err = handle(avsOptIn(event), event.Raw)
+		case event := <-middlewareSink:
+			err = handle(middlewareOptIn(event), event.Raw)
+		case event := <-vanillaSink:
+			err = handle(vanillaOptIn(event), event.Raw)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package optins
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitavs"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitmiddleware"
+	"github.com/primevprotocol/validator-registry/pkg/vanillaregistry"
+)
+
+// mockSubscription sends events to sink, then fails with err if it is not
+// nil or else stays open until unsubscribed.
+func mockSubscription[T any](sink chan<- T, events []T, err error) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		for _, e := range events {
+			select {
+			case sink <- e:
+			case <-quit:
+				return nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+		<-quit
+		return nil
+	})
+}
+
+// The embedded interfaces are nil; only the Watch methods are used.
+type mockAVS struct {
+	AVSEvents
+	events []*mevcommitavs.MevcommitavsValidatorRegistered
+	err    error
+}
+
+func (m *mockAVS) WatchValidatorRegistered(_ *bind.WatchOpts, sink chan<- *mevcommitavs.MevcommitavsValidatorRegistered, _ []common.Address) (event.Subscription, error) {
+	return mockSubscription(sink, m.events, m.err), nil
+}
+
+type mockMiddleware struct {
+	MiddlewareEvents
+	events []*mevcommitmiddleware.MevcommitmiddlewareValRecordAdded
+}
+
+func (m *mockMiddleware) WatchValRecordAdded(_ *bind.WatchOpts, sink chan<- *mevcommitmiddleware.MevcommitmiddlewareValRecordAdded, _ []common.Address, _ []common.Address, _ []*big.Int) (event.Subscription, error) {
+	return mockSubscription(sink, m.events, nil), nil
+}
+
+type mockVanilla struct {
+	VanillaEvents
+	events []*vanillaregistry.VanillaregistryStaked
+}
+
+func (m *mockVanilla) WatchStaked(_ *bind.WatchOpts, sink chan<- *vanillaregistry.VanillaregistryStaked, _ []common.Address, _ []common.Address) (event.Subscription, error) {
+	return mockSubscription(sink, m.events, nil), nil
+}
+
+type fixedBlockNumber uint64
+
+func (b fixedBlockNumber) BlockNumber(context.Context) (uint64, error) {
+	return uint64(b), nil
+}
+
+func testPubKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 48)
+}
+
+func TestWatchAppendsSubscribedOptIn(t *testing.T) {
+	podOwner := common.HexToAddress("0x01")
+	collector := NewCollector(
+		&mockAVS{events: []*mevcommitavs.MevcommitavsValidatorRegistered{{
+			ValidatorPubKey: testPubKey('a'),
+			PodOwner:        podOwner,
+			Raw:             types.Log{BlockNumber: 105},
+		}}},
+		&mockMiddleware{},
+		&mockVanilla{},
+		100,
+	)
+	path := filepath.Join(t.TempDir(), "opted_in_validators.csv")
+	writer, err := NewValidatorWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var throughBlocks []uint64
+	// The latest block is before fromBlock, so there is nothing to collect.
+	err = collector.Watch(ctx, fixedBlockNumber(99), 100, func(validators []Validator, throughBlock uint64) error {
+		throughBlocks = append(throughBlocks, throughBlock)
+		cancel()
+		return writer.Write(validators)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(throughBlocks) != 1 || throughBlocks[0] != 105 {
+		t.Errorf("onNew called through blocks %v, want [105]", throughBlocks)
+	}
+	validators, err := ReadValidatorsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validators) != 1 {
+		t.Fatalf("appended %d validators, want 1", len(validators))
+	}
+	for _, v := range validators {
+		if v.OptInType != OptInTypeEigen || v.OptInBlock != 105 || v.PodOwner != podOwner {
+			t.Errorf("appended %+v, want the eigen opt-in of block 105", v)
+		}
+	}
+}
+
+func TestWatchDropsCollectedAndRemovedEvents(t *testing.T) {
+	collector := NewCollector(
+		&mockAVS{},
+		&mockMiddleware{},
+		&mockVanilla{events: []*vanillaregistry.VanillaregistryStaked{
+			{ValBLSPubKey: testPubKey('a'), Raw: types.Log{BlockNumber: 99}},
+			{ValBLSPubKey: testPubKey('b'), Raw: types.Log{BlockNumber: 101, Removed: true}},
+			{ValBLSPubKey: testPubKey('c'), Raw: types.Log{BlockNumber: 102}},
+		}},
+		100,
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got []Validator
+	err := collector.Watch(ctx, fixedBlockNumber(0), 100, func(validators []Validator, _ uint64) error {
+		got = append(got, validators...)
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if len(got) != 1 || got[0].PubKey != hex.EncodeToString(testPubKey('c')) || got[0].OptInType != OptInTypeVanilla {
+		t.Errorf("got %+v, want only the vanilla opt-in of block 102", got)
+	}
+}
+
+func TestWatchReturnsSubscriptionError(t *testing.T) {
+	subErr := errors.New("connection lost")
+	collector := NewCollector(&mockAVS{err: subErr}, &mockMiddleware{}, &mockVanilla{}, 100)
+
+	err := collector.Watch(context.Background(), fixedBlockNumber(0), 100, func([]Validator, uint64) error {
+		t.Error("onNew called without an opt-in")
+		return nil
+	})
+	if !errors.Is(err, subErr) {
+		t.Fatalf("got error %v, want %v", err, subErr)
+	}
+}
+
+func TestWatchReturnsOnNewError(t *testing.T) {
+	writeErr := errors.New("disk full")
+	collector := NewCollector(
+		&mockAVS{},
+		&mockMiddleware{events: []*mevcommitmiddleware.MevcommitmiddlewareValRecordAdded{{
+			BlsPubkey: testPubKey('a'),
+			Raw:       types.Log{BlockNumber: 100},
+		}}},
+		&mockVanilla{},
+		100,
+	)
+
+	err := collector.Watch(context.Background(), fixedBlockNumber(0), 100, func([]Validator, uint64) error {
+		return writeErr
+	})
+	if !errors.Is(err, writeErr) {
+		t.Fatalf("got error %v, want %v", err, writeErr)
+	}
+}
@@ -0,0 +1,186 @@
+package points
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+type ManualEntry struct {
+	PubKey  string `json:"pubkey"`
+	Adder   string `json:"adder"`
+	InBlock uint64 `json:"in_block"`
+}
+
+// Client posts manual entries to the points service. The service has no
+// lookup endpoint for manual entries, so a local ledger file of already
+// posted pubkeys is used to make posting idempotent across runs.
+type Client struct {
+	httpClient  *http.Client
+	baseURL     string
+	bearerToken string
+	ledgerPath  string
+
+	mu     sync.Mutex
+	posted map[string]bool
+}
+
+func NewClient(httpClient *http.Client, baseURL, bearerToken, ledgerPath string) (*Client, error) {
+	c := &Client{
+		httpClient:  httpClient,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		bearerToken: bearerToken,
+		ledgerPath:  ledgerPath,
+		posted:      make(map[string]bool),
+	}
+	if err := c.loadLedger(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) loadLedger() error {
+	pubkeys, err := ReadLedger(c.ledgerPath)
+	if err != nil {
+		return err
+	}
+	for _, pubkey := range pubkeys {
+		c.posted[pubkey] = true
+	}
+	return nil
+}
+
+// ReadLedger returns the normalized pubkeys recorded in a ledger file, in
+// the order they were posted. A missing file or empty path is an empty
+// ledger. As the service cannot list manual entries, this is the only
+// record of which pubkeys have been credited.
+func ReadLedger(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open ledger: %w", err)
+	}
+	defer f.Close()
+
+	var pubkeys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		pubkey := strings.TrimSpace(scanner.Text())
+		if pubkey == "" {
+			continue
+		}
+		pubkeys = append(pubkeys, normalizePubkey(pubkey))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read ledger: %w", err)
+	}
+	return pubkeys, nil
+}
+
+func (c *Client) recordPosted(pubkey string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.posted[normalizePubkey(pubkey)] = true
+	if c.ledgerPath == "" {
+		return nil
+	}
+	f, err := os.OpenFile(c.ledgerPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open ledger: %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, normalizePubkey(pubkey)); err != nil {
+		return fmt.Errorf("write ledger: %w", err)
+	}
+	return nil
+}
+
+// Exists reports whether a manual entry for pubkey has already been posted.
+func (c *Client) Exists(ctx context.Context, pubkey string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.posted[normalizePubkey(pubkey)], nil
+}
+
+func (c *Client) AddManualEntry(ctx context.Context, entry ManualEntry) ([]byte, error) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/admin/add_manual_entry", c.baseURL),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("post: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return respBody, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if err := c.recordPosted(entry.PubKey); err != nil {
+		return respBody, err
+	}
+	return respBody, nil
+}
+
+// AddManualEntries posts every entry not already present and returns the
+// response bodies of the posted entries along with the number skipped.
+func (c *Client) AddManualEntries(ctx context.Context, entries []ManualEntry) ([][]byte, int, error) {
+	responses := make([][]byte, 0, len(entries))
+	skipped := 0
+	for _, entry := range entries {
+		exists, err := c.Exists(ctx, entry.PubKey)
+		if err != nil {
+			return responses, skipped, fmt.Errorf("check %s: %w", entry.PubKey, err)
+		}
+		if exists {
+			skipped++
+			continue
+		}
+		resp, err := c.AddManualEntry(ctx, entry)
+		if err != nil {
+			return responses, skipped, fmt.Errorf("add %s: %w", entry.PubKey, err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses, skipped, nil
+}
+
+func normalizePubkey(pubkey string) string {
+	return strings.ToLower(strings.TrimPrefix(pubkey, "0x"))
+}
@@ -0,0 +1,112 @@
+package points
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// newPointsServer counts the manual entries posted to it.
+func newPointsServer(t *testing.T, posts *int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/add_manual_entry" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		*posts++
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAddManualEntriesSkipsEntriesPostedInEarlierRuns(t *testing.T) {
+	posts := 0
+	server := newPointsServer(t, &posts)
+	ledger := filepath.Join(t.TempDir(), "ledger.txt")
+	entries := []ManualEntry{{PubKey: "0xAA"}, {PubKey: "0xbb"}}
+
+	first, err := NewClient(server.Client(), server.URL, "token", ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses, skipped, err := first.AddManualEntries(context.Background(), entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 2 || skipped != 0 {
+		t.Fatalf("first run posted %d and skipped %d, want 2 and 0", len(responses), skipped)
+	}
+
+	// A new client reads the ledger the first one wrote, matching pubkeys
+	// regardless of 0x prefix and case.
+	second, err := NewClient(server.Client(), server.URL, "token", ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses, skipped, err = second.AddManualEntries(context.Background(), append(entries, ManualEntry{PubKey: "cc"}, ManualEntry{PubKey: "aa"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 1 || skipped != 3 {
+		t.Fatalf("second run posted %d and skipped %d, want 1 and 3", len(responses), skipped)
+	}
+	if posts != 3 {
+		t.Errorf("server received %d posts, want 3", posts)
+	}
+
+	recorded, err := ReadLedger(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"aa", "bb", "cc"}
+	if len(recorded) != len(want) {
+		t.Fatalf("ledger holds %v, want %v", recorded, want)
+	}
+	for i := range want {
+		if recorded[i] != want[i] {
+			t.Errorf("ledger holds %v, want %v", recorded, want)
+			break
+		}
+	}
+}
+
+func TestAddManualEntryDoesNotRecordFailedPosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	ledger := filepath.Join(t.TempDir(), "ledger.txt")
+
+	client, err := NewClient(server.Client(), server.URL, "token", ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.AddManualEntry(context.Background(), ManualEntry{PubKey: "aa"}); err == nil {
+		t.Fatal("expected an error for a 500 response")
+	}
+	exists, err := client.Exists(context.Background(), "aa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Error("failed post was recorded as posted")
+	}
+	recorded, err := ReadLedger(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 0 {
+		t.Errorf("ledger holds %v after a failed post, want nothing", recorded)
+	}
+}
+
+func TestReadLedgerMissingFile(t *testing.T) {
+	recorded, err := ReadLedger(filepath.Join(t.TempDir(), "missing.txt"))
+	if err != nil || len(recorded) != 0 {
+		t.Fatalf("got %v, %v for a missing ledger, want an empty ledger", recorded, err)
+	}
+}
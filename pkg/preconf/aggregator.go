@@ -0,0 +1,104 @@
+package preconf
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/bidderregistry"
+	"github.com/primevprotocol/validator-registry/pkg/preconfmanager"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+type Commitment = preconfmanager.PreconfmanagerOpenedCommitmentStored
+
+// Aggregator scans the preconf manager and bidder registry for a provider's
+// commitments and rewards.
+type Aggregator struct {
+	preconfManager *preconfmanager.PreconfmanagerFilterer
+	bidderRegistry *bidderregistry.BidderregistryFilterer
+	windowSize     uint64
+}
+
+func NewAggregator(
+	preconfManager *preconfmanager.PreconfmanagerFilterer,
+	bidderRegistry *bidderregistry.BidderregistryFilterer,
+	windowSize uint64,
+) *Aggregator {
+	return &Aggregator{preconfManager: preconfManager, bidderRegistry: bidderRegistry, windowSize: windowSize}
+}
+
+// Commitments returns the opened commitments in [startBlock, endBlock] by
+// any of committers, or by anyone if committers is empty.
+func (a *Aggregator) Commitments(ctx context.Context, startBlock, endBlock uint64, committers []common.Address) ([]Commitment, error) {
+	return FilterOpenedCommitments(ctx, a.preconfManager, FilterConfig{
+		StartBlock: startBlock,
+		EndBlock:   endBlock,
+		WindowSize: a.windowSize,
+		Committers: committers,
+	})
+}
+
+// FundsRewarded returns the total rewarded per provider in
+// [startBlock, endBlock] to any of providers, or to anyone if providers is
+// empty.
+func (a *Aggregator) FundsRewarded(ctx context.Context, startBlock, endBlock uint64, providers []common.Address) (map[common.Address]*big.Int, error) {
+	totals := make(map[common.Address]*big.Int)
+	err := utils.FilterRangeWithRetry(ctx, startBlock, endBlock, a.windowSize, func(opts *bind.FilterOpts) error {
+		iter, err := a.bidderRegistry.FilterFundsRewarded(opts, nil, nil, providers)
+		if err != nil {
+			return fmt.Errorf("failed to filter FundsRewarded events for blocks %d to %d: %w", opts.Start, *opts.End, err)
+		}
+		window, err := utils.CollectEvents(utils.IterEvents(iter, func() *bidderregistry.BidderregistryFundsRewarded {
+			return iter.Event
+		}))
+		if err != nil {
+			return fmt.Errorf("failed to iterate FundsRewarded events for blocks %d to %d: %w", opts.Start, *opts.End, err)
+		}
+		for _, event := range window {
+			total, ok := totals[event.Provider]
+			if !ok {
+				total = big.NewInt(0)
+				totals[event.Provider] = total
+			}
+			total.Add(total, event.Amount)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// Totals sums the bid amounts of commitments, undecayed and decayed under
+// both the fixed and the pre PR #673 decay logic.
+type Totals struct {
+	BidAmt               *big.Int
+	DecayedBidAmtFixed   *big.Int
+	DecayedBidAmtWithBug *big.Int
+}
+
+func SumCommitments(commitments []Commitment) (Totals, error) {
+	totals := Totals{
+		BidAmt:               big.NewInt(0),
+		DecayedBidAmtFixed:   big.NewInt(0),
+		DecayedBidAmtWithBug: big.NewInt(0),
+	}
+	for _, commitment := range commitments {
+		totals.BidAmt.Add(totals.BidAmt, commitment.BidAmt)
+		residualFixed, err := ComputeResidualAfterDecay(commitment.DecayStartTimeStamp, commitment.DecayEndTimeStamp, commitment.DispatchTimestamp, true)
+		if err != nil {
+			return Totals{}, fmt.Errorf("commitment for tx %s: %w", commitment.TxnHash, err)
+		}
+		residualWithBug, err := ComputeResidualAfterDecay(commitment.DecayStartTimeStamp, commitment.DecayEndTimeStamp, commitment.DispatchTimestamp, false)
+		if err != nil {
+			return Totals{}, fmt.Errorf("commitment for tx %s: %w", commitment.TxnHash, err)
+		}
+		totals.DecayedBidAmtFixed.Add(totals.DecayedBidAmtFixed, DecayedAmount(commitment.BidAmt, residualFixed))
+		totals.DecayedBidAmtWithBug.Add(totals.DecayedBidAmtWithBug, DecayedAmount(commitment.BidAmt, residualWithBug))
+	}
+	return totals, nil
+}
@@ -0,0 +1,208 @@
+package preconf
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/bidderregistry"
+	"github.com/primevprotocol/validator-registry/pkg/preconfmanager"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+)
+
+var (
+	testPreconfManager = common.HexToAddress("0xaa")
+	testBidderRegistry = common.HexToAddress("0xbb")
+	testCommitterA     = common.HexToAddress("0x0a")
+	testCommitterB     = common.HexToAddress("0x0b")
+)
+
+// testCommitment is a commitment for l1Block stored in mev-commit block
+// block, dispatched at the given share of its decay window from 0 to 1000.
+type testCommitment struct {
+	block      uint64
+	committer  common.Address
+	bidAmt     int64
+	l1Block    uint64
+	dispatched uint64
+}
+
+func commitmentLog(t *testing.T, c testCommitment) types.Log {
+	t.Helper()
+	contractABI, err := preconfmanager.PreconfmanagerMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testutil.EventLog(t, contractABI, testPreconfManager, "OpenedCommitmentStored", c.block,
+		[32]byte{byte(c.block)}, common.HexToAddress("0x01"), c.committer, big.NewInt(c.bidAmt), big.NewInt(0),
+		c.l1Block, uint64(1000), uint64(2000), "tx", "", [32]byte{}, 1000+c.dispatched)
+}
+
+func fundsRewardedLog(t *testing.T, block uint64, provider common.Address, amount int64) types.Log {
+	t.Helper()
+	contractABI, err := bidderregistry.BidderregistryMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testutil.EventLog(t, contractABI, testBidderRegistry, "FundsRewarded", block,
+		[32]byte{byte(block)}, common.HexToAddress("0x01"), provider, big.NewInt(1), big.NewInt(amount))
+}
+
+func newTestAggregator(t *testing.T, logs ...types.Log) *Aggregator {
+	t.Helper()
+	backend := &testutil.LogFilterer{Logs: logs}
+	pm, err := preconfmanager.NewPreconfmanagerFilterer(testPreconfManager, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br, err := bidderregistry.NewBidderregistryFilterer(testBidderRegistry, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAggregator(pm, br, 10)
+}
+
+func TestAggregatorCommitments(t *testing.T) {
+	aggregator := newTestAggregator(t,
+		commitmentLog(t, testCommitment{block: 5, committer: testCommitterA, bidAmt: 100, l1Block: 1}),
+		commitmentLog(t, testCommitment{block: 15, committer: testCommitterB, bidAmt: 200, l1Block: 2}),
+		commitmentLog(t, testCommitment{block: 25, committer: testCommitterA, bidAmt: 300, l1Block: 3}),
+		commitmentLog(t, testCommitment{block: 35, committer: testCommitterA, bidAmt: 400, l1Block: 4}),
+	)
+
+	all, err := aggregator.Commitments(context.Background(), 0, 30, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("got %d commitments in blocks 0 to 30, want 3", len(all))
+	}
+	byA, err := aggregator.Commitments(context.Background(), 0, 30, []common.Address{testCommitterA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byA) != 2 || byA[0].BlockNumber != 1 || byA[1].BlockNumber != 3 {
+		t.Errorf("got %+v, want committer A's commitments for L1 blocks 1 and 3", byA)
+	}
+}
+
+func TestAggregatorFundsRewarded(t *testing.T) {
+	aggregator := newTestAggregator(t,
+		fundsRewardedLog(t, 5, testCommitterA, 10),
+		fundsRewardedLog(t, 15, testCommitterA, 20),
+		fundsRewardedLog(t, 16, testCommitterB, 7),
+	)
+
+	totals, err := aggregator.FundsRewarded(context.Background(), 0, 20, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 2 || totals[testCommitterA].Int64() != 30 || totals[testCommitterB].Int64() != 7 {
+		t.Errorf("got totals %v, want 30 for A and 7 for B", totals)
+	}
+	totals, err = aggregator.FundsRewarded(context.Background(), 0, 20, []common.Address{testCommitterB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 1 || totals[testCommitterB].Int64() != 7 {
+		t.Errorf("got totals %v for provider B, want only 7 for B", totals)
+	}
+}
+
+// partwayFilterer serves its logs followed by one whose data can't be
+// unpacked to the next failures FilterLogs calls that match any, so the
+// event iterator fails after yielding the good ones.
+type partwayFilterer struct {
+	*testutil.LogFilterer
+	failures int
+}
+
+func (f *partwayFilterer) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	logs, err := f.LogFilterer.FilterLogs(ctx, q)
+	if err != nil || len(logs) == 0 || f.failures == 0 {
+		return logs, err
+	}
+	f.failures--
+	malformed := logs[len(logs)-1]
+	malformed.Data = malformed.Data[:1]
+	return append(logs, malformed), nil
+}
+
+// TestAggregatorRetriesIteratorFailingPartway backs off for a second
+// before each retry, as utils.FilterRangeWithRetry does.
+func TestAggregatorRetriesIteratorFailingPartway(t *testing.T) {
+	backend := &partwayFilterer{
+		LogFilterer: &testutil.LogFilterer{Logs: []types.Log{
+			fundsRewardedLog(t, 5, testCommitterA, 10),
+			fundsRewardedLog(t, 6, testCommitterB, 7),
+			commitmentLog(t, testCommitment{block: 15, committer: testCommitterA, bidAmt: 1, l1Block: 100}),
+		}},
+		failures: 1,
+	}
+	pm, err := preconfmanager.NewPreconfmanagerFilterer(testPreconfManager, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br, err := bidderregistry.NewBidderregistryFilterer(testBidderRegistry, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggregator := NewAggregator(pm, br, 10)
+
+	totals, err := aggregator.FundsRewarded(context.Background(), 0, 9, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 2 || totals[testCommitterA].Int64() != 10 || totals[testCommitterB].Int64() != 7 {
+		t.Errorf("got totals %v, want 10 for A and 7 for B counted once", totals)
+	}
+	backend.failures = 1
+	commitments, err := aggregator.Commitments(context.Background(), 10, 19, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commitments) != 1 || commitments[0].BlockNumber != 100 {
+		t.Errorf("got %+v, want only the commitment for L1 block 100", commitments)
+	}
+	if len(backend.Queries) != 4 {
+		t.Errorf("made %d FilterLogs calls, want each of the 2 windows filtered twice", len(backend.Queries))
+	}
+}
+
+func TestAggregatorStopsWhenCancelled(t *testing.T) {
+	aggregator := newTestAggregator(t, commitmentLog(t, testCommitment{block: 5, committer: testCommitterA, bidAmt: 1, l1Block: 1}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := aggregator.Commitments(ctx, 0, 100, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Commitments got error %v, want context.Canceled", err)
+	}
+	if _, err := aggregator.FundsRewarded(ctx, 0, 100, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("FundsRewarded got error %v, want context.Canceled", err)
+	}
+}
+
+func TestSumCommitments(t *testing.T) {
+	commitments := []Commitment{
+		// Dispatched before decay starts: full bid when fixed, nothing with the bug.
+		{BidAmt: big.NewInt(1000), DecayStartTimeStamp: 100, DecayEndTimeStamp: 200, DispatchTimestamp: 50},
+		// Dispatched a quarter of the way through decay.
+		{BidAmt: big.NewInt(400), DecayStartTimeStamp: 100, DecayEndTimeStamp: 200, DispatchTimestamp: 125},
+	}
+	totals, err := SumCommitments(commitments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.BidAmt.Int64() != 1400 || totals.DecayedBidAmtFixed.Int64() != 1300 || totals.DecayedBidAmtWithBug.Int64() != 300 {
+		t.Errorf("got totals %v, %v, %v, want 1400, 1300, 300", totals.BidAmt, totals.DecayedBidAmtFixed, totals.DecayedBidAmtWithBug)
+	}
+
+	commitments = append(commitments, Commitment{BidAmt: big.NewInt(1), DecayStartTimeStamp: 200, DecayEndTimeStamp: 100})
+	if _, err := SumCommitments(commitments); err == nil {
+		t.Error("got no error for a commitment whose decay ends before it starts")
+	}
+}
@@ -0,0 +1,46 @@
+package preconf
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const (
+	Precision = 1e16
+)
+
+var (
+	BigOneHundredPercent = big.NewInt(100 * Precision)
+)
+
+// ComputeResidualAfterDecay returns the share of the bid left after decay,
+// scaled so BigOneHundredPercent is the full bid. fixedLogic selects the
+// post PR #673 behavior for commitments made before decay starts.
+//
+// Copied from https://github.com/primev/mev-commit/blob/main/oracle/pkg/updater/updater.go
+func ComputeResidualAfterDecay(startTimestamp, endTimestamp, commitTimestamp uint64, fixedLogic bool) (*big.Int, error) {
+	if startTimestamp >= endTimestamp || endTimestamp <= commitTimestamp {
+		return nil, fmt.Errorf("timestamp out of range: %v, %v, %v", startTimestamp, endTimestamp, commitTimestamp)
+	}
+	if startTimestamp > commitTimestamp {
+		if fixedLogic {
+			return BigOneHundredPercent, nil
+		}
+		return big.NewInt(0), nil
+	}
+	totalTime := new(big.Int).SetUint64(endTimestamp - startTimestamp)
+	timePassed := new(big.Int).SetUint64(commitTimestamp - startTimestamp)
+	timeRemaining := new(big.Int).Sub(totalTime, timePassed)
+	scaledRemaining := new(big.Int).Mul(timeRemaining, BigOneHundredPercent)
+	residualPercentage := new(big.Int).Div(scaledRemaining, totalTime)
+	if residualPercentage.Cmp(BigOneHundredPercent) > 0 {
+		residualPercentage = BigOneHundredPercent
+	}
+	return residualPercentage, nil
+}
+
+// DecayedAmount applies a residual from ComputeResidualAfterDecay to bidAmt.
+func DecayedAmount(bidAmt, residual *big.Int) *big.Int {
+	decayed := new(big.Int).Mul(bidAmt, residual)
+	return decayed.Div(decayed, BigOneHundredPercent)
+}
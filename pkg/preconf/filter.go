@@ -0,0 +1,83 @@
+package preconf
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/preconfmanager"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// OpenedCommitmentFilterer is implemented by
+// *preconfmanager.PreconfmanagerFilterer.
+type OpenedCommitmentFilterer interface {
+	FilterOpenedCommitmentStored(opts *bind.FilterOpts, commitmentIndex [][32]byte) (*preconfmanager.PreconfmanagerOpenedCommitmentStoredIterator, error)
+}
+
+// FilterConfig selects the commitments returned by FilterOpenedCommitments.
+type FilterConfig struct {
+	StartBlock uint64
+	EndBlock   uint64
+	// WindowSize is the most blocks queried per log filter call, as RPC
+	// providers cap the block range of a log query.
+	WindowSize uint64
+	// Committers, if not empty, keeps only commitments by one of them.
+	Committers []common.Address
+}
+
+// FilterOpenedCommitments returns the OpenedCommitmentStored events in
+// [cfg.StartBlock, cfg.EndBlock], in log order, scanning cfg.WindowSize
+// blocks at a time. A window whose filter call or iteration fails is
+// retried, per utils.FilterRangeWithRetry, rather than under-counted.
+func FilterOpenedCommitments(ctx context.Context, filterer OpenedCommitmentFilterer, cfg FilterConfig) ([]Commitment, error) {
+	commitments := []Commitment{}
+	err := utils.FilterRangeWithRetry(ctx, cfg.StartBlock, cfg.EndBlock, cfg.WindowSize, func(opts *bind.FilterOpts) error {
+		iter, err := filterer.FilterOpenedCommitmentStored(opts, nil)
+		if err != nil {
+			return fmt.Errorf("failed to filter OpenedCommitmentStored events for blocks %d to %d: %w", opts.Start, *opts.End, err)
+		}
+		window, err := utils.CollectEvents(utils.IterEvents(iter, func() *Commitment { return iter.Event }))
+		if err != nil {
+			return fmt.Errorf("failed to iterate OpenedCommitmentStored events for blocks %d to %d: %w", opts.Start, *opts.End, err)
+		}
+		for _, commitment := range window {
+			if len(cfg.Committers) == 0 || slices.Contains(cfg.Committers, commitment.Committer) {
+				commitments = append(commitments, *commitment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commitments, nil
+}
+
+// commitmentsByBlockWindow is the block range CommitmentsByBlock queries per
+// log filter call.
+const commitmentsByBlockWindow = 100000
+
+// CommitmentsByBlock reads the opened commitments stored in
+// [fromBlock, toBlock] of the mev-commit chain and indexes them by the L1
+// block they were made for. If several commitments target the same block,
+// the first in log order is kept.
+func CommitmentsByBlock(ctx context.Context, filterer OpenedCommitmentFilterer, fromBlock, toBlock uint64) (map[uint64]Commitment, error) {
+	commitments, err := FilterOpenedCommitments(ctx, filterer, FilterConfig{
+		StartBlock: fromBlock,
+		EndBlock:   toBlock,
+		WindowSize: commitmentsByBlockWindow,
+	})
+	if err != nil {
+		return nil, err
+	}
+	byBlock := make(map[uint64]Commitment, len(commitments))
+	for _, commitment := range commitments {
+		if _, ok := byBlock[commitment.BlockNumber]; !ok {
+			byBlock[commitment.BlockNumber] = commitment
+		}
+	}
+	return byBlock, nil
+}
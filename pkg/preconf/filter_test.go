@@ -0,0 +1,110 @@
+package preconf
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/preconfmanager"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+)
+
+func newTestFilterer(t *testing.T, logs ...types.Log) (*preconfmanager.PreconfmanagerFilterer, *testutil.LogFilterer) {
+	t.Helper()
+	backend := &testutil.LogFilterer{Logs: logs}
+	filterer, err := preconfmanager.NewPreconfmanagerFilterer(testPreconfManager, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filterer, backend
+}
+
+func TestFilterOpenedCommitmentsWindows(t *testing.T) {
+	filterer, backend := newTestFilterer(t,
+		commitmentLog(t, testCommitment{block: 4, committer: testCommitterA, bidAmt: 1, l1Block: 100}),
+		commitmentLog(t, testCommitment{block: 10, committer: testCommitterB, bidAmt: 2, l1Block: 101}),
+		commitmentLog(t, testCommitment{block: 25, committer: testCommitterA, bidAmt: 3, l1Block: 102}),
+		commitmentLog(t, testCommitment{block: 31, committer: testCommitterA, bidAmt: 4, l1Block: 103}),
+	)
+
+	commitments, err := FilterOpenedCommitments(context.Background(), filterer, FilterConfig{
+		StartBlock: 5,
+		EndBlock:   30,
+		WindowSize: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commitments) != 2 || commitments[0].BlockNumber != 101 || commitments[1].BlockNumber != 102 {
+		t.Fatalf("got %+v, want the commitments for L1 blocks 101 and 102", commitments)
+	}
+	if commitments[0].Committer != testCommitterB || commitments[0].BidAmt.Int64() != 2 {
+		t.Errorf("got commitment by %s for %s, want committer B's bid of 2", commitments[0].Committer.Hex(), commitments[0].BidAmt)
+	}
+
+	var windows [][2]uint64
+	for _, q := range backend.Queries {
+		windows = append(windows, [2]uint64{q.FromBlock.Uint64(), q.ToBlock.Uint64()})
+	}
+	want := [][2]uint64{{5, 14}, {15, 24}, {25, 30}}
+	if len(windows) != len(want) {
+		t.Fatalf("filtered windows %v, want %v", windows, want)
+	}
+	for i := range want {
+		if windows[i] != want[i] {
+			t.Errorf("window %d is %v, want %v", i, windows[i], want[i])
+		}
+	}
+}
+
+func TestFilterOpenedCommitmentsByCommitter(t *testing.T) {
+	filterer, _ := newTestFilterer(t,
+		commitmentLog(t, testCommitment{block: 1, committer: testCommitterA, l1Block: 100}),
+		commitmentLog(t, testCommitment{block: 12, committer: testCommitterB, l1Block: 101}),
+		commitmentLog(t, testCommitment{block: 23, committer: testCommitterA, l1Block: 102}),
+	)
+
+	commitments, err := FilterOpenedCommitments(context.Background(), filterer, FilterConfig{
+		EndBlock:   30,
+		WindowSize: 10,
+		Committers: []common.Address{testCommitterA},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commitments) != 2 || commitments[0].BlockNumber != 100 || commitments[1].BlockNumber != 102 {
+		t.Errorf("got %+v, want committer A's commitments for L1 blocks 100 and 102", commitments)
+	}
+	for _, commitment := range commitments {
+		if commitment.Committer != testCommitterA {
+			t.Errorf("got a commitment by %s", commitment.Committer.Hex())
+		}
+	}
+}
+
+func TestCommitmentsByBlockKeepsFirstPerBlock(t *testing.T) {
+	filterer, _ := newTestFilterer(t,
+		commitmentLog(t, testCommitment{block: 3, committer: testCommitterA, bidAmt: 1, l1Block: 100}),
+		commitmentLog(t, testCommitment{block: 4, committer: testCommitterB, bidAmt: 2, l1Block: 100}),
+		commitmentLog(t, testCommitment{block: 5, committer: testCommitterB, bidAmt: 3, l1Block: 101}),
+		commitmentLog(t, testCommitment{block: 9, committer: testCommitterA, bidAmt: 4, l1Block: 102}),
+	)
+
+	byBlock, err := CommitmentsByBlock(context.Background(), filterer, 0, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byBlock) != 2 {
+		t.Fatalf("got commitments for %d blocks, want 2: %v", len(byBlock), byBlock)
+	}
+	if c := byBlock[100]; c.Committer != testCommitterA || c.BidAmt.Int64() != 1 {
+		t.Errorf("got committer %s bidding %s for block 100, want the first commitment, A's bid of 1", c.Committer.Hex(), c.BidAmt)
+	}
+	if c := byBlock[101]; c.Committer != testCommitterB {
+		t.Errorf("got committer %s for block 101, want B", c.Committer.Hex())
+	}
+	if _, ok := byBlock[102]; ok {
+		t.Error("got a commitment stored after toBlock")
+	}
+}
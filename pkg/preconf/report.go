@@ -0,0 +1,74 @@
+package preconf
+
+import (
+	"maps"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CommitterReport summarizes one committer's commitments and rewards.
+type CommitterReport struct {
+	Committer   common.Address
+	Commitments int
+	Totals      Totals
+	Rewarded    *big.Int
+}
+
+// GroupByCommitter splits commitments by committer.
+func GroupByCommitter(commitments []Commitment) map[common.Address][]Commitment {
+	grouped := make(map[common.Address][]Commitment)
+	for _, commitment := range commitments {
+		grouped[commitment.Committer] = append(grouped[commitment.Committer], commitment)
+	}
+	return grouped
+}
+
+// BlocksByCommitter returns, for each committer, the set of L1 blocks it
+// opened a commitment for.
+func BlocksByCommitter(commitments []Commitment) map[common.Address]map[uint64]bool {
+	blocks := make(map[common.Address]map[uint64]bool)
+	for _, commitment := range commitments {
+		if blocks[commitment.Committer] == nil {
+			blocks[commitment.Committer] = make(map[uint64]bool)
+		}
+		blocks[commitment.Committer][commitment.BlockNumber] = true
+	}
+	return blocks
+}
+
+// ReportByCommitter builds a report for every committer with commitments or
+// rewards, sorted by total rewarded, highest first.
+func ReportByCommitter(commitments []Commitment, rewarded map[common.Address]*big.Int) ([]CommitterReport, error) {
+	grouped := GroupByCommitter(commitments)
+	committers := make(map[common.Address]bool, len(grouped)+len(rewarded))
+	for committer := range grouped {
+		committers[committer] = true
+	}
+	for committer := range rewarded {
+		committers[committer] = true
+	}
+
+	reports := make([]CommitterReport, 0, len(committers))
+	for _, committer := range slices.SortedFunc(maps.Keys(committers), func(a, b common.Address) int { return a.Cmp(b) }) {
+		totals, err := SumCommitments(grouped[committer])
+		if err != nil {
+			return nil, err
+		}
+		reward := rewarded[committer]
+		if reward == nil {
+			reward = big.NewInt(0)
+		}
+		reports = append(reports, CommitterReport{
+			Committer:   committer,
+			Commitments: len(grouped[committer]),
+			Totals:      totals,
+			Rewarded:    reward,
+		})
+	}
+	slices.SortStableFunc(reports, func(a, b CommitterReport) int {
+		return b.Rewarded.Cmp(a.Rewarded)
+	})
+	return reports, nil
+}
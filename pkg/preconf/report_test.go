@@ -0,0 +1,61 @@
+package preconf
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestReportByCommitter(t *testing.T) {
+	testCommitterC := common.HexToAddress("0x0c")
+	commitments := []Commitment{
+		{Committer: testCommitterA, BidAmt: big.NewInt(100), BlockNumber: 1, DecayStartTimeStamp: 100, DecayEndTimeStamp: 200, DispatchTimestamp: 50},
+		{Committer: testCommitterB, BidAmt: big.NewInt(40), BlockNumber: 2, DecayStartTimeStamp: 100, DecayEndTimeStamp: 200, DispatchTimestamp: 150},
+		{Committer: testCommitterA, BidAmt: big.NewInt(10), BlockNumber: 3, DecayStartTimeStamp: 100, DecayEndTimeStamp: 200, DispatchTimestamp: 50},
+	}
+	// C was rewarded without commitments in range; A has none.
+	rewarded := map[common.Address]*big.Int{
+		testCommitterB: big.NewInt(20),
+		testCommitterC: big.NewInt(5),
+	}
+
+	reports, err := ReportByCommitter(commitments, rewarded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		committer   common.Address
+		commitments int
+		bidAmt      int64
+		rewarded    int64
+	}{
+		{testCommitterB, 1, 40, 20},
+		{testCommitterC, 0, 0, 5},
+		{testCommitterA, 2, 110, 0},
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("got %d reports, want %d", len(reports), len(want))
+	}
+	for i, w := range want {
+		r := reports[i]
+		if r.Committer != w.committer || r.Commitments != w.commitments || r.Totals.BidAmt.Int64() != w.bidAmt || r.Rewarded.Int64() != w.rewarded {
+			t.Errorf("report %d is %s with %d commitments, bid %v, rewarded %v; want %s with %d, %d, %d",
+				i, r.Committer, r.Commitments, r.Totals.BidAmt, r.Rewarded, w.committer, w.commitments, w.bidAmt, w.rewarded)
+		}
+	}
+	if got := reports[0].Totals.DecayedBidAmtFixed.Int64(); got != 20 {
+		t.Errorf("got B's decayed bid %d, want 20", got)
+	}
+}
+
+func TestBlocksByCommitter(t *testing.T) {
+	blocks := BlocksByCommitter([]Commitment{
+		{Committer: testCommitterA, BlockNumber: 1},
+		{Committer: testCommitterA, BlockNumber: 2},
+		{Committer: testCommitterB, BlockNumber: 2},
+	})
+	if !blocks[testCommitterA][1] || !blocks[testCommitterA][2] || blocks[testCommitterB][1] || !blocks[testCommitterB][2] {
+		t.Errorf("got blocks %v", blocks)
+	}
+}
@@ -0,0 +1,76 @@
+package preconf
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+// ResidualStats summarizes residual-after-decay percentages, in the range
+// 0 to 100.
+type ResidualStats struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Median float64
+	Mean   float64
+}
+
+func (s ResidualStats) String() string {
+	return fmt.Sprintf("n=%d min=%.2f%% max=%.2f%% median=%.2f%% mean=%.2f%%", s.Count, s.Min, s.Max, s.Median, s.Mean)
+}
+
+// DecayStatsResult holds the residual distribution under both the fixed and
+// the pre PR #673 decay logic.
+type DecayStatsResult struct {
+	Fixed   ResidualStats
+	WithBug ResidualStats
+}
+
+// DecayStats computes the distribution of residual-after-decay percentages
+// across commitments.
+func DecayStats(commitments []Commitment) (DecayStatsResult, error) {
+	fixed := make([]float64, 0, len(commitments))
+	withBug := make([]float64, 0, len(commitments))
+	for _, commitment := range commitments {
+		residualFixed, err := ComputeResidualAfterDecay(commitment.DecayStartTimeStamp, commitment.DecayEndTimeStamp, commitment.DispatchTimestamp, true)
+		if err != nil {
+			return DecayStatsResult{}, fmt.Errorf("commitment for tx %s: %w", commitment.TxnHash, err)
+		}
+		residualWithBug, err := ComputeResidualAfterDecay(commitment.DecayStartTimeStamp, commitment.DecayEndTimeStamp, commitment.DispatchTimestamp, false)
+		if err != nil {
+			return DecayStatsResult{}, fmt.Errorf("commitment for tx %s: %w", commitment.TxnHash, err)
+		}
+		fixed = append(fixed, residualPercent(residualFixed))
+		withBug = append(withBug, residualPercent(residualWithBug))
+	}
+	return DecayStatsResult{Fixed: residualStats(fixed), WithBug: residualStats(withBug)}, nil
+}
+
+func residualPercent(residual *big.Int) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(residual), big.NewFloat(Precision)).Float64()
+	return f
+}
+
+func residualStats(residuals []float64) ResidualStats {
+	if len(residuals) == 0 {
+		return ResidualStats{}
+	}
+	slices.Sort(residuals)
+	sum := 0.0
+	for _, r := range residuals {
+		sum += r
+	}
+	n := len(residuals)
+	median := residuals[n/2]
+	if n%2 == 0 {
+		median = (residuals[n/2-1] + residuals[n/2]) / 2
+	}
+	return ResidualStats{
+		Count:  n,
+		Min:    residuals[0],
+		Max:    residuals[n-1],
+		Median: median,
+		Mean:   sum / float64(n),
+	}
+}
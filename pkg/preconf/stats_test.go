@@ -0,0 +1,54 @@
+package preconf
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDecayStats(t *testing.T) {
+	// Residuals under the fixed logic are 100%, 75%, 50% and 0%.
+	commitments := []Commitment{
+		{BidAmt: big.NewInt(1), DecayStartTimeStamp: 100, DecayEndTimeStamp: 200, DispatchTimestamp: 50},
+		{BidAmt: big.NewInt(1), DecayStartTimeStamp: 100, DecayEndTimeStamp: 200, DispatchTimestamp: 125},
+		{BidAmt: big.NewInt(1), DecayStartTimeStamp: 100, DecayEndTimeStamp: 200, DispatchTimestamp: 150},
+		{BidAmt: big.NewInt(1), DecayStartTimeStamp: 100, DecayEndTimeStamp: 200, DispatchTimestamp: 199},
+	}
+	stats, err := DecayStats(commitments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ResidualStats{Count: 4, Min: 1, Max: 100, Median: 62.5, Mean: 56.5}
+	if stats.Fixed != want {
+		t.Errorf("got fixed stats %v, want %v", stats.Fixed, want)
+	}
+	// With the bug the commitment dispatched before decay starts gets nothing.
+	want = ResidualStats{Count: 4, Min: 0, Max: 75, Median: 25.5, Mean: 31.5}
+	if stats.WithBug != want {
+		t.Errorf("got stats with bug %v, want %v", stats.WithBug, want)
+	}
+}
+
+func TestDecayStatsEmpty(t *testing.T) {
+	stats, err := DecayStats(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Fixed != (ResidualStats{}) || stats.WithBug != (ResidualStats{}) {
+		t.Errorf("got %+v, want zero stats", stats)
+	}
+}
+
+func TestDecayStatsOddCountMedian(t *testing.T) {
+	stats, err := DecayStats([]Commitment{
+		{DecayStartTimeStamp: 0, DecayEndTimeStamp: 100, DispatchTimestamp: 90},
+		{DecayStartTimeStamp: 0, DecayEndTimeStamp: 100, DispatchTimestamp: 10},
+		{DecayStartTimeStamp: 0, DecayEndTimeStamp: 100, DispatchTimestamp: 50},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Fixed.Median != 50 {
+		t.Errorf("got median %v, want the middle residual 50", stats.Fixed.Median)
+	}
+}
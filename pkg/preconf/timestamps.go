@@ -0,0 +1,25 @@
+package preconf
+
+import (
+	"context"
+
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// CheckDispatchTimestamps returns the commitments whose dispatch timestamp,
+// in milliseconds, is later than the timestamp of the mev-commit chain
+// block that stored them. Such a commitment could not have been dispatched
+// in time, so its decay inputs are suspect.
+func CheckDispatchTimestamps(ctx context.Context, client utils.HeaderReader, commitments []Commitment) ([]Commitment, error) {
+	suspect := []Commitment{}
+	for _, commitment := range commitments {
+		blockTime, err := utils.BlockTimestamp(ctx, client, commitment.Raw.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if commitment.DispatchTimestamp > (blockTime+1)*1000 {
+			suspect = append(suspect, commitment)
+		}
+	}
+	return suspect, nil
+}
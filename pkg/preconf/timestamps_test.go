@@ -0,0 +1,32 @@
+package preconf
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// secondsPerBlock mines block n at 12n seconds.
+type secondsPerBlock struct{}
+
+func (*secondsPerBlock) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: number, Time: 12 * number.Uint64()}, nil
+}
+
+func TestCheckDispatchTimestamps(t *testing.T) {
+	stored := func(block, dispatchMillis uint64) Commitment {
+		return Commitment{TxnHash: "tx", DispatchTimestamp: dispatchMillis, Raw: types.Log{BlockNumber: block}}
+	}
+	// Block 10 is at 120s; a dispatch up to a second later is tolerated.
+	commitments := []Commitment{stored(10, 119000), stored(10, 121000), stored(10, 121001)}
+
+	suspect, err := CheckDispatchTimestamps(context.Background(), &secondsPerBlock{}, commitments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suspect) != 1 || suspect[0].DispatchTimestamp != 121001 {
+		t.Errorf("got suspect commitments %+v, want only the one dispatched at 121001", suspect)
+	}
+}
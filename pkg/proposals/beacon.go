@@ -0,0 +1,15 @@
+package proposals
+
+import (
+	"context"
+
+	"github.com/primevprotocol/validator-registry/pkg/beacon"
+)
+
+// BeaconClient is the subset of beacon.Client the scanner and monitors
+// depend on.
+type BeaconClient interface {
+	ProposerDuties(ctx context.Context, epoch uint64) ([]beacon.ProposerDuty, error)
+	ProposerDutiesRange(ctx context.Context, startEpoch, endEpoch uint64, concurrency int) (map[uint64][]beacon.ProposerDuty, error)
+	BlockNumberForSlot(ctx context.Context, slot uint64) (uint64, error)
+}
@@ -0,0 +1,84 @@
+package proposals
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Checkpoint records scanned epochs in an append-only file, one per line,
+// so an interrupted scan can resume without rescanning them.
+type Checkpoint struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[uint64]bool
+}
+
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	done := make(map[uint64]bool)
+
+	existing, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("opening checkpoint: %w", err)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			epoch, err := strconv.ParseUint(line, 10, 64)
+			if err != nil {
+				// A crash mid-write can leave a truncated last line.
+				fmt.Printf("Ignoring malformed checkpoint line %q\n", line)
+				continue
+			}
+			done[epoch] = true
+		}
+		existing.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading checkpoint: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening checkpoint: %w", err)
+	}
+	return &Checkpoint{file: file, done: done}, nil
+}
+
+func (c *Checkpoint) Done(epoch uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done[epoch]
+}
+
+func (c *Checkpoint) NumDone() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.done)
+}
+
+func (c *Checkpoint) MarkDone(epoch uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Leading newline keeps a record from merging with a line truncated by a crash.
+	if _, err := fmt.Fprintf(c.file, "\n%d\n", epoch); err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	if err := c.file.Sync(); err != nil {
+		return fmt.Errorf("syncing checkpoint: %w", err)
+	}
+	c.done[epoch] = true
+	return nil
+}
+
+func (c *Checkpoint) Close() error {
+	return c.file.Close()
+}
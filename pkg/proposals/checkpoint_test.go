@@ -0,0 +1,122 @@
+package proposals
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+func TestCheckpointSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	checkpoint, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, epoch := range []uint64{5, 7} {
+		if err := checkpoint.MarkDone(epoch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := checkpoint.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if !reopened.Done(5) || !reopened.Done(7) || reopened.Done(6) || reopened.NumDone() != 2 {
+		t.Errorf("reopened checkpoint has %d epochs done, want exactly 5 and 7", reopened.NumDone())
+	}
+}
+
+func TestCheckpointIgnoresTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	// A crash mid-write left a malformed last line; the next record
+	// starts on its own line.
+	if err := os.WriteFile(path, []byte("\n10\n\n1x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkpoint.MarkDone(12); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint.Close()
+
+	reopened, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if !reopened.Done(10) || !reopened.Done(12) || reopened.NumDone() != 2 {
+		t.Errorf("got %d epochs done, want 10 and 12", reopened.NumDone())
+	}
+}
+
+func TestScanEpochsResumesFromPartialFile(t *testing.T) {
+	dir := t.TempDir()
+	partialPath := filepath.Join(dir, "opted_in_slots.partial.csv")
+	validators := map[string]optins.Validator{"v": {PubKey: "v", OptInType: optins.OptInTypeVanilla}}
+	client := &fakeBeacon{
+		duties: map[uint64]map[uint64]string{1: {32: "v"}, 2: {64: "v"}},
+		blocks: map[uint64]uint64{32: 1, 64: 2},
+	}
+
+	// The first run is interrupted after epoch 1.
+	checkpoint, err := OpenCheckpoint(filepath.Join(dir, "checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := optins.NewSlotWriter(partialPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := NewScanner(client, validators)
+	scanner.SetCheckpoint(checkpoint, sink)
+	if _, err := scanner.ScanEpochs(context.Background(), 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+	checkpoint.Close()
+
+	// The second run reads the partial file and scans only epoch 2.
+	checkpoint, err = OpenCheckpoint(filepath.Join(dir, "checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer checkpoint.Close()
+	collector := NewSlotCollector()
+	scanned, err := optins.ReadSlotsFile(partialPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, slot := range scanned {
+		collector.Add(slot)
+	}
+	sink, err = optins.NewSlotWriter(partialPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	scanner.SetCheckpoint(checkpoint, sink)
+	slots, err := scanner.ScanEpochs(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 1 || slots[0].Slot != 64 {
+		t.Errorf("resumed scan found %+v, want only slot 64", slots)
+	}
+	collector.Add(slots...)
+
+	result := collector.Result()
+	if len(result) != 2 || result[0].Slot != 32 || result[1].Slot != 64 {
+		t.Errorf("got slots %+v, want 32 and 64", result)
+	}
+}
@@ -0,0 +1,49 @@
+package proposals
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+// SlotCollector aggregates the slots found by concurrent scan workers. A
+// slot added more than once, as when an epoch interrupted between writing
+// and checkpointing is rescanned, is kept once. It is safe for concurrent
+// use.
+type SlotCollector struct {
+	mu    sync.Mutex
+	slots map[uint64]optins.Slot
+}
+
+func NewSlotCollector() *SlotCollector {
+	return &SlotCollector{slots: make(map[uint64]optins.Slot)}
+}
+
+func (c *SlotCollector) Add(slots ...optins.Slot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, slot := range slots {
+		c.slots[slot.Slot] = slot
+	}
+}
+
+func (c *SlotCollector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.slots)
+}
+
+// Result returns the collected slots sorted by slot number.
+func (c *SlotCollector) Result() []optins.Slot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make([]optins.Slot, 0, len(c.slots))
+	for _, slot := range c.slots {
+		result = append(result, slot)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Slot < result[j].Slot
+	})
+	return result
+}
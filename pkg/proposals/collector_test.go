@@ -0,0 +1,52 @@
+package proposals
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+func TestSlotCollectorConcurrentAdd(t *testing.T) {
+	const workers, slotsPerWorker = 8, 100
+	collector := NewSlotCollector()
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Workers add interleaved slots in descending order, one or
+			// two at a time.
+			for i := slotsPerWorker - 1; i >= 0; i -= 2 {
+				slot := uint64(i*workers + w)
+				collector.Add(
+					optins.Slot{Slot: slot, BlockNumber: slot + 1000},
+					optins.Slot{Slot: slot - uint64(workers), BlockNumber: slot - uint64(workers) + 1000},
+				)
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := collector.Result()
+	if len(result) != workers*slotsPerWorker || collector.Len() != len(result) {
+		t.Fatalf("collected %d slots (Len %d), want %d", len(result), collector.Len(), workers*slotsPerWorker)
+	}
+	for i, slot := range result {
+		if slot.Slot != uint64(i) || slot.BlockNumber != uint64(i)+1000 {
+			t.Fatalf("result[%d] is slot %d in block %d, want slot %d in block %d", i, slot.Slot, slot.BlockNumber, i, i+1000)
+		}
+	}
+}
+
+func TestSlotCollectorKeepsRescannedSlotOnce(t *testing.T) {
+	collector := NewSlotCollector()
+	collector.Add(optins.Slot{Slot: 7, BlockNumber: 100}, optins.Slot{Slot: 3, BlockNumber: 96})
+	collector.Add(optins.Slot{Slot: 7, BlockNumber: 100})
+
+	result := collector.Result()
+	if len(result) != 2 || result[0].Slot != 3 || result[1].Slot != 7 {
+		t.Errorf("got %+v, want slots 3 and 7 once each", result)
+	}
+}
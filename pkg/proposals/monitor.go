@@ -0,0 +1,84 @@
+package proposals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/primevprotocol/validator-registry/pkg/beacon"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+// CommitmentChecker reports whether an opened commitment was stored for an
+// L1 block.
+type CommitmentChecker interface {
+	HasCommitment(ctx context.Context, blockNumber uint64) (bool, error)
+}
+
+// EpochReport counts the outcome of an epoch's opted-in slots.
+type EpochReport struct {
+	Epoch uint64
+	// Proposed counts opted-in slots whose block has an opened commitment.
+	Proposed int
+	// Missed counts opted-in slots with no block, or whose block has no
+	// opened commitment.
+	Missed int
+	// MissedSlots lists the missed slots.
+	MissedSlots []uint64
+}
+
+// CheckEpoch classifies each slot of epoch assigned to one of validators as
+// proposed or missed. Slots of validators that opted in after their block,
+// or for a missed slot the block it would have produced, are ignored. Unlike ScanEpoch it doesn't retry, so a caller polling
+// epochs can retry the whole epoch later.
+func CheckEpoch(
+	ctx context.Context,
+	client BeaconClient,
+	validators map[string]optins.Validator,
+	commitments CommitmentChecker,
+	epoch uint64,
+) (EpochReport, error) {
+	report := EpochReport{Epoch: epoch}
+	duties, err := client.ProposerDuties(ctx, epoch)
+	if err != nil {
+		return report, err
+	}
+	for _, duty := range duties {
+		validator, ok := validators[duty.Pubkey]
+		if !ok {
+			continue
+		}
+		slot := duty.Slot
+		blockNumber, err := client.BlockNumberForSlot(ctx, slot)
+		if errors.Is(err, beacon.ErrNotFound) {
+			optedIn, err := optedInAtMissedSlot(ctx, client, validator, slot)
+			if err != nil {
+				return report, err
+			}
+			if !optedIn {
+				continue
+			}
+			report.Missed++
+			report.MissedSlots = append(report.MissedSlots, slot)
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("getting block number for slot %d: %w", slot, err)
+		}
+		if blockNumber < validator.OptInBlock {
+			continue
+		}
+
+		committed, err := commitments.HasCommitment(ctx, blockNumber)
+		if err != nil {
+			return report, fmt.Errorf("checking commitments for block %d: %w", blockNumber, err)
+		}
+		if committed {
+			report.Proposed++
+		} else {
+			report.Missed++
+			report.MissedSlots = append(report.MissedSlots, slot)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,40 @@
+package proposals
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+// fakeCommitments reports the blocks in committed as having a commitment.
+type fakeCommitments map[uint64]bool
+
+func (c fakeCommitments) HasCommitment(ctx context.Context, blockNumber uint64) (bool, error) {
+	return c[blockNumber], nil
+}
+
+func TestCheckEpochAppliesOptInToMissedSlots(t *testing.T) {
+	validators := map[string]optins.Validator{
+		"v": {PubKey: "v", OptInBlock: 102},
+	}
+	client := &fakeBeacon{
+		duties: map[uint64]map[uint64]string{1: {
+			33: "v", // missed, would have been block 101, before the opt-in
+			35: "v", // missed, would have been block 102
+			36: "v", // block 102 without a commitment
+			37: "v", // block 103 with a commitment
+		}},
+		blocks: map[uint64]uint64{32: 100, 34: 101, 36: 102, 37: 103},
+	}
+
+	report, err := CheckEpoch(context.Background(), client, validators, fakeCommitments{103: true}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(report.MissedSlots)
+	if report.Proposed != 1 || report.Missed != 2 || !slices.Equal(report.MissedSlots, []uint64{35, 36}) {
+		t.Errorf("got %d proposed, %d missed %v, want 1 and 2 [35 36]", report.Proposed, report.Missed, report.MissedSlots)
+	}
+}
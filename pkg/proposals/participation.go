@@ -0,0 +1,130 @@
+package proposals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/primevprotocol/validator-registry/pkg/beacon"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+// maxRetries is how many times ParticipationByEpoch tries an epoch.
+const maxRetries = 5
+
+// EpochParticipation counts how validators' proposer duties in an epoch
+// turned out.
+type EpochParticipation struct {
+	Epoch uint64
+	// Duties counts the slots assigned to validators, excluding those whose
+	// block, or for a missed slot the block it would have produced, precedes
+	// the validator's opt-in.
+	Duties int
+	// Proposed counts duties with a block.
+	Proposed int
+	// Missed counts duties the beacon node has no block for.
+	Missed int
+}
+
+// ParticipationByEpoch returns, for each epoch in [startEpoch, endEpoch],
+// how many of validators had proposer duties and how many of those produced
+// a block. Unlike CheckEpoch it doesn't look at commitments, only at
+// whether the slot was proposed at all. Each epoch is retried as a whole if
+// a beacon call fails.
+func ParticipationByEpoch(
+	ctx context.Context,
+	client BeaconClient,
+	validators map[string]optins.Validator,
+	startEpoch, endEpoch uint64,
+) ([]EpochParticipation, error) {
+	var participation []EpochParticipation
+	for epoch := startEpoch; epoch <= endEpoch; epoch++ {
+		var p EpochParticipation
+		var err error
+		for retries := 0; retries < maxRetries; retries++ {
+			p, err = epochParticipation(ctx, client, validators, epoch)
+			if err == nil {
+				break
+			}
+			fmt.Printf("Failed to check participation in epoch %d: %v\n", epoch, err)
+			if err := sleepCtx(ctx, time.Duration(retries)*time.Second); err != nil {
+				return nil, err
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("checking participation in epoch %d: %w", epoch, err)
+		}
+		participation = append(participation, p)
+	}
+	return participation, nil
+}
+
+func epochParticipation(
+	ctx context.Context,
+	client BeaconClient,
+	validators map[string]optins.Validator,
+	epoch uint64,
+) (EpochParticipation, error) {
+	p := EpochParticipation{Epoch: epoch}
+	duties, err := client.ProposerDuties(ctx, epoch)
+	if err != nil {
+		return p, err
+	}
+	for _, duty := range duties {
+		validator, ok := validators[duty.Pubkey]
+		if !ok {
+			continue
+		}
+		slot := duty.Slot
+		blockNumber, err := client.BlockNumberForSlot(ctx, slot)
+		if errors.Is(err, beacon.ErrNotFound) {
+			optedIn, err := optedInAtMissedSlot(ctx, client, validator, slot)
+			if err != nil {
+				return p, err
+			}
+			if optedIn {
+				p.Duties++
+				p.Missed++
+			}
+			continue
+		}
+		if err != nil {
+			return p, fmt.Errorf("getting block number for slot %d: %w", slot, err)
+		}
+		if blockNumber < validator.OptInBlock {
+			continue
+		}
+		p.Duties++
+		p.Proposed++
+	}
+	return p, nil
+}
+
+// optedInAtMissedSlot reports whether validator had opted in by the block
+// missed slot would have produced, the one after the last block proposed
+// before it, so missed slots are held to the same opt-in check as proposed
+// ones.
+func optedInAtMissedSlot(ctx context.Context, client BeaconClient, validator optins.Validator, slot uint64) (bool, error) {
+	for prev := slot; prev > 0; {
+		prev--
+		blockNumber, err := client.BlockNumberForSlot(ctx, prev)
+		if errors.Is(err, beacon.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("getting block number for slot %d: %w", prev, err)
+		}
+		return blockNumber+1 >= validator.OptInBlock, nil
+	}
+	return true, nil
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
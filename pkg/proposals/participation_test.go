@@ -0,0 +1,86 @@
+package proposals
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/beacon"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+// fakeBeacon serves proposer duties by epoch and block numbers by slot.
+// Slots absent from blocks are missed, and epochs from future on are in
+// the future if it is set.
+type fakeBeacon struct {
+	duties map[uint64]map[uint64]string
+	blocks map[uint64]uint64
+	future uint64
+}
+
+func (b *fakeBeacon) ProposerDuties(ctx context.Context, epoch uint64) ([]beacon.ProposerDuty, error) {
+	if b.future != 0 && epoch >= b.future {
+		return nil, fmt.Errorf("fetching proposer duties for epoch %d: %w", epoch, beacon.ErrFutureEpoch)
+	}
+	var duties []beacon.ProposerDuty
+	for slot, pubkey := range b.duties[epoch] {
+		duties = append(duties, beacon.ProposerDuty{Pubkey: pubkey, Slot: slot})
+	}
+	slices.SortFunc(duties, func(a, b beacon.ProposerDuty) int { return cmp.Compare(a.Slot, b.Slot) })
+	return duties, nil
+}
+
+func (b *fakeBeacon) ProposerDutiesRange(ctx context.Context, startEpoch, endEpoch uint64, concurrency int) (map[uint64][]beacon.ProposerDuty, error) {
+	byEpoch := make(map[uint64][]beacon.ProposerDuty)
+	for epoch := startEpoch; epoch <= endEpoch; epoch++ {
+		duties, err := b.ProposerDuties(ctx, epoch)
+		if errors.Is(err, beacon.ErrFutureEpoch) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		byEpoch[epoch] = duties
+	}
+	return byEpoch, nil
+}
+
+func (b *fakeBeacon) BlockNumberForSlot(ctx context.Context, slot uint64) (uint64, error) {
+	blockNumber, ok := b.blocks[slot]
+	if !ok {
+		return 0, fmt.Errorf("fetching block for slot %d: %w", slot, beacon.ErrNotFound)
+	}
+	return blockNumber, nil
+}
+
+func TestParticipationAppliesOptInToMissedSlots(t *testing.T) {
+	validators := map[string]optins.Validator{
+		"early": {PubKey: "early", OptInBlock: 100},
+		"late":  {PubKey: "late", OptInBlock: 105},
+	}
+	client := &fakeBeacon{
+		duties: map[uint64]map[uint64]string{1: {
+			32: "late",  // block 100, before the opt-in
+			33: "late",  // missed, would have been block 101, before the opt-in
+			34: "early", // missed, would have been block 101
+			35: "late",  // missed, would have been block 101, before the opt-in
+			36: "early", // block 104
+			37: "late",  // missed, would have been block 105
+			38: "early", // block 106
+			39: "other",
+		}},
+		blocks: map[uint64]uint64{32: 100, 36: 104, 38: 106},
+	}
+
+	participation, err := ParticipationByEpoch(context.Background(), client, validators, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := participation[0]
+	if got.Duties != 4 || got.Proposed != 2 || got.Missed != 2 {
+		t.Errorf("got %d duties, %d proposed, %d missed, want 4, 2, 2", got.Duties, got.Proposed, got.Missed)
+	}
+}
@@ -0,0 +1,134 @@
+package proposals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/primevprotocol/validator-registry/pkg/beacon"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+// dutiesChunk is how many epochs of duties ScanEpochs fetches at once, and
+// dutiesConcurrency how many of those requests are in flight together. The
+// beacon client's rate limit bounds the overall request rate.
+const (
+	dutiesChunk       = 32
+	dutiesConcurrency = 4
+)
+
+// Scanner finds proposer slots assigned to opted-in validators at or after
+// their opt-in block.
+type Scanner struct {
+	client     BeaconClient
+	validators map[string]optins.Validator
+	checkpoint *Checkpoint
+	sink       *optins.SlotWriter
+}
+
+func NewScanner(client BeaconClient, validators map[string]optins.Validator) *Scanner {
+	return &Scanner{client: client, validators: validators}
+}
+
+// SetCheckpoint makes ScanEpochs skip epochs already recorded in checkpoint
+// and, after each scanned epoch, append its slots to sink before recording
+// the epoch as done.
+func (s *Scanner) SetCheckpoint(checkpoint *Checkpoint, sink *optins.SlotWriter) {
+	s.checkpoint = checkpoint
+	s.sink = sink
+}
+
+// ScanEpochs scans every epoch in [startEpoch, endEpoch], fetching the
+// duties of up to dutiesChunk epochs at once. It stops early, without an
+// error, at the first epoch the beacon node reports as in the future. With a
+// checkpoint set, only the slots of epochs scanned by this call are
+// returned.
+func (s *Scanner) ScanEpochs(ctx context.Context, startEpoch, endEpoch uint64) ([]optins.Slot, error) {
+	optedInSlots := []optins.Slot{}
+	for chunkStart := startEpoch; chunkStart <= endEpoch; chunkStart += dutiesChunk {
+		chunkEnd := min(chunkStart+dutiesChunk-1, endEpoch)
+		byEpoch, err := s.client.ProposerDutiesRange(ctx, chunkStart, chunkEnd, dutiesConcurrency)
+		if err != nil {
+			return nil, err
+		}
+		for epoch := chunkStart; epoch <= chunkEnd; epoch++ {
+			duties, ok := byEpoch[epoch]
+			if !ok {
+				fmt.Printf("Epoch %d is in the future, stopping scan\n", epoch)
+				return optedInSlots, nil
+			}
+			if s.checkpoint != nil && s.checkpoint.Done(epoch) {
+				continue
+			}
+			start := time.Now()
+			fmt.Printf("Scanning proposer duties for epoch %d. Epochs left for this worker: %d\n", epoch, endEpoch-epoch)
+
+			slots, err := s.scanDuties(ctx, epoch, duties)
+			if err != nil {
+				return nil, err
+			}
+			optedInSlots = append(optedInSlots, slots...)
+
+			if s.checkpoint != nil {
+				if s.sink != nil {
+					if err := s.sink.Write(slots); err != nil {
+						return nil, fmt.Errorf("writing slots for epoch %d: %w", epoch, err)
+					}
+				}
+				if err := s.checkpoint.MarkDone(epoch); err != nil {
+					return nil, err
+				}
+			}
+			fmt.Printf("Time taken for epoch %d: %v\n", epoch, time.Since(start))
+		}
+	}
+	return optedInSlots, nil
+}
+
+// ScanEpoch returns the opted-in slots of a single epoch. An epoch for which
+// the beacon node returns no duties yields no slots rather than an error.
+func (s *Scanner) ScanEpoch(ctx context.Context, epoch uint64) ([]optins.Slot, error) {
+	duties, err := s.client.ProposerDuties(ctx, epoch)
+	if err != nil {
+		return nil, err
+	}
+	return s.scanDuties(ctx, epoch, duties)
+}
+
+// scanDuties returns the slots of duties assigned to opted-in validators
+// whose block is at or after the validator's opt-in. Missed slots have no
+// block, so are skipped.
+func (s *Scanner) scanDuties(ctx context.Context, epoch uint64, duties []beacon.ProposerDuty) ([]optins.Slot, error) {
+	if len(duties) == 0 {
+		fmt.Printf("No proposer duties returned for epoch %d, skipping\n", epoch)
+		return nil, nil
+	}
+
+	optedInSlots := []optins.Slot{}
+	for _, duty := range duties {
+		validator, ok := s.validators[duty.Pubkey]
+		if !ok {
+			continue
+		}
+		blockNumber, err := s.client.BlockNumberForSlot(ctx, duty.Slot)
+		if errors.Is(err, beacon.ErrNotFound) {
+			fmt.Printf("Skipping missed slot %d of opted-in validator %s\n", duty.Slot, validator.PubKey)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting block number for slot %d: %w", duty.Slot, err)
+		}
+
+		if blockNumber >= validator.OptInBlock {
+			optedInSlots = append(optedInSlots, optins.Slot{
+				Slot:        duty.Slot,
+				BlockNumber: blockNumber,
+				Validator:   validator,
+			})
+			fmt.Printf("Found opted-in slot. Slot number: %d, block number: %d, pubkey: %s\n",
+				duty.Slot, blockNumber, validator.PubKey)
+		}
+	}
+	return optedInSlots, nil
+}
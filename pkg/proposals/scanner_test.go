@@ -0,0 +1,90 @@
+package proposals
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+)
+
+func TestScanEpochsStopsAtFutureEpoch(t *testing.T) {
+	validators := map[string]optins.Validator{"v": {PubKey: "v", OptInBlock: 100}}
+	client := &fakeBeacon{
+		duties: map[uint64]map[uint64]string{
+			1: {32: "v", 33: "other"},
+			2: {64: "v", 65: "v"},
+			3: {96: "v"},
+		},
+		// Slot 32 precedes the opt-in and slot 65 is missed.
+		blocks: map[uint64]uint64{32: 99, 33: 100, 64: 130, 96: 160},
+		future: 3,
+	}
+
+	slots, err := NewScanner(client, validators).ScanEpochs(context.Background(), 1, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 1 || slots[0].Slot != 64 || slots[0].BlockNumber != 130 {
+		t.Errorf("got slots %+v, want only slot 64", slots)
+	}
+}
+
+func TestScanEpochsSkipsCheckpointedEpochs(t *testing.T) {
+	validators := map[string]optins.Validator{"v": {PubKey: "v"}}
+	client := &fakeBeacon{
+		duties: map[uint64]map[uint64]string{1: {32: "v"}, 2: {64: "v"}},
+		blocks: map[uint64]uint64{32: 1, 64: 2},
+	}
+	checkpoint, err := OpenCheckpoint(filepath.Join(t.TempDir(), "checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer checkpoint.Close()
+	if err := checkpoint.MarkDone(1); err != nil {
+		t.Fatal(err)
+	}
+	scanner := NewScanner(client, validators)
+	scanner.SetCheckpoint(checkpoint, nil)
+
+	slots, err := scanner.ScanEpochs(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 1 || slots[0].Slot != 64 {
+		t.Errorf("got slots %+v, want only slot 64", slots)
+	}
+	if !checkpoint.Done(2) {
+		t.Error("epoch 2 not checkpointed")
+	}
+}
+
+func TestScanEpochEmptyDuties(t *testing.T) {
+	validators := map[string]optins.Validator{"v": {PubKey: "v"}}
+	client := &fakeBeacon{duties: map[uint64]map[uint64]string{}}
+
+	slots, err := NewScanner(client, validators).ScanEpoch(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("got error %v for an epoch without duties, want none", err)
+	}
+	if len(slots) != 0 {
+		t.Errorf("got slots %+v, want none", slots)
+	}
+}
+
+func TestScanEpochsContinuesPastEmptyEpoch(t *testing.T) {
+	validators := map[string]optins.Validator{"v": {PubKey: "v"}}
+	client := &fakeBeacon{
+		// Epoch 2 has no duties, epoch 3 only some of its slots.
+		duties: map[uint64]map[uint64]string{1: {32: "v"}, 3: {97: "v"}},
+		blocks: map[uint64]uint64{32: 10, 97: 20},
+	}
+
+	slots, err := NewScanner(client, validators).ScanEpochs(context.Background(), 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 2 || slots[0].Slot != 32 || slots[1].Slot != 97 {
+		t.Errorf("got slots %+v, want slots 32 and 97", slots)
+	}
+}
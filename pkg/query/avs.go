@@ -0,0 +1,88 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitavs"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// AVSValidatorCaller is implemented by mevcommitavs.MevcommitavsCaller.
+type AVSValidatorCaller interface {
+	GetValidatorRegInfo(opts *bind.CallOpts, valPubKey []byte) (mevcommitavs.IMevCommitAVSValidatorRegistrationInfo, error)
+}
+
+// ActiveAVSValidators returns the candidates still registered with the AVS
+// under podOwner, or under any pod owner if podOwner is the zero address,
+// preserving their order. The AVS can't enumerate its validators, so
+// candidates usually come from ValidatorRegistered events, which also
+// include validators that have since deregistered.
+func ActiveAVSValidators(
+	ctx context.Context,
+	avs AVSValidatorCaller,
+	podOwner common.Address,
+	candidates [][]byte,
+) ([][]byte, error) {
+	active := make([][]byte, 0, len(candidates))
+	for _, pubKey := range candidates {
+		info, err := avs.GetValidatorRegInfo(&bind.CallOpts{Context: ctx}, pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("getting registration of %x: %w", pubKey, err)
+		}
+		if !info.Exists {
+			continue
+		}
+		if podOwner != (common.Address{}) && info.PodOwner != podOwner {
+			continue
+		}
+		active = append(active, pubKey)
+	}
+	return active, nil
+}
+
+// AVSRegistrationFilterer is implemented by mevcommitavs.MevcommitavsFilterer.
+type AVSRegistrationFilterer interface {
+	FilterValidatorRegistered(opts *bind.FilterOpts, podOwner []common.Address) (*mevcommitavs.MevcommitavsValidatorRegisteredIterator, error)
+}
+
+// ValidatorsByPodOwners returns the pubkeys of the validators each of owners
+// registered with the AVS from fromBlock on, in registration order and
+// without duplicates. Owners that registered nothing are absent from the
+// result. Like ValidatorRegistered events, it includes validators that have
+// since deregistered; pass each owner's pubkeys to ActiveAVSValidators to
+// drop them.
+func ValidatorsByPodOwners(
+	ctx context.Context,
+	avs AVSRegistrationFilterer,
+	owners []common.Address,
+	fromBlock uint64,
+) (map[common.Address][][]byte, error) {
+	byOwner := make(map[common.Address][][]byte, len(owners))
+	if len(owners) == 0 {
+		return byOwner, nil
+	}
+	it, err := avs.FilterValidatorRegistered(&bind.FilterOpts{Start: fromBlock, Context: ctx}, owners)
+	if err != nil {
+		return nil, fmt.Errorf("filtering ValidatorRegistered events: %w", err)
+	}
+	registered, err := utils.CollectEvents(utils.IterEvents(it, func() *mevcommitavs.MevcommitavsValidatorRegistered {
+		return it.Event
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("iterating ValidatorRegistered events: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, event := range registered {
+		key := event.PodOwner.Hex() + string(event.ValidatorPubKey)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		byOwner[event.PodOwner] = append(byOwner[event.PodOwner], event.ValidatorPubKey)
+	}
+	return byOwner, nil
+}
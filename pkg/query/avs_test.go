@@ -0,0 +1,103 @@
+package query
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/mevcommitavs"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+)
+
+// fakeAVS returns the registration in regs for each pubkey, or a
+// non-existent one.
+type fakeAVS struct {
+	regs map[string]mevcommitavs.IMevCommitAVSValidatorRegistrationInfo
+}
+
+func (a *fakeAVS) GetValidatorRegInfo(opts *bind.CallOpts, valPubKey []byte) (mevcommitavs.IMevCommitAVSValidatorRegistrationInfo, error) {
+	return a.regs[string(valPubKey)], nil
+}
+
+func TestActiveAVSValidators(t *testing.T) {
+	owner, other := common.Address{1}, common.Address{2}
+	avs := &fakeAVS{regs: map[string]mevcommitavs.IMevCommitAVSValidatorRegistrationInfo{
+		"a": {Exists: true, PodOwner: owner},
+		"c": {Exists: true, PodOwner: other},
+		"d": {Exists: true, PodOwner: owner},
+	}}
+	candidates := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")}
+
+	for _, tc := range []struct {
+		podOwner common.Address
+		want     string
+	}{
+		{owner, "ad"},
+		{common.Address{}, "acd"},
+	} {
+		active, err := ActiveAVSValidators(context.Background(), avs, tc.podOwner, candidates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		for _, pubKey := range active {
+			got += string(pubKey)
+		}
+		if got != tc.want {
+			t.Errorf("pod owner %s: got active %q, want %q", tc.podOwner.Hex(), got, tc.want)
+		}
+	}
+}
+
+func TestValidatorsByPodOwners(t *testing.T) {
+	avsABI, err := mevcommitavs.MevcommitavsMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	avsAddress := common.HexToAddress("0xa1")
+	ownerA, ownerB, other := common.HexToAddress("0x0a"), common.HexToAddress("0x0b"), common.HexToAddress("0x0c")
+	registered := func(block uint64, pubKey string, owner common.Address) types.Log {
+		return testutil.EventLog(t, avsABI, avsAddress, "ValidatorRegistered", block, []byte(pubKey), owner)
+	}
+	backend := &testutil.LogFilterer{Logs: []types.Log{
+		registered(1, "early", ownerA),
+		registered(5, "a1", ownerA),
+		registered(6, "b1", ownerB),
+		registered(7, "c1", other),
+		registered(8, "a2", ownerA),
+		// Re-registered after deregistering.
+		registered(9, "a1", ownerA),
+	}}
+	filterer, err := mevcommitavs.NewMevcommitavsFilterer(avsAddress, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byOwner, err := ValidatorsByPodOwners(context.Background(), filterer, []common.Address{ownerA, ownerB}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byOwner) != 2 {
+		t.Fatalf("got validators of %d owners, want 2", len(byOwner))
+	}
+	for owner, want := range map[common.Address][]string{ownerA: {"a1", "a2"}, ownerB: {"b1"}} {
+		var got []string
+		for _, pubKey := range byOwner[owner] {
+			got = append(got, string(pubKey))
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("owner %s: got %q, want %q", owner.Hex(), got, want)
+		}
+	}
+
+	byOwner, err = ValidatorsByPodOwners(context.Background(), filterer, nil, 0)
+	if err != nil || len(byOwner) != 0 {
+		t.Errorf("got %v, %v for no owners, want an empty map", byOwner, err)
+	}
+	if len(backend.Queries) != 1 {
+		t.Errorf("got %d filter calls, want one for both owners", len(backend.Queries))
+	}
+}
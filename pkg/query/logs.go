@@ -0,0 +1,38 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+)
+
+// EventFilterer is implemented by registry.Registry.
+type EventFilterer interface {
+	Events(opts *bind.FilterOpts, eventType events.EventKind) ([]events.Event, error)
+}
+
+// StakedValidatorsFromLogs returns the validators staked per the registry's
+// Staked, Unstaked and Withdrawn logs from fromBlock on, in staking order,
+// i.e. by the block and log index of their stake event. It doesn't depend
+// on the registry's GetStakedValidators view function, so can cross-check
+// it. fromBlock must not be later than the registry's first Staked log, or
+// the result is incomplete.
+func StakedValidatorsFromLogs(ctx context.Context, filterer EventFilterer, fromBlock uint64) ([]StakedValidator, error) {
+	opts := &bind.FilterOpts{Start: fromBlock, Context: ctx}
+	byType := make(map[events.EventKind][]events.Event, len(events.EventKinds))
+	for _, eventType := range events.EventKinds {
+		e, err := filterer.Events(opts, eventType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter %s events: %w", eventType, err)
+		}
+		byType[eventType] = e
+	}
+	staked := events.Reconstruct(byType[events.EventStaked], byType[events.EventUnstaked], byType[events.EventWithdraw])
+	validators := make([]StakedValidator, 0, len(staked))
+	for pubKey, event := range staked {
+		validators = append(validators, StakedValidator{PubKey: pubKey, StakedBlock: event.Block, LogIndex: event.LogIndex})
+	}
+	return SortStakedValidators(validators, SortStaked), nil
+}
@@ -0,0 +1,91 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// fakeViewRegistry serves pubKeys from the GetStakedValidators view.
+type fakeViewRegistry struct {
+	pubKeys []string
+}
+
+func (r *fakeViewRegistry) GetNumberOfStakedValidators(*bind.CallOpts) (*big.Int, *big.Int, error) {
+	return big.NewInt(int64(len(r.pubKeys))), big.NewInt(1), nil
+}
+
+func (r *fakeViewRegistry) GetStakedValidators(_ *bind.CallOpts, start, end *big.Int) ([][]byte, *big.Int, error) {
+	var page [][]byte
+	for _, pubKey := range r.pubKeys[start.Int64():end.Int64()] {
+		page = append(page, common.Hex2Bytes(pubKey))
+	}
+	return page, big.NewInt(1), nil
+}
+
+func TestStakedValidatorsFromLogsMatchesView(t *testing.T) {
+	// aa, bb, cc and dd stake, then bb unstakes and the registry moves dd,
+	// its last validator, into bb's slot.
+	view := &fakeViewRegistry{pubKeys: []string{"aa", "dd", "cc"}}
+	logs := fakeFilterer{
+		events.EventStaked: {
+			events.NewEvent("0x01", "aa", big.NewInt(1), 1),
+			events.NewEvent("0x01", "bb", big.NewInt(1), 2),
+			events.NewEvent("0x02", "cc", big.NewInt(1), 3),
+			events.NewEvent("0x02", "dd", big.NewInt(1), 4),
+		},
+		events.EventUnstaked: {events.NewEvent("0x01", "bb", big.NewInt(1), 5)},
+	}
+
+	fromView, err := StakedValidatorsFromView(context.Background(), view, utils.GetStakedValidatorsOpts{BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromLogs, err := StakedValidatorsFromLogs(context.Background(), logs, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onlyInLogs, onlyInView := events.Diff(pubKeySet(fromLogs), pubKeySet(fromView))
+	if len(onlyInLogs) != 0 || len(onlyInView) != 0 {
+		t.Errorf("got %v only in logs and %v only in the view, want the same set", onlyInLogs, onlyInView)
+	}
+	if len(fromLogs) != 3 {
+		t.Errorf("got %d validators from logs, want 3", len(fromLogs))
+	}
+}
+
+func pubKeySet(validators []StakedValidator) map[string]struct{} {
+	set := make(map[string]struct{}, len(validators))
+	for _, validator := range validators {
+		set[validator.PubKey] = struct{}{}
+	}
+	return set
+}
+
+// failingFilterer fails to filter events of kind fail.
+type failingFilterer struct {
+	fakeFilterer
+	fail events.EventKind
+	err  error
+}
+
+func (f failingFilterer) Events(opts *bind.FilterOpts, eventType events.EventKind) ([]events.Event, error) {
+	if eventType == f.fail {
+		return nil, f.err
+	}
+	return f.fakeFilterer.Events(opts, eventType)
+}
+
+func TestStakedValidatorsFromLogsReturnsFilterError(t *testing.T) {
+	filterErr := errors.New("query returned more than 10000 results")
+	filterer := failingFilterer{fakeFilterer: fakeFilterer{}, fail: events.EventWithdraw, err: filterErr}
+	if _, err := StakedValidatorsFromLogs(context.Background(), filterer, 0); !errors.Is(err, filterErr) {
+		t.Errorf("got error %v, want %v", err, filterErr)
+	}
+}
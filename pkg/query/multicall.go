@@ -0,0 +1,56 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+	"github.com/primevprotocol/validator-registry/pkg/validatorregistryv1"
+)
+
+// AreStaked reports whether each of pubKeys is staked with the registry at
+// registry, in the order of pubKeys. It checks batchSize pubkeys per
+// eth_call through Multicall3 rather than calling isStaked once per pubkey.
+func AreStaked(
+	ctx context.Context,
+	caller bind.ContractCaller,
+	registry common.Address,
+	pubKeys [][]byte,
+	batchSize int,
+) ([]bool, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	registryABI, err := validatorregistryv1.Validatorregistryv1MetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registry abi: %w", err)
+	}
+
+	staked := make([]bool, 0, len(pubKeys))
+	for i := 0; i < len(pubKeys); i += batchSize {
+		batch := pubKeys[i:min(i+batchSize, len(pubKeys))]
+		calls := make([]utils.Call3, len(batch))
+		for j, pubKey := range batch {
+			data, err := registryABI.Pack("isStaked", pubKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to pack isStaked for %x: %w", pubKey, err)
+			}
+			calls[j] = utils.Call3{Target: registry, CallData: data}
+		}
+		results, err := utils.Multicall(ctx, caller, utils.Multicall3Address, calls)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check batch starting at %d: %w", i, err)
+		}
+		for j, result := range results {
+			out, err := registryABI.Unpack("isStaked", result.ReturnData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack isStaked for %x: %w", batch[j], err)
+			}
+			staked = append(staked, *abi.ConvertType(out[0], new(bool)).(*bool))
+		}
+	}
+	return staked, nil
+}
@@ -0,0 +1,38 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+)
+
+func TestAreStakedBatchesThroughMulticall(t *testing.T) {
+	registry := common.HexToAddress("0x47afdcB2B089C16CEe354811EA1Bbe0DB7c335E9")
+	caller := &testutil.StakedMulticall{T: t, Registry: registry, Staked: map[string]bool{"b": true, "e": true}}
+	pubKeys := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+
+	staked, err := AreStaked(context.Background(), caller, registry, pubKeys, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(caller.Calls) != 3 || caller.Calls[0] != 2 || caller.Calls[2] != 1 {
+		t.Errorf("got aggregates of sizes %v, want [2 2 1]", caller.Calls)
+	}
+	for i, want := range []bool{false, true, false, false, true} {
+		if staked[i] != want {
+			t.Errorf("pubkey %s staked = %v, want %v", pubKeys[i], staked[i], want)
+		}
+	}
+}
+
+func TestAreStakedRejectsNonPositiveBatchSize(t *testing.T) {
+	caller := &testutil.StakedMulticall{T: t}
+	if _, err := AreStaked(context.Background(), caller, common.Address{}, [][]byte{[]byte("a")}, 0); err == nil {
+		t.Fatal("expected an error for batch size 0")
+	}
+	if len(caller.Calls) != 0 {
+		t.Errorf("made %d calls, want none", len(caller.Calls))
+	}
+}
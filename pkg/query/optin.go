@@ -0,0 +1,86 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/primevprotocol/validator-registry/pkg/optins"
+	"github.com/primevprotocol/validator-registry/pkg/validatoroptinrouter"
+)
+
+// waitOptedInBatchSize is the number of pubkeys WaitOptedIn checks per
+// router call.
+const waitOptedInBatchSize = 50
+
+// OptedInStatus queries the router for the opt-in status of each pubkey,
+// batchSize pubkeys per call, returning statuses in the order of pubkeys.
+func OptedInStatus(
+	ctx context.Context,
+	router optins.RouterCaller,
+	pubkeys [][]byte,
+	batchSize int,
+) ([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, error) {
+	return OptedInStatusAt(ctx, router, pubkeys, batchSize, nil)
+}
+
+// OptedInStatusAt is like OptedInStatus but reads the router state as of
+// blockNumber, or the latest block if nil. Historical reads need an archive
+// node. batchSize must be positive.
+func OptedInStatusAt(
+	ctx context.Context,
+	router optins.RouterCaller,
+	pubkeys [][]byte,
+	batchSize int,
+	blockNumber *big.Int,
+) ([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	statuses := make([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, 0, len(pubkeys))
+	for i := 0; i < len(pubkeys); i += batchSize {
+		end := min(i+batchSize, len(pubkeys))
+		batch, err := router.AreValidatorsOptedIn(&bind.CallOpts{Context: ctx, BlockNumber: blockNumber}, pubkeys[i:end])
+		if err != nil {
+			return nil, fmt.Errorf("checking batch %d to %d: %w", i, end, err)
+		}
+		if len(batch) != end-i {
+			return nil, fmt.Errorf("router returned %d statuses for batch of %d", len(batch), end-i)
+		}
+		statuses = append(statuses, batch...)
+	}
+	return statuses, nil
+}
+
+// WaitOptedIn polls the router every poll until it reports every pubkey as
+// opted in, rechecking only those not yet opted in. It returns ctx's error,
+// wrapped with how many are still pending, if ctx is done first, so it
+// confirms staked validators were indexed by the router.
+func WaitOptedIn(ctx context.Context, router optins.RouterCaller, pubkeys [][]byte, poll time.Duration) error {
+	pending := pubkeys
+	for {
+		statuses, err := OptedInStatus(ctx, router, pending, waitOptedInBatchSize)
+		if err != nil {
+			return err
+		}
+		var still [][]byte
+		for i, status := range statuses {
+			if !optins.IsOptedIn(status) {
+				still = append(still, pending[i])
+			}
+		}
+		pending = still
+		if len(pending) == 0 {
+			return nil
+		}
+		fmt.Printf("%d of %d validators not opted in yet, polling again in %s\n", len(pending), len(pubkeys), poll)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d of %d validators not opted in: %w", len(pending), len(pubkeys), ctx.Err())
+		case <-time.After(poll):
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/primevprotocol/validator-registry/pkg/validatoroptinrouter"
+)
+
+// fakeRouter reports the pubkeys in optedIn as vanilla opted in and records
+// the size of each call.
+type fakeRouter struct {
+	optedIn map[string]bool
+	calls   []int
+}
+
+func (r *fakeRouter) AreValidatorsOptedIn(opts *bind.CallOpts, pubKeys [][]byte) ([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, error) {
+	r.calls = append(r.calls, len(pubKeys))
+	statuses := make([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		statuses[i].IsVanillaOptedIn = r.optedIn[string(pubKey)]
+	}
+	return statuses, nil
+}
+
+func TestOptedInStatusBatches(t *testing.T) {
+	router := &fakeRouter{optedIn: map[string]bool{"b": true, "e": true}}
+	pubKeys := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+
+	statuses, err := OptedInStatus(context.Background(), router, pubKeys, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(router.calls) != 3 || router.calls[2] != 1 {
+		t.Errorf("got calls of sizes %v, want [2 2 1]", router.calls)
+	}
+	for i, want := range []bool{false, true, false, false, true} {
+		if statuses[i].IsVanillaOptedIn != want {
+			t.Errorf("status %d opted in = %v, want %v", i, statuses[i].IsVanillaOptedIn, want)
+		}
+	}
+}
+
+func TestOptedInStatusRejectsNonPositiveBatchSize(t *testing.T) {
+	for _, batchSize := range []int{0, -1} {
+		router := &fakeRouter{}
+		if _, err := OptedInStatusAt(context.Background(), router, [][]byte{[]byte("a")}, batchSize, nil); err == nil {
+			t.Errorf("batch size %d accepted", batchSize)
+		}
+		if len(router.calls) != 0 {
+			t.Errorf("batch size %d made %d router calls", batchSize, len(router.calls))
+		}
+	}
+}
+
+// indexingRouter reports each pubkey in optedInFrom as opted in from that
+// call on, counting from 1, as if the router indexed it then.
+type indexingRouter struct {
+	fakeRouter
+	optedInFrom map[string]int
+}
+
+func (r *indexingRouter) AreValidatorsOptedIn(opts *bind.CallOpts, pubKeys [][]byte) ([]validatoroptinrouter.IValidatorOptInRouterOptInStatus, error) {
+	r.optedIn = map[string]bool{}
+	for pubKey, from := range r.optedInFrom {
+		r.optedIn[pubKey] = len(r.calls)+1 >= from
+	}
+	return r.fakeRouter.AreValidatorsOptedIn(opts, pubKeys)
+}
+
+func TestWaitOptedInPollsUntilAllOptedIn(t *testing.T) {
+	router := &indexingRouter{optedInFrom: map[string]int{"a": 1, "b": 2, "c": 3}}
+	pubKeys := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+
+	if err := WaitOptedIn(context.Background(), router, pubKeys, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(router.calls, []int{3, 2, 1}) {
+		t.Errorf("got calls of sizes %v, want [3 2 1] rechecking only pending pubkeys", router.calls)
+	}
+}
+
+func TestWaitOptedInGivesUpWhenContextDone(t *testing.T) {
+	router := &indexingRouter{optedInFrom: map[string]int{"a": 1}}
+	pubKeys := [][]byte{[]byte("a"), []byte("b")}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := WaitOptedIn(ctx, router, pubKeys, time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want context.DeadlineExceeded", err)
+	}
+	if len(router.calls) < 2 {
+		t.Errorf("polled %d times before giving up, want more than once", len(router.calls))
+	}
+}
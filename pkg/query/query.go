@@ -1,39 +1,65 @@
 package query
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
-	"log"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	utils "github.com/primevprotocol/validator-registry/pkg/utils"
 	vr "github.com/primevprotocol/validator-registry/pkg/validatorregistry"
 )
 
+const defaultRPCURL = "https://ethereum-holesky-rpc.publicnode.com"
+
+var registryAddress = common.HexToAddress("0x5d4fC7B5Aeea4CF4F0Ca6Be09A2F5AaDAd2F2803") // Holesky validator registry 6/13
+
+// RegistryCaller is implemented by *vr.ValidatorregistryCaller.
+type RegistryCaller interface {
+	utils.StakedValidatorsCaller
+	GetNumberOfStakedValidators(opts *bind.CallOpts) (*big.Int, *big.Int, error)
+}
+
+// GetAllStakedValsFromRegistry dials the default Holesky RPC endpoint and
+// returns the hex BLS pubkeys staked with the Holesky validator registry.
+// Callers with a client should use GetAllStakedVals.
 func GetAllStakedValsFromRegistry() ([]string, error) {
-	client, err := ethclient.Dial("https://ethereum-holesky-rpc.publicnode.com")
+	client, err := ethclient.Dial(defaultRPCURL)
 	if err != nil {
-		log.Fatalf("Failed to create eth client: %v", err)
+		return nil, fmt.Errorf("failed to create eth client: %w", err)
 	}
+	defer client.Close()
+	return GetAllStakedVals(context.Background(), client)
+}
 
-	contractAddress := common.HexToAddress("0x5d4fC7B5Aeea4CF4F0Ca6Be09A2F5AaDAd2F2803") // Holesky validator registry 6/13
-
-	vrc, err := vr.NewValidatorregistryCaller(contractAddress, client)
+// GetAllStakedVals returns the hex BLS pubkeys staked with the Holesky
+// validator registry, queried through client.
+func GetAllStakedVals(ctx context.Context, client bind.ContractCaller) ([]string, error) {
+	vrc, err := vr.NewValidatorregistryCaller(registryAddress, client)
 	if err != nil {
-		log.Fatalf("Failed to create Validator Registry caller: %v", err)
+		return nil, fmt.Errorf("failed to create Validator Registry caller: %w", err)
 	}
+	return GetAllStakedValsWithCaller(ctx, vrc)
+}
 
+// GetAllStakedValsWithCaller returns the hex BLS pubkeys staked with the
+// registry bound by vrc.
+func GetAllStakedValsWithCaller(ctx context.Context, vrc RegistryCaller) ([]string, error) {
 	fmt.Println("-------------------")
 	fmt.Println("Querying full set of validators BLS pubkeys staked with the registry contract...")
 	fmt.Println("-------------------")
 
-	numStakedVals, valsetVersion, err := vrc.GetNumberOfStakedValidators(nil)
+	numStakedVals, valsetVersion, err := vrc.GetNumberOfStakedValidators(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get number of staked validators: %w", err)
+	}
+	aggregatedValset, err := utils.GetStakedValidatorsWithOpts(ctx, vrc, numStakedVals, valsetVersion, utils.GetStakedValidatorsOpts{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get number of staked validators: %v", err)
+		return nil, err
 	}
-	aggregatedValset := utils.GetStakedValidators(vrc, numStakedVals, valsetVersion)
 
 	vals := make([]string, len(aggregatedValset))
 	for i, val := range aggregatedValset {
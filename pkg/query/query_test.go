@@ -0,0 +1,74 @@
+package query
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	vr "github.com/primevprotocol/validator-registry/pkg/validatorregistry"
+)
+
+// fakeRegistryClient answers the registry's staked validator views with
+// pubKeys, recording the contract each call targets.
+type fakeRegistryClient struct {
+	t       *testing.T
+	pubKeys [][]byte
+	targets []common.Address
+}
+
+func (f *fakeRegistryClient) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return []byte{0x01}, nil
+}
+
+func (f *fakeRegistryClient) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	f.t.Helper()
+	f.targets = append(f.targets, *msg.To)
+	registryABI, err := vr.ValidatorregistryMetaData.GetAbi()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	method, err := registryABI.MethodById(msg.Data[:4])
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	version := big.NewInt(3)
+	switch method.Name {
+	case "getNumberOfStakedValidators":
+		return method.Outputs.Pack(big.NewInt(int64(len(f.pubKeys))), version)
+	case "getStakedValidators":
+		args, err := method.Inputs.Unpack(msg.Data[4:])
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		start, end := args[0].(*big.Int).Int64(), args[1].(*big.Int).Int64()
+		return method.Outputs.Pack(f.pubKeys[start:end], version)
+	}
+	f.t.Fatalf("unexpected call of %s", method.Name)
+	return nil, nil
+}
+
+func TestGetAllStakedValsUsesGivenClient(t *testing.T) {
+	client := &fakeRegistryClient{t: t, pubKeys: [][]byte{{0xaa, 0x01}, {0xbb, 0x02}}}
+
+	vals, err := GetAllStakedVals(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{hex.EncodeToString([]byte{0xaa, 0x01}), hex.EncodeToString([]byte{0xbb, 0x02})}
+	if !slices.Equal(vals, want) {
+		t.Errorf("got %v, want %v", vals, want)
+	}
+	// Every call went through client, to the Holesky registry.
+	if len(client.targets) != 2 {
+		t.Fatalf("got %d calls through the client, want 2", len(client.targets))
+	}
+	for _, target := range client.targets {
+		if target != registryAddress {
+			t.Errorf("called %s, want the registry %s", target.Hex(), registryAddress.Hex())
+		}
+	}
+}
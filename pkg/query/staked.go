@@ -0,0 +1,139 @@
+package query
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/registry"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// StakedValidator is a staked validator and, if read from logs, the stake
+// event that staked it.
+type StakedValidator struct {
+	// PubKey is the hex BLS pubkey without 0x prefix.
+	PubKey string
+	// StakedBlock and LogIndex locate the validator's stake event. Both are
+	// zero for validators read from the GetStakedValidators view.
+	StakedBlock uint64
+	LogIndex    uint
+}
+
+// SortOrder is an order StakedValidators can be listed in.
+type SortOrder string
+
+const (
+	// SortStaked lists the earliest staked validators first, by the block
+	// and log index of their stake event.
+	SortStaked SortOrder = "staked"
+	// SortRecent lists the most recently staked validators first, by the
+	// block and log index of their stake event.
+	SortRecent SortOrder = "recent"
+	// SortPubKey lists validators by pubkey.
+	SortPubKey SortOrder = "pubkey"
+)
+
+// SortOrders lists every SortOrder.
+var SortOrders = []SortOrder{SortStaked, SortRecent, SortPubKey}
+
+// ParseSortOrder returns the SortOrder named s.
+func ParseSortOrder(s string) (SortOrder, error) {
+	order := SortOrder(s)
+	if !slices.Contains(SortOrders, order) {
+		return "", fmt.Errorf("unknown sort order %q, expected one of %v", s, SortOrders)
+	}
+	return order, nil
+}
+
+// StakedValidatorsFromView returns the validators staked with the registry
+// bound by vrc in the order its GetStakedValidators view lists them. The
+// registry moves its last validator into the slot of one that unstakes, so
+// that isn't staking order, and the view has no stake events to sort by.
+func StakedValidatorsFromView(ctx context.Context, vrc RegistryCaller, opts utils.GetStakedValidatorsOpts) ([]StakedValidator, error) {
+	numStakedVals, valsetVersion, err := vrc.GetNumberOfStakedValidators(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get number of staked validators: %w", err)
+	}
+	pubKeys, err := utils.GetStakedValidatorsWithOpts(ctx, vrc, numStakedVals, valsetVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	if numStakedVals.Cmp(big.NewInt(int64(len(pubKeys)))) != 0 {
+		return nil, fmt.Errorf("got %d staked validators, registry reports %v", len(pubKeys), numStakedVals)
+	}
+	validators := make([]StakedValidator, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		validators[i] = StakedValidator{PubKey: common.Bytes2Hex(pubKey)}
+	}
+	return validators, nil
+}
+
+// SortStakedValidators returns a copy of validators in order. Validators
+// staked by the same event are ordered by pubkey.
+func SortStakedValidators(validators []StakedValidator, order SortOrder) []StakedValidator {
+	sorted := slices.Clone(validators)
+	slices.SortFunc(sorted, func(a, b StakedValidator) int {
+		switch order {
+		case SortRecent:
+			a, b = b, a
+		case SortPubKey:
+			return strings.Compare(a.PubKey, b.PubKey)
+		}
+		return cmp.Or(
+			cmp.Compare(a.StakedBlock, b.StakedBlock),
+			cmp.Compare(a.LogIndex, b.LogIndex),
+			strings.Compare(a.PubKey, b.PubKey),
+		)
+	})
+	return sorted
+}
+
+// LimitStakedValidators returns the first limit validators, or all of them
+// if limit is not positive.
+func LimitStakedValidators(validators []StakedValidator, limit int) []StakedValidator {
+	if limit <= 0 || limit >= len(validators) {
+		return validators
+	}
+	return validators[:limit]
+}
+
+// PubKeys returns the pubkeys of validators, in order.
+func PubKeys(validators []StakedValidator) []string {
+	pubKeys := make([]string, len(validators))
+	for i, validator := range validators {
+		pubKeys[i] = validator.PubKey
+	}
+	return pubKeys
+}
+
+// RegistryDiff returns the hex BLS pubkeys staked with oldRegistry but not
+// newRegistry, i.e. the validators a migration still has to move, and those
+// staked only with the new one. Registries that cannot enumerate their
+// staked validators are reconstructed from their events. Both are sorted.
+func RegistryDiff(ctx context.Context, oldRegistry, newRegistry registry.Registry) (onlyInOld, onlyInNew []string, err error) {
+	oldValidators, err := registry.StakedPubKeys(ctx, oldRegistry)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read old registry: %w", err)
+	}
+	newValidators, err := registry.StakedPubKeys(ctx, newRegistry)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read new registry: %w", err)
+	}
+	onlyInOld, onlyInNew = events.Diff(stringSet(oldValidators), stringSet(newValidators))
+	return onlyInOld, onlyInNew, nil
+}
+
+func stringSet(pubKeys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(pubKeys))
+	for _, pubKey := range pubKeys {
+		set[pubKey] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,182 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/registry"
+)
+
+func testStakedValidators() []StakedValidator {
+	return []StakedValidator{
+		{PubKey: "cc", StakedBlock: 10, LogIndex: 2},
+		{PubKey: "aa", StakedBlock: 20, LogIndex: 0},
+		{PubKey: "bb", StakedBlock: 10, LogIndex: 7},
+		{PubKey: "dd", StakedBlock: 10, LogIndex: 2},
+	}
+}
+
+func TestSortStakedValidators(t *testing.T) {
+	for _, tc := range []struct {
+		order SortOrder
+		want  []string
+	}{
+		{SortStaked, []string{"cc", "dd", "bb", "aa"}},
+		{SortRecent, []string{"aa", "bb", "dd", "cc"}},
+		{SortPubKey, []string{"aa", "bb", "cc", "dd"}},
+	} {
+		validators := testStakedValidators()
+		got := PubKeys(SortStakedValidators(validators, tc.order))
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("by %s: got %v, want %v", tc.order, got, tc.want)
+		}
+		if validators[0].PubKey != "cc" {
+			t.Errorf("by %s: input reordered", tc.order)
+		}
+	}
+}
+
+func TestLimitStakedValidators(t *testing.T) {
+	validators := testStakedValidators()
+	for _, tc := range []struct {
+		limit, want int
+	}{
+		{0, 4}, {-1, 4}, {2, 2}, {4, 4}, {10, 4},
+	} {
+		if got := len(LimitStakedValidators(validators, tc.limit)); got != tc.want {
+			t.Errorf("limit %d: got %d validators, want %d", tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	if order, err := ParseSortOrder("recent"); err != nil || order != SortRecent {
+		t.Errorf("got %q, %v, want recent", order, err)
+	}
+	if _, err := ParseSortOrder("random"); err == nil {
+		t.Error("unknown order accepted")
+	}
+}
+
+// fakeFilterer serves events by kind.
+type fakeFilterer map[events.EventKind][]events.Event
+
+func (f fakeFilterer) Events(opts *bind.FilterOpts, eventType events.EventKind) ([]events.Event, error) {
+	return f[eventType], nil
+}
+
+func TestStakedValidatorsFromLogsInStakingOrder(t *testing.T) {
+	staked := func(pubKey string, block uint64, logIndex uint) events.Event {
+		event := events.NewEvent("0x01", pubKey, big.NewInt(1), block)
+		event.LogIndex = logIndex
+		return event
+	}
+	filterer := fakeFilterer{
+		events.EventStaked: {
+			staked("bb", 5, 9),
+			staked("aa", 5, 3),
+			staked("cc", 4, 12),
+			staked("dd", 6, 0),
+		},
+		events.EventUnstaked: {events.NewEvent("0x01", "dd", big.NewInt(1), 7)},
+	}
+
+	validators, err := StakedValidatorsFromLogs(context.Background(), filterer, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := PubKeys(validators); !slices.Equal(got, []string{"cc", "aa", "bb"}) {
+		t.Errorf("got %v, want [cc aa bb]", got)
+	}
+	if validators[1].StakedBlock != 5 || validators[1].LogIndex != 3 {
+		t.Errorf("got stake event %d/%d for aa, want 5/3", validators[1].StakedBlock, validators[1].LogIndex)
+	}
+}
+
+// fakeRegistry is a registry.Registry listing pubKeys, or failing with err.
+// Its embedded Registry is nil; only StakedValidators and Version are used.
+type fakeRegistry struct {
+	registry.Registry
+	pubKeys []string
+	err     error
+}
+
+func (r *fakeRegistry) Version() config.RegistryVersion { return config.RegistryOriginal }
+
+func (r *fakeRegistry) StakedValidators(context.Context) ([]string, error) {
+	return r.pubKeys, r.err
+}
+
+// eventRegistry is a registry.Registry that cannot enumerate its validators
+// and serves the events of fakeFilterer.
+type eventRegistry struct {
+	registry.Registry
+	fakeFilterer
+}
+
+func (r *eventRegistry) Version() config.RegistryVersion { return config.RegistryV1Aug15 }
+
+func (r *eventRegistry) StakedValidators(context.Context) ([]string, error) {
+	return nil, registry.ErrNotEnumerable
+}
+
+func (r *eventRegistry) Events(opts *bind.FilterOpts, eventType events.EventKind) ([]events.Event, error) {
+	return r.fakeFilterer.Events(opts, eventType)
+}
+
+func TestRegistryDiff(t *testing.T) {
+	oldRegistry := &fakeRegistry{pubKeys: []string{"dd", "aa", "bb", "cc"}}
+	newRegistry := &fakeRegistry{pubKeys: []string{"ee", "bb", "aa"}}
+
+	onlyInOld, onlyInNew, err := RegistryDiff(context.Background(), oldRegistry, newRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(onlyInOld, []string{"cc", "dd"}) {
+		t.Errorf("got %v only in the old registry, want [cc dd]", onlyInOld)
+	}
+	if !slices.Equal(onlyInNew, []string{"ee"}) {
+		t.Errorf("got %v only in the new registry, want [ee]", onlyInNew)
+	}
+}
+
+func TestRegistryDiffReconstructsNonEnumerableRegistry(t *testing.T) {
+	oldRegistry := &fakeRegistry{pubKeys: []string{"aa", "bb", "cc"}}
+	newRegistry := &eventRegistry{fakeFilterer: fakeFilterer{
+		events.EventStaked: {
+			events.NewEvent("0x01", "aa", big.NewInt(1), 1),
+			events.NewEvent("0x01", "bb", big.NewInt(1), 2),
+			events.NewEvent("0x01", "ee", big.NewInt(1), 3),
+		},
+		events.EventWithdraw: {events.NewEvent("0x01", "bb", big.NewInt(1), 4)},
+	}}
+
+	onlyInOld, onlyInNew, err := RegistryDiff(context.Background(), oldRegistry, newRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(onlyInOld, []string{"bb", "cc"}) {
+		t.Errorf("got %v only in the old registry, want [bb cc]", onlyInOld)
+	}
+	if !slices.Equal(onlyInNew, []string{"ee"}) {
+		t.Errorf("got %v only in the new registry, want [ee]", onlyInNew)
+	}
+}
+
+func TestRegistryDiffReturnsReadError(t *testing.T) {
+	callErr := errors.New("execution reverted")
+	reg := &fakeRegistry{pubKeys: []string{"aa"}}
+
+	if _, _, err := RegistryDiff(context.Background(), &fakeRegistry{err: callErr}, reg); !errors.Is(err, callErr) {
+		t.Errorf("got error %v reading the old registry, want %v", err, callErr)
+	}
+	if _, _, err := RegistryDiff(context.Background(), reg, &fakeRegistry{err: callErr}); !errors.Is(err, callErr) {
+		t.Errorf("got error %v reading the new registry, want %v", err, callErr)
+	}
+}
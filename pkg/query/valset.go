@@ -0,0 +1,68 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// ValsetVersionCaller is the subset of the validator registry caller needed
+// to read the valset version.
+type ValsetVersionCaller interface {
+	GetNumberOfStakedValidators(opts *bind.CallOpts) (*big.Int, *big.Int, error)
+}
+
+// ValsetVersion is a snapshot of the registry's valset version and size.
+type ValsetVersion struct {
+	Version       *big.Int
+	NumStakedVals *big.Int
+}
+
+// GetValsetVersion reads the current valset version from the registry.
+func GetValsetVersion(ctx context.Context, caller ValsetVersionCaller) (ValsetVersion, error) {
+	numStakedVals, version, err := caller.GetNumberOfStakedValidators(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return ValsetVersion{}, fmt.Errorf("failed to get number of staked validators: %w", err)
+	}
+	return ValsetVersion{Version: version, NumStakedVals: numStakedVals}, nil
+}
+
+// StakedCountAtBlock returns the number of validators staked with the
+// registry as of blockNumber, or the latest block if nil, so growth can be
+// charted by sampling historical blocks. Historical reads need an archive
+// node.
+func StakedCountAtBlock(ctx context.Context, caller ValsetVersionCaller, blockNumber *big.Int) (*big.Int, error) {
+	numStakedVals, _, err := caller.GetNumberOfStakedValidators(&bind.CallOpts{Context: ctx, BlockNumber: blockNumber})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get number of staked validators at block %v: %w", blockNumber, err)
+	}
+	return numStakedVals, nil
+}
+
+// WatchValsetVersion polls the valset version every interval, starting from
+// prev, and calls onChange whenever it differs from the last one seen. It
+// returns when ctx is done or a read or onChange fails.
+func WatchValsetVersion(ctx context.Context, caller ValsetVersionCaller, prev ValsetVersion, interval time.Duration, onChange func(prev, cur ValsetVersion) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		cur, err := GetValsetVersion(ctx, caller)
+		if err != nil {
+			return err
+		}
+		if prev.Version == nil || cur.Version.Cmp(prev.Version) != 0 {
+			if err := onChange(prev, cur); err != nil {
+				return err
+			}
+		}
+		prev = cur
+	}
+}
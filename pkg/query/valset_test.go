@@ -0,0 +1,119 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// fakeValsetCaller returns the versions in order, repeating the last one,
+// with a staked count of ten times the version.
+type fakeValsetCaller struct {
+	versions []int64
+	calls    int
+}
+
+func (f *fakeValsetCaller) GetNumberOfStakedValidators(*bind.CallOpts) (*big.Int, *big.Int, error) {
+	version := f.versions[min(f.calls, len(f.versions)-1)]
+	f.calls++
+	return big.NewInt(10 * version), big.NewInt(version), nil
+}
+
+func TestGetValsetVersion(t *testing.T) {
+	got, err := GetValsetVersion(context.Background(), &fakeValsetCaller{versions: []int64{3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version.Int64() != 3 || got.NumStakedVals.Int64() != 30 {
+		t.Errorf("got version %v with %v staked, want 3 with 30", got.Version, got.NumStakedVals)
+	}
+}
+
+func TestWatchValsetVersionReportsChanges(t *testing.T) {
+	caller := &fakeValsetCaller{versions: []int64{1, 1, 2, 2, 3}}
+	prev, err := GetValsetVersion(context.Background(), caller)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var changes [][2]int64
+	err = WatchValsetVersion(ctx, caller, prev, time.Millisecond, func(prev, cur ValsetVersion) error {
+		changes = append(changes, [2]int64{prev.Version.Int64(), cur.Version.Int64()})
+		if cur.Version.Int64() == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if len(changes) != 2 || changes[0] != [2]int64{1, 2} || changes[1] != [2]int64{2, 3} {
+		t.Errorf("got changes %v, want [[1 2] [2 3]]", changes)
+	}
+}
+
+func TestWatchValsetVersionReturnsOnChangeError(t *testing.T) {
+	stop := errors.New("stop")
+	err := WatchValsetVersion(context.Background(), &fakeValsetCaller{versions: []int64{1}}, ValsetVersion{}, time.Millisecond, func(prev, cur ValsetVersion) error {
+		if prev.Version != nil {
+			t.Errorf("got previous version %v, want nil for the first read", prev.Version)
+		}
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("got error %v, want %v", err, stop)
+	}
+}
+
+// historicalCounts serves staked counts by block and records the block
+// number of each call, nil for the latest block.
+type historicalCounts struct {
+	counts map[int64]int64
+	blocks []*big.Int
+}
+
+func (h *historicalCounts) GetNumberOfStakedValidators(opts *bind.CallOpts) (*big.Int, *big.Int, error) {
+	h.blocks = append(h.blocks, opts.BlockNumber)
+	if opts.BlockNumber == nil {
+		return big.NewInt(h.counts[-1]), big.NewInt(1), nil
+	}
+	count, ok := h.counts[opts.BlockNumber.Int64()]
+	if !ok {
+		return nil, nil, errors.New("missing trie node")
+	}
+	return big.NewInt(count), big.NewInt(1), nil
+}
+
+func TestStakedCountAtBlock(t *testing.T) {
+	caller := &historicalCounts{counts: map[int64]int64{100: 5, 200: 12, -1: 20}}
+
+	for _, tc := range []struct {
+		block *big.Int
+		want  int64
+	}{
+		{big.NewInt(100), 5},
+		{big.NewInt(200), 12},
+		{nil, 20},
+	} {
+		got, err := StakedCountAtBlock(context.Background(), caller, tc.block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Int64() != tc.want {
+			t.Errorf("got %v staked at block %v, want %d", got, tc.block, tc.want)
+		}
+		if last := caller.blocks[len(caller.blocks)-1]; last != tc.block {
+			t.Errorf("called at block %v, want %v", last, tc.block)
+		}
+	}
+
+	if _, err := StakedCountAtBlock(context.Background(), caller, big.NewInt(50)); err == nil {
+		t.Error("got no error for a block the node has no state for")
+	}
+}
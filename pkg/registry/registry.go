@@ -0,0 +1,250 @@
+// Package registry reads validator registry deployments of any version
+// through one interface, so reconstruction and validation can target
+// either side of a migration.
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+	vr "github.com/primevprotocol/validator-registry/pkg/validatorregistry"
+	vrv1 "github.com/primevprotocol/validator-registry/pkg/validatorregistryv1"
+	vrv1_aug15 "github.com/primevprotocol/validator-registry/pkg/validatorregistryv1_aug15"
+)
+
+// ErrNotEnumerable is returned by StakedValidators for registries that
+// cannot list their staked validators.
+var ErrNotEnumerable = errors.New("registry cannot enumerate staked validators")
+
+// Registry is a validator registry deployment.
+type Registry interface {
+	Version() config.RegistryVersion
+	// Events returns the events of eventType in the range of opts.
+	Events(opts *bind.FilterOpts, eventType events.EventKind) ([]events.Event, error)
+	// StakedValidators returns the currently staked validators' hex BLS
+	// pubkeys, or ErrNotEnumerable.
+	StakedValidators(ctx context.Context) ([]string, error)
+}
+
+// New binds the registry of version at address.
+func New(version config.RegistryVersion, address common.Address, backend bind.ContractBackend) (Registry, error) {
+	switch version {
+	case config.RegistryOriginal:
+		r, err := vr.NewValidatorregistry(address, backend)
+		if err != nil {
+			return nil, fmt.Errorf("failed to bind %s registry: %w", version, err)
+		}
+		return &original{r}, nil
+	case config.RegistryV1:
+		r, err := vrv1.NewValidatorregistryv1(address, backend)
+		if err != nil {
+			return nil, fmt.Errorf("failed to bind %s registry: %w", version, err)
+		}
+		return &v1{r}, nil
+	case config.RegistryV1Aug15:
+		r, err := vrv1_aug15.NewValidatorregistryv1(address, backend)
+		if err != nil {
+			return nil, fmt.Errorf("failed to bind %s registry: %w", version, err)
+		}
+		return &v1Aug15{r}, nil
+	}
+	return nil, fmt.Errorf("unknown registry version %q", version)
+}
+
+// ForNetwork binds the registry of version configured for network.
+func ForNetwork(network config.Network, version config.RegistryVersion, backend bind.ContractBackend) (Registry, error) {
+	address, err := network.RegistryAddress(version)
+	if err != nil {
+		return nil, err
+	}
+	return New(version, address, backend)
+}
+
+// StakedPubKeys returns the hex BLS pubkeys currently staked with reg. For
+// registries that are not enumerable it replays all of reg's staked,
+// unstaked and withdrawn events instead.
+func StakedPubKeys(ctx context.Context, reg Registry) ([]string, error) {
+	pubKeys, err := reg.StakedValidators(ctx)
+	if !errors.Is(err, ErrNotEnumerable) {
+		return pubKeys, err
+	}
+	slog.Info("registry cannot enumerate its staked validators, reconstructing them from events", "version", reg.Version())
+	opts := &bind.FilterOpts{Start: 0, End: nil, Context: ctx}
+	var all [3][]events.Event
+	for i, kind := range []events.EventKind{events.EventStaked, events.EventUnstaked, events.EventWithdraw} {
+		if all[i], err = reg.Events(opts, kind); err != nil {
+			return nil, err
+		}
+	}
+	staked := events.Reconstruct(all[0], all[1], all[2])
+	pubKeys = make([]string, 0, len(staked))
+	for pubKey := range staked {
+		pubKeys = append(pubKeys, pubKey)
+	}
+	return pubKeys, nil
+}
+
+// drain collects events from iter, converting each with current.
+func drain(iter utils.BoundIterator, current func() events.Event) ([]events.Event, error) {
+	e, err := utils.CollectEvents(utils.IterEvents(iter, current))
+	if err != nil {
+		return nil, fmt.Errorf("error encountered during iteration: %w", err)
+	}
+	return e, nil
+}
+
+func newEvent(originator common.Address, pubKey []byte, amount *big.Int, raw types.Log) events.Event {
+	event := events.NewEvent(originator.Hex(), common.Bytes2Hex(pubKey), amount, raw.BlockNumber)
+	event.LogIndex = raw.Index
+	return event
+}
+
+func enumerate(ctx context.Context, caller interface {
+	utils.StakedValidatorsCaller
+	GetNumberOfStakedValidators(opts *bind.CallOpts) (*big.Int, *big.Int, error)
+}) ([]string, error) {
+	numStakedVals, valsetVersion, err := caller.GetNumberOfStakedValidators(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get number of staked validators: %w", err)
+	}
+	vals, err := utils.GetStakedValidatorsWithOpts(ctx, caller, numStakedVals, valsetVersion, utils.GetStakedValidatorsOpts{})
+	if err != nil {
+		return nil, err
+	}
+	pubKeys := make([]string, len(vals))
+	for i, val := range vals {
+		pubKeys[i] = common.Bytes2Hex(val)
+	}
+	return pubKeys, nil
+}
+
+type original struct {
+	r *vr.Validatorregistry
+}
+
+func (o *original) Version() config.RegistryVersion { return config.RegistryOriginal }
+
+func (o *original) Events(opts *bind.FilterOpts, eventType events.EventKind) ([]events.Event, error) {
+	switch eventType {
+	case events.EventStaked:
+		iter, err := o.r.FilterStaked(opts, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get staked events: %w", err)
+		}
+		return drain(iter, func() events.Event {
+			return newEvent(iter.Event.TxOriginator, iter.Event.ValBLSPubKey, iter.Event.Amount, iter.Event.Raw)
+		})
+	case events.EventUnstaked:
+		iter, err := o.r.FilterUnstaked(opts, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get unstaked events: %w", err)
+		}
+		return drain(iter, func() events.Event {
+			return newEvent(iter.Event.TxOriginator, iter.Event.ValBLSPubKey, iter.Event.Amount, iter.Event.Raw)
+		})
+	case events.EventWithdraw:
+		iter, err := o.r.FilterStakeWithdrawn(opts, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get withdraw events: %w", err)
+		}
+		return drain(iter, func() events.Event {
+			return newEvent(iter.Event.TxOriginator, iter.Event.ValBLSPubKey, iter.Event.Amount, iter.Event.Raw)
+		})
+	}
+	return nil, fmt.Errorf("unknown event type: %s", eventType)
+}
+
+func (o *original) StakedValidators(ctx context.Context) ([]string, error) {
+	return enumerate(ctx, &o.r.ValidatorregistryCaller)
+}
+
+type v1 struct {
+	r *vrv1.Validatorregistryv1
+}
+
+func (v *v1) Version() config.RegistryVersion { return config.RegistryV1 }
+
+func (v *v1) Events(opts *bind.FilterOpts, eventType events.EventKind) ([]events.Event, error) {
+	switch eventType {
+	case events.EventStaked:
+		iter, err := v.r.FilterStaked(opts, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get staked events: %w", err)
+		}
+		return drain(iter, func() events.Event {
+			return newEvent(iter.Event.TxOriginator, iter.Event.ValBLSPubKey, iter.Event.Amount, iter.Event.Raw)
+		})
+	case events.EventUnstaked:
+		iter, err := v.r.FilterUnstaked(opts, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get unstaked events: %w", err)
+		}
+		return drain(iter, func() events.Event {
+			return newEvent(iter.Event.TxOriginator, iter.Event.ValBLSPubKey, iter.Event.Amount, iter.Event.Raw)
+		})
+	case events.EventWithdraw:
+		iter, err := v.r.FilterStakeWithdrawn(opts, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get withdraw events: %w", err)
+		}
+		return drain(iter, func() events.Event {
+			return newEvent(iter.Event.TxOriginator, iter.Event.ValBLSPubKey, iter.Event.Amount, iter.Event.Raw)
+		})
+	}
+	return nil, fmt.Errorf("unknown event type: %s", eventType)
+}
+
+func (v *v1) StakedValidators(ctx context.Context) ([]string, error) {
+	return enumerate(ctx, &v.r.Validatorregistryv1Caller)
+}
+
+// v1Aug15 events carry the staking msg.sender, which is recorded as the
+// event's originator.
+type v1Aug15 struct {
+	r *vrv1_aug15.Validatorregistryv1
+}
+
+func (v *v1Aug15) Version() config.RegistryVersion { return config.RegistryV1Aug15 }
+
+func (v *v1Aug15) Events(opts *bind.FilterOpts, eventType events.EventKind) ([]events.Event, error) {
+	switch eventType {
+	case events.EventStaked:
+		iter, err := v.r.FilterStaked(opts, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get staked events: %w", err)
+		}
+		return drain(iter, func() events.Event {
+			return newEvent(iter.Event.MsgSender, iter.Event.ValBLSPubKey, iter.Event.Amount, iter.Event.Raw)
+		})
+	case events.EventUnstaked:
+		iter, err := v.r.FilterUnstaked(opts, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get unstaked events: %w", err)
+		}
+		return drain(iter, func() events.Event {
+			return newEvent(iter.Event.MsgSender, iter.Event.ValBLSPubKey, iter.Event.Amount, iter.Event.Raw)
+		})
+	case events.EventWithdraw:
+		iter, err := v.r.FilterStakeWithdrawn(opts, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get withdraw events: %w", err)
+		}
+		return drain(iter, func() events.Event {
+			return newEvent(iter.Event.MsgSender, iter.Event.ValBLSPubKey, iter.Event.Amount, iter.Event.Raw)
+		})
+	}
+	return nil, fmt.Errorf("unknown event type: %s", eventType)
+}
+
+func (v *v1Aug15) StakedValidators(ctx context.Context) ([]string, error) {
+	return nil, ErrNotEnumerable
+}
@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/config"
+	"github.com/primevprotocol/validator-registry/pkg/events"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+	vr "github.com/primevprotocol/validator-registry/pkg/validatorregistry"
+	vrv1 "github.com/primevprotocol/validator-registry/pkg/validatorregistryv1"
+	vrv1_aug15 "github.com/primevprotocol/validator-registry/pkg/validatorregistryv1_aug15"
+)
+
+// logBackend serves logs; its embedded ContractBackend is nil, so only log
+// filtering works.
+type logBackend struct {
+	bind.ContractBackend
+	logs *testutil.LogFilterer
+}
+
+func (b logBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return b.logs.FilterLogs(ctx, q)
+}
+
+var (
+	testRegistry   = common.HexToAddress("0xaa")
+	testOriginator = common.HexToAddress("0x01")
+	testWithdrawal = common.HexToAddress("0x02")
+	testPubKey     = []byte{0xab, 0xcd}
+)
+
+func TestEventsForEachVersion(t *testing.T) {
+	tests := []struct {
+		version  config.RegistryVersion
+		metadata *bind.MetaData
+		// args are the event arguments before the pubkey and amount.
+		args []any
+	}{
+		{config.RegistryOriginal, vr.ValidatorregistryMetaData, []any{testOriginator}},
+		{config.RegistryV1, vrv1.Validatorregistryv1MetaData, []any{testOriginator}},
+		{config.RegistryV1Aug15, vrv1_aug15.Validatorregistryv1MetaData, []any{testOriginator, testWithdrawal}},
+	}
+	kinds := map[events.EventKind]string{
+		events.EventStaked:   "Staked",
+		events.EventUnstaked: "Unstaked",
+		events.EventWithdraw: "StakeWithdrawn",
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.version), func(t *testing.T) {
+			contractABI, err := tt.metadata.GetAbi()
+			if err != nil {
+				t.Fatal(err)
+			}
+			logs := &testutil.LogFilterer{}
+			for kind, name := range kinds {
+				log := testutil.EventLog(t, contractABI, testRegistry, name, 10, append(tt.args, testPubKey, big.NewInt(32))...)
+				log.Index = uint(len(kind))
+				logs.Logs = append(logs.Logs, log)
+			}
+			reg, err := New(tt.version, testRegistry, logBackend{logs: logs})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reg.Version() != tt.version {
+				t.Errorf("got version %s, want %s", reg.Version(), tt.version)
+			}
+
+			end := uint64(10)
+			for kind := range kinds {
+				got, err := reg.Events(&bind.FilterOpts{Start: 0, End: &end}, kind)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 1 {
+					t.Fatalf("got %d %s events, want 1", len(got), kind)
+				}
+				event := got[0]
+				if event.TxOriginator != testOriginator.Hex() || event.ValBLSPubKey != "abcd" || event.Amount.Int64() != 32 ||
+					event.Block != 10 || event.LogIndex != uint(len(kind)) {
+					t.Errorf("got %s event %+v", kind, event)
+				}
+			}
+			if _, err := reg.Events(&bind.FilterOpts{End: &end}, events.EventKind("slashed")); err == nil {
+				t.Error("got no error for an unknown event kind")
+			}
+		})
+	}
+}
+
+func TestNewUnknownVersion(t *testing.T) {
+	if _, err := New("v9", testRegistry, logBackend{}); err == nil {
+		t.Error("got no error for an unknown registry version")
+	}
+}
+
+func TestV1Aug15IsNotEnumerable(t *testing.T) {
+	reg, err := New(config.RegistryV1Aug15, testRegistry, logBackend{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.StakedValidators(context.Background()); !errors.Is(err, ErrNotEnumerable) {
+		t.Errorf("got error %v, want ErrNotEnumerable", err)
+	}
+}
+
+func TestStakedPubKeysReconstructsFromEvents(t *testing.T) {
+	contractABI, err := vrv1_aug15.Validatorregistryv1MetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := &testutil.LogFilterer{}
+	for _, e := range []struct {
+		name   string
+		pubKey []byte
+	}{
+		{"Staked", []byte{0xaa}},
+		{"Staked", []byte{0xbb}},
+		{"Unstaked", []byte{0xbb}},
+	} {
+		logs.Logs = append(logs.Logs, testutil.EventLog(t, contractABI, testRegistry, e.name, 10, testOriginator, testWithdrawal, e.pubKey, big.NewInt(32)))
+	}
+	reg, err := New(config.RegistryV1Aug15, testRegistry, logBackend{logs: logs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubKeys, err := StakedPubKeys(context.Background(), reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pubKeys) != 1 || pubKeys[0] != "aa" {
+		t.Errorf("got staked pubkeys %v, want [aa]", pubKeys)
+	}
+}
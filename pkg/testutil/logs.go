@@ -0,0 +1,95 @@
+package testutil
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// LogFilterer is a bind.ContractFilterer serving Logs to FilterLogs calls,
+// matching them on address, block range and topics as a node would.
+type LogFilterer struct {
+	mu   sync.Mutex
+	Logs []types.Log
+	// Queries records every FilterLogs call.
+	Queries []ethereum.FilterQuery
+}
+
+func (f *LogFilterer) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Queries = append(f.Queries, q)
+	matched := []types.Log{}
+	for _, log := range f.Logs {
+		if matchLog(q, log) {
+			matched = append(matched, log)
+		}
+	}
+	return matched, nil
+}
+
+func (f *LogFilterer) SubscribeFilterLogs(context.Context, ethereum.FilterQuery, chan<- types.Log) (ethereum.Subscription, error) {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	}), nil
+}
+
+func matchLog(q ethereum.FilterQuery, log types.Log) bool {
+	if len(q.Addresses) > 0 && !slices.Contains(q.Addresses, log.Address) {
+		return false
+	}
+	if q.FromBlock != nil && log.BlockNumber < q.FromBlock.Uint64() {
+		return false
+	}
+	if q.ToBlock != nil && log.BlockNumber > q.ToBlock.Uint64() {
+		return false
+	}
+	for i, options := range q.Topics {
+		if len(options) == 0 {
+			continue
+		}
+		if i >= len(log.Topics) || !slices.Contains(options, log.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// EventLog builds the log contract emits in block for the event name of
+// contractABI, with args given in the order the event declares them.
+func EventLog(t testing.TB, contractABI *abi.ABI, contract common.Address, name string, block uint64, args ...any) types.Log {
+	t.Helper()
+	ev, ok := contractABI.Events[name]
+	if !ok {
+		t.Fatalf("ABI has no event %s", name)
+	}
+	if len(args) != len(ev.Inputs) {
+		t.Fatalf("event %s takes %d arguments, got %d", name, len(ev.Inputs), len(args))
+	}
+	topics := []common.Hash{ev.ID}
+	var nonIndexed []any
+	for i, input := range ev.Inputs {
+		if !input.Indexed {
+			nonIndexed = append(nonIndexed, args[i])
+			continue
+		}
+		indexed, err := abi.MakeTopics([]any{args[i]})
+		if err != nil {
+			t.Fatalf("encoding topic %s of event %s: %v", input.Name, name, err)
+		}
+		topics = append(topics, indexed[0][0])
+	}
+	data, err := ev.Inputs.NonIndexed().Pack(nonIndexed...)
+	if err != nil {
+		t.Fatalf("encoding data of event %s: %v", name, err)
+	}
+	return types.Log{Address: contract, Topics: topics, Data: data, BlockNumber: block}
+}
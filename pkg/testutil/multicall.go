@@ -0,0 +1,70 @@
+package testutil
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+	"github.com/primevprotocol/validator-registry/pkg/validatorregistryv1"
+)
+
+// StakedMulticall is a bind.ContractCaller answering Multicall3 aggregate3
+// calls of isStaked on Registry, reporting the pubkeys in Staked as staked.
+// It records the number of isStaked calls in each aggregate in Calls.
+type StakedMulticall struct {
+	T        testing.TB
+	Registry common.Address
+	Staked   map[string]bool
+	Calls    []int
+}
+
+func (f *StakedMulticall) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return []byte{0x01}, nil
+}
+
+func (f *StakedMulticall) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	f.T.Helper()
+	if msg.To == nil || *msg.To != utils.Multicall3Address {
+		f.T.Fatalf("called %v, want multicall3", msg.To)
+	}
+	callTuple, _ := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
+		{Name: "target", Type: "address"},
+		{Name: "allowFailure", Type: "bool"},
+		{Name: "callData", Type: "bytes"},
+	})
+	resultTuple, _ := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{
+		{Name: "success", Type: "bool"},
+		{Name: "returnData", Type: "bytes"},
+	})
+	in, err := abi.Arguments{{Type: callTuple}}.Unpack(msg.Data[4:])
+	if err != nil {
+		f.T.Fatal(err)
+	}
+	calls := *abi.ConvertType(in[0], new([]utils.Call3)).(*[]utils.Call3)
+	f.Calls = append(f.Calls, len(calls))
+
+	registryABI, err := validatorregistryv1.Validatorregistryv1MetaData.GetAbi()
+	if err != nil {
+		f.T.Fatal(err)
+	}
+	results := make([]utils.Result, len(calls))
+	for i, call := range calls {
+		if call.Target != f.Registry {
+			f.T.Fatalf("call %d targets %s, want the registry", i, call.Target)
+		}
+		args, err := registryABI.Methods["isStaked"].Inputs.Unpack(call.CallData[4:])
+		if err != nil {
+			f.T.Fatal(err)
+		}
+		out, err := registryABI.Methods["isStaked"].Outputs.Pack(f.Staked[string(args[0].([]byte))])
+		if err != nil {
+			f.T.Fatal(err)
+		}
+		results[i] = utils.Result{Success: true, ReturnData: out}
+	}
+	return abi.Arguments{{Type: resultTuple}}.Pack(results)
+}
@@ -0,0 +1,91 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Tracer is a trace.Tracer recording the spans it starts. It is safe for
+// concurrent use.
+type Tracer struct {
+	noop.Tracer
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// Span is a span started by Tracer.
+type Span struct {
+	noop.Span
+	mu         sync.Mutex
+	name       string
+	attributes []attribute.KeyValue
+	status     codes.Code
+	ended      bool
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	span := &Span{name: name, attributes: cfg.Attributes()}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// Spans returns the spans started so far, in start order.
+func (t *Tracer) Spans() []*Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Span(nil), t.spans...)
+}
+
+func (s *Span) SetAttributes(attrs ...attribute.KeyValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attrs...)
+}
+
+func (s *Span) SetStatus(code codes.Code, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+}
+
+func (s *Span) End(...trace.SpanEndOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = true
+}
+
+func (s *Span) Name() string {
+	return s.name
+}
+
+// Attribute returns the last value set for key, and whether it was set.
+func (s *Span) Attribute(key attribute.Key) (attribute.Value, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.attributes) - 1; i >= 0; i-- {
+		if s.attributes[i].Key == key {
+			return s.attributes[i].Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func (s *Span) Status() codes.Code {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (s *Span) Ended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ended
+}
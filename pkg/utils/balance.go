@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ErrInsufficientBalance is returned by EnsureMinBalance when Address holds
+// less than Need wei.
+type ErrInsufficientBalance struct {
+	Address common.Address
+	Have    *big.Int
+	Need    *big.Int
+}
+
+func (e *ErrInsufficientBalance) Error() string {
+	return fmt.Sprintf("insufficient balance: %s holds %s ETH, please fund it with at least %s ETH",
+		e.Address.Hex(), FormatEther(e.Have), FormatEther(e.Need))
+}
+
+// FormatEther formats wei as a decimal amount of ETH without trailing zeros,
+// e.g. 3.1 for 3100000000000000000.
+func FormatEther(wei *big.Int) string {
+	ether := new(big.Rat).SetFrac(wei, big.NewInt(params.Ether))
+	s := strings.TrimRight(ether.FloatString(18), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// EnsureMinBalance returns an *ErrInsufficientBalance if addr holds less
+// than need wei.
+func EnsureMinBalance(ctx context.Context, client BalanceReader, addr common.Address, need *big.Int) error {
+	balance, err := BalanceAtWithRetry(ctx, client, addr, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get balance of %s: %w", addr.Hex(), err)
+	}
+	if balance.Cmp(need) < 0 {
+		return &ErrInsufficientBalance{Address: addr, Have: balance, Need: need}
+	}
+	return nil
+}
+
+type BalanceReader interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+// WaitForMinBalance polls the balance of addr every poll interval, timed by
+// the retry clock, until it reaches minBalance or ctx is done.
+func WaitForMinBalance(
+	ctx context.Context,
+	client BalanceReader,
+	addr common.Address,
+	minBalance *big.Int,
+	poll time.Duration,
+) error {
+	for {
+		balance, err := client.BalanceAt(ctx, addr, nil)
+		if err != nil {
+			return fmt.Errorf("failed to get balance of %s: %w", addr.Hex(), err)
+		}
+		if balance.Cmp(minBalance) >= 0 {
+			slog.Info("balance reached", "address", addr.Hex(), "balance", balance.String())
+			return nil
+		}
+		slog.Info("waiting for balance", "address", addr.Hex(), "balance", balance.String(), "min", minBalance.String())
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", &ErrInsufficientBalance{Address: addr, Have: balance, Need: minBalance}, ctx.Err())
+		case <-retryClock.After(poll):
+		}
+	}
+}
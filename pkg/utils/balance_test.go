@@ -0,0 +1,98 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// fakeBalances returns successive balances, repeating the last one.
+type fakeBalances struct {
+	balances []int64
+	calls    int
+}
+
+func (f *fakeBalances) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	balance := f.balances[min(f.calls, len(f.balances)-1)]
+	f.calls++
+	return big.NewInt(balance), nil
+}
+
+func TestWaitForMinBalancePollsUntilReached(t *testing.T) {
+	clock := useFakeRetryClock(t)
+	client := &fakeBalances{balances: []int64{1, 5, 10}}
+	done := make(chan error, 1)
+	go func() {
+		done <- utils.WaitForMinBalance(context.Background(), client, common.Address{1}, big.NewInt(10), 10*time.Second)
+	}()
+	for range 2 {
+		waitForWaiter(t, clock)
+		clock.Advance(10 * time.Second)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if client.calls != 3 {
+		t.Errorf("polled %d times, want 3", client.calls)
+	}
+}
+
+func TestWaitForMinBalanceReportsShortfallOnTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := utils.WaitForMinBalance(ctx, &fakeBalances{balances: []int64{4}}, common.Address{1}, big.NewInt(10), time.Millisecond)
+
+	var insufficient *utils.ErrInsufficientBalance
+	if !errors.As(err, &insufficient) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want insufficient balance wrapping the deadline", err)
+	}
+	if insufficient.Have.Int64() != 4 || insufficient.Need.Int64() != 10 {
+		t.Errorf("got have %s need %s, want 4 and 10", insufficient.Have, insufficient.Need)
+	}
+}
+
+func TestEnsureMinBalance(t *testing.T) {
+	addr := common.HexToAddress("0x4535bd6ff24860b5fd2889857651a85fb3d3c6b1")
+	need := new(big.Int).Mul(big.NewInt(3), big.NewInt(params.Ether))
+	have := big.NewInt(25 * params.Ether / 100)
+
+	err := utils.EnsureMinBalance(context.Background(), &fakeBalances{balances: []int64{have.Int64()}}, addr, need)
+	var insufficient *utils.ErrInsufficientBalance
+	if !errors.As(err, &insufficient) {
+		t.Fatalf("got %v, want *ErrInsufficientBalance", err)
+	}
+	if insufficient.Address != addr || insufficient.Have.Cmp(have) != 0 || insufficient.Need.Cmp(need) != 0 {
+		t.Errorf("got %+v, want %s holding %s of %s", insufficient, addr.Hex(), have, need)
+	}
+	want := "insufficient balance: " + addr.Hex() + " holds 0.25 ETH, please fund it with at least 3 ETH"
+	if err.Error() != want {
+		t.Errorf("got message %q, want %q", err, want)
+	}
+
+	if err := utils.EnsureMinBalance(context.Background(), &fakeBalances{balances: []int64{need.Int64()}}, addr, need); err != nil {
+		t.Errorf("got %v for a balance of exactly the minimum", err)
+	}
+}
+
+func TestFormatEther(t *testing.T) {
+	for _, tc := range []struct {
+		wei  *big.Int
+		want string
+	}{
+		{big.NewInt(0), "0"},
+		{big.NewInt(params.Ether), "1"},
+		{big.NewInt(3100000000000000000), "3.1"},
+		{big.NewInt(1), "0.000000000000000001"},
+		{new(big.Int).Mul(big.NewInt(32000), big.NewInt(params.Ether)), "32000"},
+	} {
+		if got := utils.FormatEther(tc.wei); got != tc.want {
+			t.Errorf("FormatEther(%s) = %q, want %q", tc.wei, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+type ChainIDReader interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// EnsureChainID returns an error unless the client is connected to the chain
+// with the expected ID. Call it before transacting to guard against a
+// misconfigured RPC endpoint.
+func EnsureChainID(ctx context.Context, client ChainIDReader, expected *big.Int) error {
+	chainID, err := ChainIDWithRetry(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to get chain id: %w", err)
+	}
+	if chainID.Cmp(expected) != 0 {
+		return fmt.Errorf("connected to chain id %s, expected %s", chainID, expected)
+	}
+	return nil
+}
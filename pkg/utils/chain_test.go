@@ -0,0 +1,26 @@
+package utils_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+type fixedChainID int64
+
+func (id fixedChainID) ChainID(context.Context) (*big.Int, error) {
+	return big.NewInt(int64(id)), nil
+}
+
+func TestEnsureChainID(t *testing.T) {
+	if err := utils.EnsureChainID(context.Background(), fixedChainID(17000), big.NewInt(17000)); err != nil {
+		t.Errorf("got error %v for the expected chain", err)
+	}
+	err := utils.EnsureChainID(context.Background(), fixedChainID(1), big.NewInt(17000))
+	if err == nil || !strings.Contains(err.Error(), "connected to chain id 1, expected 17000") {
+		t.Errorf("got error %v for the wrong chain, want one naming both chain ids", err)
+	}
+}
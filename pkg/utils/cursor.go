@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReadBlockCursor returns the block number saved at path by
+// WriteBlockCursor, and false if there is no cursor yet.
+func ReadBlockCursor(path string) (uint64, bool, error) {
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("reading cursor: %w", err)
+	}
+	block, err := strconv.ParseUint(strings.TrimSpace(string(contents)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("parsing cursor %s: %w", path, err)
+	}
+	return block, true, nil
+}
+
+// WriteBlockCursor saves block to path, replacing the file atomically so a
+// crash never leaves a partial cursor.
+func WriteBlockCursor(path string, block uint64) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("writing cursor: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := fmt.Fprintf(tmp, "%d\n", block); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing cursor: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing cursor: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package utils
+
+// SetRetryClock makes the retry helpers and WaitForMinBalance wait on clock
+// until the returned restore func is called.
+func SetRetryClock(clock Clock) (restore func()) {
+	prev := retryClock
+	retryClock = clock
+	return func() { retryClock = prev }
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// FilterRetries is how many times FilterRangeWithRetry retries a failed
+// window.
+const FilterRetries = 5
+
+// FilterRange calls fn with FilterOpts for each window of at most
+// windowSize blocks in [startBlock, endBlock], logging progress as it
+// goes. Many RPC providers cap the block range of a log query, so event
+// scans over long histories should go through this rather than a single
+// filter call. It stops early if ctx is done or fn fails.
+func FilterRange(ctx context.Context, startBlock, endBlock, windowSize uint64, fn func(opts *bind.FilterOpts) error) error {
+	if windowSize == 0 {
+		return fmt.Errorf("window size must be positive")
+	}
+	for from := startBlock; from <= endBlock; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		to := endBlock
+		if endBlock-from >= windowSize {
+			to = from + windowSize - 1
+		}
+		progress := 100.0
+		if endBlock > startBlock {
+			progress = 100 * float64(to-startBlock) / float64(endBlock-startBlock)
+		}
+		slog.Info("processing blocks", "from", from, "to", to, "progress", fmt.Sprintf("%.0f%%", progress))
+		opts := &bind.FilterOpts{
+			Start:   from,
+			End:     &to,
+			Context: ctx,
+		}
+		if err := fn(opts); err != nil {
+			return err
+		}
+		if to == endBlock {
+			break
+		}
+		from = to + 1
+	}
+	return nil
+}
+
+// FilterRangeWithRetry is FilterRange, retrying a window whose fn fails up
+// to FilterRetries times with exponential backoff, so one failed log query
+// or iteration doesn't abort a long scan. fn may be called more than once
+// per window, so it must only record a window's events once they have all
+// been read without error.
+func FilterRangeWithRetry(ctx context.Context, startBlock, endBlock, windowSize uint64, fn func(opts *bind.FilterOpts) error) error {
+	return FilterRange(ctx, startBlock, endBlock, windowSize, func(opts *bind.FilterOpts) error {
+		var lastErr error
+		for attempt := 0; attempt <= FilterRetries; attempt++ {
+			if attempt > 0 {
+				slog.Warn("log filter failed, retrying", "from", opts.Start, "to", *opts.End, "attempt", attempt, "error", lastErr)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-retryClock.After(time.Second << (attempt - 1)):
+				}
+			}
+			err := fn(opts)
+			if err == nil {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = err
+		}
+		return fmt.Errorf("blocks %d to %d failed after %d attempts: %w", opts.Start, *opts.End, FilterRetries+1, lastErr)
+	})
+}
+
+// SafeTip returns the latest block number less confirmations, the newest
+// block a scan can treat as unlikely to be reorged. Fetch it once and scan
+// every range up to it, so separate loops see the same chain.
+func SafeTip(ctx context.Context, client BlockNumberReader, confirmations uint64) (uint64, error) {
+	latest, err := BlockNumberWithRetry(ctx, client)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block number: %w", err)
+	}
+	if latest < confirmations {
+		return 0, fmt.Errorf("latest block %d is below the %d required confirmations", latest, confirmations)
+	}
+	return latest - confirmations, nil
+}
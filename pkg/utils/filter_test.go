@@ -0,0 +1,185 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+func useFakeRetryClock(t *testing.T) *testutil.FakeClock {
+	t.Helper()
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	t.Cleanup(utils.SetRetryClock(clock))
+	return clock
+}
+
+// waitForWaiter blocks until the code under test is waiting on clock.
+func waitForWaiter(t *testing.T, clock *testutil.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a timer")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFilterRangeWindows(t *testing.T) {
+	var windows [][2]uint64
+	err := utils.FilterRange(context.Background(), 10, 34, 10, func(opts *bind.FilterOpts) error {
+		windows = append(windows, [2]uint64{opts.Start, *opts.End})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]uint64{{10, 19}, {20, 29}, {30, 34}}
+	if len(windows) != len(want) {
+		t.Fatalf("got windows %v, want %v", windows, want)
+	}
+	for i := range want {
+		if windows[i] != want[i] {
+			t.Errorf("window %d is %v, want %v", i, windows[i], want[i])
+		}
+	}
+}
+
+func TestFilterRangeEdges(t *testing.T) {
+	tests := []struct {
+		name               string
+		start, end, window uint64
+		want               [][2]uint64
+	}{
+		{"single block", 5, 5, 10, [][2]uint64{{5, 5}}},
+		{"exact windows", 0, 19, 10, [][2]uint64{{0, 9}, {10, 19}}},
+		{"ends at the last block number", math.MaxUint64 - 1, math.MaxUint64, 1, [][2]uint64{{math.MaxUint64 - 1, math.MaxUint64 - 1}, {math.MaxUint64, math.MaxUint64}}},
+		{"empty range", 10, 9, 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var windows [][2]uint64
+			err := utils.FilterRange(context.Background(), tt.start, tt.end, tt.window, func(opts *bind.FilterOpts) error {
+				windows = append(windows, [2]uint64{opts.Start, *opts.End})
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(windows, tt.want) {
+				t.Errorf("got windows %v, want %v", windows, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterRangeStops(t *testing.T) {
+	if err := utils.FilterRange(context.Background(), 0, 10, 0, func(*bind.FilterOpts) error { return nil }); err == nil {
+		t.Error("got no error for a zero window size")
+	}
+
+	fnErr := errors.New("filter failed")
+	calls := 0
+	err := utils.FilterRange(context.Background(), 0, 100, 10, func(*bind.FilterOpts) error {
+		calls++
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) || calls != 1 {
+		t.Errorf("got error %v after %d calls, want %v after 1", err, calls, fnErr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	err = utils.FilterRange(ctx, 0, 100, 10, func(*bind.FilterOpts) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("got error %v after %d calls, want context.Canceled after 1", err, calls)
+	}
+}
+
+func TestFilterRangeWithRetryBacksOffExponentially(t *testing.T) {
+	clock := useFakeRetryClock(t)
+	calls := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- utils.FilterRangeWithRetry(context.Background(), 0, 9, 10, func(opts *bind.FilterOpts) error {
+			calls++
+			if calls <= 2 {
+				return errors.New("rpc blip")
+			}
+			return nil
+		})
+	}()
+
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second} {
+		waitForWaiter(t, clock)
+		clock.Advance(backoff - time.Millisecond)
+		select {
+		case <-done:
+			t.Fatalf("retried before the %s backoff elapsed", backoff)
+		case <-time.After(10 * time.Millisecond):
+		}
+		clock.Advance(time.Millisecond)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("fn called %d times, want 3", calls)
+	}
+}
+
+func TestFilterRangeWithRetryGivesUp(t *testing.T) {
+	clock := useFakeRetryClock(t)
+	errBlip := errors.New("rpc blip")
+	done := make(chan error, 1)
+	go func() {
+		done <- utils.FilterRangeWithRetry(context.Background(), 0, 9, 10, func(opts *bind.FilterOpts) error {
+			return errBlip
+		})
+	}()
+	for attempt := 1; attempt <= utils.FilterRetries; attempt++ {
+		waitForWaiter(t, clock)
+		clock.Advance(time.Second << (attempt - 1))
+	}
+	if err := <-done; !errors.Is(err, errBlip) {
+		t.Fatalf("got %v, want the last fn error", err)
+	}
+}
+
+type latestBlock uint64
+
+func (b latestBlock) BlockNumber(context.Context) (uint64, error) {
+	return uint64(b), nil
+}
+
+func TestSafeTip(t *testing.T) {
+	for _, tc := range []struct {
+		latest, confirmations, want uint64
+	}{
+		{100, 0, 100},
+		{100, 12, 88},
+		{12, 12, 0},
+	} {
+		got, err := utils.SafeTip(context.Background(), latestBlock(tc.latest), tc.confirmations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("SafeTip of block %d with %d confirmations = %d, want %d", tc.latest, tc.confirmations, got, tc.want)
+		}
+	}
+	if _, err := utils.SafeTip(context.Background(), latestBlock(5), 12); err == nil {
+		t.Error("got no error for a chain shorter than the confirmation depth")
+	}
+}
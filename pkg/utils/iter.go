@@ -0,0 +1,46 @@
+package utils
+
+// BoundIterator is implemented by abigen's event iterators, which expose
+// the current event as a field rather than a method.
+type BoundIterator interface {
+	Next() bool
+	Error() error
+	Close() error
+}
+
+// EventIterator iterates over events of type T.
+type EventIterator[T any] interface {
+	BoundIterator
+	Event() T
+}
+
+// IterEvents adapts an abigen iterator to an EventIterator, reading each
+// event with current, usually a closure returning the iterator's Event
+// field.
+func IterEvents[T any](iter BoundIterator, current func() T) EventIterator[T] {
+	return boundEvents[T]{BoundIterator: iter, current: current}
+}
+
+type boundEvents[T any] struct {
+	BoundIterator
+	current func() T
+}
+
+func (it boundEvents[T]) Event() T {
+	return it.current()
+}
+
+// CollectEvents drains and closes iter, returning its events. If iteration
+// fails part way, it returns the error and no events, since a partial set
+// would silently under-count.
+func CollectEvents[T any](iter EventIterator[T]) ([]T, error) {
+	defer iter.Close()
+	var events []T
+	for iter.Next() {
+		events = append(events, iter.Event())
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
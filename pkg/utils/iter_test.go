@@ -0,0 +1,73 @@
+package utils_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// sliceIterator yields events and then fails with err, if it is set, the
+// way an abigen iterator fails on an unpack error or a failed subscription.
+type sliceIterator struct {
+	events []int
+	err    error
+	pos    int
+	closed bool
+}
+
+func (it *sliceIterator) Next() bool {
+	if it.pos >= len(it.events) {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *sliceIterator) Error() error {
+	if it.pos < len(it.events) {
+		return nil
+	}
+	return it.err
+}
+
+func (it *sliceIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+func (it *sliceIterator) current() int {
+	return it.events[it.pos-1]
+}
+
+func TestCollectEvents(t *testing.T) {
+	iter := &sliceIterator{events: []int{1, 2, 3}}
+
+	events, err := utils.CollectEvents(utils.IterEvents(iter, iter.current))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(events, []int{1, 2, 3}) {
+		t.Errorf("got events %v, want [1 2 3]", events)
+	}
+	if !iter.closed {
+		t.Error("iterator not closed")
+	}
+}
+
+func TestCollectEventsFailingPartway(t *testing.T) {
+	iterErr := errors.New("subscription dropped")
+	iter := &sliceIterator{events: []int{1, 2}, err: iterErr}
+
+	events, err := utils.CollectEvents(utils.IterEvents(iter, iter.current))
+	if !errors.Is(err, iterErr) {
+		t.Fatalf("got error %v, want %v", err, iterErr)
+	}
+	if events != nil {
+		t.Errorf("got partial events %v, want none", events)
+	}
+	if !iter.closed {
+		t.Error("iterator not closed")
+	}
+}
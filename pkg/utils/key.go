@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// LoadPrivateKey parses a hex private key read from keyFile or, if keyFile
+// is empty, from the envVar environment variable. Surrounding whitespace and
+// an optional 0x prefix are stripped.
+func LoadPrivateKey(envVar, keyFile string) (*ecdsa.PrivateKey, error) {
+	var keyHex string
+	if keyFile != "" {
+		contents, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file: %w", err)
+		}
+		keyHex = string(contents)
+	} else {
+		keyHex = os.Getenv(envVar)
+		if keyHex == "" {
+			return nil, fmt.Errorf("%s env var not supplied", envVar)
+		}
+	}
+
+	keyHex = strings.TrimSpace(keyHex)
+	keyHex = strings.TrimPrefix(strings.TrimPrefix(keyHex, "0x"), "0X")
+	privateKey, err := crypto.HexToECDSA(keyHex)
+	if err != nil {
+		// Don't wrap err, it may echo part of the key.
+		return nil, fmt.Errorf("failed to parse private key")
+	}
+	return privateKey, nil
+}
+
+// ErrAccountNotFound is returned by SelectAccount when the keystore holds no
+// matching account.
+var ErrAccountNotFound = errors.New("account not found in keystore")
+
+// SelectAccount returns the account of ks with address want. If want is the
+// zero address and ks holds exactly one account, that account is returned.
+func SelectAccount(ks *keystore.KeyStore, want common.Address) (accounts.Account, error) {
+	ksAccounts := ks.Accounts()
+	if want == (common.Address{}) {
+		if len(ksAccounts) != 1 {
+			return accounts.Account{}, fmt.Errorf("no signer address given and keystore holds %d accounts", len(ksAccounts))
+		}
+		return ksAccounts[0], nil
+	}
+	for _, account := range ksAccounts {
+		if account.Address == want {
+			return account, nil
+		}
+	}
+	return accounts.Account{}, fmt.Errorf("%s: %w", want.Hex(), ErrAccountNotFound)
+}
@@ -0,0 +1,99 @@
+package utils_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+const testKeyHex = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func TestLoadPrivateKey(t *testing.T) {
+	want, err := crypto.HexToECDSA(testKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("0x"+testKeyHex+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_PRIVATE_KEY", " 0X"+testKeyHex+" ")
+
+	fromFile, err := utils.LoadPrivateKey("UNSET_PRIVATE_KEY", keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromEnv, err := utils.LoadPrivateKey("TEST_PRIVATE_KEY", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fromFile.Equal(want) || !fromEnv.Equal(want) {
+		t.Error("loaded a different key from the one written")
+	}
+}
+
+func TestLoadPrivateKeyErrors(t *testing.T) {
+	t.Setenv("BAD_PRIVATE_KEY", "zz"+testKeyHex[2:])
+	if _, err := utils.LoadPrivateKey("BAD_PRIVATE_KEY", ""); err == nil || strings.Contains(err.Error(), testKeyHex[2:10]) {
+		t.Errorf("got error %v, want a parse error not echoing the key", err)
+	}
+	if _, err := utils.LoadPrivateKey("UNSET_PRIVATE_KEY", ""); err == nil || !strings.Contains(err.Error(), "UNSET_PRIVATE_KEY") {
+		t.Errorf("got error %v, want one naming the missing env var", err)
+	}
+	if _, err := utils.LoadPrivateKey("", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("got no error for a missing key file")
+	}
+}
+
+func newTestKeyStore(t *testing.T, accounts int) (*keystore.KeyStore, []common.Address) {
+	t.Helper()
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	var addresses []common.Address
+	for range accounts {
+		account, err := ks.NewAccount("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addresses = append(addresses, account.Address)
+	}
+	return ks, addresses
+}
+
+func TestSelectAccount(t *testing.T) {
+	ks, addresses := newTestKeyStore(t, 3)
+	for _, want := range addresses {
+		account, err := utils.SelectAccount(ks, want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if account.Address != want {
+			t.Errorf("selected %s, want %s", account.Address.Hex(), want.Hex())
+		}
+	}
+
+	_, err := utils.SelectAccount(ks, common.HexToAddress("0x01"))
+	if !errors.Is(err, utils.ErrAccountNotFound) {
+		t.Errorf("got error %v for an address not in the keystore, want ErrAccountNotFound", err)
+	}
+	if _, err := utils.SelectAccount(ks, common.Address{}); err == nil {
+		t.Error("got no error selecting without an address among several accounts")
+	}
+}
+
+func TestSelectAccountDefaultsToOnlyAccount(t *testing.T) {
+	ks, addresses := newTestKeyStore(t, 1)
+	account, err := utils.SelectAccount(ks, common.Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Address != addresses[0] {
+		t.Errorf("selected %s, want the only account %s", account.Address.Hex(), addresses[0].Hex())
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Multicall3Address is the address Multicall3 is deployed at on mainnet,
+// holesky and most other EVM chains.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+const multicall3ABI = `[{"inputs":[{"components":[{"internalType":"address","name":"target","type":"address"},{"internalType":"bool","name":"allowFailure","type":"bool"},{"internalType":"bytes","name":"callData","type":"bytes"}],"internalType":"struct Multicall3.Call3[]","name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"internalType":"bool","name":"success","type":"bool"},{"internalType":"bytes","name":"returnData","type":"bytes"}],"internalType":"struct Multicall3.Result[]","name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+var parsedMulticall3ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse multicall3 abi: %v", err))
+	}
+	return parsed
+}()
+
+// Call3 is a call aggregated by Multicall3's aggregate3. If AllowFailure is
+// false, a revert of the call reverts the whole aggregate.
+type Call3 struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// Result is the outcome of a Call3. ReturnData holds the call's ABI encoded
+// return values, or its revert data if Success is false.
+type Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// EncodeAggregate3 returns the calldata of an aggregate3 call making calls.
+func EncodeAggregate3(calls []Call3) ([]byte, error) {
+	data, err := parsedMulticall3ABI.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack aggregate3: %w", err)
+	}
+	return data, nil
+}
+
+// DecodeAggregate3 decodes the return data of an aggregate3 call into one
+// Result per call, in call order.
+func DecodeAggregate3(data []byte) ([]Result, error) {
+	out, err := parsedMulticall3ABI.Unpack("aggregate3", data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack aggregate3: %w", err)
+	}
+	results := *abi.ConvertType(out[0], new([]Result)).(*[]Result)
+	return results, nil
+}
+
+// Multicall executes calls in a single eth_call through Multicall3's
+// aggregate3. Results are returned in the order of calls.
+func Multicall(
+	ctx context.Context,
+	client bind.ContractCaller,
+	multicallAddr common.Address,
+	calls []Call3,
+) ([]Result, error) {
+	if len(calls) == 0 {
+		return []Result{}, nil
+	}
+
+	data, err := EncodeAggregate3(calls)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &multicallAddr, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call aggregate3: %w", err)
+	}
+
+	results, err := DecodeAggregate3(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(results), len(calls))
+	}
+	return results, nil
+}
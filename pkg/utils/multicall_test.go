@@ -0,0 +1,148 @@
+package utils_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// cannedAggregate3 is the aggregate3 return data for two calls: the first
+// succeeded returning the bool true, the second reverted with data 0xdead.
+const cannedAggregate3 = "" +
+	"0000000000000000000000000000000000000000000000000000000000000020" +
+	"0000000000000000000000000000000000000000000000000000000000000002" +
+	"0000000000000000000000000000000000000000000000000000000000000040" +
+	"00000000000000000000000000000000000000000000000000000000000000c0" +
+	"0000000000000000000000000000000000000000000000000000000000000001" +
+	"0000000000000000000000000000000000000000000000000000000000000040" +
+	"0000000000000000000000000000000000000000000000000000000000000020" +
+	"0000000000000000000000000000000000000000000000000000000000000001" +
+	"0000000000000000000000000000000000000000000000000000000000000000" +
+	"0000000000000000000000000000000000000000000000000000000000000040" +
+	"0000000000000000000000000000000000000000000000000000000000000002" +
+	"dead000000000000000000000000000000000000000000000000000000000000"
+
+// aggregate3Selector is the selector of aggregate3((address,bool,bytes)[]).
+var aggregate3Selector = []byte{0x82, 0xad, 0x56, 0xcb}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func checkCannedResults(t *testing.T, results []utils.Result) {
+	t.Helper()
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if !results[0].Success || new(big.Int).SetBytes(results[0].ReturnData).Uint64() != 1 || len(results[0].ReturnData) != 32 {
+		t.Errorf("result 0 = %+v, want success returning true", results[0])
+	}
+	if results[1].Success || !bytes.Equal(results[1].ReturnData, []byte{0xde, 0xad}) {
+		t.Errorf("result 1 = %+v, want failure with 0xdead", results[1])
+	}
+}
+
+func TestDecodeAggregate3(t *testing.T) {
+	results, err := utils.DecodeAggregate3(mustDecodeHex(t, cannedAggregate3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCannedResults(t, results)
+}
+
+func TestDecodeAggregate3RejectsTruncatedData(t *testing.T) {
+	data := mustDecodeHex(t, cannedAggregate3)
+	if _, err := utils.DecodeAggregate3(data[:len(data)-64]); err == nil {
+		t.Fatal("expected an error for truncated return data")
+	}
+}
+
+func TestEncodeAggregate3(t *testing.T) {
+	data, err := utils.EncodeAggregate3([]utils.Call3{
+		{Target: common.HexToAddress("0x01"), CallData: []byte{0x12, 0x34}},
+		{Target: common.HexToAddress("0x02"), AllowFailure: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, aggregate3Selector) {
+		t.Fatalf("calldata starts with %x, want selector %x", data[:4], aggregate3Selector)
+	}
+	// The word after the array offset is the number of calls.
+	if got := new(big.Int).SetBytes(data[4+32 : 4+64]).Uint64(); got != 2 {
+		t.Errorf("encoded %d calls, want 2", got)
+	}
+}
+
+type fakeMulticaller struct {
+	out   []byte
+	calls []ethereum.CallMsg
+}
+
+func (f *fakeMulticaller) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return []byte{0x01}, nil
+}
+
+func (f *fakeMulticaller) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	f.calls = append(f.calls, msg)
+	return f.out, nil
+}
+
+func TestMulticall(t *testing.T) {
+	caller := &fakeMulticaller{out: mustDecodeHex(t, cannedAggregate3)}
+	calls := []utils.Call3{
+		{Target: common.HexToAddress("0x01"), CallData: []byte{0x01}},
+		{Target: common.HexToAddress("0x02"), AllowFailure: true, CallData: []byte{0x02}},
+	}
+	results, err := utils.Multicall(context.Background(), caller, utils.Multicall3Address, calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCannedResults(t, results)
+
+	if len(caller.calls) != 1 {
+		t.Fatalf("made %d eth_calls, want 1", len(caller.calls))
+	}
+	msg := caller.calls[0]
+	if msg.To == nil || *msg.To != utils.Multicall3Address {
+		t.Errorf("called %v, want %s", msg.To, utils.Multicall3Address)
+	}
+	want, err := utils.EncodeAggregate3(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg.Data, want) {
+		t.Error("calldata is not the aggregate3 encoding of calls")
+	}
+}
+
+func TestMulticallNoCalls(t *testing.T) {
+	caller := &fakeMulticaller{}
+	results, err := utils.Multicall(context.Background(), caller, utils.Multicall3Address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 || len(caller.calls) != 0 {
+		t.Fatalf("got %d results from %d eth_calls, want none", len(results), len(caller.calls))
+	}
+}
+
+func TestMulticallResultCountMismatch(t *testing.T) {
+	caller := &fakeMulticaller{out: mustDecodeHex(t, cannedAggregate3)}
+	_, err := utils.Multicall(context.Background(), caller, utils.Multicall3Address, []utils.Call3{{Target: common.HexToAddress("0x01")}})
+	if err == nil || !strings.Contains(err.Error(), "2 results for 1 calls") {
+		t.Fatalf("got error %v, want a result count mismatch", err)
+	}
+}
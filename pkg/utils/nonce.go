@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type NonceSource interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// NonceManager hands out monotonically increasing nonces for a single account.
+// The pending nonce is queried once and incremented locally afterwards, so
+// back-to-back submissions never depend on the RPC's view of the mempool.
+type NonceManager struct {
+	mu          sync.Mutex
+	source      NonceSource
+	account     common.Address
+	next        uint64
+	initialized bool
+}
+
+func NewNonceManager(source NonceSource, account common.Address) *NonceManager {
+	return &NonceManager{source: source, account: account}
+}
+
+func (n *NonceManager) Next(ctx context.Context) (uint64, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.initialized {
+		nonce, err := n.source.PendingNonceAt(ctx, n.account)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get pending nonce: %w", err)
+		}
+		n.next = nonce
+		n.initialized = true
+	}
+
+	nonce := n.next
+	n.next++
+	return nonce, nil
+}
+
+// Reset forces the next call to Next to re-query the pending nonce.
+func (n *NonceManager) Reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.initialized = false
+}
@@ -0,0 +1,76 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+type fakeNonceSource struct {
+	pending uint64
+	queries int
+	err     error
+}
+
+func (s *fakeNonceSource) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	s.queries++
+	return s.pending, s.err
+}
+
+func TestNonceManagerIncrementsLocally(t *testing.T) {
+	source := &fakeNonceSource{pending: 7}
+	nonces := utils.NewNonceManager(source, common.HexToAddress("0x01"))
+
+	for want := uint64(7); want < 10; want++ {
+		got, err := nonces.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got nonce %d, want %d", got, want)
+		}
+	}
+	if source.queries != 1 {
+		t.Errorf("queried the pending nonce %d times, want once", source.queries)
+	}
+}
+
+func TestNonceManagerResetRequeries(t *testing.T) {
+	source := &fakeNonceSource{pending: 3}
+	nonces := utils.NewNonceManager(source, common.HexToAddress("0x01"))
+	if _, err := nonces.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A dropped tx leaves the chain's pending nonce behind the local one.
+	source.pending = 3
+	nonces.Reset()
+	got, err := nonces.Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 3 || source.queries != 2 {
+		t.Errorf("got nonce %d after %d queries, want 3 after 2", got, source.queries)
+	}
+}
+
+func TestNonceManagerRetriesQueryAfterError(t *testing.T) {
+	source := &fakeNonceSource{err: errors.New("rpc down")}
+	nonces := utils.NewNonceManager(source, common.HexToAddress("0x01"))
+	if _, err := nonces.Next(context.Background()); err == nil {
+		t.Fatal("expected an error while the source fails")
+	}
+
+	source.err = nil
+	source.pending = 5
+	got, err := nonces.Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 5 {
+		t.Errorf("got nonce %d, want 5", got)
+	}
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ContractCallSimulator is implemented by *ethclient.Client.
+type ContractCallSimulator interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// RevertReasonAtBlock re-simulates tx, included in blockNumber, against the
+// state of the parent block, which is what the tx executed against barring
+// earlier txs in the same block, and returns the decoded revert reason.
+// Calling at blockNumber itself would run against state the tx and its
+// block already changed. It returns an error if the call doesn't revert.
+func RevertReasonAtBlock(ctx context.Context, client ContractCallSimulator, tx *types.Transaction, blockNumber *big.Int) (string, error) {
+	if blockNumber == nil || blockNumber.Sign() <= 0 {
+		return "", fmt.Errorf("invalid block number %v", blockNumber)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return "", fmt.Errorf("recovering tx sender: %w", err)
+	}
+
+	msg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	parent := new(big.Int).Sub(blockNumber, big.NewInt(1))
+	_, callErr := client.CallContract(ctx, msg, parent)
+	if callErr == nil {
+		return "", fmt.Errorf("tx %s does not revert at block %d", tx.Hash().Hex(), parent)
+	}
+	return decodeRevertReason(callErr), nil
+}
+
+// decodeRevertReason unpacks an Error(string) revert from the data of a
+// call error, falling back to the error's message.
+func decodeRevertReason(callErr error) string {
+	var dataErr rpc.DataError
+	if !errors.As(callErr, &dataErr) {
+		return callErr.Error()
+	}
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return callErr.Error()
+	}
+	data, err := hexutil.Decode(hexData)
+	if err != nil {
+		return callErr.Error()
+	}
+	reason, err := abi.UnpackRevert(data)
+	if err != nil {
+		// A custom error; return its raw data for decoding against the ABI.
+		return fmt.Sprintf("%s: %s", callErr.Error(), hexData)
+	}
+	return reason
+}
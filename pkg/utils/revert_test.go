@@ -0,0 +1,130 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// callError is the error a node returns for a reverted call, with the
+// revert data attached.
+type callError struct {
+	message string
+	data    any
+}
+
+func (e *callError) Error() string  { return e.message }
+func (e *callError) ErrorData() any { return e.data }
+
+// recordingSimulator fails every call with err and records the block each
+// call ran against.
+type recordingSimulator struct {
+	err    error
+	blocks []*big.Int
+}
+
+func (s *recordingSimulator) CallContract(_ context.Context, _ ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	s.blocks = append(s.blocks, blockNumber)
+	return nil, s.err
+}
+
+func revertData(t *testing.T, reason string) string {
+	t.Helper()
+	stringType, err := abi.NewType("string", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err := abi.Arguments{{Type: stringType}}.Pack(reason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selector := crypto.Keccak256([]byte("Error(string)"))[:4]
+	return hexutil.Encode(append(selector, packed...))
+}
+
+func signedTestTx(t *testing.T) *types.Transaction {
+	t.Helper()
+	key, err := crypto.HexToECDSA(testKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0x01")
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		To:        &to,
+		Gas:       21000,
+		GasFeeCap: big.NewInt(1),
+		Data:      []byte{0x12, 0x34},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestRevertReasonAtBlock(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "Error(string)",
+			err:  &callError{message: "execution reverted", data: revertData(t, "validator already staked")},
+			want: "validator already staked",
+		},
+		{
+			name: "custom error",
+			err:  &callError{message: "execution reverted", data: "0xdeadbeef"},
+			want: "execution reverted: 0xdeadbeef",
+		},
+		{
+			name: "no data",
+			err:  errors.New("out of gas"),
+			want: "out of gas",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &recordingSimulator{err: tt.err}
+			reason, err := utils.RevertReasonAtBlock(context.Background(), client, signedTestTx(t), big.NewInt(100))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reason != tt.want {
+				t.Errorf("got reason %q, want %q", reason, tt.want)
+			}
+			if len(client.blocks) != 1 || client.blocks[0].Uint64() != 99 {
+				t.Errorf("simulated at blocks %v, want the parent block 99", client.blocks)
+			}
+		})
+	}
+}
+
+func TestRevertReasonAtBlockErrors(t *testing.T) {
+	tx := signedTestTx(t)
+	for _, block := range []*big.Int{nil, big.NewInt(0)} {
+		client := &recordingSimulator{err: errors.New("reverted")}
+		if _, err := utils.RevertReasonAtBlock(context.Background(), client, tx, block); err == nil {
+			t.Errorf("block %v: got no error", block)
+		}
+		if len(client.blocks) != 0 {
+			t.Errorf("block %v: simulated the tx anyway", block)
+		}
+	}
+
+	_, err := utils.RevertReasonAtBlock(context.Background(), &recordingSimulator{}, tx, big.NewInt(100))
+	if err == nil || !strings.Contains(err.Error(), "does not revert") {
+		t.Errorf("got error %v for a call that succeeds, want one saying it does not revert", err)
+	}
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// StartupRetries is how many times the *WithRetry helpers retry a failed
+// call, so that a single RPC blip doesn't stop a tool from starting.
+const StartupRetries = 5
+
+// retryClock times the backoff between retries of the *WithRetry helpers
+// and the polls of WaitForMinBalance.
+var retryClock Clock = RealClock{}
+
+type BlockNumberReader interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+type BlockByNumberReader interface {
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+}
+
+// withRetry calls op until it succeeds, retrying up to StartupRetries times
+// with linear backoff. The last error is returned if all attempts fail.
+func withRetry[T any](ctx context.Context, name string, op func(ctx context.Context) (T, error)) (result T, err error) {
+	ctx, span := StartSpan(ctx, name)
+	defer func() { EndSpan(span, err) }()
+
+	var zero T
+	var lastErr error
+	for attempt := 0; attempt <= StartupRetries; attempt++ {
+		span.SetAttributes(attribute.Int("rpc.attempts", attempt+1))
+		if attempt > 0 {
+			slog.Warn("rpc call failed, retrying", "call", name, "attempt", attempt, "error", lastErr)
+			select {
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			case <-retryClock.After(time.Duration(attempt) * time.Second):
+			}
+		}
+		result, err := op(ctx)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+		lastErr = err
+	}
+	return zero, fmt.Errorf("%s failed after %d attempts: %w", name, StartupRetries+1, lastErr)
+}
+
+// ChainIDWithRetry returns client's chain ID, retrying transient failures.
+func ChainIDWithRetry(ctx context.Context, client ChainIDReader) (*big.Int, error) {
+	return withRetry(ctx, "eth_chainId", client.ChainID)
+}
+
+// BlockNumberWithRetry returns client's latest block number, retrying
+// transient failures.
+func BlockNumberWithRetry(ctx context.Context, client BlockNumberReader) (uint64, error) {
+	return withRetry(ctx, "eth_blockNumber", client.BlockNumber)
+}
+
+// BlockByNumberWithRetry returns the block number, or the latest block if
+// number is nil, retrying transient failures.
+func BlockByNumberWithRetry(ctx context.Context, client BlockByNumberReader, number *big.Int) (*types.Block, error) {
+	return withRetry(ctx, "eth_getBlockByNumber", func(ctx context.Context) (*types.Block, error) {
+		return client.BlockByNumber(ctx, number)
+	})
+}
+
+// BalanceAtWithRetry returns the balance of account at blockNumber, or at
+// the latest block if blockNumber is nil, retrying transient failures.
+func BalanceAtWithRetry(ctx context.Context, client BalanceReader, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return withRetry(ctx, "eth_getBalance", func(ctx context.Context) (*big.Int, error) {
+		return client.BalanceAt(ctx, account, blockNumber)
+	})
+}
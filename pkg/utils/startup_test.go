@@ -0,0 +1,132 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// flakyClient fails its first failures calls of each method.
+type flakyClient struct {
+	failures int
+	calls    int
+}
+
+var errRPCBlip = errors.New("rpc blip")
+
+func (c *flakyClient) call() error {
+	c.calls++
+	if c.calls <= c.failures {
+		return errRPCBlip
+	}
+	return nil
+}
+
+func (c *flakyClient) ChainID(context.Context) (*big.Int, error) {
+	if err := c.call(); err != nil {
+		return nil, err
+	}
+	return big.NewInt(17000), nil
+}
+
+func (c *flakyClient) BlockNumber(context.Context) (uint64, error) {
+	if err := c.call(); err != nil {
+		return 0, err
+	}
+	return 100, nil
+}
+
+func (c *flakyClient) BalanceAt(context.Context, common.Address, *big.Int) (*big.Int, error) {
+	if err := c.call(); err != nil {
+		return nil, err
+	}
+	return big.NewInt(5), nil
+}
+
+func TestWithRetryRecoversFromOneFailure(t *testing.T) {
+	clock := useFakeRetryClock(t)
+	calls := []struct {
+		name string
+		call func(client *flakyClient) (any, error)
+		want any
+	}{
+		{"ChainIDWithRetry", func(c *flakyClient) (any, error) {
+			id, err := utils.ChainIDWithRetry(context.Background(), c)
+			return id.Int64(), err
+		}, int64(17000)},
+		{"BlockNumberWithRetry", func(c *flakyClient) (any, error) {
+			return utils.BlockNumberWithRetry(context.Background(), c)
+		}, uint64(100)},
+		{"BalanceAtWithRetry", func(c *flakyClient) (any, error) {
+			balance, err := utils.BalanceAtWithRetry(context.Background(), c, common.Address{}, nil)
+			return balance.Int64(), err
+		}, int64(5)},
+	}
+	for _, tc := range calls {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &flakyClient{failures: 1}
+			type result struct {
+				value any
+				err   error
+			}
+			done := make(chan result, 1)
+			go func() {
+				value, err := tc.call(client)
+				done <- result{value, err}
+			}()
+			waitForWaiter(t, clock)
+			clock.Advance(time.Second)
+			got := <-done
+			if got.err != nil {
+				t.Fatal(got.err)
+			}
+			if got.value != tc.want || client.calls != 2 {
+				t.Errorf("got %v after %d calls, want %v after 2", got.value, client.calls, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithRetryGivesUp(t *testing.T) {
+	clock := useFakeRetryClock(t)
+	client := &flakyClient{failures: utils.StartupRetries + 1}
+	done := make(chan error, 1)
+	go func() {
+		_, err := utils.BlockNumberWithRetry(context.Background(), client)
+		done <- err
+	}()
+	for attempt := 1; attempt <= utils.StartupRetries; attempt++ {
+		waitForWaiter(t, clock)
+		clock.Advance(time.Duration(attempt) * time.Second)
+	}
+	if err := <-done; !errors.Is(err, errRPCBlip) {
+		t.Fatalf("got %v, want the last call's error", err)
+	}
+	if client.calls != utils.StartupRetries+1 {
+		t.Errorf("got %d calls, want %d", client.calls, utils.StartupRetries+1)
+	}
+}
+
+func TestWithRetryStopsOnCancel(t *testing.T) {
+	clock := useFakeRetryClock(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &flakyClient{failures: 1}
+	done := make(chan error, 1)
+	go func() {
+		_, err := utils.ChainIDWithRetry(ctx, client)
+		done <- err
+	}()
+	waitForWaiter(t, clock)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if client.calls != 1 {
+		t.Errorf("got %d calls, want no retry after cancelling", client.calls)
+	}
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// transferGas is the gas used by a plain ETH transfer to an EOA.
+const transferGas = 21000
+
+// SweepBalance sends the balance of privateKey's account, less the maximum
+// fee of the transfer, to `to`, e.g. to refund a funding account after a
+// run. The value is recomputed whenever WaitMinedWithRetry boosts the fee,
+// so the account never needs more than its balance. It refuses to run while
+// the account has pending txs, whose cost the balance doesn't reflect.
+func (c *ETHClient) SweepBalance(ctx context.Context, privateKey *ecdsa.PrivateKey, to common.Address) (*types.Receipt, error) {
+	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+	pendingNonce, err := c.client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+	nonce, err := c.client.NonceAt(ctx, from, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	if pendingNonce != nonce {
+		return nil, fmt.Errorf("account %s has %d pending txs, not sweeping", from.Hex(), pendingNonce-nonce)
+	}
+	balance, err := c.client.BalanceAt(ctx, from, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	chainID, err := c.client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain id: %w", err)
+	}
+	opts, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	}
+	opts.Context = ctx
+	opts.Nonce = new(big.Int).SetUint64(nonce)
+	opts.GasLimit = transferGas
+	opts.GasTipCap, opts.GasFeeCap, err = c.SuggestGasTipCapAndPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest gas tip cap and price: %w", err)
+	}
+
+	submitTx := func(ctx context.Context, opts *bind.TransactOpts) (*types.Transaction, error) {
+		value := sweepValue(balance, opts.GasFeeCap)
+		if value.Sign() <= 0 {
+			return nil, fmt.Errorf("balance %s does not cover the transfer fee at fee cap %s", balance, opts.GasFeeCap)
+		}
+		tx, err := opts.Signer(from, types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     opts.Nonce.Uint64(),
+			GasTipCap: opts.GasTipCap,
+			GasFeeCap: opts.GasFeeCap,
+			Gas:       opts.GasLimit,
+			To:        &to,
+			Value:     value,
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign sweep tx: %w", err)
+		}
+		if err := c.client.SendTransaction(ctx, tx); err != nil {
+			return nil, err
+		}
+		slog.Info("sweep tx sent", "tx_hash", tx.Hash().Hex(), "value", value.String(), "to", to.Hex())
+		return tx, nil
+	}
+	return c.WaitMinedWithRetry(ctx, opts, submitTx)
+}
+
+// sweepValue is what's left of balance after the maximum fee of a transfer
+// at gasFeeCap.
+func sweepValue(balance, gasFeeCap *big.Int) *big.Int {
+	maxFee := new(big.Int).Mul(gasFeeCap, big.NewInt(transferGas))
+	return new(big.Int).Sub(balance, maxFee)
+}
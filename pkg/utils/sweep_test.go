@@ -0,0 +1,107 @@
+package utils_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// sweepBackend holds one account with balance and mines every sent tx at
+// once. Its embedded Backend is nil; only the methods below are used.
+type sweepBackend struct {
+	utils.Backend
+	nonce, pendingNonce uint64
+	balance             int64
+	sent                []*types.Transaction
+}
+
+func (b *sweepBackend) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	return b.pendingNonce, nil
+}
+
+func (b *sweepBackend) NonceAt(context.Context, common.Address, *big.Int) (uint64, error) {
+	return b.nonce, nil
+}
+
+func (b *sweepBackend) BalanceAt(context.Context, common.Address, *big.Int) (*big.Int, error) {
+	return big.NewInt(b.balance), nil
+}
+
+func (b *sweepBackend) ChainID(context.Context) (*big.Int, error) { return big.NewInt(1), nil }
+
+func (b *sweepBackend) SuggestGasTipCap(context.Context) (*big.Int, error) { return big.NewInt(1), nil }
+
+func (b *sweepBackend) SuggestGasPrice(context.Context) (*big.Int, error) { return big.NewInt(10), nil }
+
+func (b *sweepBackend) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+func (b *sweepBackend) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	for _, tx := range b.sent {
+		if tx.Hash() == hash {
+			return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(1)}, nil
+		}
+	}
+	return nil, ethereum.NotFound
+}
+
+func TestSweepBalance(t *testing.T) {
+	key, err := crypto.HexToECDSA(testKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0xbb")
+	backend := &sweepBackend{nonce: 3, pendingNonce: 3, balance: 1_000_000}
+
+	receipt, err := utils.NewETHClient(backend).SweepBalance(context.Background(), key, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.sent) != 1 || receipt.TxHash != backend.sent[0].Hash() {
+		t.Fatalf("sent %d txs, want the one receipted", len(backend.sent))
+	}
+	tx := backend.sent[0]
+	// The max fee is 21000 gas at the suggested fee cap of 10.
+	if *tx.To() != to || tx.Value().Int64() != 1_000_000-210_000 || tx.Nonce() != 3 || tx.Gas() != 21000 {
+		t.Errorf("sent %d to %s with nonce %d and gas %d, want 790000 to %s with nonce 3 and gas 21000", tx.Value(), tx.To(), tx.Nonce(), tx.Gas(), to)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), tx)
+	if err != nil || sender != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("tx signed by %s, %v, want the swept account", sender, err)
+	}
+}
+
+func TestSweepBalanceRefuses(t *testing.T) {
+	key, err := crypto.HexToECDSA(testKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		backend *sweepBackend
+		want    string
+	}{
+		{"pending txs", &sweepBackend{nonce: 3, pendingNonce: 5, balance: 1_000_000}, "2 pending txs"},
+		{"balance below fee", &sweepBackend{balance: 210_000}, "does not cover the transfer fee"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := utils.NewETHClient(tt.backend).SweepBalance(context.Background(), key, common.HexToAddress("0xbb"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+			if len(tt.backend.sent) != 0 {
+				t.Errorf("sent %d txs, want none", len(tt.backend.sent))
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// HeaderReader is implemented by *ethclient.Client.
+type HeaderReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+type blockKey struct {
+	client HeaderReader
+	number uint64
+}
+
+var (
+	blockTimestampsMu sync.Mutex
+	blockTimestamps   = map[blockKey]uint64{}
+)
+
+// BlockTimestamp returns the timestamp in seconds of block blockNumber.
+// Timestamps are cached per client for the life of the process, as they
+// never change once a block is final. client must be comparable, e.g. a
+// pointer.
+func BlockTimestamp(ctx context.Context, client HeaderReader, blockNumber uint64) (uint64, error) {
+	key := blockKey{client: client, number: blockNumber}
+	blockTimestampsMu.Lock()
+	ts, ok := blockTimestamps[key]
+	blockTimestampsMu.Unlock()
+	if ok {
+		return ts, nil
+	}
+
+	header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get header %d: %w", blockNumber, err)
+	}
+
+	blockTimestampsMu.Lock()
+	blockTimestamps[key] = header.Time
+	blockTimestampsMu.Unlock()
+	return header.Time, nil
+}
@@ -0,0 +1,43 @@
+package utils_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// countingHeaders mines block n at 100n and counts header requests.
+type countingHeaders struct {
+	requests int
+}
+
+func (h *countingHeaders) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	h.requests++
+	return &types.Header{Number: number, Time: 100 * number.Uint64()}, nil
+}
+
+func TestBlockTimestampCachesPerClient(t *testing.T) {
+	first, second := &countingHeaders{}, &countingHeaders{}
+	for _, block := range []uint64{1, 2, 1, 2} {
+		ts, err := utils.BlockTimestamp(context.Background(), first, block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ts != 100*block {
+			t.Errorf("got timestamp %d for block %d, want %d", ts, block, 100*block)
+		}
+	}
+	if first.requests != 2 {
+		t.Errorf("got %d header requests, want one per block", first.requests)
+	}
+
+	if _, err := utils.BlockTimestamp(context.Background(), second, 1); err != nil {
+		t.Fatal(err)
+	}
+	if second.requests != 1 {
+		t.Errorf("a second client made %d header requests, want 1", second.requests)
+	}
+}
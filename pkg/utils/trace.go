@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var tracer atomic.Value // of tracerHolder
+
+// tracerHolder lets tracer hold tracers of differing concrete types.
+type tracerHolder struct {
+	trace.Tracer
+}
+
+func init() {
+	tracer.Store(tracerHolder{noop.NewTracerProvider().Tracer("")})
+}
+
+// SetTracer makes the RPC wrappers in this package and HTTP clients using
+// TracingTransport record a span per external call with t. Tracing is a
+// no-op until it is called.
+func SetTracer(t trace.Tracer) {
+	tracer.Store(tracerHolder{t})
+}
+
+// Tracer returns the tracer set by SetTracer.
+func Tracer() trace.Tracer {
+	return tracer.Load().(tracerHolder).Tracer
+}
+
+// StartSpan starts a span named name with Tracer. Pass the error the traced
+// call returned to EndSpan.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err, if any, on span and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TracingTransport is an http.RoundTripper recording a span per request
+// with Tracer.
+type TracingTransport struct {
+	// Base makes the requests. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// NewTracingHTTPClient returns an HTTP client whose requests are traced.
+func NewTracingHTTPClient() *http.Client {
+	return &http.Client{Transport: &TracingTransport{}}
+}
+
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx, span := StartSpan(req.Context(), "HTTP "+req.Method,
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+		attribute.String("url.path", req.URL.Path),
+	)
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err == nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+	}
+	EndSpan(span, err)
+	return resp, err
+}
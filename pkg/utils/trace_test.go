@@ -0,0 +1,57 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func useTestTracer(t *testing.T) *testutil.Tracer {
+	t.Helper()
+	tracer := &testutil.Tracer{}
+	utils.SetTracer(tracer)
+	t.Cleanup(func() { utils.SetTracer(noop.NewTracerProvider().Tracer("")) })
+	return tracer
+}
+
+func TestRetriedRPCCallIsOneSpan(t *testing.T) {
+	tracer := useTestTracer(t)
+	clock := useFakeRetryClock(t)
+	done := make(chan error, 1)
+	go func() {
+		_, err := utils.ChainIDWithRetry(context.Background(), &flakyClient{failures: 1})
+		done <- err
+	}()
+	waitForWaiter(t, clock)
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 1 || spans[0].Name() != "eth_chainId" || !spans[0].Ended() {
+		t.Fatalf("got %d spans, want one ended eth_chainId span", len(spans))
+	}
+	if attempts, _ := spans[0].Attribute("rpc.attempts"); attempts.AsInt64() != 2 {
+		t.Errorf("got rpc.attempts %d, want 2", attempts.AsInt64())
+	}
+}
+
+func TestFailedRPCCallSpanHasErrorStatus(t *testing.T) {
+	tracer := useTestTracer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := utils.BlockNumberWithRetry(ctx, &flakyClient{failures: 1}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	spans := tracer.Spans()
+	if len(spans) != 1 || spans[0].Status() != codes.Error {
+		t.Errorf("got %d spans, want one with error status", len(spans))
+	}
+}
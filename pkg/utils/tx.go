@@ -4,22 +4,49 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"fmt"
+	"log/slog"
 	"math/big"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// Backend is the subset of *ethclient.Client ETHClient sends txs and
+// waits for receipts through.
+type Backend interface {
+	bind.DeployBackend
+	NonceSource
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
 type ETHClient struct {
-	client *ethclient.Client
+	client Backend
+	// MinGasTip, if set, is the floor suggested gas tips are raised to, for
+	// chains where SuggestGasTipCap can return an unusable tip such as 0.
+	MinGasTip *big.Int
+	// Clock times the inclusion and cancellation waits. Defaults to RealClock.
+	Clock Clock
 }
 
-func NewETHClient(client *ethclient.Client) *ETHClient {
-	return &ETHClient{client: client}
+func NewETHClient(client Backend) *ETHClient {
+	return &ETHClient{client: client, Clock: RealClock{}}
+}
+
+func (c *ETHClient) clock() Clock {
+	if c.Clock == nil {
+		return RealClock{}
+	}
+	return c.Clock
 }
 
 func (c *ETHClient) CreateTransactOpts(
@@ -61,14 +88,26 @@ func (c *ETHClient) SuggestGasTipCapAndPrice(ctx context.Context) (*big.Int, *bi
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get gas price: %w", err)
 	}
+	gasTip, gasPrice = c.applyMinGasTip(gasTip, gasPrice)
 	return gasTip, gasPrice, nil
 }
 
+// applyMinGasTip raises gasTip to MinGasTip, raising gasPrice by the same
+// amount so the implied base fee is unchanged.
+func (c *ETHClient) applyMinGasTip(gasTip, gasPrice *big.Int) (*big.Int, *big.Int) {
+	if c.MinGasTip == nil || gasTip.Cmp(c.MinGasTip) >= 0 {
+		return gasTip, gasPrice
+	}
+	shortfall := new(big.Int).Sub(c.MinGasTip, gasTip)
+	slog.Warn("suggested gas tip below floor, clamping", "suggested_tip", gasTip.String(), "min_tip", c.MinGasTip.String())
+	return new(big.Int).Set(c.MinGasTip), new(big.Int).Add(gasPrice, shortfall)
+}
+
 func (c *ETHClient) BoostTipForTransactOpts(
 	ctx context.Context,
 	opts *bind.TransactOpts,
 ) error {
-	fmt.Println(
+	slog.Debug(
 		"gas params for tx that were not included",
 		"gas_tip", opts.GasTipCap.String(),
 		"gas_fee_cap", opts.GasFeeCap.String(),
@@ -113,9 +152,8 @@ func (c *ETHClient) BoostTipForTransactOpts(
 	opts.GasTipCap = boostedTip
 	opts.GasFeeCap = new(big.Int).Add(boostedBaseFee, boostedTip)
 
-	fmt.Println("tip and base fee will be boosted by 10%")
-	fmt.Println(
-		"boosted gas",
+	slog.Info(
+		"tip and base fee boosted by 10%",
 		"get_tip_cap", opts.GasTipCap.String(),
 		"gas_fee_cap", opts.GasFeeCap.String(),
 		"base_fee", boostedBaseFee.String(),
@@ -136,15 +174,16 @@ func (c *ETHClient) WaitMinedWithRetry(
 	ctx context.Context,
 	opts *bind.TransactOpts,
 	submitTx TxSubmitFunc,
-) (*types.Receipt, error) {
+) (_ *types.Receipt, err error) {
+	ctx, span := StartSpan(ctx, "WaitMinedWithRetry", attribute.String("from", opts.From.Hex()))
+	defer func() { EndSpan(span, err) }()
 
 	const maxRetries = 10
-	var err error
 	var tx *types.Transaction
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
-			fmt.Println("transaction not included within 60 seconds, boosting gas tip by 10%", "attempt", attempt)
+			slog.Warn("transaction not included within 60 seconds, boosting gas tip by 10%", "attempt", attempt)
 			if err := c.BoostTipForTransactOpts(ctx, opts); err != nil {
 				return nil, fmt.Errorf("failed to boost gas tip for attempt %d: %w", attempt, err)
 			}
@@ -153,18 +192,19 @@ func (c *ETHClient) WaitMinedWithRetry(
 		tx, err = submitTx(ctx, opts)
 		if err != nil {
 			if strings.Contains(err.Error(), "replacement transaction underpriced") || strings.Contains(err.Error(), "already known") {
-				fmt.Println("tx submission failed", "attempt", attempt, "error", err)
+				slog.Warn("tx submission failed", "attempt", attempt, "error", err)
 				continue
 			}
 			return nil, fmt.Errorf("tx submission failed on attempt %d: %w", attempt, err)
 		}
 
-		timeoutCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
-		receiptChan := make(chan *types.Receipt)
-		errChan := make(chan error)
+		waitCtx, cancel := context.WithCancel(ctx)
+		timeout := c.clock().After(60 * time.Second)
+		receiptChan := make(chan *types.Receipt, 1)
+		errChan := make(chan error, 1)
 
 		go func() {
-			receipt, err := bind.WaitMined(timeoutCtx, c.client, tx)
+			receipt, err := bind.WaitMined(waitCtx, c.client, tx)
 			if err != nil {
 				errChan <- err
 				return
@@ -179,7 +219,7 @@ func (c *ETHClient) WaitMinedWithRetry(
 		case err := <-errChan:
 			cancel()
 			return nil, err
-		case <-timeoutCtx.Done():
+		case <-timeout:
 			cancel()
 			if attempt == maxRetries-1 {
 				return nil, fmt.Errorf("tx not included after %d attempts", maxRetries)
@@ -206,10 +246,14 @@ func (c *ETHClient) CancelPendingTxes(ctx context.Context, privateKey *ecdsa.Pri
 			return fmt.Errorf("failed to check pending transactions: %w", err)
 		}
 		if !exist {
-			fmt.Println("all pending transactions for signing account have been cancelled")
+			slog.Info("all pending transactions for signing account have been cancelled")
 			return nil
 		}
-		time.Sleep(1 * time.Second)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.clock().After(1 * time.Second):
+		}
 		idx++
 	}
 }
@@ -228,16 +272,16 @@ func (c *ETHClient) cancelAllPendingTransactions(
 	if err != nil {
 		return fmt.Errorf("failed to get current pending nonce: %w", err)
 	}
-	fmt.Println("current pending nonce", "nonce", currentNonce)
+	slog.Debug("current pending nonce", "nonce", currentNonce)
 
 	latestNonce, err := c.client.NonceAt(ctx, fromAddress, nil)
 	if err != nil {
 		return fmt.Errorf("failed to get latest nonce: %w", err)
 	}
-	fmt.Println("latest nonce", "nonce", latestNonce)
+	slog.Debug("latest nonce", "nonce", latestNonce)
 
 	if currentNonce <= latestNonce {
-		fmt.Println("no pending transactions to cancel")
+		slog.Info("no pending transactions to cancel")
 		return nil
 	}
 
@@ -245,7 +289,7 @@ func (c *ETHClient) cancelAllPendingTransactions(
 	if err != nil {
 		return fmt.Errorf("failed to get suggested gas price: %w", err)
 	}
-	fmt.Println("suggested gas price", "gas_price", suggestedGasPrice.String())
+	slog.Debug("suggested gas price", "gas_price", suggestedGasPrice.String())
 
 	for nonce := latestNonce; nonce < currentNonce; nonce++ {
 		gasPrice := new(big.Int).Set(suggestedGasPrice)
@@ -255,7 +299,7 @@ func (c *ETHClient) cancelAllPendingTransactions(
 				increase := new(big.Int).Div(gasPrice, big.NewInt(10))
 				gasPrice = gasPrice.Add(gasPrice, increase)
 				gasPrice = gasPrice.Add(gasPrice, big.NewInt(1))
-				fmt.Println("increased gas price for retry", "retry", retry, "gas_price", gasPrice.String())
+				slog.Debug("increased gas price for retry", "retry", retry, "gas_price", gasPrice.String())
 			}
 
 			tx := types.NewTransaction(nonce, fromAddress, big.NewInt(0), 21000, gasPrice, nil)
@@ -267,16 +311,16 @@ func (c *ETHClient) cancelAllPendingTransactions(
 			err = c.client.SendTransaction(ctx, signedTx)
 			if err != nil {
 				if err.Error() == "replacement transaction underpriced" {
-					fmt.Println("underpriced transaction, increasing gas price", "retry", retry+1, "nonce", nonce, "error", err)
+					slog.Warn("underpriced transaction, increasing gas price", "retry", retry+1, "nonce", nonce, "error", err)
 					continue // Try again with a higher gas price
 				}
 				if err.Error() == "already known" {
-					fmt.Println("already known transaction", "retry", retry+1, "nonce", nonce, "error", err)
+					slog.Warn("already known transaction", "retry", retry+1, "nonce", nonce, "error", err)
 					continue // Try again with a higher gas price
 				}
 				return fmt.Errorf("failed to send cancellation transaction for nonce %d: %w", nonce, err)
 			}
-			fmt.Println("sent cancel transaction", "nonce", nonce, "tx_hash", signedTx.Hash().Hex(), "gas_price", gasPrice.String())
+			slog.Info("sent cancel transaction", "nonce", nonce, "tx_hash", signedTx.Hash().Hex(), "gas_price", gasPrice.String())
 			break
 		}
 	}
@@ -0,0 +1,127 @@
+package utils_test
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/primevprotocol/validator-registry/pkg/testutil"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// gasBackend suggests fixed gas prices. Its embedded Backend is nil; only
+// the gas methods are used.
+type gasBackend struct {
+	utils.Backend
+	tip, price int64
+}
+
+func (b gasBackend) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	return big.NewInt(b.tip), nil
+}
+
+func (b gasBackend) SuggestGasPrice(context.Context) (*big.Int, error) {
+	return big.NewInt(b.price), nil
+}
+
+func TestSuggestGasTipCapAndPriceAppliesFloor(t *testing.T) {
+	for _, tt := range []struct {
+		name               string
+		tip, price, minTip int64
+		wantTip, wantPrice int64
+	}{
+		{name: "no floor", tip: 0, price: 10, wantTip: 0, wantPrice: 10},
+		{name: "below floor", tip: 1, price: 10, minTip: 3, wantTip: 3, wantPrice: 12},
+		{name: "above floor", tip: 5, price: 10, minTip: 3, wantTip: 5, wantPrice: 10},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := utils.NewETHClient(gasBackend{tip: tt.tip, price: tt.price})
+			if tt.minTip != 0 {
+				client.MinGasTip = big.NewInt(tt.minTip)
+			}
+			tip, price, err := client.SuggestGasTipCapAndPrice(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tip.Int64() != tt.wantTip || price.Int64() != tt.wantPrice {
+				t.Errorf("got tip %s and price %s, want %d and %d", tip, price, tt.wantTip, tt.wantPrice)
+			}
+		})
+	}
+}
+
+// inclusionBackend returns a receipt only for the tx marked included.
+type inclusionBackend struct {
+	gasBackend
+	mu       sync.Mutex
+	included common.Hash
+}
+
+func (b *inclusionBackend) include(hash common.Hash) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.included = hash
+}
+
+func (b *inclusionBackend) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if hash != b.included {
+		return nil, ethereum.NotFound
+	}
+	return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(1)}, nil
+}
+
+func TestWaitMinedWithRetryBoostsAfterEachWindow(t *testing.T) {
+	backend := &inclusionBackend{gasBackend: gasBackend{tip: 100, price: 1100}}
+	client := utils.NewETHClient(backend)
+	clock := testutil.NewFakeClock(time.Unix(0, 0))
+	client.Clock = clock
+
+	var tips []int64
+	// Only the fourth tx, sent after three 60s windows, is included.
+	submit := func(_ context.Context, opts *bind.TransactOpts) (*types.Transaction, error) {
+		tx := types.NewTx(&types.DynamicFeeTx{Nonce: 1, GasTipCap: opts.GasTipCap, GasFeeCap: opts.GasFeeCap})
+		tips = append(tips, opts.GasTipCap.Int64())
+		if len(tips) == 4 {
+			backend.include(tx.Hash())
+		}
+		return tx, nil
+	}
+	opts := &bind.TransactOpts{GasTipCap: big.NewInt(100), GasFeeCap: big.NewInt(1100)}
+	type result struct {
+		receipt *types.Receipt
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		receipt, err := client.WaitMinedWithRetry(context.Background(), opts, submit)
+		done <- result{receipt, err}
+	}()
+
+	for range 3 {
+		waitForWaiter(t, clock)
+		clock.Advance(60 * time.Second)
+	}
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if len(tips) != 4 {
+		t.Fatalf("submitted %d txs, want 4", len(tips))
+	}
+	for i := 1; i < len(tips); i++ {
+		if tips[i] <= tips[i-1] {
+			t.Errorf("tx %d tip %d not boosted above %d", i, tips[i], tips[i-1])
+		}
+	}
+	if res.receipt.Status != types.ReceiptStatusSuccessful {
+		t.Errorf("got receipt status %d, want success", res.receipt.Status)
+	}
+}
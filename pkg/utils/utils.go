@@ -1,11 +1,15 @@
 package utils
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"math"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/ethclient"
-	vr "github.com/primevprotocol/validator-registry/pkg/validatorregistry"
+	"golang.org/x/sync/errgroup"
 )
 
 func InitClient() *ethclient.Client {
@@ -16,23 +20,85 @@ func InitClient() *ethclient.Client {
 	return client
 }
 
-func GetStakedValidators(vrc *vr.ValidatorregistryCaller, numStakedVals *big.Int, valsetVersion *big.Int) [][]byte {
-	queryBatchSize := 1000
-	aggregatedValset := make([][]byte, 0)
+// StakedValidatorsCaller is the subset of the validator registry caller
+// needed to page through the staked validator set.
+type StakedValidatorsCaller interface {
+	GetStakedValidators(opts *bind.CallOpts, start *big.Int, end *big.Int) ([][]byte, *big.Int, error)
+}
+
+// GetStakedValidatorsOpts tunes how the staked validator set is paged.
+type GetStakedValidatorsOpts struct {
+	// BatchSize is the number of validators fetched per call. Defaults to 1000.
+	BatchSize int
+	// Concurrency is the number of calls in flight at once. Defaults to 1.
+	Concurrency int
+}
+
+func GetStakedValidators(vrc StakedValidatorsCaller, numStakedVals *big.Int, valsetVersion *big.Int) [][]byte {
+	vals, err := GetStakedValidatorsWithOpts(context.Background(), vrc, numStakedVals, valsetVersion, GetStakedValidatorsOpts{})
+	if err != nil {
+		log.Fatalf("Failed to get staked validators: %v", err)
+	}
+	return vals
+}
+
+// GetStakedValidatorsWithOpts fetches the first numStakedVals staked
+// validators in order, failing if any page reports a valset version other
+// than valsetVersion.
+func GetStakedValidatorsWithOpts(
+	ctx context.Context,
+	vrc StakedValidatorsCaller,
+	numStakedVals *big.Int,
+	valsetVersion *big.Int,
+	opts GetStakedValidatorsOpts,
+) ([][]byte, error) {
+	queryBatchSize := opts.BatchSize
+	if queryBatchSize <= 0 {
+		queryBatchSize = 1000
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	if numStakedVals == nil || numStakedVals.Sign() == 0 {
+		return [][]byte{}, nil
+	}
+	if numStakedVals.Sign() < 0 {
+		return nil, fmt.Errorf("invalid staked validator count %v", numStakedVals)
+	}
+	// Pages are indexed with int, so a count beyond it can't be fetched.
+	if !numStakedVals.IsInt64() || numStakedVals.Int64() > math.MaxInt-int64(queryBatchSize) {
+		return nil, fmt.Errorf("staked validator count %v is too large to fetch", numStakedVals)
+	}
 	numStakedValsInt := int(numStakedVals.Int64())
-	for i := 0; i < numStakedValsInt; i += queryBatchSize {
-		end := i + queryBatchSize
-		if end > numStakedValsInt {
-			end = numStakedValsInt
-		}
-		vals, valsetVer, err := vrc.GetStakedValidators(nil, big.NewInt(int64(i)), big.NewInt(int64(end)))
-		if err != nil {
-			log.Fatalf("Failed to get staked validators: %v", err)
-		}
-		if valsetVer.Cmp(valsetVersion) != 0 {
-			log.Fatalf("Valset version mismatch from len query: %v != %v", valsetVer, valsetVersion)
-		}
+	numBatches := (numStakedValsInt + queryBatchSize - 1) / queryBatchSize
+	batches := make([][][]byte, numBatches)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for b := 0; b < numBatches; b++ {
+		start := b * queryBatchSize
+		end := min(start+queryBatchSize, numStakedValsInt)
+		g.Go(func() error {
+			vals, valsetVer, err := vrc.GetStakedValidators(&bind.CallOpts{Context: ctx}, big.NewInt(int64(start)), big.NewInt(int64(end)))
+			if err != nil {
+				return fmt.Errorf("failed to get staked validators %d to %d: %w", start, end, err)
+			}
+			if valsetVer.Cmp(valsetVersion) != 0 {
+				return fmt.Errorf("valset version mismatch from len query: %v != %v", valsetVer, valsetVersion)
+			}
+			batches[b] = vals
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	aggregatedValset := make([][]byte, 0, numStakedValsInt)
+	for _, vals := range batches {
 		aggregatedValset = append(aggregatedValset, vals...)
 	}
-	return aggregatedValset
+	return aggregatedValset, nil
 }
@@ -0,0 +1,122 @@
+package utils_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/primevprotocol/validator-registry/pkg/utils"
+)
+
+// fakeStakedValidators serves numbered pubkeys and reports version as the
+// valset version, recording the pages requested and the most calls seen in
+// flight at once.
+type fakeStakedValidators struct {
+	version int64
+	// started, if not nil, receives a value as each call starts.
+	started chan struct{}
+	// release, if not nil, holds every call until closed.
+	release chan struct{}
+
+	mu          sync.Mutex
+	pages       [][2]int64
+	inFlight    int
+	maxInFlight int
+}
+
+func (f *fakeStakedValidators) GetStakedValidators(_ *bind.CallOpts, start, end *big.Int) ([][]byte, *big.Int, error) {
+	f.mu.Lock()
+	f.pages = append(f.pages, [2]int64{start.Int64(), end.Int64()})
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	f.mu.Unlock()
+	if f.started != nil {
+		f.started <- struct{}{}
+	}
+	if f.release != nil {
+		<-f.release
+	}
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+
+	vals := [][]byte{}
+	for i := start.Int64(); i < end.Int64(); i++ {
+		vals = append(vals, []byte(fmt.Sprint(i)))
+	}
+	return vals, big.NewInt(f.version), nil
+}
+
+func TestGetStakedValidatorsWithOptsPagesInOrder(t *testing.T) {
+	caller := &fakeStakedValidators{version: 7}
+	vals, err := utils.GetStakedValidatorsWithOpts(context.Background(), caller, big.NewInt(25), big.NewInt(7), utils.GetStakedValidatorsOpts{BatchSize: 10, Concurrency: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 25 {
+		t.Fatalf("got %d validators, want 25", len(vals))
+	}
+	for i, val := range vals {
+		if string(val) != fmt.Sprint(i) {
+			t.Fatalf("validator %d is %s, want them in registry order", i, val)
+		}
+	}
+	if len(caller.pages) != 3 {
+		t.Errorf("got pages %v, want 3 pages of at most 10", caller.pages)
+	}
+}
+
+func TestGetStakedValidatorsWithOptsLimitsConcurrency(t *testing.T) {
+	caller := &fakeStakedValidators{version: 1, started: make(chan struct{}, 10), release: make(chan struct{})}
+	done := make(chan error)
+	go func() {
+		_, err := utils.GetStakedValidatorsWithOpts(context.Background(), caller, big.NewInt(100), big.NewInt(1), utils.GetStakedValidatorsOpts{BatchSize: 10, Concurrency: 2})
+		done <- err
+	}()
+	// Let the first two calls block, then release them all.
+	<-caller.started
+	<-caller.started
+	close(caller.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if caller.maxInFlight != 2 {
+		t.Errorf("got up to %d calls in flight, want 2", caller.maxInFlight)
+	}
+}
+
+func TestGetStakedValidatorsWithOptsVersionMismatch(t *testing.T) {
+	_, err := utils.GetStakedValidatorsWithOpts(context.Background(), &fakeStakedValidators{version: 2}, big.NewInt(5), big.NewInt(1), utils.GetStakedValidatorsOpts{})
+	if err == nil || !strings.Contains(err.Error(), "valset version mismatch") {
+		t.Errorf("got error %v, want a valset version mismatch", err)
+	}
+}
+
+func TestGetStakedValidatorsWithOptsCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   *big.Int
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"zero", big.NewInt(0), false},
+		{"negative", big.NewInt(-1), true},
+		{"too large", new(big.Int).Lsh(big.NewInt(1), 64), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller := &fakeStakedValidators{}
+			vals, err := utils.GetStakedValidatorsWithOpts(context.Background(), caller, tt.count, big.NewInt(0), utils.GetStakedValidatorsOpts{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if len(vals) != 0 || len(caller.pages) != 0 {
+				t.Errorf("got %d validators from %d calls, want none", len(vals), len(caller.pages))
+			}
+		})
+	}
+}